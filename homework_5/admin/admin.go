@@ -0,0 +1,261 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// Member 是 /members 返回的节点, 带上路由状态
+type Member struct {
+	consistent.Node
+	Draining bool `json:"draining,omitempty"`
+	Down     bool `json:"down,omitempty"`
+}
+
+// Handler 是环的管理接口, 路径相对于挂载点, 挂在子路径下时用 http.StripPrefix:
+//
+//	GET    /members                列出节点
+//	POST   /members                加入节点, body 是 Node 的 JSON
+//	GET    /members/{id}           查看节点
+//	DELETE /members/{id}           删除节点
+//	PUT    /members/{id}/weight    修改权重, body 是 {"weight": n}
+//	POST   /members/{id}/drain     开始下线, DELETE 取消下线
+//	POST   /members/{id}/complete  删除已经下线的节点
+//	GET    /get?key=k&n=1          查询 key 的节点和环的版本, 带 &version=v 时按保留的旧版本查询
+//	GET    /ownership              区间归属表, ?summary=1 时只返回每个节点的比例
+//	GET    /pins                   列出固定表
+//	PUT    /pins?key=k             固定 key, body 是 {"node": id}, DELETE 取消固定
+//
+// Handler 本身不做鉴权, 需要由外层中间件负责
+type Handler struct {
+	ring *consistent.Consistent
+}
+
+func NewHandler(ring *consistent.Consistent) *Handler {
+	return &Handler{ring: ring}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := "/" + strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "/members":
+		h.members(w, r)
+	case strings.HasPrefix(path, "/members/"):
+		h.member(w, r, strings.Split(strings.TrimPrefix(path, "/members/"), "/"))
+	case path == "/get":
+		h.get(w, r)
+	case path == "/ownership":
+		h.ownership(w, r)
+	case path == "/pins":
+		h.pins(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "unknown path "+path)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func (h *Handler) describe(node consistent.Node) Member {
+	return Member{Node: node, Draining: h.ring.IsDraining(node.Id), Down: h.ring.IsDown(node.Id)}
+}
+
+func (h *Handler) lookup(id int) (consistent.Node, bool) {
+	for _, node := range h.ring.Members() {
+		if node.Id == id {
+			return node, true
+		}
+	}
+	return consistent.Node{}, false
+}
+
+func (h *Handler) members(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		nodes := h.ring.Members()
+		members := make([]Member, len(nodes))
+		for i, node := range nodes {
+			members[i] = h.describe(node)
+		}
+		writeJSON(w, http.StatusOK, members)
+	case http.MethodPost:
+		var node consistent.Node
+		if err := json.NewDecoder(r.Body).Decode(&node); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if node.Weight <= 0 && node.Capacity <= 0 && node.Replicas <= 0 {
+			writeError(w, http.StatusBadRequest, "weight, capacity or replicas must be positive")
+			return
+		}
+
+		if !h.ring.Add(&node) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("node %d already exists", node.Id))
+			return
+		}
+		writeJSON(w, http.StatusCreated, h.describe(node))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "use GET or POST")
+	}
+}
+
+func (h *Handler) member(w http.ResponseWriter, r *http.Request, parts []string) {
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) > 2 {
+		writeError(w, http.StatusBadRequest, "bad node id")
+		return
+	}
+
+	node, ok := h.lookup(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("node %d not found", id))
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, h.describe(node))
+	case action == "" && r.Method == http.MethodDelete:
+		h.ring.RemoveByID(id)
+		w.WriteHeader(http.StatusNoContent)
+	case action == "weight" && r.Method == http.MethodPut:
+		var body struct {
+			Weight int `json:"weight"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if body.Weight <= 0 {
+			writeError(w, http.StatusBadRequest, "weight must be positive")
+			return
+		}
+		if !h.ring.UpdateWeight(id, body.Weight) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("node %d not found", id))
+			return
+		}
+		node.Weight = body.Weight
+		writeJSON(w, http.StatusOK, h.describe(node))
+	case action == "drain" && r.Method == http.MethodPost:
+		h.ring.Drain(id)
+		writeJSON(w, http.StatusOK, h.describe(node))
+	case action == "drain" && r.Method == http.MethodDelete:
+		h.ring.Undrain(id)
+		writeJSON(w, http.StatusOK, h.describe(node))
+	case action == "complete" && r.Method == http.MethodPost:
+		if !h.ring.CompleteDrain(id) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("node %d is not draining", id))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path))
+	}
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, "missing key")
+		return
+	}
+
+	n := 1
+	if s := r.URL.Query().Get("n"); s != "" {
+		var err error
+		if n, err = strconv.Atoi(s); err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "bad n")
+			return
+		}
+	}
+
+	view := h.ring.Current()
+	if s := r.URL.Query().Get("version"); s != "" {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad version")
+			return
+		}
+		if view, err = h.ring.AtVersion(v); err != nil {
+			writeError(w, http.StatusGone, err.Error())
+			return
+		}
+	}
+
+	nodes := view.GetN(key, n)
+	if len(nodes) == 0 {
+		writeError(w, http.StatusServiceUnavailable, "no routable node")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"key": key, "nodes": nodes, "version": view.Version()})
+}
+
+func (h *Handler) ownership(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	if r.URL.Query().Get("summary") != "" {
+		writeJSON(w, http.StatusOK, h.ring.Ownership())
+		return
+	}
+	writeJSON(w, http.StatusOK, h.ring.OwnershipTable())
+}
+
+// pins 的 key 放在查询参数里, key 可以包含 /
+func (h *Handler) pins(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if r.Method != http.MethodGet && key == "" {
+		writeError(w, http.StatusBadRequest, "missing key")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, h.ring.Pins())
+	case http.MethodPut:
+		var body struct {
+			Node int `json:"node"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !h.ring.Pin(key, body.Node) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("node %d not found", body.Node))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"key": key, "node": body.Node})
+	case http.MethodDelete:
+		if !h.ring.Unpin(key) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("key %q is not pinned", key))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "use GET, PUT or DELETE")
+	}
+}
@@ -0,0 +1,154 @@
+package affinity
+
+import (
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_TTL = 10 * time.Minute
+)
+
+type entry struct {
+	node     consistent.Node
+	lastSeen time.Time
+}
+
+type Stats struct {
+	Entries int `json:"entries"`
+	Hits    int `json:"hits"`
+	Misses  int `json:"misses"`
+	// Pinned 是环上的 owner 已经变了但仍然返回记住的节点的次数
+	Pinned  int `json:"pinned"`
+	Rehomed int `json:"rehomed"`
+	Expired int `json:"expired"`
+}
+
+// Table 记住 key 第一次分配到的节点, 在 TTL 内一直返回它, 即使环的成员变化让 owner 变了.
+// 只有过期或者记住的节点不在环上了才会重新分配, 适合会话迁移代价很高的协议.
+// TTL 从最后一次访问开始计算.
+type Table struct {
+	sync.Mutex
+	ring    *consistent.Consistent
+	ttl     time.Duration
+	entries map[string]*entry
+	stats   Stats
+	now     func() time.Time
+}
+
+func NewTable(ring *consistent.Consistent, ttl time.Duration) *Table {
+	if ttl <= 0 {
+		ttl = DEFAULT_TTL
+	}
+	return &Table{ring: ring, ttl: ttl, entries: make(map[string]*entry), now: time.Now}
+}
+
+func (t *Table) member(id int) (consistent.Node, bool) {
+	for _, node := range t.ring.Members() {
+		if node.Id == id {
+			return node, true
+		}
+	}
+	return consistent.Node{}, false
+}
+
+func (t *Table) Get(key string) (consistent.Node, error) {
+	owners := t.ring.GetN(key, 1)
+	if len(owners) == 0 {
+		return consistent.Node{}, consistent.ErrEmptyRing
+	}
+	owner := owners[0]
+
+	t.Lock()
+	defer t.Unlock()
+
+	now := t.now()
+	e, ok := t.entries[key]
+	if ok && now.Sub(e.lastSeen) > t.ttl {
+		t.stats.Expired++
+		ok = false
+	}
+
+	if ok {
+		if e.node.Id == owner.Id {
+			t.stats.Hits++
+			e.node, e.lastSeen = owner, now
+			return owner, nil
+		}
+
+		// owner 变了, 只有记住的节点还在环上才继续使用
+		if node, alive := t.member(e.node.Id); alive {
+			t.stats.Hits++
+			t.stats.Pinned++
+			e.node, e.lastSeen = node, now
+			return node, nil
+		}
+		t.stats.Rehomed++
+	} else {
+		t.stats.Misses++
+	}
+
+	t.entries[key] = &entry{node: owner, lastSeen: now}
+	return owner, nil
+}
+
+// Lookup 只查询记住的节点, 不分配也不刷新 TTL
+func (t *Table) Lookup(key string) (consistent.Node, bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok || t.now().Sub(e.lastSeen) > t.ttl {
+		return consistent.Node{}, false
+	}
+	return e.node, true
+}
+
+func (t *Table) Forget(key string) {
+	t.Lock()
+	defer t.Unlock()
+
+	delete(t.entries, key)
+}
+
+// Evict 在节点宕机时立即删除它的所有会话, 返回删除的数量
+func (t *Table) Evict(id int) int {
+	t.Lock()
+	defer t.Unlock()
+
+	n := 0
+	for key, e := range t.entries {
+		if e.node.Id == id {
+			delete(t.entries, key)
+			n++
+		}
+	}
+	return n
+}
+
+// Sweep 删除过期的记录, 由调用方定期执行
+func (t *Table) Sweep() int {
+	t.Lock()
+	defer t.Unlock()
+
+	now, n := t.now(), 0
+	for key, e := range t.entries {
+		if now.Sub(e.lastSeen) > t.ttl {
+			delete(t.entries, key)
+			n++
+		}
+	}
+	t.stats.Expired += n
+	return n
+}
+
+func (t *Table) Stats() Stats {
+	t.Lock()
+	defer t.Unlock()
+
+	s := t.stats
+	s.Entries = len(t.entries)
+	return s
+}
@@ -0,0 +1,78 @@
+package asg
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+type SQSAPI interface {
+	ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, in *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+}
+
+type AutoScalingAPI interface {
+	CompleteLifecycleAction(ctx context.Context, in *autoscaling.CompleteLifecycleActionInput, optFns ...func(*autoscaling.Options)) (*autoscaling.CompleteLifecycleActionOutput, error)
+	RecordLifecycleActionHeartbeat(ctx context.Context, in *autoscaling.RecordLifecycleActionHeartbeatInput, optFns ...func(*autoscaling.Options)) (*autoscaling.RecordLifecycleActionHeartbeatOutput, error)
+}
+
+type SQSQueue struct {
+	Client   SQSAPI
+	QueueUrl string
+}
+
+func (q *SQSQueue) Receive(ctx context.Context) ([]Message, error) {
+	out, err := q.Client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(q.QueueUrl),
+		MaxNumberOfMessages: 10,
+		WaitTimeSeconds:     20,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]Message, 0, len(out.Messages))
+	for _, m := range out.Messages {
+		msgs = append(msgs, Message{
+			Body:   aws.ToString(m.Body),
+			Handle: aws.ToString(m.ReceiptHandle),
+		})
+	}
+
+	return msgs, nil
+}
+
+func (q *SQSQueue) Delete(ctx context.Context, m Message) error {
+	_, err := q.Client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(q.QueueUrl),
+		ReceiptHandle: aws.String(m.Handle),
+	})
+	return err
+}
+
+type AutoScalingLifecycle struct {
+	Client AutoScalingAPI
+}
+
+func (l *AutoScalingLifecycle) Complete(ctx context.Context, a *Action, result string) error {
+	_, err := l.Client.CompleteLifecycleAction(ctx, &autoscaling.CompleteLifecycleActionInput{
+		AutoScalingGroupName:  aws.String(a.AutoScalingGroupName),
+		LifecycleHookName:     aws.String(a.LifecycleHookName),
+		LifecycleActionToken:  aws.String(a.LifecycleActionToken),
+		LifecycleActionResult: aws.String(result),
+		InstanceId:            aws.String(a.InstanceId),
+	})
+	return err
+}
+
+func (l *AutoScalingLifecycle) Heartbeat(ctx context.Context, a *Action) error {
+	_, err := l.Client.RecordLifecycleActionHeartbeat(ctx, &autoscaling.RecordLifecycleActionHeartbeatInput{
+		AutoScalingGroupName: aws.String(a.AutoScalingGroupName),
+		LifecycleHookName:    aws.String(a.LifecycleHookName),
+		LifecycleActionToken: aws.String(a.LifecycleActionToken),
+		InstanceId:           aws.String(a.InstanceId),
+	})
+	return err
+}
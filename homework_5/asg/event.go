@@ -0,0 +1,84 @@
+package asg
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const (
+	TRANSITION_LAUNCHING   = "autoscaling:EC2_INSTANCE_LAUNCHING"
+	TRANSITION_TERMINATING = "autoscaling:EC2_INSTANCE_TERMINATING"
+	TRANSITION_TEST        = "autoscaling:TEST_NOTIFICATION"
+
+	RESULT_CONTINUE = "CONTINUE"
+	RESULT_ABANDON  = "ABANDON"
+
+	DETAIL_STATE_CHANGE = "EC2 Instance State-change Notification"
+)
+
+var ErrUnknownEvent = errors.New("asg: unknown event")
+
+// Action 是一次生命周期钩子, 完成时需要原样带回这些字段
+type Action struct {
+	AutoScalingGroupName string `json:"AutoScalingGroupName"`
+	LifecycleHookName    string `json:"LifecycleHookName"`
+	LifecycleTransition  string `json:"LifecycleTransition"`
+	LifecycleActionToken string `json:"LifecycleActionToken"`
+	InstanceId           string `json:"EC2InstanceId"`
+}
+
+// Event 是解析后的 SQS 消息, 生命周期钩子和 EC2 状态变化二选一
+type Event struct {
+	Action *Action
+	// 实例状态变化 (比如 terminated) 时只有实例 ID 和状态
+	InstanceId string
+	State      string
+}
+
+type eventBridge struct {
+	DetailType string          `json:"detail-type"`
+	Source     string          `json:"source"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+type stateChange struct {
+	InstanceId string `json:"instance-id"`
+	State      string `json:"state"`
+}
+
+// ParseEvent 同时支持 ASG 直接投递到 SQS 的通知和经过 EventBridge 转发的事件
+func ParseEvent(body []byte) (*Event, error) {
+	var eb eventBridge
+	if err := json.Unmarshal(body, &eb); err != nil {
+		return nil, err
+	}
+
+	if eb.DetailType == "" {
+		var a Action
+		if err := json.Unmarshal(body, &a); err != nil {
+			return nil, err
+		}
+		if a.LifecycleTransition == "" {
+			return nil, ErrUnknownEvent
+		}
+		return &Event{Action: &a}, nil
+	}
+
+	if eb.DetailType == DETAIL_STATE_CHANGE {
+		var sc stateChange
+		if err := json.Unmarshal(eb.Detail, &sc); err != nil {
+			return nil, err
+		}
+		return &Event{InstanceId: sc.InstanceId, State: sc.State}, nil
+	}
+
+	var a Action
+	if err := json.Unmarshal(eb.Detail, &a); err != nil {
+		return nil, err
+	}
+	if a.LifecycleTransition == "" {
+		return nil, ErrUnknownEvent
+	}
+
+	return &Event{Action: &a}, nil
+}
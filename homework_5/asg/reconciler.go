@@ -0,0 +1,210 @@
+package asg
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_HEARTBEAT_INTERVAL = time.Minute
+)
+
+type Message struct {
+	Body   string
+	Handle string
+}
+
+type Queue interface {
+	Receive(ctx context.Context) ([]Message, error)
+	Delete(ctx context.Context, m Message) error
+}
+
+type Lifecycle interface {
+	Complete(ctx context.Context, a *Action, result string) error
+	Heartbeat(ctx context.Context, a *Action) error
+}
+
+// Resolver 根据实例 ID 构造环上的节点 (IP, 端口, 权重等)
+type Resolver func(ctx context.Context, instanceId string) (*consistent.Node, error)
+
+// Drainer 在节点离开环之前把它负责的数据迁走, 返回 nil 之前生命周期动作不会被完成
+type Drainer func(ctx context.Context, node consistent.Node) error
+
+type Reconciler struct {
+	sync.Mutex
+	Ring              *consistent.Consistent
+	Queue             Queue
+	Lifecycle         Lifecycle
+	Resolve           Resolver
+	Drain             Drainer
+	HeartbeatInterval time.Duration
+	// Logger 为空时使用环的 Logger, 接收、处理消息和心跳失败时输出一条日志
+	Logger    consistent.Logger
+	instances map[string]consistent.Node
+}
+
+func NewReconciler(ring *consistent.Consistent, queue Queue, lifecycle Lifecycle, resolve Resolver) *Reconciler {
+	return &Reconciler{
+		Ring:              ring,
+		Queue:             queue,
+		Lifecycle:         lifecycle,
+		Resolve:           resolve,
+		HeartbeatInterval: DEFAULT_HEARTBEAT_INTERVAL,
+		instances:         make(map[string]consistent.Node),
+	}
+}
+
+func (r *Reconciler) logger() consistent.Logger {
+	if r.Logger != nil {
+		return r.Logger
+	}
+	return r.Ring.Logger()
+}
+
+// Run 一直消费队列直到 ctx 结束, 处理失败的消息不删除, 等可见性超时后重试;
+// 解析不了的消息重试也不会成功, 输出一条日志后删除
+func (r *Reconciler) Run(ctx context.Context) error {
+	for {
+		msgs, err := r.Queue.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			r.logger().Warn("asg receive failed", "error", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, m := range msgs {
+			e, err := ParseEvent([]byte(m.Body))
+			if err != nil && err != ErrUnknownEvent {
+				r.logger().Warn("asg bad message dropped", "message", m.Handle, "error", err)
+				r.Queue.Delete(ctx, m)
+				continue
+			}
+			if err == nil {
+				err = r.Handle(ctx, e)
+			}
+
+			if err == ErrUnknownEvent || err == nil {
+				r.Queue.Delete(ctx, m)
+				continue
+			}
+			r.logger().Warn("asg handle failed", "message", m.Handle, "error", err)
+		}
+	}
+}
+
+func (r *Reconciler) Handle(ctx context.Context, e *Event) error {
+	if e.Action == nil {
+		if e.State == "shutting-down" || e.State == "terminated" || e.State == "stopped" {
+			r.remove(e.InstanceId)
+		}
+		return nil
+	}
+
+	switch e.Action.LifecycleTransition {
+	case TRANSITION_LAUNCHING:
+		return r.launch(ctx, e.Action)
+	case TRANSITION_TERMINATING:
+		return r.terminate(ctx, e.Action)
+	case TRANSITION_TEST:
+		return nil
+	}
+
+	return ErrUnknownEvent
+}
+
+func (r *Reconciler) launch(ctx context.Context, a *Action) error {
+	node, err := r.Resolve(ctx, a.InstanceId)
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	if _, ok := r.instances[a.InstanceId]; !ok && r.Ring.Add(node) {
+		r.instances[a.InstanceId] = *node
+	}
+	r.Unlock()
+
+	return r.Lifecycle.Complete(ctx, a, RESULT_CONTINUE)
+}
+
+func (r *Reconciler) terminate(ctx context.Context, a *Action) error {
+	r.Lock()
+	node, ok := r.instances[a.InstanceId]
+	r.Unlock()
+
+	// 先在环上 Drain, 新的 key 不再落到这个节点, 再让 Drainer 迁走已有的数据;
+	// Drainer 失败时节点保持 draining, 消息重新投递后再试
+	if ok {
+		r.Ring.Drain(node.Id)
+		if r.Drain != nil {
+			if err := r.drain(ctx, a, node); err != nil {
+				return err
+			}
+		}
+	}
+
+	r.remove(a.InstanceId)
+	return r.Lifecycle.Complete(ctx, a, RESULT_CONTINUE)
+}
+
+// drain 期间定时发心跳, 防止钩子超时后 ASG 直接终止实例
+func (r *Reconciler) drain(ctx context.Context, a *Action, node consistent.Node) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Drain(ctx, node)
+	}()
+
+	ticker := time.NewTicker(r.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			if err := r.Lifecycle.Heartbeat(ctx, a); err != nil {
+				r.logger().Warn("asg heartbeat failed", "instance", a.InstanceId, "error", err)
+			}
+		}
+	}
+}
+
+func (r *Reconciler) remove(instanceId string) {
+	r.Lock()
+	defer r.Unlock()
+
+	node, ok := r.instances[instanceId]
+	if !ok {
+		return
+	}
+
+	// draining 的节点用 CompleteDrain 删除, 不会再移动 key
+	if !r.Ring.CompleteDrain(node.Id) {
+		r.Ring.Remove(&node)
+	}
+	delete(r.instances, instanceId)
+}
+
+// Track 把启动前就已存在的实例登记进来, 之后的终止事件才能找到对应节点
+func (r *Reconciler) Track(instanceId string, node *consistent.Node) {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.instances[instanceId]; ok {
+		return
+	}
+
+	if r.Ring.Add(node) {
+		r.instances[instanceId] = *node
+	}
+}
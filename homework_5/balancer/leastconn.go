@@ -0,0 +1,109 @@
+package balancer
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// inflight 记录每个节点正在处理的请求数, 按 inflight/weight 比较负载
+type inflight struct {
+	sync.Mutex
+	counts map[int]int
+}
+
+func (f *inflight) load(node consistent.Node) float64 {
+	return float64(f.counts[node.Id]+1) / float64(node.Weight)
+}
+
+func (f *inflight) acquire(node consistent.Node) consistent.Node {
+	f.counts[node.Id]++
+	return node
+}
+
+func (f *inflight) Done(node consistent.Node) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.counts[node.Id] > 1 {
+		f.counts[node.Id]--
+	} else {
+		delete(f.counts, node.Id)
+	}
+}
+
+// Inflight 返回节点当前未完成的请求数
+func (f *inflight) Inflight(id int) int {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.counts[id]
+}
+
+// LeastConn 选择 inflight/weight 最小的节点, 相同时取 Id 小的
+type LeastConn struct {
+	inflight
+	members *Members
+}
+
+func NewLeastConn(m *Members) *LeastConn {
+	return &LeastConn{inflight: inflight{counts: make(map[int]int)}, members: m}
+}
+
+func (l *LeastConn) Pick() (consistent.Node, error) {
+	nodes, _ := l.members.Available()
+	if len(nodes) == 0 {
+		return consistent.Node{}, ErrNoNodes
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	best := nodes[0]
+	for _, node := range nodes[1:] {
+		if l.load(node) < l.load(best) {
+			best = node
+		}
+	}
+
+	return l.acquire(best), nil
+}
+
+// P2C 随机取两个节点, 选负载低的一个, 比 LeastConn 少一次全量扫描, 也不会让所有客户端同时涌向同一个最空闲的节点
+type P2C struct {
+	inflight
+	members *Members
+	rnd     *rand.Rand
+}
+
+func NewP2C(m *Members, seed int64) *P2C {
+	return &P2C{inflight: inflight{counts: make(map[int]int)}, members: m, rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (p *P2C) Pick() (consistent.Node, error) {
+	nodes, _ := p.members.Available()
+	if len(nodes) == 0 {
+		return consistent.Node{}, ErrNoNodes
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if len(nodes) == 1 {
+		return p.acquire(nodes[0]), nil
+	}
+
+	i := p.rnd.Intn(len(nodes))
+	j := p.rnd.Intn(len(nodes) - 1)
+	if j >= i {
+		j++
+	}
+
+	a, b := nodes[i], nodes[j]
+	if p.load(b) < p.load(a) {
+		a = b
+	}
+
+	return p.acquire(a), nil
+}
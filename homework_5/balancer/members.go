@@ -0,0 +1,96 @@
+package balancer
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	WEIGHT_SCALE = 100
+)
+
+// Members 是所有 Picker 共用的成员列表, 可以直接从一致性哈希环同步, 让哈希路由和 RR 用同一份节点
+type Members struct {
+	sync.RWMutex
+	nodes   []consistent.Node
+	down    map[int]bool
+	version uint64
+	scale   func(id int) float64
+}
+
+func NewMembers(nodes ...consistent.Node) *Members {
+	m := &Members{down: make(map[int]bool)}
+	m.Set(nodes)
+	return m
+}
+
+// Set 替换全部节点, 已有的故障标记会保留
+func (m *Members) Set(nodes []consistent.Node) {
+	sorted := make([]consistent.Node, len(nodes))
+	copy(sorted, nodes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Id < sorted[j].Id
+	})
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.nodes = sorted
+	m.version++
+}
+
+func (m *Members) Sync(c *consistent.Consistent) {
+	m.Set(c.Members())
+}
+
+func (m *Members) MarkDown(id int) {
+	m.Lock()
+	defer m.Unlock()
+
+	if !m.down[id] {
+		m.down[id] = true
+		m.version++
+	}
+}
+
+func (m *Members) MarkUp(id int) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.down[id] {
+		delete(m.down, id)
+		m.version++
+	}
+}
+
+// SetScale 设置动态的权重系数, 例如 pressure.Tracker.Factor; 设置之后 Available 返回的权重放大 WEIGHT_SCALE 倍再乘以系数
+func (m *Members) SetScale(scale func(id int) float64) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.scale = scale
+	m.version++
+}
+
+// Available 按 Id 排序返回健康且权重大于 0 的节点, 以及当前的版本号
+func (m *Members) Available() ([]consistent.Node, uint64) {
+	m.RLock()
+	defer m.RUnlock()
+
+	nodes := make([]consistent.Node, 0, len(m.nodes))
+	for _, node := range m.nodes {
+		if node.Weight <= 0 || m.down[node.Id] {
+			continue
+		}
+		if m.scale != nil {
+			if node.Weight = int(float64(node.Weight*WEIGHT_SCALE) * m.scale(node.Id)); node.Weight < 1 {
+				node.Weight = 1
+			}
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, m.version
+}
@@ -0,0 +1,147 @@
+package balancer
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	ROUND_ROBIN          = "round_robin"
+	WEIGHTED_ROUND_ROBIN = "weighted_round_robin"
+	RANDOM               = "random"
+	LEAST_CONN           = "least_conn"
+	POWER_OF_TWO         = "p2c"
+)
+
+var ErrNoNodes = errors.New("balancer: no available nodes")
+
+// Picker 为无状态请求选择节点, 请求结束后调用 Done; 不关心并发数的实现里 Done 什么都不做
+type Picker interface {
+	Pick() (consistent.Node, error)
+	Done(node consistent.Node)
+}
+
+// New 按名称创建 Picker, seed 只对 random 和 p2c 有效
+func New(name string, m *Members, seed int64) (Picker, error) {
+	switch name {
+	case ROUND_ROBIN:
+		return NewRoundRobin(m), nil
+	case WEIGHTED_ROUND_ROBIN:
+		return NewWeightedRoundRobin(m), nil
+	case RANDOM:
+		return NewRandom(m, seed), nil
+	case LEAST_CONN:
+		return NewLeastConn(m), nil
+	case POWER_OF_TWO:
+		return NewP2C(m, seed), nil
+	}
+
+	return nil, fmt.Errorf("balancer: unknown picker %q", name)
+}
+
+// RoundRobin 忽略权重, 依次轮询可用节点
+type RoundRobin struct {
+	members *Members
+	next    uint64
+}
+
+func NewRoundRobin(m *Members) *RoundRobin {
+	return &RoundRobin{members: m}
+}
+
+func (r *RoundRobin) Pick() (consistent.Node, error) {
+	nodes, _ := r.members.Available()
+	if len(nodes) == 0 {
+		return consistent.Node{}, ErrNoNodes
+	}
+
+	i := atomic.AddUint64(&r.next, 1) - 1
+	return nodes[i%uint64(len(nodes))], nil
+}
+
+func (r *RoundRobin) Done(node consistent.Node) {}
+
+// WeightedRoundRobin 是 nginx 的平滑加权轮询, 权重高的节点不会连续被选中
+type WeightedRoundRobin struct {
+	sync.Mutex
+	members *Members
+	version uint64
+	current map[int]int
+}
+
+func NewWeightedRoundRobin(m *Members) *WeightedRoundRobin {
+	return &WeightedRoundRobin{members: m, current: make(map[int]int)}
+}
+
+func (w *WeightedRoundRobin) Pick() (consistent.Node, error) {
+	nodes, version := w.members.Available()
+	if len(nodes) == 0 {
+		return consistent.Node{}, ErrNoNodes
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	// 成员变化后重新开始, 避免已删除节点的累计值影响结果
+	if version != w.version {
+		w.version = version
+		w.current = make(map[int]int, len(nodes))
+	}
+
+	total, best := 0, -1
+	for i, node := range nodes {
+		w.current[node.Id] += node.Weight
+		total += node.Weight
+		if best < 0 || w.current[node.Id] > w.current[nodes[best].Id] {
+			best = i
+		}
+	}
+
+	w.current[nodes[best].Id] -= total
+	return nodes[best], nil
+}
+
+func (w *WeightedRoundRobin) Done(node consistent.Node) {}
+
+// Random 按权重随机选择
+type Random struct {
+	sync.Mutex
+	members *Members
+	rnd     *rand.Rand
+}
+
+func NewRandom(m *Members, seed int64) *Random {
+	return &Random{members: m, rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (r *Random) Pick() (consistent.Node, error) {
+	nodes, _ := r.members.Available()
+	if len(nodes) == 0 {
+		return consistent.Node{}, ErrNoNodes
+	}
+
+	total := 0
+	for _, node := range nodes {
+		total += node.Weight
+	}
+
+	r.Lock()
+	n := r.rnd.Intn(total)
+	r.Unlock()
+
+	for _, node := range nodes {
+		if n < node.Weight {
+			return node, nil
+		}
+		n -= node.Weight
+	}
+
+	return nodes[len(nodes)-1], nil
+}
+
+func (r *Random) Done(node consistent.Node) {}
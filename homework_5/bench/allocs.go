@@ -0,0 +1,65 @@
+package bench
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// AllocResult 是 Consistent 不同查找入口的每次分配次数和耗时, key 的 []byte 和哈希值在计时之前准备好
+type AllocResult struct {
+	Name        string  `json:"name"`
+	AllocsPerOp float64 `json:"allocs_per_op"`
+	NsPerOp     float64 `json:"ns_per_op"`
+}
+
+func LookupAllocs(nodes []consistent.Node, sample []string) ([]AllocResult, error) {
+	if len(nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	c := consistent.NewConsistent()
+	for i := range nodes {
+		node := nodes[i]
+		c.Add(&node)
+	}
+
+	keys := make([][]byte, len(sample))
+	hashes := make([]uint64, len(sample))
+	for i, key := range sample {
+		keys[i] = []byte(key)
+		hashes[i] = c.Hasher().Hash(keys[i])
+	}
+
+	lookups := []struct {
+		name string
+		get  func(i int)
+	}{
+		{"Get", func(i int) { c.Get(sample[i]) }},
+		{"GetBytes", func(i int) { c.GetBytes(keys[i]) }},
+		{"GetUint64", func(i int) { c.GetUint64(hashes[i]) }},
+	}
+
+	results := make([]AllocResult, 0, len(lookups))
+	for _, l := range lookups {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		start := time.Now()
+		for i := range sample {
+			l.get(i)
+		}
+		elapsed := time.Since(start)
+
+		runtime.ReadMemStats(&after)
+		results = append(results, AllocResult{
+			Name:        l.name,
+			AllocsPerOp: float64(after.Mallocs-before.Mallocs) / float64(len(sample)),
+			NsPerOp:     float64(elapsed.Nanoseconds()) / float64(len(sample)),
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,56 @@
+package bench
+
+import (
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// BatchResult 是一整批 key 的平均每个 key 的耗时
+type BatchResult struct {
+	Name     string  `json:"name"`
+	Keys     int     `json:"keys"`
+	NsPerKey float64 `json:"ns_per_key"`
+}
+
+// Batch 对比循环调用 Get、GetMany 和 GroupByNode 查完同一批 key 的耗时
+func Batch(nodes []consistent.Node, sample []string) ([]BatchResult, error) {
+	if len(nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	c := consistent.NewConsistent()
+	for i := range nodes {
+		node := nodes[i]
+		c.Add(&node)
+	}
+
+	lookups := []struct {
+		name string
+		run  func()
+	}{
+		{"Get", func() {
+			owners := make([]consistent.Node, len(sample))
+			for i, key := range sample {
+				owners[i], _ = c.Get(key)
+			}
+		}},
+		{"GetMany", func() { c.GetMany(sample) }},
+		{"GroupByNode", func() { c.GroupByNode(sample) }},
+	}
+
+	results := make([]BatchResult, 0, len(lookups))
+	for _, l := range lookups {
+		start := time.Now()
+		l.run()
+		elapsed := time.Since(start)
+
+		results = append(results, BatchResult{
+			Name:     l.name,
+			Keys:     len(sample),
+			NsPerKey: float64(elapsed.Nanoseconds()) / float64(len(sample)),
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,209 @@
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+	"github.com/axiusilihao/geek_homework/homework_5/stats"
+)
+
+var ErrNoNodes = errors.New("bench: no nodes")
+
+type Latency struct {
+	P50 float64 `json:"p50_ns"`
+	P90 float64 `json:"p90_ns"`
+	P99 float64 `json:"p99_ns"`
+	Max float64 `json:"max_ns"`
+}
+
+type Result struct {
+	Name          string  `json:"name"`
+	LookupsPerSec float64 `json:"lookups_per_sec"`
+	Latency       Latency `json:"latency"`
+	AllocsPerOp   float64 `json:"allocs_per_op"`
+	BytesPerOp    float64 `json:"bytes_per_op"`
+	Bytes         uint64  `json:"bytes"`
+	StdDev        float64 `json:"stddev"`
+	PeakToMean    float64 `json:"peak_to_mean"`
+	MovedOnAdd    float64 `json:"moved_on_add"`
+	MovedOnRemove float64 `json:"moved_on_remove"`
+}
+
+func Key(i int) string {
+	return fmt.Sprintf("key%d", i)
+}
+
+// Run 用同一组节点和 key 跑每个算法; 移动比例分别是加一个节点和去掉 Id 最大的节点之后换了 owner 的 key 占比
+func Run(nodes []consistent.Node, src keygen.Source, names []string) ([]Result, error) {
+	if len(nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	sample, err := src.Sample()
+	if err != nil {
+		return nil, err
+	}
+
+	added, removed := neighbours(nodes)
+	results := make([]Result, 0, len(names))
+
+	for _, name := range names {
+		build, ok := Builders[name]
+		if !ok {
+			return nil, fmt.Errorf("bench: unknown strategy %q", name)
+		}
+
+		s, bytes, err := measureBuild(build, nodes)
+		if err != nil {
+			return nil, err
+		}
+		onAdd, err := build(added)
+		if err != nil {
+			return nil, err
+		}
+
+		owners, elapsed, latency, mem := lookup(s, sample)
+
+		stddev, peak := balance(owners, nodes)
+		r := Result{
+			Name:          name,
+			LookupsPerSec: float64(len(sample)) / elapsed.Seconds(),
+			Latency:       latency,
+			AllocsPerOp:   float64(mem.Mallocs) / float64(len(sample)),
+			BytesPerOp:    float64(mem.TotalAlloc) / float64(len(sample)),
+			Bytes:         bytes,
+			StdDev:        stddev,
+			PeakToMean:    peak,
+			MovedOnAdd:    moved(owners, onAdd, sample),
+		}
+		if len(removed) > 0 {
+			onRemove, err := build(removed)
+			if err != nil {
+				return nil, err
+			}
+			r.MovedOnRemove = moved(owners, onRemove, sample)
+		}
+
+		results = append(results, r)
+	}
+
+	return results, nil
+}
+
+const (
+	LATENCY_BATCH = 64
+)
+
+// lookup 按 LATENCY_BATCH 个 key 一批计时, 单次调用 time.Now 的开销比一次查找还大,
+// 所以延迟分位数是每批的平均值的分位数
+func lookup(s Strategy, sample []string) ([]int, time.Duration, Latency, runtime.MemStats) {
+	owners := make([]int, len(sample))
+	batches := make([]float64, 0, len(sample)/LATENCY_BATCH+1)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	for i := 0; i < len(sample); i += LATENCY_BATCH {
+		end := i + LATENCY_BATCH
+		if end > len(sample) {
+			end = len(sample)
+		}
+
+		t := time.Now()
+		for j := i; j < end; j++ {
+			owners[j] = s.Get(sample[j])
+		}
+		batches = append(batches, float64(time.Since(t).Nanoseconds())/float64(end-i))
+	}
+	elapsed := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+	mem := runtime.MemStats{
+		Mallocs:    after.Mallocs - before.Mallocs,
+		TotalAlloc: after.TotalAlloc - before.TotalAlloc,
+	}
+
+	sort.Float64s(batches)
+	latency := Latency{
+		P50: stats.Percentile(batches, 0.50),
+		P90: stats.Percentile(batches, 0.90),
+		P99: stats.Percentile(batches, 0.99),
+		Max: stats.Percentile(batches, 1),
+	}
+
+	return owners, elapsed, latency, mem
+}
+
+func measureBuild(build Builder, nodes []consistent.Node) (Strategy, uint64, error) {
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	s, err := build(nodes)
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	runtime.KeepAlive(s)
+	if err != nil {
+		return nil, 0, err
+	}
+	if after.HeapAlloc < before.HeapAlloc {
+		return s, 0, nil
+	}
+	return s, after.HeapAlloc - before.HeapAlloc, nil
+}
+
+func neighbours(nodes []consistent.Node) ([]consistent.Node, []consistent.Node) {
+	maxIdx := 0
+	for i, node := range nodes {
+		if node.Id > nodes[maxIdx].Id {
+			maxIdx = i
+		}
+	}
+
+	last := nodes[maxIdx]
+	extra := consistent.NewNode(last.Id+1, last.Ip+"-new", last.Port, "bench_new", 1)
+	added := append(append([]consistent.Node(nil), nodes...), *extra)
+
+	removed := make([]consistent.Node, 0, len(nodes)-1)
+	removed = append(removed, nodes[:maxIdx]...)
+	removed = append(removed, nodes[maxIdx+1:]...)
+
+	return added, removed
+}
+
+func moved(owners []int, s Strategy, sample []string) float64 {
+	n := 0
+	for i, key := range sample {
+		if s.Get(key) != owners[i] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(sample))
+}
+
+func balance(owners []int, nodes []consistent.Node) (float64, float64) {
+	counts := make(map[int]int, len(nodes))
+	for _, id := range owners {
+		counts[id]++
+	}
+
+	mean := float64(len(owners)) / float64(len(nodes))
+	variance, peak := 0.0, 0.0
+	for _, node := range nodes {
+		v := float64(counts[node.Id])
+		variance += math.Pow(v-mean, 2)
+		if v > peak {
+			peak = v
+		}
+	}
+
+	return math.Sqrt(variance / float64(len(nodes))), peak / mean
+}
@@ -0,0 +1,60 @@
+package bench
+
+import (
+	"encoding/json"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+)
+
+const (
+	SCHEMA         = "hashring.bench"
+	SCHEMA_VERSION = 1
+)
+
+type Environment struct {
+	GoVersion string `json:"go_version"`
+	GOOS      string `json:"goos"`
+	GOARCH    string `json:"goarch"`
+	NumCPU    int    `json:"num_cpu"`
+	Commit    string `json:"commit,omitempty"`
+}
+
+// Report 是 bench 的 JSON 输出, 字段有不兼容的变化时 SCHEMA_VERSION 加一
+type Report struct {
+	Schema        string        `json:"schema"`
+	SchemaVersion int           `json:"schema_version"`
+	Timestamp     time.Time     `json:"timestamp"`
+	Environment   Environment   `json:"environment"`
+	Nodes         int           `json:"nodes"`
+	Keys          int           `json:"keys"`
+	Source        keygen.Source `json:"source"`
+	Results       []Result      `json:"results"`
+}
+
+func NewReport(nodes int, src keygen.Source, commit string, results []Result) *Report {
+	return &Report{
+		Schema:        SCHEMA,
+		SchemaVersion: SCHEMA_VERSION,
+		Timestamp:     time.Now().UTC(),
+		Environment: Environment{
+			GoVersion: runtime.Version(),
+			GOOS:      runtime.GOOS,
+			GOARCH:    runtime.GOARCH,
+			NumCPU:    runtime.NumCPU(),
+			Commit:    commit,
+		},
+		Nodes:   nodes,
+		Keys:    src.Keys,
+		Source:  src,
+		Results: results,
+	}
+}
+
+func (r *Report) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,122 @@
+package bench
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	MEMBERSHIP_ROUNDS = 20
+)
+
+// MembershipResult 是一次成员变更的平均耗时
+type MembershipResult struct {
+	Nodes        int     `json:"nodes"`
+	Replicas     int     `json:"replicas"`
+	VirtualNodes int     `json:"virtual_nodes"`
+	Add          float64 `json:"add_ns"`
+	Remove       float64 `json:"remove_ns"`
+	UpdateWeight float64 `json:"update_weight_ns"`
+}
+
+func membershipNode(id int) *consistent.Node {
+	ip := fmt.Sprintf("10.%d.%d.%d", id/65536%256, id/256%256, id%256)
+	return consistent.NewNode(id, ip, 8080, fmt.Sprintf("host_%d", id), 1)
+}
+
+func buildRing(n int, opts ...consistent.Option) *consistent.Consistent {
+	c := consistent.NewConsistent(opts...)
+	for id := 0; id < n; id++ {
+		c.Add(membershipNode(id))
+	}
+	return c
+}
+
+// Membership 对每个集群规模测量加一个节点、删一个节点和改一次权重的耗时, 每种操作做 rounds 次取平均
+func Membership(sizes []int, rounds int) []MembershipResult {
+	if rounds <= 0 {
+		rounds = MEMBERSHIP_ROUNDS
+	}
+
+	results := make([]MembershipResult, 0, len(sizes))
+	for _, size := range sizes {
+		results = append(results, membership(buildRing(size), size, rounds))
+	}
+	return results
+}
+
+// MembershipReplicas 固定节点数, 对每个虚拟节点数测量成员变更的耗时, 看耗时随虚拟节点数怎样增长
+func MembershipReplicas(nodes int, replicas []int, rounds int) []MembershipResult {
+	if rounds <= 0 {
+		rounds = MEMBERSHIP_ROUNDS
+	}
+
+	results := make([]MembershipResult, 0, len(replicas))
+	for _, reps := range replicas {
+		results = append(results, membership(buildRing(nodes, consistent.WithReplicas(reps)), nodes, rounds))
+	}
+	return results
+}
+
+func membership(c *consistent.Consistent, size, rounds int) MembershipResult {
+	r := MembershipResult{Nodes: size, Replicas: c.Replicas(), VirtualNodes: c.VirtualNodeCount()}
+
+	var add, remove, update time.Duration
+	for i := 0; i < rounds; i++ {
+		node := membershipNode(size + i)
+
+		t := time.Now()
+		c.Add(node)
+		add += time.Since(t)
+
+		t = time.Now()
+		c.UpdateWeight(node.Id, 2)
+		update += time.Since(t)
+
+		t = time.Now()
+		c.Remove(node)
+		remove += time.Since(t)
+	}
+
+	r.Add = float64(add.Nanoseconds()) / float64(rounds)
+	r.Remove = float64(remove.Nanoseconds()) / float64(rounds)
+	r.UpdateWeight = float64(update.Nanoseconds()) / float64(rounds)
+	return r
+}
+
+// BootstrapResult 是从空环加入 Nodes 个节点的总耗时, 对比逐个 Add 和一次 AddBatch
+type BootstrapResult struct {
+	Nodes      int     `json:"nodes"`
+	Sequential float64 `json:"sequential_ns"`
+	Batch      float64 `json:"batch_ns"`
+	Speedup    float64 `json:"speedup"`
+}
+
+// Bootstrap 对每个集群规模测量逐个 Add 和 AddBatch 建环的耗时, 两种方式建出的环相同
+func Bootstrap(sizes []int) []BootstrapResult {
+	results := make([]BootstrapResult, 0, len(sizes))
+	for _, size := range sizes {
+		nodes := make([]*consistent.Node, size)
+		for id := range nodes {
+			nodes[id] = membershipNode(id)
+		}
+
+		t := time.Now()
+		buildRing(size)
+		sequential := time.Since(t)
+
+		t = time.Now()
+		consistent.NewConsistent().AddBatch(nodes)
+		batch := time.Since(t)
+
+		results = append(results, BootstrapResult{
+			Nodes:      size,
+			Sequential: float64(sequential.Nanoseconds()),
+			Batch:      float64(batch.Nanoseconds()),
+			Speedup:    float64(sequential) / float64(batch),
+		})
+	}
+	return results
+}
@@ -0,0 +1,99 @@
+package bench
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// rebuildRing 是增量维护之前的做法, 留作 benchmark 的基线: 每次成员变化都把所有虚拟节点重新收集、排序;
+// 读写由一把 RWMutex 保护, 与 copy-on-write 快照之前的 Get 相同
+type rebuildRing struct {
+	sync.RWMutex
+	nodes map[uint64]int
+	ring  consistent.HashRing
+	reps  int
+}
+
+func newRebuildRing(n int) *rebuildRing {
+	r := &rebuildRing{nodes: make(map[uint64]int), reps: consistent.DEFAULT_REPLICAS}
+	for id := 0; id < n; id++ {
+		r.add(membershipNode(id))
+	}
+	return r
+}
+
+func (r *rebuildRing) label(i int, node *consistent.Node) []byte {
+	return []byte(node.Ip + ":" + strconv.Itoa(node.Port) + "-" + strconv.Itoa(i))
+}
+
+func (r *rebuildRing) sortHashRing() {
+	r.ring = make(consistent.HashRing, 0, len(r.nodes))
+	for hash := range r.nodes {
+		r.ring = append(r.ring, hash)
+	}
+	sort.Sort(r.ring)
+}
+
+func (r *rebuildRing) add(node *consistent.Node) {
+	r.Lock()
+	defer r.Unlock()
+	for i := 0; i < r.reps*node.Weight; i++ {
+		r.nodes[consistent.CRC32.Hash(r.label(i, node))] = node.Id
+	}
+	r.sortHashRing()
+}
+
+func (r *rebuildRing) remove(node *consistent.Node) {
+	r.Lock()
+	defer r.Unlock()
+	for i := 0; i < r.reps*node.Weight; i++ {
+		delete(r.nodes, consistent.CRC32.Hash(r.label(i, node)))
+	}
+	r.sortHashRing()
+}
+
+func (r *rebuildRing) get(key string) int {
+	r.RLock()
+	defer r.RUnlock()
+	if len(r.ring) == 0 {
+		return -1
+	}
+	hash := consistent.CRC32.Hash([]byte(key))
+	i := sort.Search(len(r.ring), func(i int) bool {
+		return r.ring[i] >= hash
+	})
+	if i == len(r.ring) {
+		i = 0
+	}
+	return r.nodes[r.ring[i]]
+}
+
+// BenchmarkAddRemove 对比增量维护和每次重建整个环: 每轮加一个节点再删掉它
+func BenchmarkAddRemove(b *testing.B) {
+	for _, size := range SUITE_SIZES {
+		b.Run("incremental/nodes="+strconv.Itoa(size), func(b *testing.B) {
+			c := buildRing(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				node := membershipNode(size + i)
+				c.Add(node)
+				c.Remove(node)
+			}
+		})
+		b.Run("rebuild/nodes="+strconv.Itoa(size), func(b *testing.B) {
+			r := newRebuildRing(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				node := membershipNode(size + i)
+				r.add(node)
+				r.remove(node)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package bench
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	PARALLEL_DURATION = 500 * time.Millisecond
+	CHURN_INTERVAL    = time.Millisecond
+)
+
+// ParallelResult 是 goroutines 个读者同时调用 Get 的吞吐, Churn 为 true 时另有一个写者不停地加减节点
+type ParallelResult struct {
+	Goroutines    int     `json:"goroutines"`
+	Churn         bool    `json:"churn"`
+	LookupsPerSec float64 `json:"lookups_per_sec"`
+	Changes       int     `json:"changes"`
+}
+
+// Parallel 对每个 goroutine 数分别测量没有写者和有写者时的 Get 吞吐
+func Parallel(nodes []consistent.Node, sample []string, goroutines []int) ([]ParallelResult, error) {
+	if len(nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	results := make([]ParallelResult, 0, 2*len(goroutines))
+	for _, g := range goroutines {
+		for _, churn := range []bool{false, true} {
+			results = append(results, parallel(nodes, sample, g, churn))
+		}
+	}
+	return results, nil
+}
+
+func parallel(nodes []consistent.Node, sample []string, goroutines int, churn bool) ParallelResult {
+	c := consistent.NewConsistent()
+	for i := range nodes {
+		node := nodes[i]
+		c.Add(&node)
+	}
+
+	var lookups int64
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			n := int64(0)
+			for i := offset; ; i++ {
+				if i%LATENCY_BATCH == 0 {
+					select {
+					case <-stop:
+						atomic.AddInt64(&lookups, n)
+						return
+					default:
+					}
+				}
+				c.Get(sample[i%len(sample)])
+				n++
+			}
+		}(g * len(sample) / goroutines)
+	}
+
+	changes := 0
+	added, _ := neighbours(nodes)
+	node := added[len(added)-1]
+
+	start := time.Now()
+	if churn {
+		for time.Since(start) < PARALLEL_DURATION {
+			if changes%2 == 0 {
+				c.Add(&node)
+			} else {
+				c.Remove(&node)
+			}
+			changes++
+			time.Sleep(CHURN_INTERVAL)
+		}
+	} else {
+		time.Sleep(PARALLEL_DURATION)
+	}
+	close(stop)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	return ParallelResult{
+		Goroutines:    goroutines,
+		Churn:         churn,
+		LookupsPerSec: float64(lookups) / elapsed.Seconds(),
+		Changes:       changes,
+	}
+}
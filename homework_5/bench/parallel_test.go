@@ -0,0 +1,87 @@
+package bench
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// getter 是 BenchmarkSnapshotGet 对比的两种实现共同的查找和修改
+type getter struct {
+	get    func(key string) int
+	change func(i int)
+}
+
+// BenchmarkSnapshotGet 对比 copy-on-write 快照和 RWMutex 基线下的并发 Get, churn 时另有一个写者不停地加减节点
+func BenchmarkSnapshotGet(b *testing.B) {
+	impls := []struct {
+		name  string
+		build func() getter
+	}{
+		{"snapshot", func() getter {
+			c := buildRing(SUITE_NODES)
+			return getter{
+				get: func(key string) int {
+					node, _ := c.Get(key)
+					return node.Id
+				},
+				change: func(i int) {
+					node := membershipNode(SUITE_NODES + i%8)
+					c.Add(node)
+					c.Remove(node)
+				},
+			}
+		}},
+		{"rwmutex", func() getter {
+			r := newRebuildRing(SUITE_NODES)
+			return getter{
+				get: r.get,
+				change: func(i int) {
+					node := membershipNode(SUITE_NODES + i%8)
+					r.add(node)
+					r.remove(node)
+				},
+			}
+		}},
+	}
+
+	for _, impl := range impls {
+		for _, g := range SUITE_GOROUTINES {
+			for _, churn := range []bool{false, true} {
+				name := impl.name + "/goroutines=" + strconv.Itoa(g)
+				if churn {
+					name += "/churn"
+				}
+				b.Run(name, func(b *testing.B) {
+					r := impl.build()
+					stop, done := make(chan struct{}), make(chan struct{})
+					go func() {
+						defer close(done)
+						for i := 0; churn; i++ {
+							select {
+							case <-stop:
+								return
+							default:
+							}
+							r.change(i)
+						}
+					}()
+
+					var offset int64
+					b.SetParallelism(g)
+					b.ResetTimer()
+					b.RunParallel(func(pb *testing.PB) {
+						i := int(atomic.AddInt64(&offset, 1)) * 7919
+						for pb.Next() {
+							r.get(benchKeys[i%len(benchKeys)])
+							i++
+						}
+					})
+					b.StopTimer()
+					close(stop)
+					<-done
+				})
+			}
+		}
+	}
+}
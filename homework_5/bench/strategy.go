@@ -0,0 +1,127 @@
+package bench
+
+import (
+	"hash/crc32"
+	"hash/fnv"
+	"sort"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// Strategy 是参与对比的算法, Get 返回节点 Id
+type Strategy interface {
+	Get(key string) int
+}
+
+type Builder func(nodes []consistent.Node) (Strategy, error)
+
+var Builders = map[string]Builder{
+	"ring": newRing,
+	// hrw 和 maglev 是 consistent 包里的 Rendezvous 和 Maglev
+	"hrw":    newStrategy(consistent.STRATEGY_RENDEZVOUS),
+	"maglev": newMaglev,
+	"jump":   newJump,
+	"modulo": newModulo,
+	// rendezvous 与 hrw 相同, 保留这个名字与 consistent.New 的策略名一致
+	"rendezvous": newStrategy(consistent.STRATEGY_RENDEZVOUS),
+	// weighted-rendezvous 是按 Weight 打分的 consistent.Rendezvous
+	"weighted-rendezvous": newStrategy(consistent.STRATEGY_WEIGHTED_RENDEZVOUS),
+	// slots 是 Redis Cluster 式的固定 slot, 结果取决于节点加入的顺序
+	"slots": newStrategy(consistent.STRATEGY_SLOTS),
+}
+
+var Names = []string{"ring", "hrw", "maglev", "jump", "modulo"}
+
+func hash64(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+func sortedIds(nodes []consistent.Node) []int {
+	ids := make([]int, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.Id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+type ring struct {
+	c *consistent.Consistent
+}
+
+func newRing(nodes []consistent.Node) (Strategy, error) {
+	c := consistent.NewConsistent()
+	for i := range nodes {
+		node := nodes[i]
+		c.Add(&node)
+	}
+	return &ring{c}, nil
+}
+
+func (r *ring) Get(key string) int {
+	node, _ := r.c.Get(key)
+	return node.Id
+}
+
+type consistentRing struct {
+	r consistent.Ring
+}
+
+// newStrategy 用 consistent.New 构造对应算法的 Ring
+func newStrategy(strategy string) Builder {
+	return func(nodes []consistent.Node) (Strategy, error) {
+		r, err := consistent.New(strategy)
+		if err != nil {
+			return nil, err
+		}
+		for i := range nodes {
+			node := nodes[i]
+			r.Add(&node)
+		}
+		return &consistentRing{r}, nil
+	}
+}
+
+func (r *consistentRing) Get(key string) int {
+	node, _ := r.r.Get(key)
+	return node.Id
+}
+
+// newMaglev 直接用 consistent.NewMaglev 一次建表, 不经过 Ring 接口逐个 Add, 避免每加一个节点重建一次表
+func newMaglev(nodes []consistent.Node) (Strategy, error) {
+	return &consistentRing{consistent.NewMaglev(nodes, consistent.DEFAULT_MAGLEV_TABLE_SIZE)}, nil
+}
+
+// jump 只能从尾部增删节点, 节点按 Id 排序后对应 bucket
+type jump struct {
+	ids []int
+}
+
+func newJump(nodes []consistent.Node) (Strategy, error) {
+	return &jump{sortedIds(nodes)}, nil
+}
+
+func (j *jump) Get(key string) int {
+	k := hash64(key)
+	b, next := int64(-1), int64(0)
+	for next < int64(len(j.ids)) {
+		b = next
+		k = k*2862933555777941757 + 1
+		next = int64(float64(b+1) * (float64(int64(1)<<31) / float64((k>>33)+1)))
+	}
+	return j.ids[b]
+}
+
+type modulo struct {
+	ids []int
+}
+
+func newModulo(nodes []consistent.Node) (Strategy, error) {
+	return &modulo{sortedIds(nodes)}, nil
+}
+
+func (m *modulo) Get(key string) int {
+	return m.ids[crc32.ChecksumIEEE([]byte(key))%uint32(len(m.ids))]
+}
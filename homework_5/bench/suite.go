@@ -0,0 +1,75 @@
+package bench
+
+import (
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+var (
+	SUITE_SIZES      = []int{10, 100, 1000}
+	SUITE_REPLICAS   = []int{10, 50, 200, 1000}
+	SUITE_GOROUTINES = []int{1, 4, 16}
+)
+
+const (
+	// SUITE_NODES 是按虚拟节点数、并发和分配测量时使用的环大小
+	SUITE_NODES = 100
+)
+
+// SizeResult 是一种环大小下的 Get 吞吐和分配
+type SizeResult struct {
+	Nodes         int     `json:"nodes"`
+	VirtualNodes  int     `json:"virtual_nodes"`
+	LookupsPerSec float64 `json:"lookups_per_sec"`
+	Latency       Latency `json:"latency"`
+	AllocsPerOp   float64 `json:"allocs_per_op"`
+}
+
+// Sizes 对每个节点数构造环并测量同一批 key 的 Get
+func Sizes(sizes []int, sample []string) []SizeResult {
+	results := make([]SizeResult, 0, len(sizes))
+	for _, size := range sizes {
+		c := buildRing(size)
+		_, elapsed, latency, mem := lookup(&ring{c}, sample)
+		results = append(results, SizeResult{
+			Nodes:         size,
+			VirtualNodes:  c.VirtualNodeCount(),
+			LookupsPerSec: float64(len(sample)) / elapsed.Seconds(),
+			Latency:       latency,
+			AllocsPerOp:   float64(mem.Mallocs) / float64(len(sample)),
+		})
+	}
+	return results
+}
+
+// Suite 是一组固定的测量, 同一台机器上前后两次的结果可以直接比较, 用来发现环的性能回退
+type Suite struct {
+	Sizes      []SizeResult       `json:"sizes"`
+	Membership []MembershipResult `json:"membership"`
+	Replicas   []MembershipResult `json:"replicas"`
+	Parallel   []ParallelResult   `json:"parallel"`
+	Allocs     []AllocResult      `json:"allocs"`
+}
+
+// RunSuite 依次测量不同环大小的 Get、不同环大小和虚拟节点数下的 Add/Remove/UpdateWeight、
+// 并发读的吞吐和各查找入口的分配次数
+func RunSuite(sample []string) (*Suite, error) {
+	nodes := make([]consistent.Node, SUITE_NODES)
+	for i := range nodes {
+		nodes[i] = *membershipNode(i)
+	}
+
+	s := &Suite{
+		Sizes:      Sizes(SUITE_SIZES, sample),
+		Membership: Membership(SUITE_SIZES, MEMBERSHIP_ROUNDS),
+		Replicas:   MembershipReplicas(SUITE_NODES, SUITE_REPLICAS, MEMBERSHIP_ROUNDS),
+	}
+
+	var err error
+	if s.Parallel, err = Parallel(nodes, sample, SUITE_GOROUTINES); err != nil {
+		return nil, err
+	}
+	if s.Allocs, err = LookupAllocs(nodes, sample); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,130 @@
+package bench
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// benchKeys 是所有 Benchmark 共用的 key, 规模与 keygen 默认的 sequential 相同
+var benchKeys = func() []string {
+	keys := make([]string, 1<<16)
+	for i := range keys {
+		keys[i] = Key(i)
+	}
+	return keys
+}()
+
+// BenchmarkGet 对应 RunSuite 的 Sizes: 不同节点数的环上的 Get
+func BenchmarkGet(b *testing.B) {
+	for _, size := range SUITE_SIZES {
+		b.Run("nodes="+strconv.Itoa(size), func(b *testing.B) {
+			c := buildRing(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.Get(benchKeys[i%len(benchKeys)])
+			}
+		})
+	}
+}
+
+// benchMembership 每轮加一个节点、改一次权重、删掉它, 环的大小保持不变
+func benchMembership(b *testing.B, c *consistent.Consistent, size int) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		node := membershipNode(size + i)
+		c.Add(node)
+		c.UpdateWeight(node.Id, 2)
+		c.Remove(node)
+	}
+}
+
+// BenchmarkMembership 对应 RunSuite 的 Membership 和 Replicas: 不同节点数和不同虚拟节点数下的成员变更
+func BenchmarkMembership(b *testing.B) {
+	for _, size := range SUITE_SIZES {
+		b.Run("nodes="+strconv.Itoa(size), func(b *testing.B) {
+			benchMembership(b, buildRing(size), size)
+		})
+	}
+	for _, reps := range SUITE_REPLICAS {
+		b.Run("nodes="+strconv.Itoa(SUITE_NODES)+"/replicas="+strconv.Itoa(reps), func(b *testing.B) {
+			benchMembership(b, buildRing(SUITE_NODES, consistent.WithReplicas(reps)), SUITE_NODES)
+		})
+	}
+}
+
+// BenchmarkParallelGet 对应 RunSuite 的 Parallel: 每个 GOMAXPROCS 上 goroutines 个读者, churn 时另有一个写者不停地加减节点
+func BenchmarkParallelGet(b *testing.B) {
+	for _, g := range SUITE_GOROUTINES {
+		for _, churn := range []bool{false, true} {
+			name := "goroutines=" + strconv.Itoa(g)
+			if churn {
+				name += "/churn"
+			}
+			b.Run(name, func(b *testing.B) {
+				c := buildRing(SUITE_NODES)
+				stop, done := make(chan struct{}), make(chan struct{})
+				go func() {
+					defer close(done)
+					for i := 0; churn; i++ {
+						select {
+						case <-stop:
+							return
+						default:
+						}
+						node := membershipNode(SUITE_NODES + i%8)
+						c.Add(node)
+						c.Remove(node)
+					}
+				}()
+
+				var offset int64
+				b.SetParallelism(g)
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					i := int(atomic.AddInt64(&offset, 1)) * 7919
+					for pb.Next() {
+						c.Get(benchKeys[i%len(benchKeys)])
+						i++
+					}
+				})
+				b.StopTimer()
+				close(stop)
+				<-done
+			})
+		}
+	}
+}
+
+// BenchmarkLookup 对应 RunSuite 的 Allocs: Get、GetBytes 和 GetUint64 的分配次数, key 的 []byte 和哈希值在计时之前准备好
+func BenchmarkLookup(b *testing.B) {
+	c := buildRing(SUITE_NODES)
+	keys := make([][]byte, len(benchKeys))
+	hashes := make([]uint64, len(benchKeys))
+	for i, key := range benchKeys {
+		keys[i] = []byte(key)
+		hashes[i] = c.Hasher().Hash(keys[i])
+	}
+
+	lookups := []struct {
+		name string
+		get  func(i int)
+	}{
+		{"Get", func(i int) { c.Get(benchKeys[i]) }},
+		{"GetBytes", func(i int) { c.GetBytes(keys[i]) }},
+		{"GetUint64", func(i int) { c.GetUint64(hashes[i]) }},
+	}
+	for _, l := range lookups {
+		b.Run(l.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.get(i % len(benchKeys))
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// WEIGHTED_NAMES 是 Weighted 默认对比的算法, rendezvous 不考虑权重, 作为对照
+var WEIGHTED_NAMES = []string{"ring", "weighted-rendezvous", "rendezvous"}
+
+// WeightedShare 是一个节点按权重应得的比例和实际拿到的比例
+type WeightedShare struct {
+	Id     int     `json:"id"`
+	Weight int     `json:"weight"`
+	Target float64 `json:"target"`
+	Share  float64 `json:"share"`
+}
+
+// WeightedResult 是一个算法在一组权重下的分布, MaxError 是 |Share/Target - 1| 的最大值
+type WeightedResult struct {
+	Name     string          `json:"name"`
+	Shares   []WeightedShare `json:"shares"`
+	MaxError float64         `json:"max_error"`
+}
+
+// weightedNodes 按 weights 的顺序生成节点, Id 从 1 开始
+func weightedNodes(weights []int) []consistent.Node {
+	nodes := make([]consistent.Node, len(weights))
+	for i, w := range weights {
+		nodes[i] = consistent.Node{Id: i + 1, Ip: fmt.Sprintf("10.0.%d.%d", i/250, i%250+1), Port: 8000, Weight: w}
+	}
+	return nodes
+}
+
+// Weighted 用权重为 weights 的节点构造每个算法, 统计 sample 里的 key 落到各节点的比例与权重比例的偏差
+func Weighted(weights []int, sample []string, names []string) ([]WeightedResult, error) {
+	if len(weights) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	total := 0
+	for _, w := range weights {
+		if w <= 0 {
+			return nil, fmt.Errorf("bench: bad weight %d", w)
+		}
+		total += w
+	}
+
+	nodes := weightedNodes(weights)
+	results := make([]WeightedResult, 0, len(names))
+	for _, name := range names {
+		build, ok := Builders[name]
+		if !ok {
+			return nil, fmt.Errorf("bench: unknown strategy %q", name)
+		}
+
+		s, err := build(nodes)
+		if err != nil {
+			return nil, err
+		}
+		counts := make(map[int]int, len(nodes))
+		for _, key := range sample {
+			counts[s.Get(key)]++
+		}
+
+		r := WeightedResult{Name: name, Shares: make([]WeightedShare, 0, len(nodes))}
+		for _, node := range nodes {
+			share := WeightedShare{
+				Id:     node.Id,
+				Weight: node.Weight,
+				Target: float64(node.Weight) / float64(total),
+				Share:  float64(counts[node.Id]) / float64(len(sample)),
+			}
+			r.MaxError = math.Max(r.MaxError, math.Abs(share.Share/share.Target-1))
+			r.Shares = append(r.Shares, share)
+		}
+		results = append(results, r)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,63 @@
+package bench
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWeightedShares(t *testing.T) {
+	weights := []int{1, 2, 3, 4}
+	total := 10
+	sample := make([]string, 100000)
+	for i := range sample {
+		sample[i] = Key(i)
+	}
+
+	// tolerance 是 |share/(weight/total) - 1| 的上限; ring 的偏差来自虚拟节点的抽样, rendezvous 不看权重, 作为对照必须超出
+	cases := []struct {
+		name      string
+		tolerance float64
+		ignores   bool
+	}{
+		{"ring", 0.25, false},
+		{"weighted-rendezvous", 0.05, false},
+		{"rendezvous", 0.5, true},
+	}
+	for _, tc := range cases {
+		results, err := Weighted(weights, sample, []string{tc.name})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		worst := 0.0
+		for i, share := range results[0].Shares {
+			target := float64(weights[i]) / float64(total)
+			if share.Weight != weights[i] || math.Abs(share.Target-target) > 1e-12 {
+				t.Fatalf("%s: node %d has weight %d target %v, want %d and %v", tc.name, share.Id, share.Weight, share.Target, weights[i], target)
+			}
+			e := math.Abs(share.Share/target - 1)
+			worst = math.Max(worst, e)
+			if !tc.ignores && e > tc.tolerance {
+				t.Errorf("%s: node %d weight %d got %.4f of the keys, want %.4f ± %.0f%%", tc.name, share.Id, share.Weight, share.Share, target, tc.tolerance*100)
+			}
+		}
+		if math.Abs(worst-results[0].MaxError) > 1e-9 {
+			t.Errorf("%s: MaxError %v, want %v", tc.name, results[0].MaxError, worst)
+		}
+		if tc.ignores && worst <= tc.tolerance {
+			t.Errorf("%s ignores weights but its worst error %.3f is within %.0f%%", tc.name, worst, tc.tolerance*100)
+		}
+	}
+}
+
+func TestWeightedBadInput(t *testing.T) {
+	if _, err := Weighted(nil, []string{"a"}, WEIGHTED_NAMES); err != ErrNoNodes {
+		t.Fatalf("no weights: got %v, want ErrNoNodes", err)
+	}
+	if _, err := Weighted([]int{1, 0}, []string{"a"}, WEIGHTED_NAMES); err == nil {
+		t.Fatal("zero weight: want an error")
+	}
+	if _, err := Weighted([]int{1}, []string{"a"}, []string{"nope"}); err == nil {
+		t.Fatal("unknown strategy: want an error")
+	}
+}
@@ -0,0 +1,156 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	STATE_CLOSED    = "closed"
+	STATE_OPEN      = "open"
+	STATE_HALF_OPEN = "half_open"
+)
+
+type Config struct {
+	// Window 是参与计算失败率的最近请求数
+	Window      int
+	MinRequests int
+	FailureRate float64
+	// OpenTimeout 之后进入 half-open, 放行 HalfOpenProbes 个探测请求, 全部成功才关闭
+	OpenTimeout    time.Duration
+	HalfOpenProbes int
+	OnChange       func(Event)
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Window:         100,
+		MinRequests:    20,
+		FailureRate:    0.5,
+		OpenTimeout:    10 * time.Second,
+		HalfOpenProbes: 3,
+	}
+}
+
+// Breaker 是单个节点的熔断器, 不关心节点是谁, 由 Router 负责关联
+type Breaker struct {
+	sync.Mutex
+	cfg      Config
+	state    string
+	results  []bool
+	pos      int
+	failures int
+	openedAt time.Time
+	inflight int
+	probeOKs int
+	trips    int
+}
+
+func NewBreaker(cfg Config) *Breaker {
+	if cfg.Window <= 0 {
+		cfg.Window = 1
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	return &Breaker{cfg: cfg, state: STATE_CLOSED, results: make([]bool, 0, cfg.Window)}
+}
+
+// Allow 判断现在能否发请求, 返回状态变化 (open 超时转 half-open) 供调用方发事件
+func (b *Breaker) Allow(now time.Time) (bool, string, string) {
+	b.Lock()
+	defer b.Unlock()
+
+	from := b.state
+	if b.state == STATE_OPEN && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.state = STATE_HALF_OPEN
+		b.inflight, b.probeOKs = 0, 0
+	}
+
+	switch b.state {
+	case STATE_CLOSED:
+		return true, from, b.state
+	case STATE_HALF_OPEN:
+		if b.inflight < b.cfg.HalfOpenProbes {
+			b.inflight++
+			return true, from, b.state
+		}
+	}
+	return false, from, b.state
+}
+
+// Record 记录一次请求结果, 返回记录前后的状态
+func (b *Breaker) Record(now time.Time, success bool) (string, string) {
+	b.Lock()
+	defer b.Unlock()
+
+	from := b.state
+	switch b.state {
+	case STATE_HALF_OPEN:
+		if b.inflight > 0 {
+			b.inflight--
+		}
+		if !success {
+			b.open(now)
+			break
+		}
+		b.probeOKs++
+		if b.probeOKs >= b.cfg.HalfOpenProbes {
+			b.state = STATE_CLOSED
+			b.reset()
+		}
+	case STATE_CLOSED:
+		b.push(success)
+		if len(b.results) >= b.cfg.MinRequests && float64(b.failures)/float64(len(b.results)) >= b.cfg.FailureRate {
+			b.open(now)
+		}
+	}
+
+	return from, b.state
+}
+
+func (b *Breaker) push(success bool) {
+	if len(b.results) < b.cfg.Window {
+		b.results = append(b.results, success)
+	} else {
+		if !b.results[b.pos] {
+			b.failures--
+		}
+		b.results[b.pos] = success
+		b.pos = (b.pos + 1) % b.cfg.Window
+	}
+	if !success {
+		b.failures++
+	}
+}
+
+func (b *Breaker) open(now time.Time) {
+	b.state = STATE_OPEN
+	b.openedAt = now
+	b.trips++
+	b.reset()
+}
+
+func (b *Breaker) reset() {
+	b.results = b.results[:0]
+	b.pos, b.failures = 0, 0
+}
+
+type Snapshot struct {
+	State    string  `json:"state"`
+	Requests int     `json:"requests"`
+	Failures int     `json:"failures"`
+	Rate     float64 `json:"failure_rate"`
+	Trips    int     `json:"trips"`
+}
+
+func (b *Breaker) Snapshot() Snapshot {
+	b.Lock()
+	defer b.Unlock()
+
+	s := Snapshot{State: b.state, Requests: len(b.results), Failures: b.failures, Trips: b.trips}
+	if s.Requests > 0 {
+		s.Rate = float64(s.Failures) / float64(s.Requests)
+	}
+	return s
+}
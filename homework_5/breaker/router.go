@@ -0,0 +1,121 @@
+package breaker
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+var ErrAllOpen = errors.New("breaker: every candidate node is open")
+
+// Event 在节点的熔断状态变化时发出
+type Event struct {
+	Node consistent.Node `json:"node"`
+	From string          `json:"from"`
+	To   string          `json:"to"`
+	At   time.Time       `json:"at"`
+}
+
+type NodeState struct {
+	Node consistent.Node `json:"node"`
+	Snapshot
+}
+
+// Router 查找时跳过熔断中的节点, half-open 的节点按 Config 放行少量探测请求
+type Router struct {
+	sync.Mutex
+	ring     *consistent.Consistent
+	cfg      Config
+	breakers map[int]*Breaker
+	nodes    map[int]consistent.Node
+	now      func() time.Time
+}
+
+func NewRouter(ring *consistent.Consistent, cfg Config) *Router {
+	return &Router{
+		ring:     ring,
+		cfg:      cfg,
+		breakers: make(map[int]*Breaker),
+		nodes:    make(map[int]consistent.Node),
+		now:      time.Now,
+	}
+}
+
+func (r *Router) breaker(node consistent.Node) *Breaker {
+	r.Lock()
+	defer r.Unlock()
+
+	b, ok := r.breakers[node.Id]
+	if !ok {
+		b = NewBreaker(r.cfg)
+		r.breakers[node.Id] = b
+	}
+	r.nodes[node.Id] = node
+	return b
+}
+
+func (r *Router) emit(node consistent.Node, from, to string, now time.Time) {
+	if from != to && r.cfg.OnChange != nil {
+		r.cfg.OnChange(Event{Node: node, From: from, To: to, At: now})
+	}
+}
+
+// Get 顺时针返回第一个熔断器放行的节点, 请求结束后必须调用 Done
+func (r *Router) Get(key string) (consistent.Node, error) {
+	candidates := r.ring.GetN(key, math.MaxInt32)
+	if len(candidates) == 0 {
+		return consistent.Node{}, consistent.ErrEmptyRing
+	}
+
+	for _, node := range candidates {
+		if r.Allow(node) {
+			return node, nil
+		}
+	}
+
+	return consistent.Node{}, ErrAllOpen
+}
+
+// Allow 询问节点的熔断器能否发请求, 放行之后同样需要调用 Done
+func (r *Router) Allow(node consistent.Node) bool {
+	now := r.now()
+	ok, from, to := r.breaker(node).Allow(now)
+	r.emit(node, from, to, now)
+	return ok
+}
+
+// Done 记录请求结果, err 为 nil 表示成功
+func (r *Router) Done(node consistent.Node, err error) {
+	now := r.now()
+	from, to := r.breaker(node).Record(now, err == nil)
+	r.emit(node, from, to, now)
+}
+
+// States 按 Id 返回所有出现过的节点的熔断状态
+func (r *Router) States() []NodeState {
+	r.Lock()
+	defer r.Unlock()
+
+	states := make([]NodeState, 0, len(r.breakers))
+	for id, b := range r.breakers {
+		states = append(states, NodeState{Node: r.nodes[id], Snapshot: b.Snapshot()})
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Node.Id < states[j].Node.Id
+	})
+	return states
+}
+
+// Forget 删除节点的熔断状态, 节点下线后调用
+func (r *Router) Forget(id int) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.breakers, id)
+	delete(r.nodes, id)
+}
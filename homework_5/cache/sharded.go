@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_SHARDS = 16
+)
+
+type entry struct {
+	key   string
+	value interface{}
+}
+
+// shard 是一个普通的 LRU, 由 ShardedCache 的读锁加上自己的锁保护
+type shard struct {
+	sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+func newShard(capacity int) *shard {
+	return &shard{capacity: capacity, items: make(map[string]*list.Element), order: list.New()}
+}
+
+func (s *shard) get(key string) (interface{}, bool) {
+	e, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(e)
+	return e.Value.(*entry).value, true
+}
+
+// set 返回被淘汰的项, 没有淘汰时为 nil
+func (s *shard) set(key string, value interface{}) []*entry {
+	if e, ok := s.items[key]; ok {
+		e.Value.(*entry).value = value
+		s.order.MoveToFront(e)
+		return nil
+	}
+	s.items[key] = s.order.PushFront(&entry{key: key, value: value})
+	return s.trim()
+}
+
+func (s *shard) trim() []*entry {
+	var evicted []*entry
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		e := s.order.Back()
+		s.order.Remove(e)
+		delete(s.items, e.Value.(*entry).key)
+		evicted = append(evicted, e.Value.(*entry))
+	}
+	return evicted
+}
+
+func (s *shard) delete(key string) bool {
+	e, ok := s.items[key]
+	if !ok {
+		return false
+	}
+	s.order.Remove(e)
+	delete(s.items, key)
+	return true
+}
+
+// ShardedCache 把一个容量为 capacity 的 LRU 按环分成多个分片, 每个 key 只锁自己的分片, 并发的 Get/Set 互不阻塞.
+// Resize 改变分片数时只有归属变了的 key 在分片之间移动, 与一致性哈希在节点之间移动 key 完全一样
+type ShardedCache struct {
+	sync.RWMutex
+	ring     *consistent.Consistent
+	shards   map[int]*shard
+	capacity int
+	// OnEvict 在 key 因为容量被淘汰时调用, 调用时持有分片的锁, 不能再访问缓存
+	OnEvict func(key string, value interface{})
+}
+
+// NewShardedCache 的 capacity 为 0 表示不限制容量, shards <= 0 时使用 DEFAULT_SHARDS
+func NewShardedCache(shards, capacity int) *ShardedCache {
+	if shards <= 0 {
+		shards = DEFAULT_SHARDS
+	}
+	// 内部的环不需要和旧的部署兼容, 用严格的回绕规则, 增删分片时其余分片之间不会有 key 移动
+	c := &ShardedCache{ring: consistent.NewConsistent(consistent.WithStrictWrap()), shards: make(map[int]*shard), capacity: capacity}
+	c.grow(shards)
+	return c
+}
+
+func shardNode(id int) *consistent.Node {
+	return consistent.NewNode(id, "shard", id, "shard_"+strconv.Itoa(id), 1)
+}
+
+// perShard 把总容量平均分到每个分片, 余数分给前面的分片
+func (c *ShardedCache) perShard(id, n int) int {
+	if c.capacity <= 0 {
+		return 0
+	}
+	size := c.capacity / n
+	if id < c.capacity%n {
+		size++
+	}
+	return size
+}
+
+// grow 在写锁内加入 [len(shards), n) 的分片, 不移动数据
+func (c *ShardedCache) grow(n int) {
+	for id := len(c.shards); id < n; id++ {
+		c.ring.Add(shardNode(id))
+		c.shards[id] = newShard(0)
+	}
+	for id, s := range c.shards {
+		s.capacity = c.perShard(id, n)
+	}
+}
+
+func (c *ShardedCache) shard(key string) *shard {
+	node, _ := c.ring.Get(key)
+	return c.shards[node.Id]
+}
+
+func (c *ShardedCache) evicted(evicted []*entry) {
+	if c.OnEvict == nil {
+		return
+	}
+	for _, e := range evicted {
+		c.OnEvict(e.key, e.value)
+	}
+}
+
+func (c *ShardedCache) Get(key string) (interface{}, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	s := c.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	return s.get(key)
+}
+
+func (c *ShardedCache) Set(key string, value interface{}) {
+	c.RLock()
+	defer c.RUnlock()
+
+	s := c.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	c.evicted(s.set(key, value))
+}
+
+func (c *ShardedCache) Delete(key string) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	s := c.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	return s.delete(key)
+}
+
+func (c *ShardedCache) Len() int {
+	c.RLock()
+	defer c.RUnlock()
+
+	n := 0
+	for _, s := range c.shards {
+		s.Lock()
+		n += s.order.Len()
+		s.Unlock()
+	}
+	return n
+}
+
+// ShardLens 按分片 Id 返回每个分片的项数
+func (c *ShardedCache) ShardLens() []int {
+	c.RLock()
+	defer c.RUnlock()
+
+	lens := make([]int, len(c.shards))
+	for id, s := range c.shards {
+		s.Lock()
+		lens[id] = s.order.Len()
+		s.Unlock()
+	}
+	return lens
+}
+
+func (c *ShardedCache) Shards() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.shards)
+}
+
+// Resize 把分片数改成 n 并返回移动的项数. 增加分片时只有新分片接管的 key 移过去, 减少时只移动被删除的分片上的 key;
+// 移动时保持每个分片内的 LRU 顺序, 新的分片容量小于项数时按 LRU 淘汰. 期间所有读写都会等待
+func (c *ShardedCache) Resize(n int) int {
+	if n <= 0 {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	old := len(c.shards)
+	if n == old {
+		return 0
+	}
+
+	// 先改环, 再把归属变了的 key 从旧分片搬到新分片; 按分片号依次从最近使用的一端遍历, 搬过去的 key 依次追加到目标分片最久未使用的一端,
+	// 目标分片原有的 key 和搬过去的 key 各自保持原来的顺序, 容量不够时先淘汰搬过去的 key
+	ids := make([]int, 0)
+	moving := make(map[int]*shard)
+	if n > old {
+		c.grow(n)
+		for id := 0; id < old; id++ {
+			ids = append(ids, id)
+			moving[id] = c.shards[id]
+		}
+	} else {
+		for id := n; id < old; id++ {
+			c.ring.RemoveByID(id)
+			ids = append(ids, id)
+			moving[id] = c.shards[id]
+			delete(c.shards, id)
+		}
+		for id, s := range c.shards {
+			s.capacity = c.perShard(id, n)
+		}
+	}
+
+	moved := 0
+	for _, id := range ids {
+		from := moving[id]
+		for e := from.order.Front(); e != nil; {
+			next := e.Next()
+			item := e.Value.(*entry)
+			node, _ := c.ring.Get(item.key)
+			if node.Id != id {
+				from.order.Remove(e)
+				delete(from.items, item.key)
+				to := c.shards[node.Id]
+				to.items[item.key] = to.order.PushBack(item)
+				moved++
+			}
+			e = next
+		}
+	}
+
+	for _, s := range c.shards {
+		c.evicted(s.trim())
+	}
+	return moved
+}
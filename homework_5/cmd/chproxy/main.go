@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/admin"
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/discovery"
+	"github.com/axiusilihao/geek_homework/homework_5/health"
+	"github.com/axiusilihao/geek_homework/homework_5/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// chproxy 是一致性哈希的 HTTP 反向代理: 后端列表是 topology 格式的文件, 每个请求按 -key 在环上选后端转发.
+// 管理端口上 / 是 admin 接口, /metrics 是 prometheus 指标, /health 是健康检查的结果
+func main() {
+	addr := flag.String("addr", ":8000", "proxy listen address")
+	adminAddr := flag.String("admin", ":8001", "admin and metrics listen address, empty to disable")
+	backends := flag.String("backends", "", "backend list, a topology file in JSON or YAML")
+	keySpec := flag.String("key", "path", "routing key: path, header:<name>, cookie:<name> or query:<name>")
+	reload := flag.Duration("reload", 5*time.Second, "how often to check the backend list for changes, SIGHUP reloads at once")
+	interval := flag.Duration("health-interval", health.DEFAULT_INTERVAL, "health check interval, 0 to disable")
+	healthPath := flag.String("health-path", "", "HTTP path to probe, empty for a TCP connect")
+	flag.Parse()
+
+	if *backends == "" {
+		log.Fatal("chproxy: -backends is required")
+	}
+	key, err := parseKey(*keySpec)
+	if err != nil {
+		log.Fatal("chproxy: ", err)
+	}
+
+	t, modTime, err := load(*backends)
+	if err != nil {
+		log.Fatalf("chproxy: load %s: %v", *backends, err)
+	}
+	ring := consistent.NewConsistent(t.Options()...)
+	discovery.Sync(ring, t.Nodes)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ring.Go(newReloader(*backends, ring, *reload, modTime).Run)
+
+	var checker *health.Checker
+	if *interval > 0 {
+		cfg := health.DefaultConfig()
+		cfg.Interval = *interval
+		if *healthPath != "" {
+			cfg.Probe = httpProbe(*healthPath)
+		}
+		cfg.OnChange = func(node consistent.Node, up bool, err error) {
+			log.Printf("chproxy: node %d up=%v: %v", node.Id, up, err)
+		}
+		checker = health.NewChecker(ring, cfg)
+		ring.Go(checker.Run)
+	}
+
+	m := metrics.New(ring, "chproxy")
+	if *adminAddr != "" {
+		reg := prometheus.NewRegistry()
+		m.Register(reg)
+
+		mux := http.NewServeMux()
+		mux.Handle("/", admin.NewHandler(ring))
+		mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+		mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+			statuses := []health.Status{}
+			if checker != nil {
+				statuses = checker.Statuses()
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(statuses)
+		})
+
+		go func() {
+			log.Println("chproxy admin listening on", *adminAddr)
+			log.Fatal(http.ListenAndServe(*adminAddr, mux))
+		}()
+	}
+
+	srv := &http.Server{Addr: *addr, Handler: newProxy(ring, key, m)}
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(sctx)
+		if err := ring.Close(sctx); err != nil {
+			log.Println("chproxy: close ring:", err)
+		}
+	}()
+
+	log.Printf("chproxy with %d backends listening on %s", ring.NodeCount(), *addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
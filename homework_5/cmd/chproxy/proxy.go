@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/httproute"
+	"github.com/axiusilihao/geek_homework/homework_5/metrics"
+)
+
+const (
+	NODE_HEADER = "X-Ring-Node"
+)
+
+// parseKey 解析 -key: path, header:<name>, cookie:<name> 或 query:<name>
+func parseKey(spec string) (httproute.KeyFunc, error) {
+	kind, name := spec, ""
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		kind, name = spec[:i], spec[i+1:]
+	}
+
+	switch {
+	case kind == "path" && name == "":
+		return httproute.Path(), nil
+	case kind == "header" && name != "":
+		return httproute.Header(name), nil
+	case kind == "cookie" && name != "":
+		return httproute.Cookie(name), nil
+	case kind == "query" && name != "":
+		return httproute.Query(name), nil
+	}
+	return nil, fmt.Errorf("bad key %q, want path, header:<name>, cookie:<name> or query:<name>", spec)
+}
+
+// newProxy 按 key 在环上选后端并转发, 取不到 key 的请求按客户端地址路由, 响应里带上后端的 Id
+func newProxy(ring *consistent.Consistent, key httproute.KeyFunc, m *metrics.Metrics) *httproute.ReverseProxy {
+	p := httproute.NewReverseProxy(ring, httproute.First(key, httproute.RemoteAddr()))
+	p.Lookup = m.Get
+	p.ModifyResponse = func(resp *http.Response) error {
+		node, _ := httproute.NodeFromContext(resp.Request.Context())
+		resp.Header.Set(NODE_HEADER, fmt.Sprint(node.Id))
+		return nil
+	}
+	p.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		node, _ := httproute.NodeFromContext(r.Context())
+		log.Printf("chproxy: node %d (%s): %v", node.Id, httproute.Addr(node), err)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}
+	return p
+}
+
+// httpProbe 对后端发 GET path, 2xx 和 3xx 算健康
+func httpProbe(path string) func(ctx context.Context, node consistent.Node) error {
+	return func(ctx context.Context, node consistent.Node) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+httproute.Addr(node)+path, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("status %s", resp.Status)
+		}
+		return nil
+	}
+}
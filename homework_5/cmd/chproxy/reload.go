@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/discovery"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+var errBadBackends = errors.New("backend list has errors")
+
+// load 读取并校验后端列表, 返回文件的修改时间; 校验有错误时返回 errBadBackends
+func load(path string) (*topology.Topology, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	t, err := topology.Load(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if problems := t.Validate(); topology.HasErrors(problems) {
+		for _, p := range problems {
+			log.Println("chproxy:", p)
+		}
+		return nil, time.Time{}, errBadBackends
+	}
+	return t, info.ModTime(), nil
+}
+
+// reloader 在后端列表文件的修改时间变化或收到 SIGHUP 时重新加载, 用 discovery.Sync 只改动有变化的节点.
+// 只有 nodes 会被重新加载, hasher、replicas 等环的参数需要重启才能生效
+type reloader struct {
+	path     string
+	ring     *consistent.Consistent
+	interval time.Duration
+	modTime  time.Time
+}
+
+func newReloader(path string, ring *consistent.Consistent, interval time.Duration, modTime time.Time) *reloader {
+	return &reloader{path: path, ring: ring, interval: interval, modTime: modTime}
+}
+
+// reload 加载失败时保留当前的后端
+func (l *reloader) reload() {
+	t, modTime, err := load(l.path)
+	if err != nil {
+		log.Printf("chproxy: reload %s: %v, keeping %d backends", l.path, err, l.ring.NodeCount())
+		return
+	}
+
+	l.modTime = modTime
+	r := discovery.Sync(l.ring, t.Nodes)
+	if !r.Empty() {
+		log.Printf("chproxy: reloaded %s: %d added, %d removed, %d updated", l.path, len(r.Added), len(r.Removed), len(r.Updated))
+	}
+}
+
+// changed 同时记下新的修改时间, 加载失败的文件不会每个周期都重试
+func (l *reloader) changed() bool {
+	info, err := os.Stat(l.path)
+	if err != nil || info.ModTime().Equal(l.modTime) {
+		return false
+	}
+	l.modTime = info.ModTime()
+	return true
+}
+
+// Run 直到 ctx 结束
+func (l *reloader) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hup:
+			l.reload()
+		case <-ticker.C:
+			if l.changed() {
+				l.reload()
+			}
+		}
+	}
+}
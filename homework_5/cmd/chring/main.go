@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+	"gopkg.in/yaml.v3"
+)
+
+// chring 离线查看一组节点组成的环: 归属表、均衡统计、key 的节点, 以及假设增删节点时的迁移报告.
+// 节点文件可以是 hashring 用的拓扑文件, 也可以只是一个节点数组, JSON 或 YAML 都可以
+//
+//	chring [-f nodes.json] [-json] table
+//	chring [-f nodes.json] [-json] stats [-warn 0.1]
+//	chring [-f nodes.json] [-json] get [-n 1] <key>...
+//	chring [-f nodes.json] [-json] plan [-add host:port] [-weight 1] [-remove id]
+//	chring [-f nodes.json] [-json] scale -option 2@2 -option 4
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: chring [-f nodes.json] [-json] <table|stats|get|plan|scale> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "  table   print every contiguous range, its owner and the share of each node")
+	fmt.Fprintln(os.Stderr, "  stats   print balance statistics of the ring")
+	fmt.Fprintln(os.Stderr, "  get     print the nodes owning each key")
+	fmt.Fprintln(os.Stderr, "  plan    report key movement for a hypothetical add or remove")
+	fmt.Fprintln(os.Stderr, "  scale   compare scale-out options by movement and resulting balance")
+	fmt.Fprintln(os.Stderr)
+	flag.PrintDefaults()
+}
+
+func main() {
+	file := flag.String("f", "nodes.json", "node list or topology file, json or yaml")
+	asJSON := flag.Bool("json", false, "print results as json")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	t, err := load(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "chring:", err)
+		os.Exit(1)
+	}
+
+	args := flag.Args()[1:]
+	switch flag.Arg(0) {
+	case "table":
+		err = table(t, *asJSON)
+	case "stats":
+		err = stats(t, args, *asJSON)
+	case "get":
+		err = get(t, args, *asJSON)
+	case "plan":
+		err = plan(t, args, *asJSON)
+	case "scale":
+		err = scale(t, args, *asJSON)
+	default:
+		fmt.Fprintf(os.Stderr, "chring: unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "chring:", err)
+		os.Exit(1)
+	}
+}
+
+// load 读取拓扑文件或者节点数组, 节点数组使用默认的环参数
+func load(path string) (*topology.Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		var v interface{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &topology.Topology{}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		err = json.Unmarshal(data, &t.Nodes)
+	} else {
+		err = json.Unmarshal(data, t)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(t.Nodes) == 0 {
+		return nil, fmt.Errorf("%s: no nodes", path)
+	}
+	return t, nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+type tableReport struct {
+	Ranges []consistent.Range `json:"ranges"`
+	Shares []nodeShare        `json:"shares"`
+}
+
+type nodeShare struct {
+	Node  consistent.Node `json:"node"`
+	Share float64         `json:"share"`
+}
+
+func shares(ring *consistent.Consistent) []nodeShare {
+	owned := ring.Ownership()
+	list := make([]nodeShare, 0)
+	for _, node := range ring.Members() {
+		list = append(list, nodeShare{Node: node, Share: owned[node.Id]})
+	}
+	return list
+}
+
+func table(t *topology.Topology, asJSON bool) error {
+	ring := t.Ring()
+	r := tableReport{Ranges: ring.OwnershipTable(), Shares: shares(ring)}
+	if asJSON {
+		return printJSON(r)
+	}
+
+	fmt.Println("start\tend\tid\taddress\tshare")
+	for _, rg := range r.Ranges {
+		fmt.Printf("%d\t%d\t%d\t%s:%d\t%.4f%%\n", rg.Start, rg.End, rg.Node.Id, rg.Node.Ip, rg.Node.Port, rg.Fraction*100)
+	}
+	fmt.Println()
+	fmt.Println("id\taddress\tweight\tshare")
+	for _, s := range r.Shares {
+		fmt.Printf("%d\t%s:%d\t%d\t%.2f%%\n", s.Node.Id, s.Node.Ip, s.Node.Port, s.Node.Weight, s.Share*100)
+	}
+	return nil
+}
+
+type statsReport struct {
+	consistent.Stats
+	Warnings []consistent.LoadWarning `json:"warnings"`
+}
+
+func stats(t *topology.Topology, args []string, asJSON bool) error {
+	fs := flag.NewFlagSet("chring stats", flag.ContinueOnError)
+	warn := fs.Float64("warn", 0.1, "flag nodes whose expected share deviates from their weight target by more than this fraction")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ring := t.Ring()
+	s := ring.Stats()
+	warnings := ring.LoadWarnings(*warn)
+	if asJSON {
+		return printJSON(statsReport{Stats: s, Warnings: warnings})
+	}
+
+	fmt.Printf("nodes          %d\n", s.Nodes)
+	fmt.Printf("virtual nodes  %d\n", ring.VirtualNodeCount())
+	fmt.Printf("mean share     %.2f%%\n", s.Mean*100)
+	fmt.Printf("stddev         %.2f%%\n", s.StdDev*100)
+	fmt.Printf("cv             %.4f\n", s.CV)
+	fmt.Printf("min            %.2f%% (node %d)\n", s.Min*100, s.MinNode)
+	fmt.Printf("max            %.2f%% (node %d)\n", s.Max*100, s.MaxNode)
+	if s.Mean > 0 {
+		fmt.Printf("peak/mean      %.3f\n", s.Max/s.Mean)
+	}
+	for _, w := range warnings {
+		fmt.Printf("warning        node %d owns %.2f%%, target %.2f%% (%+.1f%%)\n", w.Node.Id, w.Expected*100, w.Target*100, w.Deviation*100)
+	}
+	return nil
+}
+
+type keyOwners struct {
+	Key   string            `json:"key"`
+	Nodes []consistent.Node `json:"nodes"`
+}
+
+func get(t *topology.Topology, args []string, asJSON bool) error {
+	fs := flag.NewFlagSet("chring get", flag.ContinueOnError)
+	n := fs.Int("n", 1, "number of distinct nodes to print for each key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("get: missing key")
+	}
+	if *n <= 0 {
+		return errors.New("get: -n must be positive")
+	}
+
+	ring := t.Ring()
+	results := make([]keyOwners, 0, fs.NArg())
+	for _, key := range fs.Args() {
+		results = append(results, keyOwners{Key: key, Nodes: ring.GetN(key, *n)})
+	}
+	if asJSON {
+		return printJSON(results)
+	}
+
+	for _, r := range results {
+		for i, node := range r.Nodes {
+			fmt.Printf("%s\t%d\tid=%d\t%s:%d\t%s\n", r.Key, i, node.Id, node.Ip, node.Port, node.HostName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return fmt.Sprint(*l)
+}
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// planReport 是假设的变化之后每个节点的比例和节点之间迁移的 key 比例
+type planReport struct {
+	Added     []consistent.Node `json:"added"`
+	Removed   []consistent.Node `json:"removed"`
+	Moved     float64           `json:"moved"`
+	Nodes     []planShare       `json:"nodes"`
+	Transfers []transfer        `json:"transfers"`
+}
+
+type planShare struct {
+	Node   consistent.Node `json:"node"`
+	Before float64         `json:"before"`
+	After  float64         `json:"after"`
+}
+
+type transfer struct {
+	From     int     `json:"from"`
+	To       int     `json:"to"`
+	Fraction float64 `json:"fraction"`
+}
+
+// plan 在环的副本上做假设的增删, 用 Diff 得到迁移的区间, 原来的环不变
+func plan(t *topology.Topology, args []string, asJSON bool) error {
+	fs := flag.NewFlagSet("chring plan", flag.ContinueOnError)
+	var adds, removes listFlag
+	fs.Var(&adds, "add", "host:port of a node to add, can be repeated")
+	fs.Var(&removes, "remove", "id of a node to remove, can be repeated")
+	weight := fs.Int("weight", 1, "weight of the added nodes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(adds) == 0 && len(removes) == 0 {
+		return errors.New("plan: nothing to do, use -add or -remove")
+	}
+
+	ring := t.Ring()
+	next := ring.Clone()
+	r := &planReport{Added: make([]consistent.Node, 0), Removed: make([]consistent.Node, 0)}
+
+	for _, s := range removes {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("plan: bad node id %q", s)
+		}
+		node, ok := t.Find(id)
+		if !ok || !next.RemoveByID(id) {
+			return fmt.Errorf("plan: remove %d: %v", id, topology.ErrNodeNotFound)
+		}
+		r.Removed = append(r.Removed, node)
+	}
+
+	id := 0
+	for _, node := range t.Nodes {
+		if node.Id >= id {
+			id = node.Id + 1
+		}
+	}
+	for _, s := range adds {
+		host, port, err := net.SplitHostPort(s)
+		if err != nil {
+			return err
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return fmt.Errorf("plan: bad port in %q", s)
+		}
+		node := consistent.NewNode(id, host, p, "host_"+strconv.Itoa(id), *weight)
+		next.Add(node)
+		r.Added = append(r.Added, *node)
+		id++
+	}
+
+	before, after := ring.Ownership(), next.Ownership()
+	seen := make(map[int]bool)
+	for _, node := range append(ring.Members(), next.Members()...) {
+		if !seen[node.Id] {
+			seen[node.Id] = true
+			r.Nodes = append(r.Nodes, planShare{Node: node, Before: before[node.Id], After: after[node.Id]})
+		}
+	}
+	sort.Slice(r.Nodes, func(i, j int) bool {
+		return r.Nodes[i].Node.Id < r.Nodes[j].Node.Id
+	})
+
+	flows := make(map[[2]int]float64)
+	for _, m := range consistent.Diff(ring, next) {
+		r.Moved += m.Fraction
+		flows[[2]int{m.From.Id, m.To.Id}] += m.Fraction
+	}
+	for k, f := range flows {
+		r.Transfers = append(r.Transfers, transfer{From: k[0], To: k[1], Fraction: f})
+	}
+	sort.Slice(r.Transfers, func(i, j int) bool {
+		if r.Transfers[i].From != r.Transfers[j].From {
+			return r.Transfers[i].From < r.Transfers[j].From
+		}
+		return r.Transfers[i].To < r.Transfers[j].To
+	})
+
+	if asJSON {
+		return printJSON(r)
+	}
+
+	for _, node := range r.Removed {
+		fmt.Printf("remove  id=%d %s:%d\n", node.Id, node.Ip, node.Port)
+	}
+	for _, node := range r.Added {
+		fmt.Printf("add     id=%d %s:%d weight %d\n", node.Id, node.Ip, node.Port, node.Weight)
+	}
+	fmt.Printf("moved   %.2f%% of the keyspace\n", r.Moved*100)
+	fmt.Println()
+	fmt.Println("id\taddress\tbefore\tafter\tdelta")
+	for _, s := range r.Nodes {
+		fmt.Printf("%d\t%s:%d\t%.2f%%\t%.2f%%\t%+.2f%%\n", s.Node.Id, s.Node.Ip, s.Node.Port, s.Before*100, s.After*100, (s.After-s.Before)*100)
+	}
+	fmt.Println()
+	fmt.Println("from\tto\tshare")
+	for _, tr := range r.Transfers {
+		fmt.Printf("%d\t%d\t%.2f%%\n", tr.From, tr.To, tr.Fraction*100)
+	}
+	return nil
+}
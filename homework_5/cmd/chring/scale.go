@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+// scaleOption 是一种扩容方案, 写成 count 或 count@weight, 例如 2@2 表示加 2 个权重为 2 的节点
+type scaleOption struct {
+	Spec   string                 `json:"spec"`
+	Report consistent.ScaleReport `json:"report"`
+}
+
+func parseScaleOption(spec string) (int, int, error) {
+	count, weight := spec, "1"
+	if i := strings.IndexByte(spec, '@'); i >= 0 {
+		count, weight = spec[:i], spec[i+1:]
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return 0, 0, fmt.Errorf("scale: bad count in %q", spec)
+	}
+	w, err := strconv.Atoi(weight)
+	if err != nil || w <= 0 {
+		return 0, 0, fmt.Errorf("scale: bad weight in %q", spec)
+	}
+	return n, w, nil
+}
+
+// scale 对每种方案估计迁移量和扩容之后的均衡, 新节点的地址是占位的, 只影响虚拟节点的位置
+func scale(t *topology.Topology, args []string, asJSON bool) error {
+	fs := flag.NewFlagSet("chring scale", flag.ContinueOnError)
+	var specs listFlag
+	fs.Var(&specs, "option", "count or count@weight of nodes to add, can be repeated to compare options")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(specs) == 0 {
+		return errors.New("scale: nothing to compare, use -option")
+	}
+
+	ring := t.Ring()
+	first := 0
+	for _, node := range t.Nodes {
+		if node.Id >= first {
+			first = node.Id + 1
+		}
+	}
+
+	options := make([]scaleOption, 0, len(specs))
+	for _, spec := range specs {
+		n, w, err := parseScaleOption(spec)
+		if err != nil {
+			return err
+		}
+		nodes := make([]*consistent.Node, n)
+		for i := range nodes {
+			id := first + i
+			nodes[i] = consistent.NewNode(id, fmt.Sprintf("new-%d", id), 0, "new_"+strconv.Itoa(id), w)
+		}
+		options = append(options, scaleOption{Spec: spec, Report: ring.EstimateScaleOut(nodes)})
+	}
+	if asJSON {
+		return printJSON(options)
+	}
+
+	fmt.Println("option\tmoved\tcv before\tcv after\tpeak/mean after\tlargest donor")
+	for _, o := range options {
+		r := o.Report
+		donor, most := -1, 0.0
+		for id, f := range r.Donations {
+			if f > most || (f == most && id < donor) {
+				donor, most = id, f
+			}
+		}
+		peak := 0.0
+		if r.After.Mean > 0 {
+			peak = r.After.Max / r.After.Mean
+		}
+		fmt.Printf("%s\t%.2f%%\t%.4f\t%.4f\t%.3f\tnode %d (%.2f%%)\n", o.Spec, r.Moved*100, r.Before.CV, r.After.CV, peak, donor, most*100)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/dispersion"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("analyze", "report virtual node collisions, gaps and clusters per hasher", analyze)
+}
+
+func analyze(args []string) error {
+	fs, file := newFlagSet("analyze")
+	hasher := fs.String("hash", "", "hasher to analyze, empty for all")
+	buckets := fs.Int("buckets", dispersion.DEFAULT_BUCKETS, "number of buckets used to find clusters")
+	top := fs.Int("top", dispersion.DEFAULT_TOP, "number of gaps and clusters to show")
+	asJSON := fs.Bool("json", false, "print the analysis as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	cfg := dispersion.Config{Replicas: t.Ring().Replicas(), Buckets: *buckets, Top: *top, Label: consistent.Labels[t.Label]}
+	var res []*dispersion.Analysis
+	if *hasher == "" {
+		res = dispersion.Compare(t.Nodes, cfg)
+	} else {
+		hash, ok := dispersion.Hashers[*hasher]
+		if !ok {
+			return fmt.Errorf("analyze: unknown hasher %q", *hasher)
+		}
+		res = append(res, dispersion.Analyze(*hasher, hash, t.Nodes, cfg))
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+
+	for _, a := range res {
+		verdict := "ok"
+		if a.Poor {
+			verdict = "POOR"
+		}
+		fmt.Printf("%s: %s\n", a.Hasher, verdict)
+		fmt.Printf("  points %d, distinct %d, collisions %d (expected %.2f)\n", a.Points, a.Distinct, len(a.Collisions), a.ExpectedCollisions)
+		fmt.Printf("  max gap %.2fx mean (expected ~%.2fx), bucket chi-square/df %.3f\n", a.MaxGapRatio, a.ExpectedMaxGap, a.ChiSquare)
+		for _, c := range a.Collisions {
+			fmt.Printf("  collision %08x nodes %v label %q\n", c.Hash, c.Nodes, c.Label)
+		}
+		for _, g := range a.Gaps {
+			fmt.Printf("  gap     %08x-%08x %.2fx owner %d\n", g.Start, g.End, g.Ratio, g.Owner)
+		}
+		for _, c := range a.Clusters {
+			fmt.Printf("  cluster %08x-%08x %d points %.2fx\n", c.Start, c.End, c.Points, c.Ratio)
+		}
+		for _, r := range a.Reasons {
+			fmt.Println("  !", r)
+		}
+	}
+
+	return nil
+}
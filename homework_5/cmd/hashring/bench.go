@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/axiusilihao/geek_homework/homework_5/bench"
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("bench", "compare ring, hrw, maglev, jump and modulo on the same keys", benchCmd)
+}
+
+func benchCmd(args []string) error {
+	fs, file := newFlagSet("bench")
+	src := sourceFlags(fs)
+	algos := fs.String("algos", strings.Join(bench.Names, ","), "comma separated strategies")
+	asJSON := fs.Bool("json", false, "print a versioned json report")
+	commit := fs.String("commit", "", "commit id recorded in the json report")
+	membership := fs.String("membership", "", "time Add/Remove/UpdateWeight on rings of these sizes instead, e.g. 100,500,1000")
+	bootstrap := fs.String("bootstrap", "", "compare building rings of these sizes with Add and AddBatch instead, e.g. 100,500")
+	parallel := fs.String("parallel", "", "measure concurrent Get throughput with these goroutine counts instead, e.g. 1,4,16")
+	allocs := fs.Bool("allocs", false, "compare allocations of Get, GetBytes and GetUint64 instead")
+	batch := fs.Bool("batch", false, "compare a Get loop with GetMany and GroupByNode instead")
+	weights := fs.String("weights", "", "compare how closely ring and weighted-rendezvous follow these node weights instead, e.g. 1,2,4,8")
+	suite := fs.Bool("suite", false, "run the fixed regression suite on generated rings instead, ignoring the topology")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := src.Validate(); err != nil {
+		return err
+	}
+
+	if *suite {
+		return benchSuite(*src, *asJSON)
+	}
+
+	if *membership != "" {
+		return benchMembership(*membership, *asJSON)
+	}
+
+	if *bootstrap != "" {
+		return benchBootstrap(*bootstrap, *asJSON)
+	}
+
+	if *weights != "" {
+		return benchWeighted(*weights, *src, *asJSON)
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	if *parallel != "" {
+		return benchParallel(t, *src, *parallel, *asJSON)
+	}
+	if *allocs {
+		return benchAllocs(t, *src, *asJSON)
+	}
+	if *batch {
+		return benchBatch(t, *src, *asJSON)
+	}
+
+	results, err := bench.Run(t.Nodes, *src, strings.Split(*algos, ","))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return bench.NewReport(len(t.Nodes), *src, *commit, results).Encode(os.Stdout)
+	}
+
+	fmt.Println("algo\tlookups/s\tp50\tp99\tallocs/op\tmemory\tstddev\tpeak/mean\tmoved(add)\tmoved(remove)")
+	for _, r := range results {
+		fmt.Printf("%s\t%.0f\t%.0fns\t%.0fns\t%.2f\t%dKB\t%.1f\t%.3f\t%.2f%%\t%.2f%%\n",
+			r.Name, r.LookupsPerSec, r.Latency.P50, r.Latency.P99, r.AllocsPerOp, r.Bytes/1024, r.StdDev, r.PeakToMean, r.MovedOnAdd*100, r.MovedOnRemove*100)
+	}
+
+	return nil
+}
+
+func positiveInts(list string) ([]int, error) {
+	ns := make([]int, 0)
+	for _, s := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("bench: bad number %q", s)
+		}
+		ns = append(ns, n)
+	}
+	return ns, nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func benchMembership(sizes string, asJSON bool) error {
+	ns, err := positiveInts(sizes)
+	if err != nil {
+		return err
+	}
+
+	results := bench.Membership(ns, bench.MEMBERSHIP_ROUNDS)
+	if asJSON {
+		return printJSON(results)
+	}
+
+	printMembership(results)
+	return nil
+}
+
+func printMembership(results []bench.MembershipResult) {
+	fmt.Println("nodes\treplicas\tvirtual\tadd\tremove\tupdate_weight")
+	for _, r := range results {
+		fmt.Printf("%d\t%d\t%d\t%.0fus\t%.0fus\t%.0fus\n", r.Nodes, r.Replicas, r.VirtualNodes, r.Add/1e3, r.Remove/1e3, r.UpdateWeight/1e3)
+	}
+}
+
+func benchBootstrap(sizes string, asJSON bool) error {
+	ns, err := positiveInts(sizes)
+	if err != nil {
+		return err
+	}
+
+	results := bench.Bootstrap(ns)
+	if asJSON {
+		return printJSON(results)
+	}
+
+	fmt.Println("nodes\tadd\tadd_batch\tspeedup")
+	for _, r := range results {
+		fmt.Printf("%d\t%.1fms\t%.1fms\t%.1fx\n", r.Nodes, r.Sequential/1e6, r.Batch/1e6, r.Speedup)
+	}
+	return nil
+}
+
+func benchWeighted(weights string, src keygen.Source, asJSON bool) error {
+	ws, err := positiveInts(weights)
+	if err != nil {
+		return err
+	}
+
+	sample, err := src.Sample()
+	if err != nil {
+		return err
+	}
+
+	results, err := bench.Weighted(ws, sample, bench.WEIGHTED_NAMES)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(results)
+	}
+
+	fmt.Println("algo\tid\tweight\ttarget\tshare\terror")
+	for _, r := range results {
+		for _, s := range r.Shares {
+			fmt.Printf("%s\t%d\t%d\t%.2f%%\t%.2f%%\t%+.1f%%\n", r.Name, s.Id, s.Weight, s.Target*100, s.Share*100, (s.Share/s.Target-1)*100)
+		}
+		fmt.Printf("%s\tmax error %.1f%%\n", r.Name, r.MaxError*100)
+	}
+	return nil
+}
+
+func benchSuite(src keygen.Source, asJSON bool) error {
+	sample, err := src.Sample()
+	if err != nil {
+		return err
+	}
+
+	s, err := bench.RunSuite(sample)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(s)
+	}
+
+	fmt.Println("nodes\tvirtual\tlookups/s\tp50\tp99\tallocs/op")
+	for _, r := range s.Sizes {
+		fmt.Printf("%d\t%d\t%.0f\t%.0fns\t%.0fns\t%.2f\n", r.Nodes, r.VirtualNodes, r.LookupsPerSec, r.Latency.P50, r.Latency.P99, r.AllocsPerOp)
+	}
+	fmt.Println()
+	printMembership(append(s.Membership, s.Replicas...))
+	fmt.Println()
+	fmt.Println("goroutines\tchurn\tlookups/s")
+	for _, r := range s.Parallel {
+		fmt.Printf("%d\t%v\t%.0f\n", r.Goroutines, r.Churn, r.LookupsPerSec)
+	}
+	fmt.Println()
+	fmt.Println("lookup\tallocs/op\tns/op")
+	for _, r := range s.Allocs {
+		fmt.Printf("%s\t%.2f\t%.0f\n", r.Name, r.AllocsPerOp, r.NsPerOp)
+	}
+	return nil
+}
+
+func benchParallel(t *topology.Topology, src keygen.Source, goroutines string, asJSON bool) error {
+	gs, err := positiveInts(goroutines)
+	if err != nil {
+		return err
+	}
+
+	sample, err := src.Sample()
+	if err != nil {
+		return err
+	}
+
+	results, err := bench.Parallel(t.Nodes, sample, gs)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(results)
+	}
+
+	fmt.Println("goroutines\tchurn\tlookups/s\tchanges")
+	for _, r := range results {
+		fmt.Printf("%d\t%v\t%.0f\t%d\n", r.Goroutines, r.Churn, r.LookupsPerSec, r.Changes)
+	}
+	return nil
+}
+
+func benchAllocs(t *topology.Topology, src keygen.Source, asJSON bool) error {
+	sample, err := src.Sample()
+	if err != nil {
+		return err
+	}
+
+	results, err := bench.LookupAllocs(t.Nodes, sample)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(results)
+	}
+
+	fmt.Println("lookup\tallocs/op\tns/op")
+	for _, r := range results {
+		fmt.Printf("%s\t%.2f\t%.0f\n", r.Name, r.AllocsPerOp, r.NsPerOp)
+	}
+	return nil
+}
+
+func benchBatch(t *topology.Topology, src keygen.Source, asJSON bool) error {
+	sample, err := src.Sample()
+	if err != nil {
+		return err
+	}
+
+	results, err := bench.Batch(t.Nodes, sample)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(results)
+	}
+
+	fmt.Println("lookup\tkeys\tns/key")
+	for _, r := range results {
+		fmt.Printf("%s\t%d\t%.0f\n", r.Name, r.Keys, r.NsPerKey)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/axiusilihao/geek_homework/homework_5/compare"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("compare", "run one key stream through several strategies and show where they disagree", compareCmd)
+}
+
+func compareCmd(args []string) error {
+	fs, file := newFlagSet("compare")
+	strategies := fs.String("strategies", "ring,hrw,maglev", "comma separated strategies, the first is the baseline")
+	src := sourceFlags(fs)
+	examples := fs.Int("examples", compare.DEFAULT_EXAMPLES, "number of disagreeing keys to keep per pair")
+	flows := fs.Int("flows", 5, "number of node to node flows to print per pair")
+	asJSON := fs.Bool("json", false, "print the result as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := src.Validate(); err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	candidates, err := compare.Candidates(t.Nodes, strings.Split(*strategies, ","))
+	if err != nil {
+		return err
+	}
+
+	res, err := compare.Run(*src, candidates, *examples)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+
+	fmt.Printf("keys %d from %s, seed %d\n", res.Source.Keys, res.Source.Name, res.Source.Seed)
+	fmt.Println("strategy\tstd dev\tpeak/mean")
+	for _, b := range res.Balances {
+		fmt.Printf("%s\t%.1f\t%.3f\n", b.Name, b.StdDev, b.PeakToMean)
+	}
+
+	for _, d := range res.Disagreements {
+		fmt.Printf("\n%s vs %s: %d of %d keys differ (%.2f%%)\n", d.A, d.B, d.Keys, res.Keys, d.Fraction*100)
+		for i, f := range d.Flows {
+			if i >= *flows {
+				break
+			}
+			fmt.Printf("  node %d -> node %d: %d keys\n", f.From, f.To, f.Keys)
+		}
+		for _, e := range d.Examples {
+			fmt.Printf("  %s: %d -> %d\n", e.Key, e.A, e.B)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/axiusilihao/geek_homework/homework_5/simulate"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("diff", "compare two topology snapshots", diffCmd)
+}
+
+type diffReport struct {
+	*topology.Diff
+	Moved  float64                `json:"moved"`
+	Shares []simulate.ShareChange `json:"shares"`
+}
+
+func diffCmd(args []string) error {
+	fs, _ := newFlagSet("diff")
+	asJSON := fs.Bool("json", false, "print the report as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errors.New("diff: usage: hashring diff old.snap new.snap")
+	}
+
+	old, err := topology.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	new, err := topology.Load(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	p := simulate.WhatIf(old.Nodes, new.Nodes)
+	r := &diffReport{Diff: topology.Compare(old, new), Moved: p.Moved, Shares: p.Shares}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	if r.Empty() {
+		fmt.Println("no changes")
+		return nil
+	}
+
+	for _, node := range r.Added {
+		fmt.Printf("+ node %d %s:%d weight=%d\n", node.Id, node.Ip, node.Port, node.Weight)
+	}
+	for _, node := range r.Removed {
+		fmt.Printf("- node %d %s:%d weight=%d\n", node.Id, node.Ip, node.Port, node.Weight)
+	}
+	for _, rw := range r.Reweighted {
+		fmt.Printf("~ node %d weight %d -> %d\n", rw.Node.Id, rw.From, rw.To)
+	}
+	for _, c := range r.Changed {
+		fmt.Printf("~ node %d %s:%d %s -> %s:%d %s\n", c.Old.Id, c.Old.Ip, c.Old.Port, c.Old.HostName, c.New.Ip, c.New.Port, c.New.HostName)
+	}
+
+	fmt.Printf("estimated movement: %.2f%% of the keyspace\n", r.Moved*100)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/axiusilihao/geek_homework/homework_5/envoy"
+	"github.com/axiusilihao/geek_homework/homework_5/haproxy"
+	"github.com/axiusilihao/geek_homework/homework_5/nginx"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+	"github.com/axiusilihao/geek_homework/homework_5/twemproxy"
+)
+
+func init() {
+	register("export", "render the topology as json, nginx, haproxy, envoy or twemproxy config, or draw it as dot, svg or html", export)
+}
+
+func export(args []string) error {
+	fs, file := newFlagSet("export")
+	format := fs.String("format", "json", "json, nginx, haproxy, envoy, twemproxy, dot, svg or html")
+	name := fs.String("name", "backend", "upstream/backend/cluster/pool name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+	ring := t.Ring()
+
+	switch *format {
+	case "json":
+		return t.Encode(os.Stdout)
+	case "nginx":
+		os.Stdout.Write(nginx.NewUpstream(*name, ring).Render())
+	case "haproxy":
+		os.Stdout.Write(haproxy.NewBackend(*name, ring).Render())
+	case "envoy":
+		data, err := envoy.NewCluster(ring, envoy.Options{Name: *name}).MarshalIndent()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "twemproxy":
+		os.Stdout.Write(twemproxy.Render(twemproxy.NewPool(*name, ring)))
+	case "dot":
+		return ring.ExportDOT(os.Stdout)
+	case "svg":
+		return ring.ExportSVG(os.Stdout)
+	case "html":
+		return ring.ExportHTML(os.Stdout)
+	default:
+		return fmt.Errorf("export: unknown format %q", *format)
+	}
+
+	return nil
+}
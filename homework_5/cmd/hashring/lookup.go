@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("lookup", "print the node owning each key", lookup)
+}
+
+func lookup(args []string) error {
+	fs, file := newFlagSet("lookup")
+	n := fs.Int("n", 1, "number of distinct nodes to print for each key")
+	explain := fs.Bool("explain", false, "print how each key was placed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("lookup: missing key")
+	}
+	if *n <= 0 {
+		return errors.New("lookup: -n must be positive")
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+	ring := t.Ring()
+
+	for _, key := range fs.Args() {
+		if *explain {
+			e, err := ring.ExplainGet(key)
+			if err != nil {
+				return err
+			}
+			printExplanation(e)
+			continue
+		}
+
+		nodes := ring.GetN(key, *n)
+		if len(nodes) == 0 {
+			return errors.New("lookup: ring is empty")
+		}
+
+		for i, node := range nodes {
+			fmt.Printf("%s\t%d\tid=%d\t%s:%d\t%s\n", key, i, node.Id, node.Ip, node.Port, node.HostName)
+		}
+	}
+
+	return nil
+}
+
+func printExplanation(e *consistent.Explanation) {
+	fmt.Printf("key      %q\n", e.Key)
+	fmt.Printf("hash     %08x\n", e.Hash)
+	if e.Found == e.RingSize {
+		fmt.Printf("found    past the end of %d points\n", e.RingSize)
+	} else {
+		fmt.Printf("found    index %d of %d\n", e.Found, e.RingSize)
+	}
+	if e.Wrapped {
+		fmt.Printf("index    %d (wrapped by search)\n", e.Index)
+	} else {
+		fmt.Printf("index    %d\n", e.Index)
+	}
+	fmt.Printf("point    %08x replica %d label %q\n", e.Point, e.Replica, e.Label)
+	for _, s := range e.Skipped {
+		fmt.Printf("skipped  index %d point %08x id=%d: %s\n", s.Index, s.Point, s.Node.Id, s.Reason)
+	}
+	fmt.Printf("owner    id=%d\t%s:%d\t%s\n", e.Owner.Id, e.Owner.Ip, e.Owner.Port, e.Owner.HostName)
+}
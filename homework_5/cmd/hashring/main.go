@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{}
+
+func register(name, usage string, run func(args []string) error) {
+	commands[name] = command{usage: usage, run: run}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: hashring <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "hashring: unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "hashring:", err)
+		os.Exit(1)
+	}
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("hashring "+name, flag.ContinueOnError)
+	file := fs.String("f", "topology.json", "topology file")
+	return fs, file
+}
+
+// sourceFlags 注册 -keys -keygen -seed, 所有需要样本 key 的命令共用, Parse 之后用 Source.Validate 检查, 结果里会记录这三个值
+func sourceFlags(fs *flag.FlagSet) *keygen.Source {
+	src := &keygen.Source{}
+	fs.IntVar(&src.Keys, "keys", 10_0000, "number of sample keys")
+	fs.StringVar(&src.Name, "keygen", "sequential", "key generator: sequential, uniform, zipf or hotspot")
+	fs.Int64Var(&src.Seed, "seed", keygen.DEFAULT_SEED, "key generator seed")
+	return src
+}
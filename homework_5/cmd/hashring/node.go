@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("add-node", "add a node to the topology file", addNode)
+	register("remove-node", "remove a node from the topology file", removeNode)
+}
+
+func addNode(args []string) error {
+	fs, file := newFlagSet("add-node")
+	id := fs.Int("id", -1, "node id, defaults to max id + 1")
+	ip := fs.String("ip", "", "node ip")
+	port := fs.Int("port", 8080, "node port")
+	name := fs.String("name", "", "host name")
+	weight := fs.Int("weight", 1, "node weight")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *ip == "" {
+		return errors.New("add-node: -ip is required")
+	}
+	if *weight <= 0 {
+		return errors.New("add-node: -weight must be positive")
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	if *id < 0 {
+		*id = 0
+		for _, node := range t.Nodes {
+			if node.Id >= *id {
+				*id = node.Id + 1
+			}
+		}
+	}
+	if *name == "" {
+		*name = "host_" + strconv.Itoa(*id)
+	}
+
+	if err := t.Add(*consistent.NewNode(*id, *ip, *port, *name, *weight)); err != nil {
+		return err
+	}
+
+	if err := t.Save(*file); err != nil {
+		return err
+	}
+
+	fmt.Println("added node", *id)
+	return nil
+}
+
+func removeNode(args []string) error {
+	fs, file := newFlagSet("remove-node")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("remove-node: expected a node id")
+	}
+
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Remove(id); err != nil {
+		return err
+	}
+
+	if err := t.Save(*file); err != nil {
+		return err
+	}
+
+	fmt.Println("removed node", id)
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("ownership", "print the exact keyspace share of each node and, optionally, every range", ownership)
+}
+
+type ownershipReport struct {
+	Nodes  []nodeOwnership    `json:"nodes"`
+	Ranges []consistent.Range `json:"ranges,omitempty"`
+}
+
+// nodeOwnership 的 Target 按虚拟节点数计算, 即 Weight、Capacity 或 Replicas 的比例
+type nodeOwnership struct {
+	Node         consistent.Node `json:"node"`
+	VirtualNodes int             `json:"virtual_nodes"`
+	Share        float64         `json:"share"`
+	Target       float64         `json:"target"`
+}
+
+func ownership(args []string) error {
+	fs, file := newFlagSet("ownership")
+	ranges := fs.Bool("ranges", false, "also print every contiguous range and its owner")
+	asJSON := fs.Bool("json", false, "print the report as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+	ring := t.Ring()
+
+	members := ring.Members()
+	vnodes := ring.VirtualNodes()
+	total := ring.VirtualNodeCount()
+
+	r := &ownershipReport{}
+	shares := ring.Ownership()
+	for _, node := range members {
+		target := 0.0
+		if total > 0 {
+			target = float64(vnodes[node.Id]) / float64(total)
+		}
+		r.Nodes = append(r.Nodes, nodeOwnership{Node: node, VirtualNodes: vnodes[node.Id], Share: shares[node.Id], Target: target})
+	}
+	if *ranges {
+		r.Ranges = ring.OwnershipTable()
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	for _, n := range r.Nodes {
+		fmt.Printf("id=%d\t%s:%d\tweight=%d\tcapacity=%g\tvnodes=%d\tshare=%.4f%%\ttarget=%.4f%%\n",
+			n.Node.Id, n.Node.Ip, n.Node.Port, n.Node.Weight, n.Node.Capacity, n.VirtualNodes, n.Share*100, n.Target*100)
+	}
+	for _, rg := range r.Ranges {
+		fmt.Printf("%016x-%016x\tid=%d\t%.6f%%\n", rg.Start, rg.End, rg.Node.Id, rg.Fraction*100)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/stats"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("repl", "explore a ring interactively", replCmd)
+}
+
+const replHelp = `commands:
+  get <key> [n]        owner (and n-1 successors) of key
+  add <ip> <weight>    add a node, port 8080
+  rm <id>              remove a node
+  nodes                list nodes
+  dist <keys>          distribution of key0..keyN, e.g. dist 1e6
+  mark                 remember the current ring for diff
+  diff [keys]          keys moved since the last mark
+  save <file>          write the current topology
+  help, quit`
+
+type session struct {
+	t        *topology.Topology
+	ring     *consistent.Consistent
+	baseline *consistent.Consistent
+	out      io.Writer
+}
+
+func replCmd(args []string) error {
+	fs, file := newFlagSet("repl")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	t := &topology.Topology{}
+	if _, err := os.Stat(*file); err == nil {
+		if t, err = topology.Load(*file); err != nil {
+			return err
+		}
+	}
+
+	s := &session{t: t, ring: t.Ring(), baseline: t.Ring(), out: os.Stdout}
+	fmt.Fprintln(s.out, "loaded", len(t.Nodes), "nodes, type help for commands")
+
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(s.out, "> ")
+		if !in.Scan() {
+			fmt.Fprintln(s.out)
+			return in.Err()
+		}
+
+		fields := strings.Fields(in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return nil
+		}
+
+		if err := s.exec(fields[0], fields[1:]); err != nil {
+			fmt.Fprintln(s.out, "error:", err)
+		}
+	}
+}
+
+func (s *session) exec(cmd string, args []string) error {
+	switch cmd {
+	case "help":
+		fmt.Fprintln(s.out, replHelp)
+	case "get":
+		return s.get(args)
+	case "add":
+		return s.add(args)
+	case "rm":
+		return s.rm(args)
+	case "nodes":
+		for _, node := range s.ring.Members() {
+			fmt.Fprintf(s.out, "id=%d\t%s:%d\t%s\tweight=%d\n", node.Id, node.Ip, node.Port, node.HostName, node.Weight)
+		}
+	case "dist":
+		return s.dist(args)
+	case "mark":
+		s.baseline = s.t.Ring()
+		fmt.Fprintln(s.out, "marked", len(s.t.Nodes), "nodes")
+	case "diff":
+		return s.diff(args)
+	case "save":
+		if len(args) != 1 {
+			return errors.New("usage: save <file>")
+		}
+		return s.t.Save(args[0])
+	default:
+		return fmt.Errorf("unknown command %q, type help", cmd)
+	}
+
+	return nil
+}
+
+func (s *session) get(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: get <key> [n]")
+	}
+
+	n := 1
+	if len(args) > 1 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v <= 0 {
+			return fmt.Errorf("bad n %q", args[1])
+		}
+		n = v
+	}
+
+	nodes := s.ring.GetN(args[0], n)
+	if len(nodes) == 0 {
+		return errors.New("ring is empty")
+	}
+
+	for i, node := range nodes {
+		fmt.Fprintf(s.out, "%d\tid=%d\t%s:%d\t%s\n", i, node.Id, node.Ip, node.Port, node.HostName)
+	}
+	return nil
+}
+
+func (s *session) add(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: add <ip> <weight>")
+	}
+
+	weight, err := strconv.Atoi(args[1])
+	if err != nil || weight <= 0 {
+		return errors.New("weight must be a positive integer")
+	}
+
+	id := 0
+	for _, node := range s.t.Nodes {
+		if node.Id >= id {
+			id = node.Id + 1
+		}
+	}
+
+	node := consistent.NewNode(id, args[0], 8080, "host_"+strconv.Itoa(id), weight)
+	if err := s.t.Add(*node); err != nil {
+		return err
+	}
+	s.ring.Add(node)
+
+	fmt.Fprintln(s.out, "added node", id)
+	return nil
+}
+
+func (s *session) rm(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: rm <id>")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+
+	node, ok := s.t.Find(id)
+	if !ok {
+		return topology.ErrNodeNotFound
+	}
+
+	s.t.Remove(id)
+	s.ring.Remove(&node)
+
+	fmt.Fprintln(s.out, "removed node", id)
+	return nil
+}
+
+func parseCount(args []string, def int) (int, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+
+	v, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("bad key count %q", args[0])
+	}
+	return int(v), nil
+}
+
+func (s *session) dist(args []string) error {
+	keys, err := parseCount(args, 10_0000)
+	if err != nil {
+		return err
+	}
+
+	members := s.ring.Members()
+	if len(members) == 0 {
+		return errors.New("ring is empty")
+	}
+
+	counts := make(map[int]int, len(members))
+	for i := 0; i < keys; i++ {
+		node, err := s.ring.Get(fmt.Sprintf("key%d", i))
+		if err != nil {
+			return err
+		}
+		counts[node.Id]++
+	}
+
+	values := make([]int, 0, len(members))
+	for _, node := range members {
+		values = append(values, counts[node.Id])
+		fmt.Fprintf(s.out, "id=%d\tweight=%d\tkeys=%d\tshare=%.4f\n", node.Id, node.Weight, counts[node.Id], float64(counts[node.Id])/float64(keys))
+	}
+
+	fmt.Fprintln(s.out, "标准差:", stats.StdDev(stats.Floats(values)))
+	return nil
+}
+
+func (s *session) diff(args []string) error {
+	keys, err := parseCount(args, 10_0000)
+	if err != nil {
+		return err
+	}
+
+	if len(s.ring.Members()) == 0 || len(s.baseline.Members()) == 0 {
+		return errors.New("ring is empty")
+	}
+
+	moved := 0
+	flows := make(map[[2]int]int)
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key%d", i)
+		a, err := s.baseline.Get(key)
+		if err != nil {
+			return err
+		}
+		b, err := s.ring.Get(key)
+		if err != nil {
+			return err
+		}
+		from, to := a.Id, b.Id
+		if from != to {
+			moved++
+			flows[[2]int{from, to}]++
+		}
+	}
+
+	order := make([][2]int, 0, len(flows))
+	for flow := range flows {
+		order = append(order, flow)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return flows[order[i]] > flows[order[j]]
+	})
+
+	fmt.Fprintf(s.out, "moved %d of %d keys (%.2f%%)\n", moved, keys, float64(moved)/float64(keys)*100)
+	for _, flow := range order {
+		fmt.Fprintf(s.out, "  node %d -> node %d: %d\n", flow[0], flow[1], flows[flow])
+	}
+	return nil
+}
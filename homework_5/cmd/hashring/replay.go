@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/axiusilihao/geek_homework/homework_5/replay"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("replay", "route real keys from a file or stdin and report distribution and hot keys", replayCmd)
+}
+
+func replayCmd(args []string) error {
+	fs, file := newFlagSet("replay")
+	format := fs.String("format", replay.FORMAT_AUTO, "auto, lines or jsonl")
+	keyField := fs.String("key-field", replay.DEFAULT_KEY_FIELD, "jsonl key field")
+	timeField := fs.String("time-field", replay.DEFAULT_TIME_FIELD, "jsonl timestamp field")
+	top := fs.Int("top", 20, "number of hot keys to report")
+	asJSON := fs.Bool("json", false, "print result as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if fs.NArg() > 0 && fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	r := replay.NewReader(in, *format)
+	r.KeyField = *keyField
+	r.TimeField = *timeField
+
+	res, err := replay.Replay(t.Ring(), r, *top)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+
+	fmt.Println("数据分布情况:", res.Total, "keys")
+	for _, n := range res.Nodes {
+		fmt.Printf("id=%d\t%s\tweight=%d\tkeys=%d\tshare=%.4f\n", n.Id, n.Name, n.Weight, n.Count, n.Share)
+	}
+	fmt.Printf("标准差: %.1f\tpeak/mean: %.3f\n", res.StdDev, res.PeakToMean)
+	if !res.First.IsZero() {
+		fmt.Println("时间范围:", res.First, "-", res.Last)
+	}
+
+	fmt.Println("热点 key:")
+	for _, kc := range res.HotKeys {
+		fmt.Printf("%s\tcount=%d\terror<=%d\tnode=%d\n", kc.Key, kc.Count, kc.Error, kc.Node)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+
+	"github.com/axiusilihao/geek_homework/homework_5/report"
+	"github.com/axiusilihao/geek_homework/homework_5/simulate"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("report", "write a self-contained html distribution report", reportCmd)
+}
+
+func reportCmd(args []string) error {
+	fs, file := newFlagSet("report")
+	src := sourceFlags(fs)
+	out := fs.String("o", "report.html", "output file")
+	eventsFile := fs.String("events", "", "optional churn event list to include movement")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := src.Validate(); err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	r, err := report.NewDistributionReportSource(t.Ring(), *src)
+	if err != nil {
+		return err
+	}
+	if *eventsFile != "" {
+		events, err := loadEvents(*eventsFile)
+		if err != nil {
+			return err
+		}
+
+		churn, err := simulate.ChurnSource(t.Nodes, events, *src)
+		if err != nil {
+			return err
+		}
+		r.WithMovement(churn.Steps)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+
+	if err := r.WriteHTML(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
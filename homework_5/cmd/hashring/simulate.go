@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+	"github.com/axiusilihao/geek_homework/homework_5/simulate"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("simulate", "run a simulation: churn, chaos or workload", simulateCmd)
+}
+
+func simulateCmd(args []string) error {
+	if len(args) == 0 {
+		return errors.New("simulate: expected a mode, e.g. simulate churn")
+	}
+
+	switch args[0] {
+	case "churn":
+		return simulateChurn(args[1:])
+	case "chaos":
+		return simulateChaos(args[1:])
+	case "workload":
+		return simulateWorkload(args[1:])
+	}
+
+	return fmt.Errorf("simulate: unknown mode %q", args[0])
+}
+
+func loadEvents(path string) ([]simulate.Event, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]simulate.Event, 0)
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func simulateChurn(args []string) error {
+	fs, file := newFlagSet("simulate churn")
+	eventsFile := fs.String("events", "events.json", `event list, e.g. [{"op":"remove","node":{"id":3}},{"op":"weight","node":{"id":1,"weight":2}}]`)
+	src := sourceFlags(fs)
+	replicas := fs.Int("replicas", consistent.DEFAULT_REPLICAS, "virtual nodes per unit of weight")
+	algos := fs.String("algos", "", "compare moved keys across algorithms instead, e.g. ring,hrw,jump")
+	asJSON := fs.Bool("json", false, "print steps as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := src.Validate(); err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	events, err := loadEvents(*eventsFile)
+	if err != nil {
+		return err
+	}
+
+	if *algos != "" {
+		return churnCompare(strings.Split(*algos, ","), t.Nodes, events, *src, *asJSON)
+	}
+
+	r, err := simulate.ChurnSource(t.Nodes, events, *src, consistent.WithReplicas(*replicas))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	fmt.Printf("keys %d from %s, seed %d\n", r.Source.Keys, r.Source.Name, r.Source.Seed)
+	fmt.Println("step\top\tnode\tnodes\tmoved\tmoved%\tcumulative\tstddev\tpeak/mean")
+	for i, s := range r.Steps {
+		fmt.Printf("%d\t%s\t%d\t%d\t%d\t%.2f\t%d\t%.1f\t%.3f\n",
+			i+1, s.Event.Op, s.Event.Node.Id, s.Nodes, s.Moved, s.MovedFraction*100, s.Cumulative, s.Balance.StdDev, s.Balance.PeakToMean)
+	}
+
+	return nil
+}
+
+// churnCompare 每一行是一步, 每一列是一个算法这一步移动的 key 的比例
+func churnCompare(names []string, nodes []consistent.Node, events []simulate.Event, src keygen.Source, asJSON bool) error {
+	sample, err := src.Sample()
+	if err != nil {
+		return err
+	}
+
+	res, err := simulate.ChurnCompare(names, nodes, events, sample)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+
+	fmt.Printf("keys %d from %s, seed %d, moved%%\n", src.Keys, src.Name, src.Seed)
+	fmt.Printf("step\top\tnode\t%s\n", strings.Join(names, "\t"))
+	for i, e := range events {
+		fmt.Printf("%d\t%s\t%d", i+1, e.Op, e.Node.Id)
+		for _, name := range names {
+			fmt.Printf("\t%.2f", res[name][i].MovedFraction*100)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func simulateChaos(args []string) error {
+	fs, file := newFlagSet("simulate chaos")
+	cfg := simulate.DefaultChaosConfig()
+	fs.Int64Var(&cfg.Seed, "seed", cfg.Seed, "chaos schedule seed")
+	fs.IntVar(&cfg.Ticks, "ticks", cfg.Ticks, "number of ticks")
+	fs.IntVar(&cfg.Keys, "keys", cfg.Keys, "number of sample keys")
+	fs.Float64Var(&cfg.KillProb, "kill", cfg.KillProb, "probability of killing a node per tick")
+	fs.Float64Var(&cfg.RestoreProb, "restore", cfg.RestoreProb, "probability of restoring a dead node per tick")
+	fs.Float64Var(&cfg.LatencyProb, "latency", cfg.LatencyProb, "probability of a health check latency spike per tick")
+	fs.IntVar(&cfg.FailThreshold, "fail-threshold", cfg.FailThreshold, "consecutive failed probes before eviction")
+	fs.IntVar(&cfg.RecoverThreshold, "recover-threshold", cfg.RecoverThreshold, "consecutive good probes before readmission")
+	fs.IntVar(&cfg.Failover, "failover", cfg.Failover, "successors to try when the owner is down")
+	asJSON := fs.Bool("json", false, "print the report as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+	if len(t.Nodes) == 0 {
+		return errors.New("simulate chaos: topology has no nodes")
+	}
+
+	r := simulate.Chaos(t.Nodes, cfg)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	fmt.Println("tick\tdown\tevicted\tfailed%\tfailover%\tmoved%\tevents")
+	for _, tk := range r.Ticks {
+		fmt.Printf("%d\t%v\t%v\t%.2f\t%.2f\t%.2f\t%v\n", tk.Tick, tk.Down, tk.Evicted, tk.Failed*100, tk.FailedOver*100, tk.Moved*100, tk.Events)
+	}
+	fmt.Printf("seed %d: failed %.2f%%, failed over %.2f%%, mean detection %.1f ticks, false evictions %d\n",
+		cfg.Seed, r.Failed*100, r.FailedOver*100, r.MeanDetection, r.FalseEvictions)
+	return nil
+}
+
+func simulateWorkload(args []string) error {
+	fs, file := newFlagSet("simulate workload")
+	src := sourceFlags(fs)
+	asJSON := fs.Bool("json", false, "print the result as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := src.Validate(); err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	gen, err := keygen.ByName(src.Name, src.Seed, src.Keys)
+	if err != nil {
+		return err
+	}
+
+	r := simulate.Simulate(t.Ring(), gen, src.Keys)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	fmt.Printf("%d requests from %s, seed %d\n", r.Requests, src.Name, src.Seed)
+	fmt.Println("id\tweight\thits\tshare\texpected")
+	for _, n := range r.Nodes {
+		fmt.Printf("%d\t%d\t%d\t%.4f\t%.4f\n", n.Id, n.Weight, n.Hits, n.Share, n.Expected)
+	}
+	fmt.Printf("stddev %.1f  p50 %.0f  p99 %.0f  peak/mean %.3f  chi-square %.1f (df %d, p %.4f)\n",
+		r.Summary.StdDev, r.Summary.P50, r.Summary.P99, r.PeakToMean, r.ChiSquare.Statistic, r.ChiSquare.DF, r.ChiSquare.PValue)
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+	"github.com/axiusilihao/geek_homework/homework_5/stats"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("stats", "hash sample keys and print the distribution", statsCmd)
+}
+
+func statsCmd(args []string) error {
+	fs, file := newFlagSet("stats")
+	src := sourceFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := src.Validate(); err != nil {
+		return err
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+	if len(t.Nodes) == 0 {
+		return errors.New("stats: ring is empty")
+	}
+
+	gen, err := keygen.ByName(src.Name, src.Seed, src.Keys)
+	if err != nil {
+		return err
+	}
+
+	ring := t.Ring()
+	keys := src.Keys
+	counts := make(map[int]int)
+	for i := 0; i < keys; i++ {
+		node, err := ring.Get(gen.Next())
+		if err != nil {
+			return err
+		}
+		counts[node.Id]++
+	}
+
+	values := make([]int, 0, len(t.Nodes))
+	expected := make([]float64, 0, len(t.Nodes))
+	weights := 0
+	for _, node := range ring.Members() {
+		weights += node.Weight
+	}
+	for _, node := range ring.Members() {
+		v := counts[node.Id]
+		values = append(values, v)
+		expected = append(expected, float64(keys)*float64(node.Weight)/float64(weights))
+		fmt.Printf("id=%d\t%s:%d\tweight=%d\tkeys=%d\tshare=%.4f\n", node.Id, node.Ip, node.Port, node.Weight, v, float64(v)/float64(keys))
+	}
+
+	sum := stats.Summarize(stats.Floats(values))
+	fmt.Printf("均值: %.1f 标准差: %.1f p50: %.0f p95: %.0f p99: %.0f 最小: %.0f 最大: %.0f\n",
+		sum.Mean, sum.StdDev, sum.P50, sum.P95, sum.P99, sum.Min, sum.Max)
+
+	// 期望按权重分配, 权重都相同时就是均匀分布
+	chi := stats.ChiSquare(stats.Floats(values), expected)
+	fmt.Printf("卡方: %.2f 自由度: %d p 值: %.4f\n", chi.Statistic, chi.DF, chi.PValue)
+	return nil
+}
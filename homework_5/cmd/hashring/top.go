@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/ringserver"
+	"github.com/axiusilihao/geek_homework/homework_5/simulate"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func init() {
+	register("top", "live view of a running ringserver", top)
+}
+
+const (
+	TOP_EVENTS = 10
+	BAR_WIDTH  = 40
+)
+
+type topState struct {
+	version uint64
+	t       *topology.Topology
+	events  []string
+	err     error
+}
+
+func top(args []string) error {
+	fs, _ := newFlagSet("top")
+	addr := fs.String("addr", "localhost:9090", "ringserver grpc address")
+	interval := fs.Duration("interval", time.Second, "redraw interval")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cc, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	events, errs, err := ringserver.NewClient(cc).Watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	s := &topState{t: &topology.Topology{}}
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				s.err = <-errs
+				s.draw(*addr)
+				return s.err
+			}
+			s.apply(e)
+			s.draw(*addr)
+		case <-ticker.C:
+			s.draw(*addr)
+		}
+	}
+}
+
+func (s *topState) apply(e *ringserver.TopologyEvent) {
+	s.version = e.Version
+
+	switch e.Type {
+	case ringserver.EVENT_SNAPSHOT:
+		s.t = &topology.Topology{Nodes: e.Nodes}
+		s.log(fmt.Sprintf("v%d snapshot with %d nodes", e.Version, len(e.Nodes)))
+	case ringserver.EVENT_ADDED:
+		s.t.Add(*e.Node)
+		s.log(fmt.Sprintf("v%d + node %d %s:%d weight=%d", e.Version, e.Node.Id, e.Node.Ip, e.Node.Port, e.Node.Weight))
+	case ringserver.EVENT_DRAINING:
+		s.t.Remove(e.Node.Id)
+		s.log(fmt.Sprintf("v%d ~ node %d %s:%d draining", e.Version, e.Node.Id, e.Node.Ip, e.Node.Port))
+	case ringserver.EVENT_REMOVED:
+		s.t.Remove(e.Node.Id)
+		s.log(fmt.Sprintf("v%d - node %d %s:%d", e.Version, e.Node.Id, e.Node.Ip, e.Node.Port))
+	}
+}
+
+func (s *topState) log(line string) {
+	s.events = append(s.events, time.Now().Format("15:04:05")+" "+line)
+	if len(s.events) > TOP_EVENTS {
+		s.events = s.events[len(s.events)-TOP_EVENTS:]
+	}
+}
+
+func (s *topState) draw(addr string) {
+	var b strings.Builder
+
+	b.WriteString("\033[H\033[2J")
+	fmt.Fprintf(&b, "hashring top - %s - version %d - %s\n\n", addr, s.version, time.Now().Format("15:04:05"))
+
+	ring := s.t.Ring()
+	shares := simulate.Shares(ring)
+	members := ring.Members()
+
+	totalWeight := 0
+	for _, node := range members {
+		totalWeight += node.Weight
+	}
+
+	fmt.Fprintf(&b, "%-6s %-22s %-8s %-7s %-8s %-8s %s\n", "ID", "ADDRESS", "STATE", "WEIGHT", "SHARE", "TARGET", "LOAD")
+	peak, variance := 0.0, 0.0
+	for _, node := range members {
+		share := shares[node.Id]
+		target := float64(node.Weight) / float64(totalWeight)
+		peak = math.Max(peak, share/target)
+		variance += math.Pow(share-1/float64(len(members)), 2)
+
+		bar := strings.Repeat("#", int(share*BAR_WIDTH*float64(len(members))/2))
+		if len(bar) > BAR_WIDTH {
+			bar = bar[:BAR_WIDTH]
+		}
+		fmt.Fprintf(&b, "%-6d %-22s %-8s %-7d %-8s %-8s %s\n", node.Id, fmt.Sprintf("%s:%d", node.Ip, node.Port), "active",
+			node.Weight, fmt.Sprintf("%.2f%%", share*100), fmt.Sprintf("%.2f%%", target*100), bar)
+	}
+
+	if len(members) > 0 {
+		fmt.Fprintf(&b, "\nnodes %d  virtual nodes %d  peak/mean %.3f  share stddev %.4f\n",
+			len(members), len(ring.Nodes), peak, math.Sqrt(variance/float64(len(members))))
+	} else {
+		b.WriteString("\nring is empty\n")
+	}
+
+	b.WriteString("\nrecent events:\n")
+	for i := len(s.events) - 1; i >= 0; i-- {
+		b.WriteString("  " + s.events[i] + "\n")
+	}
+	if s.err != nil {
+		fmt.Fprintf(&b, "\nwatch ended: %v\n", s.err)
+	}
+
+	os.Stdout.WriteString(b.String())
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/axiusilihao/geek_homework/homework_5/simulate"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("tune", "recommend the smallest replica count meeting a peak/mean target", tune)
+}
+
+func tune(args []string) error {
+	fs, file := newFlagSet("tune")
+	keys := fs.Int("keys", 10_0000, "expected key volume used for sampling, 0 for arc analysis only")
+	target := fs.Float64("target", 1.1, "maximum acceptable peak/mean ratio")
+	candidates := fs.String("replicas", "", "comma separated replica counts to try")
+	asJSON := fs.Bool("json", false, "print the result as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var reps []int
+	if *candidates != "" {
+		for _, s := range strings.Split(*candidates, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return err
+			}
+			reps = append(reps, n)
+		}
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	res := simulate.Tune(t.Nodes, *keys, *target, reps)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+
+	fmt.Println("replicas\tvnodes\tpeak/mean\tarc peak/mean\tmemory")
+	for _, o := range res.Options {
+		fmt.Printf("%d\t%d\t%.3f\t%.3f\t%dKB\n", o.Replicas, o.VirtualNodes, o.PeakToMean, o.ArcPeakToMean, o.Bytes/1024)
+	}
+
+	if res.Recommended == nil {
+		fmt.Printf("no candidate reaches peak/mean <= %.3f, try more replicas or more keys\n", *target)
+		return nil
+	}
+
+	fmt.Printf("recommended: %d replicas (%d virtual nodes, peak/mean %.3f)\n", res.Recommended.Replicas, res.Recommended.VirtualNodes, res.Recommended.PeakToMean)
+	return nil
+}
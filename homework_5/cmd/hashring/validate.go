@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("validate", "check a topology file before deploying it", validate)
+}
+
+func validate(args []string) error {
+	fs, file := newFlagSet("validate")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		*file = fs.Arg(0)
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	problems := t.Validate()
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	if topology.HasErrors(problems) {
+		return errors.New("validate: " + *file + " is not valid")
+	}
+
+	fmt.Printf("%s ok, fingerprint %016x\n", *file, t.Ring().Fingerprint())
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/simulate"
+	"github.com/axiusilihao/geek_homework/homework_5/topology"
+)
+
+func init() {
+	register("whatif", "predict key movement for a hypothetical add or remove", whatif)
+}
+
+type multiFlag []string
+
+func (m *multiFlag) String() string {
+	return fmt.Sprint(*m)
+}
+
+func (m *multiFlag) Set(v string) error {
+	*m = append(*m, v)
+	return nil
+}
+
+func whatif(args []string) error {
+	fs, file := newFlagSet("whatif")
+	var adds, removes multiFlag
+	fs.Var(&adds, "add", "ip:port of a node to add, can be repeated")
+	fs.Var(&removes, "remove", "id of a node to remove, can be repeated")
+	weight := fs.Int("weight", 1, "weight of the added nodes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(adds) == 0 && len(removes) == 0 {
+		return errors.New("whatif: nothing to do, use --add or --remove")
+	}
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	after := &topology.Topology{Nodes: append([]consistent.Node(nil), t.Nodes...)}
+	for _, s := range removes {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		if err := after.Remove(id); err != nil {
+			return fmt.Errorf("whatif: remove %d: %v", id, err)
+		}
+	}
+
+	for _, s := range adds {
+		host, port, err := net.SplitHostPort(s)
+		if err != nil {
+			return err
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return err
+		}
+
+		id := 0
+		for _, node := range append(t.Nodes, after.Nodes...) {
+			if node.Id >= id {
+				id = node.Id + 1
+			}
+		}
+		after.Add(*consistent.NewNode(id, host, p, "host_"+strconv.Itoa(id), *weight))
+	}
+
+	p := simulate.WhatIf(t.Nodes, after.Nodes)
+	fmt.Printf("predicted movement: %.2f%% of the keyspace\n", p.Moved*100)
+	fmt.Println("id\taddress\tweight\tbefore\tafter\tdelta")
+	for _, s := range p.Shares {
+		fmt.Printf("%d\t%s:%d\t%d\t%.2f%%\t%.2f%%\t%+.2f%%\n", s.Node.Id, s.Node.Ip, s.Node.Port, s.Node.Weight, s.Before*100, s.After*100, s.Delta()*100)
+	}
+
+	fmt.Print("nodes shedding load:")
+	for _, s := range p.Shares {
+		if s.Delta() < 0 {
+			fmt.Print(" ", s.Node.Id)
+		}
+	}
+	fmt.Println()
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/report"
+)
+
+const (
+	REPORT_KEYS = 10_0000
+)
+
+type demo struct {
+	sync.Mutex
+	ring *consistent.Consistent
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	nodes := flag.Int("nodes", 10, "number of fake nodes")
+	flag.Parse()
+
+	d := &demo{ring: consistent.NewConsistent()}
+	for i := 0; i < *nodes; i++ {
+		si := strconv.Itoa(i)
+		d.ring.Add(consistent.NewNode(i, "192.168.1."+si, 8080, "host_"+si, 1))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.index)
+	mux.HandleFunc("/lookup", d.lookup)
+	mux.HandleFunc("/nodes", d.nodes)
+	mux.HandleFunc("/nodes/", d.node)
+
+	log.Printf("ringdemo with %d nodes on %s, try /lookup?key=foo", *nodes, *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// index 直接返回 report 包生成的分布报告
+func (d *demo) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := report.NewDistributionReport(d.ring, REPORT_KEYS).WriteHTML(w); err != nil {
+		log.Println("ringdemo: report:", err)
+	}
+}
+
+func (d *demo) lookup(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, "missing key")
+		return
+	}
+
+	n := 1
+	if s := r.URL.Query().Get("n"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			writeError(w, http.StatusBadRequest, "bad n")
+			return
+		}
+		n = v
+	}
+
+	nodes := d.ring.GetN(key, n)
+	if len(nodes) == 0 {
+		writeError(w, http.StatusServiceUnavailable, "ring is empty")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"key": key, "nodes": nodes})
+}
+
+func (d *demo) nodes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, d.ring.Members())
+	case http.MethodPost:
+		var node consistent.Node
+		if err := json.NewDecoder(r.Body).Decode(&node); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if node.Weight <= 0 {
+			writeError(w, http.StatusBadRequest, "weight must be positive")
+			return
+		}
+
+		if !d.ring.Add(&node) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("node %d already exists", node.Id))
+			return
+		}
+		writeJSON(w, http.StatusCreated, node)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "use GET or POST")
+	}
+}
+
+func (d *demo) node(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/nodes/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad node id")
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	for _, node := range d.ring.Members() {
+		if node.Id != id {
+			continue
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, node)
+		case http.MethodDelete:
+			d.ring.Remove(&node)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "use GET or DELETE")
+		}
+		return
+	}
+
+	writeError(w, http.StatusNotFound, fmt.Sprintf("node %d not found", id))
+}
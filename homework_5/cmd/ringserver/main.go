@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/ringserver"
+	"github.com/axiusilihao/geek_homework/homework_5/ringserver/ringpb"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "grpc listen address")
+	nodes := flag.String("nodes", "", "initial nodes, e.g. 192.168.1.1:8080,192.168.1.2:8080")
+	replicas := flag.Int("replicas", consistent.DEFAULT_REPLICAS, "virtual nodes per unit of weight")
+	hasher := flag.String("hasher", "crc32", "hash function: crc32, fnv1a, xxhash or murmur3")
+	bits := flag.Int("ring-bits", consistent.DEFAULT_RING_BITS, "ring positions, 32 or 64 bits")
+	seed := flag.Uint64("seed", 0, "hash seed mixed into every position, 0 for none")
+	flag.Parse()
+
+	h, ok := consistent.Hashers[*hasher]
+	if !ok {
+		log.Fatalf("unknown hasher %q", *hasher)
+	}
+	if *bits != 32 && *bits != 64 {
+		log.Fatalf("ring-bits must be 32 or 64, got %d", *bits)
+	}
+
+	srv := ringserver.NewServer(consistent.WithReplicas(*replicas), consistent.WithHasher(h), consistent.WithRingBits(*bits), consistent.WithSeed(*seed))
+	for i, s := range strings.Split(*nodes, ",") {
+		if s == "" {
+			continue
+		}
+
+		host, port, err := net.SplitHostPort(s)
+		if err != nil {
+			log.Fatalf("bad node %q: %v", s, err)
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			log.Fatalf("bad node %q: %v", s, err)
+		}
+
+		node := consistent.NewNode(i, host, p, "host_"+strconv.Itoa(i), 1)
+		srv.AddNode(context.Background(), &ringpb.AddNodeRequest{Node: ringserver.NodeToProto(*node)})
+	}
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := grpc.NewServer()
+	ringserver.RegisterRingServer(s, srv)
+
+	log.Println("ringserver listening on", lis.Addr())
+	log.Fatal(s.Serve(lis))
+}
@@ -0,0 +1,170 @@
+package compare
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/axiusilihao/geek_homework/homework_5/bench"
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+)
+
+const (
+	DEFAULT_EXAMPLES = 10
+)
+
+type Candidate struct {
+	Name     string
+	Strategy bench.Strategy
+}
+
+// Candidates 用 bench.Builders 中的算法构建候选, 顺序与 names 一致
+func Candidates(nodes []consistent.Node, names []string) ([]Candidate, error) {
+	res := make([]Candidate, 0, len(names))
+	for _, name := range names {
+		build, ok := bench.Builders[name]
+		if !ok {
+			return nil, fmt.Errorf("compare: unknown strategy %q", name)
+		}
+		s, err := build(nodes)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, Candidate{Name: name, Strategy: s})
+	}
+	return res, nil
+}
+
+type Balance struct {
+	Name       string      `json:"name"`
+	Counts     map[int]int `json:"counts"`
+	StdDev     float64     `json:"std_dev"`
+	PeakToMean float64     `json:"peak_to_mean"`
+}
+
+type Example struct {
+	Key string `json:"key"`
+	A   int    `json:"a"`
+	B   int    `json:"b"`
+}
+
+type Flow struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	Keys int `json:"keys"`
+}
+
+// Disagreement 是两个候选之间结果不同的 key, 比例即从 A 迁到 B 需要移动的 key
+type Disagreement struct {
+	A        string    `json:"a"`
+	B        string    `json:"b"`
+	Keys     int       `json:"keys"`
+	Fraction float64   `json:"fraction"`
+	Flows    []Flow    `json:"flows"`
+	Examples []Example `json:"examples"`
+}
+
+type Result struct {
+	Keys          int            `json:"keys"`
+	Source        keygen.Source  `json:"source"`
+	Balances      []Balance      `json:"balances"`
+	Disagreements []Disagreement `json:"disagreements"`
+}
+
+// Run 让每个候选并发处理同一批 key, 再两两比较结果和均衡度, 第一个候选通常是线上正在使用的算法
+func Run(src keygen.Source, candidates []Candidate, examples int) (*Result, error) {
+	keys, err := src.Sample()
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make([][]int, len(candidates))
+
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res := make([]int, len(keys))
+			for j, key := range keys {
+				res[j] = candidates[i].Strategy.Get(key)
+			}
+			owners[i] = res
+		}(i)
+	}
+	wg.Wait()
+
+	res := &Result{Keys: len(keys), Source: src}
+	for i, c := range candidates {
+		res.Balances = append(res.Balances, balance(c.Name, owners[i]))
+	}
+
+	for i := 0; i < len(candidates); i++ {
+		for j := i + 1; j < len(candidates); j++ {
+			res.Disagreements = append(res.Disagreements, disagree(keys, candidates[i].Name, candidates[j].Name, owners[i], owners[j], examples))
+		}
+	}
+
+	return res, nil
+}
+
+func balance(name string, owners []int) Balance {
+	b := Balance{Name: name, Counts: make(map[int]int)}
+	for _, id := range owners {
+		b.Counts[id]++
+	}
+	if len(b.Counts) == 0 {
+		return b
+	}
+
+	mean := float64(len(owners)) / float64(len(b.Counts))
+	variance, peak := 0.0, 0.0
+	for _, n := range b.Counts {
+		v := float64(n)
+		variance += math.Pow(v-mean, 2)
+		if v > peak {
+			peak = v
+		}
+	}
+
+	b.StdDev = math.Sqrt(variance / float64(len(b.Counts)))
+	b.PeakToMean = peak / mean
+	return b
+}
+
+func disagree(keys []string, a, b string, ownersA, ownersB []int, examples int) Disagreement {
+	d := Disagreement{A: a, B: b, Flows: make([]Flow, 0), Examples: make([]Example, 0)}
+	flows := make(map[[2]int]int)
+	for i, key := range keys {
+		if ownersA[i] == ownersB[i] {
+			continue
+		}
+
+		d.Keys++
+		flows[[2]int{ownersA[i], ownersB[i]}]++
+		if len(d.Examples) < examples {
+			d.Examples = append(d.Examples, Example{Key: key, A: ownersA[i], B: ownersB[i]})
+		}
+	}
+
+	if len(keys) > 0 {
+		d.Fraction = float64(d.Keys) / float64(len(keys))
+	}
+
+	for k, n := range flows {
+		d.Flows = append(d.Flows, Flow{From: k[0], To: k[1], Keys: n})
+	}
+	sort.Slice(d.Flows, func(i, j int) bool {
+		if d.Flows[i].Keys != d.Flows[j].Keys {
+			return d.Flows[i].Keys > d.Flows[j].Keys
+		}
+		if d.Flows[i].From != d.Flows[j].From {
+			return d.Flows[i].From < d.Flows[j].From
+		}
+		return d.Flows[i].To < d.Flows[j].To
+	})
+
+	return d
+}
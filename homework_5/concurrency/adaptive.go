@@ -0,0 +1,151 @@
+package concurrency
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// Limit 是单个节点的自适应并发上限, 每个请求结束后用延迟和结果调整
+type Limit interface {
+	Limit() int
+	Observe(rtt time.Duration, err error, inflight int)
+}
+
+type LimitFactory func(node consistent.Node) Limit
+
+type AIMDConfig struct {
+	Initial int
+	Min     int
+	Max     int
+	// Timeout 以上的延迟和出错一样视为过载
+	Timeout time.Duration
+	Backoff float64
+}
+
+func DefaultAIMDConfig() AIMDConfig {
+	return AIMDConfig{Initial: 20, Min: 1, Max: 1000, Timeout: time.Second, Backoff: 0.9}
+}
+
+// AIMD 成功时加一, 过载时乘以 Backoff; 只有在途请求接近上限时才加, 避免空闲时上限无限增长
+type AIMD struct {
+	sync.Mutex
+	cfg   AIMDConfig
+	limit float64
+}
+
+func NewAIMD(cfg AIMDConfig) *AIMD {
+	if cfg.Min < 1 {
+		cfg.Min = 1
+	}
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		cfg.Backoff = 0.9
+	}
+	return &AIMD{cfg: cfg, limit: float64(cfg.Initial)}
+}
+
+func (a *AIMD) Limit() int {
+	a.Lock()
+	defer a.Unlock()
+
+	return int(a.limit)
+}
+
+func (a *AIMD) Observe(rtt time.Duration, err error, inflight int) {
+	a.Lock()
+	defer a.Unlock()
+
+	if err != nil || (a.cfg.Timeout > 0 && rtt > a.cfg.Timeout) {
+		a.limit = math.Max(a.limit*a.cfg.Backoff, float64(a.cfg.Min))
+		return
+	}
+	if inflight*2 >= int(a.limit) {
+		a.limit = math.Min(a.limit+1, float64(a.cfg.Max))
+	}
+}
+
+type VegasConfig struct {
+	Initial int
+	Min     int
+	Max     int
+	// 估算的排队请求数低于 Alpha 时加一, 高于 Beta 时减一
+	Alpha float64
+	Beta  float64
+	// ProbeEvery 个样本之后重新测量无负载延迟, 适应后端变快或变慢
+	ProbeEvery int
+}
+
+func DefaultVegasConfig() VegasConfig {
+	return VegasConfig{Initial: 20, Min: 1, Max: 1000, Alpha: 3, Beta: 6, ProbeEvery: 1000}
+}
+
+// Vegas 按延迟梯度调整: 把观察到的最小延迟当作无负载延迟, limit*(1-min/rtt) 估算排队中的请求数
+type Vegas struct {
+	sync.Mutex
+	cfg     VegasConfig
+	limit   float64
+	minRTT  time.Duration
+	samples int
+}
+
+func NewVegas(cfg VegasConfig) *Vegas {
+	if cfg.Min < 1 {
+		cfg.Min = 1
+	}
+	return &Vegas{cfg: cfg, limit: float64(cfg.Initial)}
+}
+
+func (v *Vegas) Limit() int {
+	v.Lock()
+	defer v.Unlock()
+
+	return int(v.limit)
+}
+
+func (v *Vegas) Observe(rtt time.Duration, err error, inflight int) {
+	v.Lock()
+	defer v.Unlock()
+
+	if err != nil {
+		v.limit = math.Max(v.limit/2, float64(v.cfg.Min))
+		return
+	}
+	if rtt <= 0 {
+		return
+	}
+
+	v.samples++
+	if v.cfg.ProbeEvery > 0 && v.samples >= v.cfg.ProbeEvery {
+		v.samples, v.minRTT = 0, 0
+	}
+	if v.minRTT == 0 || rtt < v.minRTT {
+		v.minRTT = rtt
+	}
+
+	queue := v.limit * (1 - float64(v.minRTT)/float64(rtt))
+	switch {
+	case queue < v.cfg.Alpha:
+		v.limit = math.Min(v.limit+1, float64(v.cfg.Max))
+	case queue > v.cfg.Beta:
+		v.limit = math.Max(v.limit-1, float64(v.cfg.Min))
+	}
+}
+
+// AIMDPerWeight 按节点权重放大初始值和上下限
+func AIMDPerWeight(cfg AIMDConfig) LimitFactory {
+	return func(node consistent.Node) Limit {
+		c := cfg
+		c.Initial, c.Min, c.Max = cfg.Initial*node.Weight, cfg.Min*node.Weight, cfg.Max*node.Weight
+		return NewAIMD(c)
+	}
+}
+
+func VegasPerWeight(cfg VegasConfig) LimitFactory {
+	return func(node consistent.Node) Limit {
+		c := cfg
+		c.Initial, c.Min, c.Max = cfg.Initial*node.Weight, cfg.Min*node.Weight, cfg.Max*node.Weight
+		return NewVegas(c)
+	}
+}
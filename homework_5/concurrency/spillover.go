@@ -0,0 +1,199 @@
+package concurrency
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_CAP        = 100
+	DEFAULT_CANDIDATES = 3
+)
+
+var ErrAtCapacity = errors.New("concurrency: every candidate node is at capacity")
+
+type Config struct {
+	// Cap 是权重为 1 的节点同时处理的最大请求数, 按权重线性放大
+	Cap int
+	// Candidates 是 owner 满了之后最多看几个环上的候选 (包括 owner)
+	Candidates int
+	// New 不为 nil 时用自适应上限代替固定的 Cap
+	New LimitFactory
+	// Health 拒绝的节点直接跳过, 例如 breaker.Router 或 outlier.Detector
+	Health Health
+}
+
+type Health interface {
+	Allow(node consistent.Node) bool
+	Done(node consistent.Node, err error)
+}
+
+func DefaultConfig() Config {
+	return Config{Cap: DEFAULT_CAP, Candidates: DEFAULT_CANDIDATES}
+}
+
+type NodeStats struct {
+	Id       int `json:"id"`
+	Inflight int `json:"inflight"`
+	Cap      int `json:"cap"`
+	// SpilledFrom 是这个节点作为 owner 已满、请求被转到别的节点的次数
+	SpilledFrom int `json:"spilled_from"`
+	SpilledTo   int `json:"spilled_to"`
+}
+
+type Stats struct {
+	Requests  int         `json:"requests"`
+	Spilled   int         `json:"spilled"`
+	Rejected  int         `json:"rejected"`
+	SpillRate float64     `json:"spill_rate"`
+	Nodes     []NodeStats `json:"nodes"`
+}
+
+type node struct {
+	weight      int
+	limit       Limit
+	inflight    int
+	spilledFrom int
+	spilledTo   int
+}
+
+// Spillover 记录每个节点的在途请求数, owner 满了就顺延到环上下一个未满的候选, 不排队等待
+type Spillover struct {
+	sync.Mutex
+	ring     *consistent.Consistent
+	cfg      Config
+	nodes    map[int]*node
+	requests int
+	spilled  int
+	rejected int
+}
+
+func NewSpillover(ring *consistent.Consistent, cfg Config) *Spillover {
+	if cfg.Cap <= 0 {
+		cfg.Cap = DEFAULT_CAP
+	}
+	if cfg.Candidates <= 0 {
+		cfg.Candidates = DEFAULT_CANDIDATES
+	}
+	return &Spillover{ring: ring, cfg: cfg, nodes: make(map[int]*node)}
+}
+
+func (s *Spillover) node(id int) *node {
+	n, ok := s.nodes[id]
+	if !ok {
+		n = &node{}
+		s.nodes[id] = n
+	}
+	return n
+}
+
+func (s *Spillover) capOf(n consistent.Node) int {
+	if s.cfg.New == nil {
+		return s.cfg.Cap * n.Weight
+	}
+
+	nd := s.node(n.Id)
+	if nd.limit == nil || nd.weight != n.Weight {
+		nd.limit, nd.weight = s.cfg.New(n), n.Weight
+	}
+	return nd.limit.Limit()
+}
+
+// GetAvailable 返回第一个未满的候选并占用一个名额, 请求结束后必须调用 Done
+func (s *Spillover) GetAvailable(key string) (consistent.Node, error) {
+	candidates := s.ring.GetN(key, s.cfg.Candidates)
+	if len(candidates) == 0 {
+		return consistent.Node{}, consistent.ErrEmptyRing
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.requests++
+	for i, c := range candidates {
+		n := s.node(c.Id)
+		if n.inflight >= s.capOf(c) {
+			continue
+		}
+		// 先看容量再问健康状态, 避免占用熔断器 half-open 的探测名额却不发请求
+		if s.cfg.Health != nil && !s.cfg.Health.Allow(c) {
+			continue
+		}
+
+		n.inflight++
+		if i > 0 {
+			s.spilled++
+			s.node(candidates[0].Id).spilledFrom++
+			n.spilledTo++
+		}
+		return c, nil
+	}
+
+	s.rejected++
+	s.node(candidates[0].Id).spilledFrom++
+	return consistent.Node{}, ErrAtCapacity
+}
+
+// Done 释放名额, 不反馈延迟; 使用自适应上限或 Health 时应该调用 Report
+func (s *Spillover) Done(n consistent.Node) {
+	s.Lock()
+	defer s.Unlock()
+
+	if nd, ok := s.nodes[n.Id]; ok && nd.inflight > 0 {
+		nd.inflight--
+	}
+}
+
+// Report 释放名额, 并把延迟和结果交给节点的自适应上限和 Health
+func (s *Spillover) Report(n consistent.Node, rtt time.Duration, err error) {
+	s.Lock()
+	nd, ok := s.nodes[n.Id]
+	if ok {
+		if nd.limit != nil {
+			nd.limit.Observe(rtt, err, nd.inflight)
+		}
+		if nd.inflight > 0 {
+			nd.inflight--
+		}
+	}
+	s.Unlock()
+
+	if s.cfg.Health != nil {
+		s.cfg.Health.Done(n, err)
+	}
+}
+
+func (s *Spillover) Inflight(id int) int {
+	s.Lock()
+	defer s.Unlock()
+
+	if n, ok := s.nodes[id]; ok {
+		return n.inflight
+	}
+	return 0
+}
+
+// Stats 里 SpillRate 持续偏高说明 owner 的容量不够, 应该扩容或者调高权重
+func (s *Spillover) Stats() Stats {
+	members := s.ring.Members()
+
+	s.Lock()
+	defer s.Unlock()
+
+	st := Stats{Requests: s.requests, Spilled: s.spilled, Rejected: s.rejected, Nodes: make([]NodeStats, 0, len(members))}
+	if s.requests > 0 {
+		st.SpillRate = float64(s.spilled) / float64(s.requests)
+	}
+	for _, m := range members {
+		n := s.node(m.Id)
+		st.Nodes = append(st.Nodes, NodeStats{Id: m.Id, Inflight: n.inflight, Cap: s.capOf(m), SpilledFrom: n.spilledFrom, SpilledTo: n.spilledTo})
+	}
+	sort.Slice(st.Nodes, func(i, j int) bool {
+		return st.Nodes[i].Id < st.Nodes[j].Id
+	})
+	return st
+}
@@ -0,0 +1,116 @@
+package connpool
+
+import (
+	"context"
+	"sync"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// Lease 是借出的连接, 用完调用 Release, 出错时传入错误让连接被丢弃
+type Lease[C any] struct {
+	Conn C
+	Node consistent.Node
+	pool *Pool[C]
+}
+
+func (l *Lease[C]) Release(err error) {
+	if err != nil {
+		l.pool.Discard(l.Conn)
+		return
+	}
+	l.pool.Put(l.Conn)
+}
+
+// Manager 为环上的每个节点维护一个连接池, Sync 时为新节点建池, 关闭已离开节点的池
+type Manager[C any] struct {
+	sync.Mutex
+	ring  *consistent.Consistent
+	cfg   Config[C]
+	pools map[int]*Pool[C]
+}
+
+func NewManager[C any](ring *consistent.Consistent, cfg Config[C]) *Manager[C] {
+	m := &Manager[C]{ring: ring, cfg: cfg, pools: make(map[int]*Pool[C])}
+	m.Sync()
+	return m
+}
+
+// Sync 让连接池和环的成员保持一致, 环变化之后调用; 节点地址或端口变了也会重建连接池
+func (m *Manager[C]) Sync() {
+	members := m.ring.Members()
+
+	m.Lock()
+	defer m.Unlock()
+
+	seen := make(map[int]bool, len(members))
+	for _, node := range members {
+		seen[node.Id] = true
+		if p, ok := m.pools[node.Id]; ok {
+			if p.node.Ip == node.Ip && p.node.Port == node.Port {
+				continue
+			}
+			p.Close()
+		}
+		m.pools[node.Id] = newPool(node, m.cfg)
+	}
+
+	for id, p := range m.pools {
+		if !seen[id] {
+			p.Close()
+			delete(m.pools, id)
+		}
+	}
+}
+
+func (m *Manager[C]) Pool(id int) (*Pool[C], bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	p, ok := m.pools[id]
+	return p, ok
+}
+
+// ConnFor 从 key 的 owner 的连接池借一个连接
+func (m *Manager[C]) ConnFor(ctx context.Context, key string) (*Lease[C], error) {
+	owners := m.ring.GetN(key, 1)
+	if len(owners) == 0 {
+		return nil, consistent.ErrEmptyRing
+	}
+
+	p, ok := m.Pool(owners[0].Id)
+	if !ok {
+		// 环已经有了新节点但还没有 Sync
+		m.Sync()
+		if p, ok = m.Pool(owners[0].Id); !ok {
+			return nil, ErrPoolClosed
+		}
+	}
+
+	conn, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Lease[C]{Conn: conn, Node: p.node, pool: p}, nil
+}
+
+func (m *Manager[C]) Stats() map[int]Stats {
+	m.Lock()
+	defer m.Unlock()
+
+	stats := make(map[int]Stats, len(m.pools))
+	for id, p := range m.pools {
+		stats[id] = p.Stats()
+	}
+	return stats
+}
+
+func (m *Manager[C]) Close() {
+	m.Lock()
+	defer m.Unlock()
+
+	for id, p := range m.pools {
+		p.Close()
+		delete(m.pools, id)
+	}
+}
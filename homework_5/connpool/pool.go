@@ -0,0 +1,162 @@
+package connpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+var (
+	ErrPoolClosed    = errors.New("connpool: pool closed")
+	ErrPoolExhausted = errors.New("connpool: pool exhausted")
+)
+
+type Config[C any] struct {
+	Dial  func(ctx context.Context, node consistent.Node) (C, error)
+	Close func(conn C) error
+	// MaxIdle 是每个节点保留的空闲连接数, MaxOpen 为 0 表示不限制同时打开的连接数
+	MaxIdle int
+	MaxOpen int
+	// Shared 为 true 时每个节点只建立一个连接给所有调用方共用, 例如 *http.Client 或 gRPC 连接
+	Shared bool
+}
+
+// Pool 是单个节点的连接池
+type Pool[C any] struct {
+	sync.Mutex
+	node   consistent.Node
+	cfg    Config[C]
+	idle   []C
+	open   int
+	shared *C
+	closed bool
+}
+
+func newPool[C any](node consistent.Node, cfg Config[C]) *Pool[C] {
+	return &Pool[C]{node: node, cfg: cfg}
+}
+
+func (p *Pool[C]) Node() consistent.Node {
+	return p.node
+}
+
+func (p *Pool[C]) Get(ctx context.Context) (C, error) {
+	var zero C
+
+	p.Lock()
+	if p.closed {
+		p.Unlock()
+		return zero, ErrPoolClosed
+	}
+	if p.cfg.Shared && p.shared != nil {
+		conn := *p.shared
+		p.Unlock()
+		return conn, nil
+	}
+	if n := len(p.idle); n > 0 {
+		conn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.Unlock()
+		return conn, nil
+	}
+	if p.cfg.MaxOpen > 0 && p.open >= p.cfg.MaxOpen {
+		p.Unlock()
+		return zero, ErrPoolExhausted
+	}
+	p.open++
+	p.Unlock()
+
+	conn, err := p.cfg.Dial(ctx, p.node)
+
+	p.Lock()
+	defer p.Unlock()
+
+	if err != nil {
+		p.open--
+		return zero, err
+	}
+	if p.closed {
+		p.open--
+		p.close(conn)
+		return zero, ErrPoolClosed
+	}
+	if p.cfg.Shared {
+		// 并发建立的多余连接直接关掉
+		if p.shared != nil {
+			p.open--
+			p.close(conn)
+			return *p.shared, nil
+		}
+		p.shared = &conn
+	}
+	return conn, nil
+}
+
+// Put 归还连接, 池已关闭或空闲连接已满时直接关闭
+func (p *Pool[C]) Put(conn C) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.cfg.Shared {
+		return
+	}
+	if p.closed || len(p.idle) >= p.cfg.MaxIdle {
+		p.open--
+		p.close(conn)
+		return
+	}
+	p.idle = append(p.idle, conn)
+}
+
+// Discard 关闭出错的连接, 不再放回池里
+func (p *Pool[C]) Discard(conn C) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.open--
+	if p.cfg.Shared {
+		p.shared = nil
+	}
+	p.close(conn)
+}
+
+func (p *Pool[C]) close(conn C) {
+	if p.cfg.Close != nil {
+		p.cfg.Close(conn)
+	}
+}
+
+// Close 关闭所有空闲连接, 借出的连接在归还时关闭
+func (p *Pool[C]) Close() {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+	for _, conn := range p.idle {
+		p.open--
+		p.close(conn)
+	}
+	p.idle = nil
+	if p.shared != nil {
+		p.open--
+		p.close(*p.shared)
+		p.shared = nil
+	}
+}
+
+type Stats struct {
+	Open int `json:"open"`
+	Idle int `json:"idle"`
+}
+
+func (p *Pool[C]) Stats() Stats {
+	p.Lock()
+	defer p.Unlock()
+
+	return Stats{Open: p.open, Idle: len(p.idle)}
+}
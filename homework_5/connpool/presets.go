@@ -0,0 +1,46 @@
+package connpool
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_MAX_IDLE = 8
+)
+
+// NetConn 用 net.Dialer 连接节点的 Ip:Port
+func NetConn(network string, timeout time.Duration) Config[net.Conn] {
+	d := &net.Dialer{Timeout: timeout}
+	return Config[net.Conn]{
+		Dial: func(ctx context.Context, node consistent.Node) (net.Conn, error) {
+			return d.DialContext(ctx, network, net.JoinHostPort(node.Ip, strconv.Itoa(node.Port)))
+		},
+		Close: func(conn net.Conn) error {
+			return conn.Close()
+		},
+		MaxIdle: DEFAULT_MAX_IDLE,
+	}
+}
+
+// HTTPClient 每个节点共用一个 *http.Client, 连接复用交给它自己的 Transport
+func HTTPClient(timeout time.Duration) Config[*http.Client] {
+	return Config[*http.Client]{
+		Dial: func(ctx context.Context, node consistent.Node) (*http.Client, error) {
+			return &http.Client{
+				Timeout:   timeout,
+				Transport: &http.Transport{MaxIdleConnsPerHost: DEFAULT_MAX_IDLE},
+			}, nil
+		},
+		Close: func(c *http.Client) error {
+			c.CloseIdleConnections()
+			return nil
+		},
+		Shared: true,
+	}
+}
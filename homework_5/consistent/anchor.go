@@ -0,0 +1,180 @@
+package consistent
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	DEFAULT_ANCHOR_CAPACITY = 1024
+	ANCHOR_GETN_PROBES      = 8
+)
+
+// Anchor 是 AnchorHash: 预先分配 capacity 个 bucket, 工作集是其中的一部分, 查找期望 O(1),
+// 内存只与 capacity 有关, 与节点数 × 虚拟节点数无关. 每个节点占一个 bucket, 不考虑权重.
+// 映射取决于增删的历史, 同样的成员按不同顺序加入会得到不同的结果, 各实例需要以相同的顺序变更
+type Anchor struct {
+	sync.RWMutex
+	// A[b] 为 0 表示 b 在工作集中, 否则是 b 被删除时工作集剩下的大小
+	A []int
+	// K[b] 是 b 被删除时顶替它位置的 bucket
+	K []int
+	// W 的前 n 个是工作集, L[b] 是 b 在 W 中的下标
+	W []int
+	L []int
+	// R 是删除过的 bucket, 后删的先恢复, 恢复后的状态与删除之前完全相同
+	R       []int
+	n       int
+	nodes   map[int]Node
+	buckets map[int]int
+}
+
+func NewAnchor(capacity int) *Anchor {
+	if capacity <= 0 {
+		capacity = DEFAULT_ANCHOR_CAPACITY
+	}
+
+	a := &Anchor{
+		A:       make([]int, capacity),
+		K:       make([]int, capacity),
+		W:       make([]int, capacity),
+		L:       make([]int, capacity),
+		R:       make([]int, 0, capacity),
+		nodes:   make(map[int]Node),
+		buckets: make(map[int]int),
+	}
+
+	for b := 0; b < capacity; b++ {
+		a.A[b], a.K[b], a.W[b], a.L[b] = b, b, b, b
+	}
+	for b := capacity - 1; b >= 0; b-- {
+		a.R = append(a.R, b)
+	}
+
+	return a
+}
+
+func (a *Anchor) Capacity() int {
+	return len(a.A)
+}
+
+// bucket 是论文里的 GetBucket, 调用方保证工作集不为空
+func (a *Anchor) bucket(h uint64) int {
+	b := int(h % uint64(len(a.A)))
+	for a.A[b] > 0 {
+		next := int(fmix64(h^uint64(b)*0x9e3779b97f4a7c15) % uint64(a.A[b]))
+		for a.A[next] >= a.A[b] {
+			next = a.K[next]
+		}
+		b = next
+	}
+	return b
+}
+
+// Add 从最近删除的 bucket 中取一个给节点, 超过 capacity 时返回 false
+func (a *Anchor) Add(node *Node) bool {
+	a.Lock()
+	defer a.Unlock()
+
+	if _, ok := a.buckets[node.Id]; ok || len(a.R) == 0 {
+		return false
+	}
+
+	b := a.R[len(a.R)-1]
+	a.R = a.R[:len(a.R)-1]
+	a.A[b] = 0
+	a.L[a.W[a.n]] = a.n
+	a.W[a.L[b]] = b
+	a.K[b] = b
+	a.n++
+
+	a.nodes[b] = *node
+	a.buckets[node.Id] = b
+	return true
+}
+
+func (a *Anchor) Remove(node *Node) {
+	a.Lock()
+	defer a.Unlock()
+
+	b, ok := a.buckets[node.Id]
+	if !ok {
+		return
+	}
+
+	a.R = append(a.R, b)
+	a.n--
+	a.A[b] = a.n
+	a.W[a.L[b]] = a.W[a.n]
+	a.L[a.W[a.n]] = a.L[b]
+	a.K[b] = a.W[a.n]
+
+	delete(a.nodes, b)
+	delete(a.buckets, node.Id)
+}
+
+func (a *Anchor) hash(key string) uint64 {
+	return xxhash64([]byte(key))
+}
+
+// Get 没有节点时返回 ErrEmptyRing
+func (a *Anchor) Get(key string) (Node, error) {
+	a.RLock()
+	defer a.RUnlock()
+
+	if a.n == 0 {
+		return Node{}, ErrEmptyRing
+	}
+	return a.nodes[a.bucket(a.hash(key))], nil
+}
+
+// GetN 第一个与 Get 相同, 其余的用 key 的派生哈希再查, 仍不够时按工作集的顺序补齐
+func (a *Anchor) GetN(key string, n int) []Node {
+	a.RLock()
+	defer a.RUnlock()
+
+	if n > a.n {
+		n = a.n
+	}
+	if n < 0 {
+		n = 0
+	}
+	nodes := make([]Node, 0, n)
+	if n <= 0 {
+		return nodes
+	}
+
+	seen := make(map[int]bool, n)
+	h := a.hash(key)
+	for probe := 0; probe < n*ANCHOR_GETN_PROBES && len(nodes) < n; probe++ {
+		b := a.bucket(h)
+		if !seen[b] {
+			seen[b] = true
+			nodes = append(nodes, a.nodes[b])
+		}
+		h = fmix64(h + 1)
+	}
+	for i := 0; i < a.n && len(nodes) < n; i++ {
+		if b := a.W[i]; !seen[b] {
+			seen[b] = true
+			nodes = append(nodes, a.nodes[b])
+		}
+	}
+
+	return nodes
+}
+
+// Members 按 Id 排序返回所有节点
+func (a *Anchor) Members() []Node {
+	a.RLock()
+	defer a.RUnlock()
+
+	nodes := make([]Node, 0, len(a.nodes))
+	for _, node := range a.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Id < nodes[j].Id
+	})
+	return nodes
+}
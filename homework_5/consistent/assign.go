@@ -0,0 +1,75 @@
+package consistent
+
+import (
+	"sort"
+)
+
+// Assign 用环把一组固定的分区 (队列、topic partition 等) 分给 consumers, 返回 consumer Id -> 按字典序排列的分区,
+// 没分到分区的 consumer 对应空切片. 结果只取决于输入, 组里的每个成员各自计算得到同样的分配, 不需要协调者.
+// 内部的环总是用 WithStrictWrap, 成员变化时只有离开的成员的分区和新成员接管的分区会移动, 其余的分区不动
+func Assign(partitions []string, consumers []Node, opts ...Option) map[int][]string {
+	ring := NewConsistent(append(opts, WithStrictWrap())...)
+	assignment := make(map[int][]string, len(consumers))
+	for _, node := range consumers {
+		n := node
+		if ring.Add(&n) {
+			assignment[node.Id] = []string{}
+		}
+	}
+	if len(assignment) == 0 {
+		return assignment
+	}
+
+	for _, p := range partitions {
+		node, err := ring.Get(p)
+		if err != nil {
+			break
+		}
+		assignment[node.Id] = append(assignment[node.Id], p)
+	}
+	for _, ps := range assignment {
+		sort.Strings(ps)
+	}
+	return assignment
+}
+
+// PartitionMove 是两次分配之间换了 consumer 的分区, From 或 To 为 -1 表示分区是新增的或者被删除了
+type PartitionMove struct {
+	Partition string `json:"partition"`
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+}
+
+// Moves 比较两次 Assign 的结果, 按分区排序返回所有换了 consumer 的分区; 协作式 rebalance 里
+// 每个 consumer 只需要先释放 From 是自己的分区, 其余分区可以一直消费
+func Moves(old, next map[int][]string) []PartitionMove {
+	owners := func(a map[int][]string) map[string]int {
+		m := make(map[string]int)
+		for id, ps := range a {
+			for _, p := range ps {
+				m[p] = id
+			}
+		}
+		return m
+	}
+	from, to := owners(old), owners(next)
+
+	moves := make([]PartitionMove, 0)
+	for p, id := range from {
+		if nid, ok := to[p]; !ok {
+			moves = append(moves, PartitionMove{Partition: p, From: id, To: -1})
+		} else if nid != id {
+			moves = append(moves, PartitionMove{Partition: p, From: id, To: nid})
+		}
+	}
+	for p, id := range to {
+		if _, ok := from[p]; !ok {
+			moves = append(moves, PartitionMove{Partition: p, From: -1, To: id})
+		}
+	}
+
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].Partition < moves[j].Partition
+	})
+	return moves
+}
@@ -0,0 +1,58 @@
+package consistent
+
+const (
+	CHANGE_BATCH = "batch"
+)
+
+// AddBatch 在一次写锁里加入 nodes, 所有虚拟节点只排序、合并进环一次, 只发布一个新版本, 返回实际加入的节点数.
+// 已经在环上的节点、nodes 里重复的 Id 和权重为负的节点被跳过; 结果与按 nodes 的顺序逐个 Add 完全相同, 包括冲突时的探测位置.
+// OnNodeAdded 对每个节点各调用一次, OnOwnershipChanged 和 Subscribe 只收到一次 CHANGE_BATCH.
+// 使用 WithSlowStart 时只有环原来不为空才预热, 这一点与逐个 Add 不同
+func (c *Consistent) AddBatch(nodes []*Node) int {
+	c.Lock()
+	prev := c.current()
+	added := make(HashRing, 0)
+	joined := make([]Node, 0, len(nodes))
+	warm := len(c.members) > 0
+	for _, node := range nodes {
+		if _, ok := c.resources[node.Id]; ok || node.Weight < 0 {
+			continue
+		}
+		if warm {
+			c.startWarmUp(node.Id)
+		}
+		added = append(added, c.register(node)...)
+		joined = append(joined, *node)
+	}
+	if len(joined) == 0 {
+		c.Unlock()
+		return 0
+	}
+
+	c.insertPoints(added)
+	c.publish(nil)
+	c.notifyBatch(prev, CHANGE_BATCH, joined, nil)
+	return len(joined)
+}
+
+// RemoveBatch 与 AddBatch 相同, 一次删除 ids 对应的节点, 不存在的 Id 被跳过, 返回实际删除的节点数
+func (c *Consistent) RemoveBatch(ids []int) int {
+	c.Lock()
+	prev := c.current()
+	deleted := make(map[uint64]bool)
+	left := make([]Node, 0, len(ids))
+	for _, id := range ids {
+		if node, ok := c.unregister(id, deleted); ok {
+			left = append(left, node)
+		}
+	}
+	if len(left) == 0 {
+		c.Unlock()
+		return 0
+	}
+
+	c.deletePoints(deleted)
+	c.publish(nil)
+	c.notifyBatch(prev, CHANGE_BATCH, nil, left)
+	return len(left)
+}
@@ -0,0 +1,157 @@
+package consistent
+
+import (
+	"math"
+	"sync"
+)
+
+// WithBoundedLoads 开启有界负载 (consistent hashing with bounded loads):
+// 节点的负载超过 factor 倍的平均值 (按虚拟节点数折算) 时, Get 顺时针跳到下一个节点. factor 必须 >= 1
+func WithBoundedLoads(factor float64) Option {
+	return func(c *Consistent) {
+		if factor >= 1 {
+			c.factor = factor
+		}
+	}
+}
+
+// loads 记录每个节点当前的负载, 由调用方通过 Inc/Done 维护
+type loads struct {
+	sync.Mutex
+	counts map[int]int64
+	total  int64
+}
+
+func newLoads() *loads {
+	return &loads{counts: make(map[int]int64)}
+}
+
+func (l *loads) inc(id int) {
+	l.counts[id]++
+	l.total++
+}
+
+func (l *loads) done(id int) {
+	n, ok := l.counts[id]
+	if !ok {
+		return
+	}
+
+	if n > 1 {
+		l.counts[id]--
+	} else {
+		delete(l.counts, id)
+	}
+	l.total--
+}
+
+func (l *loads) forget(id int) {
+	l.total -= l.counts[id]
+	delete(l.counts, id)
+}
+
+// LoadFactor 返回 WithBoundedLoads 设置的系数, 0 表示未开启
+func (c *Consistent) LoadFactor() float64 {
+	return c.current().factor
+}
+
+// capacity 是再分配一个请求之后节点允许的最大负载, 份额是节点的虚拟节点数占 replicas 的比例, replicas 是可以路由的节点的虚拟节点总数,
+// 与 Replicas、Capacity 覆盖权重时节点在环上的点数一致; 所有节点的 capacity 之和不小于 total+1, 所以总有节点可选
+func (c *Consistent) capacity(s *snapshot, node Node, replicas int) int64 {
+	share := float64(replicaCount(s.numReps, node)) / float64(replicas)
+	return int64(math.Ceil(s.factor * float64(c.loads.total+1) * share))
+}
+
+// routableReplicas 是 draining 和 MarkDown 之外的节点的虚拟节点总数, 这些节点不分配新请求
+func (c *Consistent) routableReplicas(s *snapshot) int {
+	replicas := 0
+	for id, node := range c.members {
+		if !s.skip[id] {
+			replicas += replicaCount(s.numReps, node)
+		}
+	}
+	return replicas
+}
+
+// bounded 从 i 开始顺时针找第一个负载未超过上限、可以路由的节点, 调用方需要持有 c 的读锁和 loads 的锁
+func (c *Consistent) bounded(i int) Node {
+	s := c.current()
+	owner := s.routes[i]
+	replicas := c.routableReplicas(s)
+
+	seen := make(map[int]bool, len(c.resources))
+	for step := 0; step < len(c.ring) && len(seen) < len(c.resources); step++ {
+		node := c.Nodes[c.ring[i]]
+		if !seen[node.Id] {
+			seen[node.Id] = true
+			if !s.skip[node.Id] && c.loads.counts[node.Id]+1 <= c.capacity(s, node, replicas) {
+				return node
+			}
+		}
+		i = (i + 1) % len(c.ring)
+	}
+
+	return owner
+}
+
+// Acquire 与 Get 选择同样的节点, 包括 Pin 固定的节点, 并在同一把锁内给它的负载加一, 用完后调用 Done;
+// 空环返回 ErrEmptyRing, 所有节点都在 draining 或 MarkDown 时返回 ErrAllDraining, 这两种情况不增加负载
+func (c *Consistent) Acquire(key string) (Node, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	c.loads.Lock()
+	defer c.loads.Unlock()
+
+	s := c.current()
+	node, ok := s.pinned(key)
+	if !ok {
+		if err := s.routable(); err != nil {
+			return Node{}, err
+		}
+		i := s.search(s.hashStr(key))
+		node = s.routes[i]
+		if s.factor > 0 {
+			node = c.bounded(i)
+		}
+	}
+
+	c.loads.inc(node.Id)
+	return node, nil
+}
+
+// Inc 记录节点上多了一个请求, 与 Get 搭配使用
+func (c *Consistent) Inc(node Node) {
+	c.loads.Lock()
+	defer c.loads.Unlock()
+
+	c.loads.inc(node.Id)
+}
+
+// Done 记录节点上的一个请求已经完成
+func (c *Consistent) Done(node Node) {
+	c.loads.Lock()
+	defer c.loads.Unlock()
+
+	c.loads.done(node.Id)
+}
+
+// Load 返回节点当前的负载
+func (c *Consistent) Load(id int) int64 {
+	c.loads.Lock()
+	defer c.loads.Unlock()
+
+	return c.loads.counts[id]
+}
+
+// Loads 返回所有负载不为 0 的节点
+func (c *Consistent) Loads() map[int]int64 {
+	c.loads.Lock()
+	defer c.loads.Unlock()
+
+	counts := make(map[int]int64, len(c.loads.counts))
+	for id, n := range c.loads.counts {
+		counts[id] = n
+	}
+	return counts
+}
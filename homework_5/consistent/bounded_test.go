@@ -0,0 +1,93 @@
+package consistent
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestAcquireEmpty(t *testing.T) {
+	c := NewConsistent()
+	if _, err := c.Acquire("key"); !errors.Is(err, ErrEmptyRing) {
+		t.Fatalf("Acquire on empty ring: got %v, want ErrEmptyRing", err)
+	}
+}
+
+func TestAcquireAllDown(t *testing.T) {
+	for _, factor := range []float64{0, 1.25} {
+		c := newTestRing(3, WithBoundedLoads(factor))
+		c.MarkDown(1)
+		c.MarkDown(2)
+		c.Drain(3)
+
+		if _, err := c.Acquire("key"); !errors.Is(err, ErrAllDraining) {
+			t.Fatalf("factor %v: got %v, want ErrAllDraining", factor, err)
+		}
+		for id := 1; id <= 3; id++ {
+			if load := c.Load(id); load != 0 {
+				t.Fatalf("factor %v: node %d has load %d after a failed Acquire", factor, id, load)
+			}
+		}
+	}
+}
+
+func TestAcquirePinned(t *testing.T) {
+	c := newTestRing(3, WithBoundedLoads(1.25))
+	owner, err := c.Get("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pin := owner.Id%3 + 1
+	if !c.Pin("key", pin) {
+		t.Fatalf("Pin(key, %d) failed", pin)
+	}
+
+	node, err := c.Acquire("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Id != pin {
+		t.Fatalf("Acquire returned node %d, want pinned node %d", node.Id, pin)
+	}
+	if load := c.Load(pin); load != 1 {
+		t.Fatalf("pinned node load is %d, want 1", load)
+	}
+}
+
+func TestAcquireMatchesGet(t *testing.T) {
+	c := newTestRing(5)
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		want, _ := c.Get(key)
+		got, err := c.Acquire(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Id != want.Id {
+			t.Fatalf("%s: Acquire %d, Get %d", key, got.Id, want.Id)
+		}
+		c.Done(got)
+	}
+}
+
+// Weight 为 0 的节点按 Capacity 放点时, 上限按虚拟节点数计算, 不会是 0 或 NaN
+func TestAcquireBoundedCapacityOnly(t *testing.T) {
+	c := NewConsistent(WithBoundedLoads(1))
+	for i := 1; i <= 4; i++ {
+		node := NewNode(i, "10.0.0.1", 8000+i, "", 0)
+		node.Capacity = 1
+		c.Add(node)
+	}
+
+	for i := 0; i < 400; i++ {
+		if _, err := c.Acquire("key" + strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id := 1; id <= 4; id++ {
+		// factor 为 1 时每个节点的上限是 ceil(400 / 4), 400 个请求正好平分
+		if load := c.Load(id); load != 100 {
+			t.Fatalf("node %d has load %d, want 100", id, load)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package consistent
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ReplicaCount 返回节点应有的虚拟节点数: 设置了 Node.Replicas 时就是它, 设置了 Capacity 时是 Replicas*Capacity 四舍五入,
+// 否则是 Replicas*Weight; 结果为负时是 0
+func (c *Consistent) ReplicaCount(node Node) int {
+	return replicaCount(c.current().numReps, node)
+}
+
+// replicaCount 在写锁内用 c.numReps 计算, Restore 换了参数还没有发布时也是新的值
+func (c *Consistent) replicaCount(node Node) int {
+	return replicaCount(c.numReps, node)
+}
+
+func replicaCount(numReps int, node Node) int {
+	if node.Replicas > 0 {
+		return node.Replicas
+	}
+	if node.Capacity > 0 {
+		return int(math.Round(float64(numReps) * node.Capacity))
+	}
+	if node.Weight < 0 {
+		return 0
+	}
+	return numReps * node.Weight
+}
+
+// VirtualNodes 返回每个节点实际的虚拟节点数
+func (c *Consistent) VirtualNodes() map[int]int {
+	c.RLock()
+	defer c.RUnlock()
+
+	counts := make(map[int]int, len(c.points))
+	for id, points := range c.points {
+		counts[id] = len(points)
+	}
+	return counts
+}
+
+// UpdateCapacity 与 UpdateWeight 相同, 只增删新旧虚拟节点数之间的那部分; capacity 为 0 时改回按 Weight 计算
+func (c *Consistent) UpdateCapacity(id int, capacity float64) bool {
+	c.Lock()
+	prev := c.current()
+	node, ok := c.members[id]
+	if !ok || capacity < 0 || math.IsNaN(capacity) || math.IsInf(capacity, 0) {
+		c.Unlock()
+		return false
+	}
+
+	node.Capacity = capacity
+	c.resize(node)
+	c.notify(prev, CHANGE_WEIGHT, node)
+	return true
+}
+
+// UpdateReplicas 与 UpdateCapacity 相同, 把节点的虚拟节点数直接改成 replicas; replicas 为 0 时改回按 Capacity 或 Weight 计算
+func (c *Consistent) UpdateReplicas(id int, replicas int) bool {
+	c.Lock()
+	prev := c.current()
+	node, ok := c.members[id]
+	if !ok || replicas < 0 {
+		c.Unlock()
+		return false
+	}
+
+	node.Replicas = replicas
+	c.resize(node)
+	c.notify(prev, CHANGE_WEIGHT, node)
+	return true
+}
+
+var capacityUnits = []struct {
+	suffix string
+	scale  float64
+}{
+	{"TIB", 1024}, {"GIB", 1}, {"MIB", 1.0 / 1024},
+	{"TB", 1000}, {"GB", 1}, {"MB", 1.0 / 1000},
+	{"T", 1000}, {"G", 1}, {"M", 1.0 / 1000},
+}
+
+// ParseCapacity 解析 "1.5" 这样的倍数或 "512GB"、"2TiB" 这样的容量, 容量按 GB (GiB) 计, 512GB 是 512.
+// 容量差别很大时用 NormalizeCapacity 换算成相对值, 否则虚拟节点会非常多
+func ParseCapacity(s string) (float64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	scale := 1.0
+	for _, u := range capacityUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v, scale = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.scale
+			break
+		}
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("consistent: bad capacity %q", s)
+	}
+	return f * scale, nil
+}
+
+// NormalizeCapacity 把 nodes 的 Capacity 除以其中最小的非零值, 最小的节点变成 1, 其余按比例; 没有 Capacity 的节点不变
+func NormalizeCapacity(nodes []Node) []Node {
+	min := 0.0
+	for _, node := range nodes {
+		if node.Capacity > 0 && (min == 0 || node.Capacity < min) {
+			min = node.Capacity
+		}
+	}
+
+	normalized := make([]Node, len(nodes))
+	copy(normalized, nodes)
+	if min == 0 {
+		return normalized
+	}
+	for i := range normalized {
+		normalized[i].Capacity /= min
+	}
+	return normalized
+}
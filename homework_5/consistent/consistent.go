@@ -0,0 +1,698 @@
+package consistent
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrEmptyRing = errors.New("consistent: ring is empty")
+
+const (
+	DEFAULT_REPLICAS  = 160
+	DEFAULT_RING_BITS = 32
+)
+
+// HashRing 的位置统一用 uint64 存, 32 位模式下只用到低 32 位
+type HashRing []uint64
+
+func (c HashRing) Len() int {
+	return len(c)
+}
+
+func (c HashRing) Less(i, j int) bool {
+	return c[i] < c[j]
+}
+
+func (c HashRing) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
+
+type Node struct {
+	Id       int    `json:"id"`
+	Ip       string `json:"ip"`
+	Port     int    `json:"port"`
+	HostName string `json:"host_name"`
+	Weight   int    `json:"weight"`
+	// Zone 和 Rack 只用于 GetNZoneAware 选副本, 不影响虚拟节点的位置
+	Zone string `json:"zone,omitempty"`
+	Rack string `json:"rack,omitempty"`
+	// Capacity 大于 0 时虚拟节点数是 Replicas*Capacity 四舍五入, 可以表示 1.5 倍这样的容量, 此时 Weight 只用于有界负载
+	Capacity float64 `json:"capacity,omitempty"`
+	// Replicas 大于 0 时直接指定虚拟节点数, 不再按 Weight 或 Capacity 计算, Weight 仍用于有界负载
+	Replicas int `json:"replicas,omitempty"`
+	// Tags 用于 GetWithFilter 和 GetTagged 在一部分节点里查找, 加入环之后不要修改
+	Tags []string `json:"tags,omitempty"`
+}
+
+func NewNode(id int, ip string, port int, name string, weight int) *Node {
+	return &Node{
+		Id:       id,
+		Ip:       ip,
+		Port:     port,
+		HostName: name,
+		Weight:   weight,
+	}
+}
+
+// Equal 比较节点的所有字段, Tags 按顺序比较
+func (n Node) Equal(o Node) bool {
+	if n.Id != o.Id || n.Ip != o.Ip || n.Port != o.Port || n.HostName != o.HostName || n.Weight != o.Weight ||
+		n.Zone != o.Zone || n.Rack != o.Rack || n.Capacity != o.Capacity || n.Replicas != o.Replicas ||
+		len(n.Tags) != len(o.Tags) {
+		return false
+	}
+	for i := range n.Tags {
+		if n.Tags[i] != o.Tags[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type Consistent struct {
+	sync.RWMutex
+	Nodes     map[uint64]Node
+	resources map[int]bool
+	// members 是当前的节点, labelWeights 是节点加入时的权重, 标签里带权重时 (LegacyLabel) UpdateWeight 之后仍按旧权重生成
+	members      map[int]Node
+	labelWeights map[int]int
+	// points 是每个节点第 i 个虚拟节点实际所在的位置, 发生冲突时不一定是标签的哈希
+	points     map[int][]uint64
+	collisions int
+	draining   map[int]bool
+	down       map[int]bool
+	pins       map[string]int
+	ring       HashRing
+	numReps    int
+	keyspaces  map[string]*Keyspace
+	hasher     Hasher
+	seed       uint64
+	bits       int
+	weights    int
+	factor     float64
+	loads      *loads
+	strict     bool
+	label      LabelFunc
+	// snap 保存 *snapshot, 每次成员变化后整体替换, ring 切片也总是新分配的
+	snap     atomic.Value
+	versions versions
+	hooks    hooks
+	life     lifecycle
+	warm     warmup
+	logger   Logger
+}
+
+type Option func(c *Consistent)
+
+// WithReplicas 设置权重为 1 的节点对应多少个虚拟节点
+func WithReplicas(n int) Option {
+	return func(c *Consistent) {
+		if n > 0 {
+			c.numReps = n
+		}
+	}
+}
+
+// WithStrictWrap 使用教科书式的顺时针后继: key 归第一个 >= 它的点, 越过末尾时归第一个点.
+// 默认的回绕规则与最初的实现兼容, 已有数据的部署切换前需要迁移
+func WithStrictWrap() Option {
+	return func(c *Consistent) {
+		c.strict = true
+	}
+}
+
+// WithRingBits 选择 32 位或 64 位的环, 64 位的环虚拟节点多时几乎不会冲突;
+// 默认 32 位, 与之前的位置完全一致
+func WithRingBits(bits int) Option {
+	return func(c *Consistent) {
+		if bits == 32 || bits == 64 {
+			c.bits = bits
+		}
+	}
+}
+
+func NewConsistent(opts ...Option) *Consistent {
+	nodes := make(map[uint64]Node)
+	resources := make(map[int]bool)
+
+	c := &Consistent{
+		Nodes:        nodes,
+		resources:    resources,
+		members:      make(map[int]Node),
+		labelWeights: make(map[int]int),
+		points:       make(map[int][]uint64),
+		draining:     make(map[int]bool),
+		down:         make(map[int]bool),
+		pins:         make(map[string]int),
+		ring:         HashRing{},
+		numReps:      DEFAULT_REPLICAS,
+		keyspaces:    make(map[string]*Keyspace),
+		hasher:       CRC32,
+		bits:         DEFAULT_RING_BITS,
+		loads:        newLoads(),
+		label:        DefaultLabel,
+		versions:     versions{retain: DEFAULT_RETAINED_VERSIONS},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.snap.Store(&snapshot{ringParams: c.ringParams()})
+
+	return c
+}
+
+func (c *Consistent) Add(node *Node) bool {
+	c.Lock()
+	prev := c.current()
+	if !c.add(node) {
+		c.Unlock()
+		return false
+	}
+
+	c.notify(prev, CHANGE_JOINED, *node)
+	return true
+}
+
+// add 拒绝已经在环上的 Id 和负的权重, 与 UpdateWeight、Restore 相同
+func (c *Consistent) add(node *Node) bool {
+	if _, ok := c.resources[node.Id]; ok || node.Weight < 0 {
+		return false
+	}
+
+	if len(c.members) > 0 {
+		c.startWarmUp(node.Id)
+	}
+	c.insertPoints(c.register(node))
+	c.publish(nil)
+	return true
+}
+
+// register 在写锁内记录新节点并给它的虚拟节点找好位置, 返回这些位置, 由调用方插入环并发布
+func (c *Consistent) register(node *Node) HashRing {
+	c.resources[node.Id] = true
+	c.members[node.Id] = *node
+	c.labelWeights[node.Id] = node.Weight
+
+	count := c.pointCount(*node)
+	added := make(HashRing, 0, count)
+	for i := 0; i < count; i++ {
+		hash := c.place(i, node)
+		c.Nodes[hash] = *(node)
+		added = append(added, hash)
+	}
+
+	c.points[node.Id] = added
+	c.weights += node.Weight
+	return append(HashRing(nil), added...)
+}
+
+// place 给节点第 i 个虚拟节点找一个没被占用的位置: 先用标签的哈希, 冲突时依次尝试 label#1, label#2, ...
+// 先加入的节点保留原位置, 所以冲突的点最终落在哪里取决于节点加入的顺序
+func (c *Consistent) place(i int, node *Node) uint64 {
+	label := c.joinStr(i, node)
+	for k := 0; ; k++ {
+		hash := c.hashStr(probeLabel(label, k))
+		if _, ok := c.Nodes[hash]; !ok {
+			if k > 0 {
+				c.collisions++
+			}
+			return hash
+		}
+	}
+}
+
+// probeLabel 返回第 k 次探测用的标签, k 为 0 时就是原标签
+func probeLabel(label string, k int) string {
+	if k == 0 {
+		return label
+	}
+	return label + "#" + strconv.Itoa(k)
+}
+
+// Collisions 返回加入以来通过探测解决的虚拟节点冲突次数
+func (c *Consistent) Collisions() int {
+	c.RLock()
+	defer c.RUnlock()
+	return c.collisions
+}
+
+// insertPoints 把不在环上的新位置排序后与环归并, O(V + k log k), 不必每次重新排序整个环
+func (c *Consistent) insertPoints(points HashRing) {
+	if len(points) == 0 {
+		return
+	}
+	sort.Sort(points)
+
+	ring := make(HashRing, 0, len(c.ring)+len(points))
+	i, j := 0, 0
+	for i < len(c.ring) && j < len(points) {
+		if c.ring[i] < points[j] {
+			ring = append(ring, c.ring[i])
+			i++
+		} else {
+			ring = append(ring, points[j])
+			j++
+		}
+	}
+	ring = append(ring, c.ring[i:]...)
+	ring = append(ring, points[j:]...)
+	c.ring = ring
+}
+
+// deletePoints 按顺序过滤掉已删除的位置, O(V)
+func (c *Consistent) deletePoints(points map[uint64]bool) {
+	if len(points) == 0 {
+		return
+	}
+
+	ring := make(HashRing, 0, len(c.ring)-len(points))
+	for _, hash := range c.ring {
+		if !points[hash] {
+			ring = append(ring, hash)
+		}
+	}
+	c.ring = ring
+}
+
+func (c *Consistent) joinStr(i int, node *Node) string {
+	n := *node
+	if w, ok := c.labelWeights[node.Id]; ok {
+		n.Weight = w
+	}
+	return c.label(n, i)
+}
+
+func (c *Consistent) hashStr(key string) uint64 {
+	return c.position(c.hash([]byte(key)))
+}
+
+// hash 是加上 seed 之后的哈希值, 还没有换算成环上的位置
+func (c *Consistent) hash(b []byte) uint64 {
+	return seeded(c.hasher.Hash(b), c.seed)
+}
+
+// position 把哈希函数的输出换算成环上的位置, 32 位的环取折叠后的低 32 位
+func (c *Consistent) position(h uint64) uint64 {
+	if c.bits == 64 {
+		return h
+	}
+	return uint64(fold(h))
+}
+
+// Position 返回 key 在环上的位置
+func (c *Consistent) Position(key string) uint64 {
+	return c.current().hashStr(key)
+}
+
+// StrictWrap 表示是否使用了 WithStrictWrap
+func (c *Consistent) StrictWrap() bool {
+	return c.current().strict
+}
+
+func (c *Consistent) RingBits() int {
+	return c.current().bits
+}
+
+// Space 返回环上位置的总数, 即 2^RingBits
+func (c *Consistent) Space() float64 {
+	return math.Ldexp(1, c.current().bits)
+}
+
+// Get 返回 key 的节点, 环上没有虚拟节点时返回 ErrEmptyRing
+func (c *Consistent) Get(key string) (Node, error) {
+	s := c.current()
+	if node, ok := s.pinned(key); ok {
+		return node, nil
+	}
+	return c.get(s, s.hashStr(key))
+}
+
+// GetBytes 与 Get(string(key)) 的结果相同, 不需要先把 key 转成 string, 查找过程不分配内存
+func (c *Consistent) GetBytes(key []byte) (Node, error) {
+	s := c.current()
+	if node, ok := s.pinned(string(key)); ok {
+		return node, nil
+	}
+	return c.get(s, s.position(s.hash(key)))
+}
+
+// GetUint64 用调用方已经算好的哈希值查找, h 必须是同一个 Hasher 的输出, 即 Get(key) 等于 GetUint64(Hasher.Hash(key));
+// 拿不到 key, 所以不查 Pin 的固定表
+func (c *Consistent) GetUint64(h uint64) (Node, error) {
+	s := c.current()
+	return c.get(s, s.position(h))
+}
+
+// get 用 s 的参数算好的 hash 查找; 有界负载要在锁内按当前的环分配, s 为空时直接返回错误
+func (c *Consistent) get(s *snapshot, hash uint64) (Node, error) {
+	if s.factor > 0 {
+		if err := s.routable(); err != nil {
+			return Node{}, err
+		}
+
+		c.RLock()
+		defer c.RUnlock()
+
+		if err := c.current().routable(); err != nil {
+			return Node{}, err
+		}
+
+		c.loads.Lock()
+		defer c.loads.Unlock()
+		return c.bounded(c.search(hash)), nil
+	}
+
+	if err := s.routable(); err != nil {
+		return Node{}, err
+	}
+	return s.routes[s.search(hash)], nil
+}
+
+// GetMany 按 keys 的顺序返回每个 key 的节点, 整批 key 看到的是同一个版本的环
+func (c *Consistent) GetMany(keys []string) ([]Node, error) {
+	nodes := make([]Node, len(keys))
+
+	if c.current().factor > 0 {
+		c.RLock()
+		defer c.RUnlock()
+
+		if err := c.current().routable(); err != nil {
+			return nil, err
+		}
+
+		c.loads.Lock()
+		defer c.loads.Unlock()
+		s := c.current()
+		for i, key := range keys {
+			if node, ok := s.pinned(key); ok {
+				nodes[i] = node
+				continue
+			}
+			nodes[i] = c.bounded(c.search(c.hashStr(key)))
+		}
+		return nodes, nil
+	}
+
+	s := c.current()
+	if err := s.routable(); err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		if node, ok := s.pinned(key); ok {
+			nodes[i] = node
+			continue
+		}
+		nodes[i] = s.routes[s.search(s.hashStr(key))]
+	}
+	return nodes, nil
+}
+
+// GroupByNode 按节点 Id 分组, 每组内保持 keys 中的顺序
+func (c *Consistent) GroupByNode(keys []string) (map[int][]string, error) {
+	nodes, err := c.GetMany(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[int][]string)
+	for i, node := range nodes {
+		groups[node.Id] = append(groups[node.Id], keys[i])
+	}
+	return groups, nil
+}
+
+// GetN 从 key 的位置顺时针找 n 个不同的物理节点, 跳过 draining 和 MarkDown 的节点, 第一个与 Get 的结果相同;
+// key 被 Pin 时第一个是固定的节点, 其余的仍按环的顺序
+func (c *Consistent) GetN(key string, n int) []Node {
+	return c.getN(c.current(), key, n)
+}
+
+func (c *Consistent) getN(s *snapshot, key string, n int) []Node {
+	if n > s.members-len(s.skip) {
+		n = s.members - len(s.skip)
+	}
+
+	if n < 0 {
+		n = 0
+	}
+	nodes := make([]Node, 0, n)
+	if n <= 0 || len(s.ring) == 0 {
+		return nodes
+	}
+
+	seen := make(map[int]bool, n)
+	if node, ok := s.pinned(key); ok {
+		seen[node.Id] = true
+		nodes = append(nodes, node)
+	}
+	i := s.search(s.hashStr(key))
+	for step := 0; step < len(s.ring) && len(nodes) < n; step++ {
+		node := s.owners[i]
+		if !seen[node.Id] && !s.skip[node.Id] {
+			seen[node.Id] = true
+			nodes = append(nodes, node)
+		}
+		i = (i + 1) % len(s.ring)
+	}
+
+	return nodes
+}
+
+// Members 按 Id 排序返回环上的物理节点
+func (c *Consistent) Members() []Node {
+	c.RLock()
+	defer c.RUnlock()
+
+	nodes := make([]Node, 0, len(c.members))
+	for _, node := range c.members {
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Id < nodes[j].Id
+	})
+
+	return nodes
+}
+
+// NodeCount 返回物理节点数
+func (c *Consistent) NodeCount() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.members)
+}
+
+// VirtualNodeCount 返回环上实际存在的虚拟节点数, 哈希冲突覆盖掉的点不算
+func (c *Consistent) VirtualNodeCount() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.ring)
+}
+
+// Contains 判断 id 对应的节点是否在环上
+func (c *Consistent) Contains(id int) bool {
+	c.RLock()
+	defer c.RUnlock()
+	_, ok := c.members[id]
+	return ok
+}
+
+// Member 返回 id 对应的节点, 不在环上时第二个返回值为 false
+func (c *Consistent) Member(id int) (Node, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	node, ok := c.members[id]
+	return node, ok
+}
+
+// Arc 表示哈希值落在 [Start, End] 区间的 key 都归 Node
+type Arc struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	Node  Node   `json:"node"`
+}
+
+// Len 是区间包含的位置数, 64 位环上覆盖整个环的区间会溢出为 0, 按比例计算时用 Share
+func (a Arc) Len() uint64 {
+	return a.End - a.Start + 1
+}
+
+// Share 返回区间占整个环的比例, space 是 Consistent.Space()
+func (a Arc) Share(space float64) float64 {
+	return (float64(a.End-a.Start) + 1) / space
+}
+
+// Arcs 按哈希值从小到大返回环上的区间, 归属与 Get 的结果一致 (包括 search 的回绕规则)
+func (c *Consistent) Arcs() []Arc {
+	return c.arcs(c.current())
+}
+
+func (c *Consistent) Replicas() int {
+	return c.current().numReps
+}
+
+// Hasher 返回环使用的哈希函数, 配合 GetUint64 在调用方预先计算 key 的哈希; 设置了 WithSeed 时返回的函数已经带上 seed
+func (c *Consistent) Hasher() Hasher {
+	p := c.current().ringParams
+	if p.seed == 0 {
+		return p.hasher
+	}
+	return HasherFunc(p.hash)
+}
+
+func (c *Consistent) Seed() uint64 {
+	return c.current().seed
+}
+
+func (c *Consistent) search(hash uint64) int {
+	return c.ring.search(hash, c.strict)
+}
+
+// search 默认保留最初的回绕规则: 落在最后一个点上的 key 归第一个点, 越过末尾的 key 归最后一个点;
+// strict 时是通常的顺时针后继, 越过末尾才回到第一个点
+func (r HashRing) search(hash uint64, strict bool) int {
+	i := sort.Search(len(r), func(i int) bool {
+		return r[i] >= hash
+	})
+
+	if strict {
+		if i == len(r) {
+			return 0
+		}
+		return i
+	}
+
+	if i < len(r) {
+		if i == len(r)-1 {
+			return 0
+		} else {
+			return i
+		}
+	}
+
+	return len(r) - 1
+}
+
+// Remove 只用到 node.Id, 虚拟节点按加入时记录的节点重新生成, 与 RemoveByID 相同
+func (c *Consistent) Remove(node *Node) {
+	c.RemoveByID(node.Id)
+}
+
+// RemoveByID 删除节点的全部虚拟节点, 节点不存在时返回 false
+func (c *Consistent) RemoveByID(id int) bool {
+	c.Lock()
+	prev := c.current()
+	node, ok := c.remove(id)
+	if !ok {
+		c.Unlock()
+		return false
+	}
+
+	c.notify(prev, CHANGE_LEFT, node)
+	return true
+}
+
+func (c *Consistent) remove(id int) (Node, bool) {
+	deleted := make(map[uint64]bool, len(c.points[id]))
+	node, ok := c.unregister(id, deleted)
+	if !ok {
+		return node, false
+	}
+
+	c.deletePoints(deleted)
+	c.publish(nil)
+	return node, true
+}
+
+// unregister 在写锁内删除节点的记录, 把它的位置加入 deleted, 由调用方从环上删除并发布
+func (c *Consistent) unregister(id int, deleted map[uint64]bool) (Node, bool) {
+	node, ok := c.members[id]
+	if !ok {
+		return node, false
+	}
+
+	c.weights -= node.Weight
+	c.loads.Lock()
+	c.loads.forget(id)
+	c.loads.Unlock()
+
+	for _, hash := range c.points[id] {
+		deleted[hash] = true
+		delete(c.Nodes, hash)
+	}
+
+	delete(c.resources, id)
+	delete(c.members, id)
+	delete(c.labelWeights, id)
+	delete(c.points, id)
+	delete(c.draining, id)
+	delete(c.down, id)
+	delete(c.warm.since, id)
+	return node, true
+}
+
+// UpdateWeight 只增删新旧虚拟节点数之间的那部分虚拟节点, 其余的位置不变,
+// 所以只有这部分区间上的 key 会移动; 节点不存在或权重为负时返回 false
+func (c *Consistent) UpdateWeight(id int, weight int) bool {
+	c.Lock()
+	prev := c.current()
+	if !c.updateWeight(id, weight) {
+		c.Unlock()
+		return false
+	}
+
+	c.notify(prev, CHANGE_WEIGHT, c.members[id])
+	return true
+}
+
+func (c *Consistent) updateWeight(id int, weight int) bool {
+	node, ok := c.members[id]
+	if !ok || weight < 0 {
+		return false
+	}
+
+	node.Weight = weight
+	c.resize(node)
+	return true
+}
+
+// resize 在写锁内把节点的虚拟节点数调整到 ReplicaCount(node), 预热中的节点按比例减少, 只增删多出或缺少的那部分
+func (c *Consistent) resize(node Node) {
+	id := node.Id
+	old := c.members[id].Weight
+
+	points := c.points[id]
+	from, to := len(points), c.pointCount(node)
+	added, deleted, touched := make(HashRing, 0), make(map[uint64]bool), make(map[uint64]bool)
+	for i, hash := range points {
+		if i >= to {
+			deleted[hash] = true
+			delete(c.Nodes, hash)
+			continue
+		}
+		// 保留下来的虚拟节点也要换成新的权重, Get 返回的 Node 与 Members 一致
+		touched[hash] = true
+		c.Nodes[hash] = node
+	}
+	if to < from {
+		points = points[:to:to]
+	}
+	for i := from; i < to; i++ {
+		hash := c.place(i, &node)
+		c.Nodes[hash] = node
+		added = append(added, hash)
+		points = append(points, hash)
+	}
+
+	c.points[id] = points
+	c.members[id] = node
+	c.weights += node.Weight - old
+	c.deletePoints(deleted)
+	c.insertPoints(added)
+	c.publish(touched)
+}
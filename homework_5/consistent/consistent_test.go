@@ -0,0 +1,109 @@
+package consistent
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestGetEmptyRing(t *testing.T) {
+	for _, c := range []*Consistent{NewConsistent(), NewConsistent(WithStrictWrap())} {
+		if _, err := c.Get("key"); !errors.Is(err, ErrEmptyRing) {
+			t.Fatalf("Get: got %v, want ErrEmptyRing", err)
+		}
+		if _, err := c.GetBytes([]byte("key")); !errors.Is(err, ErrEmptyRing) {
+			t.Fatalf("GetBytes: got %v, want ErrEmptyRing", err)
+		}
+		if _, err := c.GetUint64(1); !errors.Is(err, ErrEmptyRing) {
+			t.Fatalf("GetUint64: got %v, want ErrEmptyRing", err)
+		}
+
+		// 删掉最后一个节点之后同样是空环
+		c.Add(NewNode(1, "10.0.0.1", 8001, "", 1))
+		c.RemoveByID(1)
+		if _, err := c.Get("key"); !errors.Is(err, ErrEmptyRing) {
+			t.Fatalf("Get after removing every node: got %v, want ErrEmptyRing", err)
+		}
+	}
+}
+
+func TestSearchWrap(t *testing.T) {
+	r := HashRing{10, 20, 30}
+	cases := []struct {
+		hash   uint64
+		strict int
+		legacy int
+	}{
+		{0, 0, 0},
+		{10, 0, 0},
+		{15, 1, 1},
+		{20, 1, 1},
+		// 后继是最后一个点: 默认规则归第一个点
+		{25, 2, 0},
+		{30, 2, 0},
+		// 越过末尾: strict 回到第一个点, 默认规则归最后一个点
+		{31, 0, 2},
+		{^uint64(0), 0, 2},
+	}
+	for _, tc := range cases {
+		if got := r.search(tc.hash, true); got != tc.strict {
+			t.Errorf("strict search(%d) = %d, want %d", tc.hash, got, tc.strict)
+		}
+		if got := r.search(tc.hash, false); got != tc.legacy {
+			t.Errorf("legacy search(%d) = %d, want %d", tc.hash, got, tc.legacy)
+		}
+	}
+}
+
+// TestGetPastLastPoint 哈希值越过最后一个虚拟节点的 key: strict 时归第一个点, 默认规则归最后一个点
+func TestGetPastLastPoint(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		opts := []Option{}
+		if strict {
+			opts = append(opts, WithStrictWrap())
+		}
+		c := newTestRing(3, opts...)
+		s := c.current()
+		first, last := s.owners[0], s.owners[len(s.owners)-1]
+
+		found := false
+		for i := 0; i < 1<<16 && !found; i++ {
+			key := "wrap-" + strconv.Itoa(i)
+			if c.hashStr(key) <= s.ring[len(s.ring)-1] {
+				continue
+			}
+			found = true
+
+			want := last
+			if strict {
+				want = first
+			}
+			node, err := c.Get(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if node.Id != want.Id {
+				t.Fatalf("strict=%v: key %q past the last point went to %d, want %d", strict, key, node.Id, want.Id)
+			}
+		}
+		if !found {
+			t.Fatal("no key hashes past the last point")
+		}
+	}
+}
+
+func TestAddNegativeWeight(t *testing.T) {
+	c := newTestRing(1)
+	if c.Add(NewNode(2, "10.0.0.2", 80, "b", -1)) {
+		t.Fatal("Add accepted a negative weight")
+	}
+	if n := c.AddBatch([]*Node{NewNode(3, "10.0.0.3", 80, "c", -2), NewNode(4, "10.0.0.4", 80, "d", 1)}); n != 1 {
+		t.Fatalf("AddBatch added %d nodes, want 1", n)
+	}
+	if got := len(c.Members()); got != 2 {
+		t.Fatalf("ring has %d members, want 2", got)
+	}
+	if n := c.ReplicaCount(Node{Weight: -1}); n != 0 {
+		t.Fatalf("ReplicaCount of a negative weight is %d, want 0", n)
+	}
+}
@@ -0,0 +1,24 @@
+package consistent
+
+// Movement 是在两个环之间换了节点的区间, Fraction 是区间占整个哈希空间的比例,
+// 均匀的 key 大约有这个比例需要从 From 迁移到 To
+type Movement struct {
+	RangeChange
+	Fraction float64 `json:"fraction"`
+}
+
+// Diff 比较两个环的区间归属, 按位置从小到大返回所有换了节点的区间, 相邻且方向相同的区间会合并.
+// 两个环的 RingBits 不同时位置无法对应, 返回 nil
+func Diff(old, new *Consistent) []Movement {
+	if old.RingBits() != new.RingBits() {
+		return nil
+	}
+
+	space := old.Space()
+	changes := diffArcs(old.Arcs(), new.Arcs())
+	moves := make([]Movement, len(changes))
+	for i, rc := range changes {
+		moves[i] = Movement{RangeChange: rc, Fraction: Arc{Start: rc.Start, End: rc.End}.Share(space)}
+	}
+	return moves
+}
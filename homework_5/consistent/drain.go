@@ -0,0 +1,144 @@
+package consistent
+
+import (
+	"errors"
+)
+
+var ErrAllDraining = errors.New("consistent: every node is draining or down")
+
+const (
+	CHANGE_DRAINING = "draining"
+	CHANGE_UNDRAIN  = "undrain"
+	CHANGE_DOWN     = "down"
+	CHANGE_UP       = "up"
+)
+
+// unroutable 在锁内调用, 返回不接收新 key 的节点, 即 draining 和 MarkDown 的并集
+func (c *Consistent) unroutable() map[int]bool {
+	skip := make(map[int]bool, len(c.draining)+len(c.down))
+	for id := range c.draining {
+		skip[id] = true
+	}
+	for id := range c.down {
+		skip[id] = true
+	}
+	return skip
+}
+
+// route 计算 routes: 从后往前扫两遍, 每个下标记住顺时针方向第一个不被跳过的节点
+func (s *snapshot) route(skip map[int]bool) {
+	if len(skip) == 0 {
+		s.routes = s.owners
+		return
+	}
+	s.skip = skip
+
+	routes := make([]Node, len(s.owners))
+	var next *Node
+	for pass := 0; pass < 2; pass++ {
+		for i := len(s.owners) - 1; i >= 0; i-- {
+			if !skip[s.owners[i].Id] {
+				next = &s.owners[i]
+			}
+			if next != nil {
+				routes[i] = *next
+			}
+		}
+	}
+	if next != nil {
+		s.routes = routes
+	}
+}
+
+// routable 区分空环和所有节点都被跳过两种无法路由的情况
+func (s *snapshot) routable() error {
+	if len(s.ring) == 0 {
+		return ErrEmptyRing
+	}
+	if len(s.routes) == 0 {
+		return ErrAllDraining
+	}
+	return nil
+}
+
+// Drain 把节点标成 draining: 虚拟节点留在环上, 但 Get、GetN 和有界负载都跳过它, 原来归它的 key 落到顺时针的下一个节点;
+// GetIncludingDraining 仍然返回它, 用来读取还没迁走的数据. 迁移完成后调用 CompleteDrain 删除节点
+func (c *Consistent) Drain(id int) bool {
+	return c.mark(id, false, true, CHANGE_DRAINING)
+}
+
+// Undrain 取消 Drain, 节点重新接收新的 key
+func (c *Consistent) Undrain(id int) bool {
+	return c.mark(id, false, false, CHANGE_UNDRAIN)
+}
+
+// MarkDown 把不健康的节点从路由中摘掉, 效果与 Drain 相同, 但两者互不影响: 节点 MarkUp 之后如果还在 draining 仍然不接收新 key.
+// down 是运行时的健康状态, 不写进 Snapshot
+func (c *Consistent) MarkDown(id int) bool {
+	return c.mark(id, true, true, CHANGE_DOWN)
+}
+
+// MarkUp 取消 MarkDown
+func (c *Consistent) MarkUp(id int) bool {
+	return c.mark(id, true, false, CHANGE_UP)
+}
+
+func (c *Consistent) IsDown(id int) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.down[id]
+}
+
+// mark 修改节点的 draining (down 为 false) 或 down 状态并发布新的路由, 节点不存在或状态没有变化时返回 false
+func (c *Consistent) mark(id int, down, on bool, kind string) bool {
+	c.Lock()
+	set := c.draining
+	if down {
+		set = c.down
+	}
+	node, ok := c.members[id]
+	if !ok || set[id] == on {
+		c.Unlock()
+		return false
+	}
+
+	prev := c.current()
+	if on {
+		set[id] = true
+	} else {
+		delete(set, id)
+	}
+	c.publish(nil)
+
+	c.notify(prev, kind, node)
+	return true
+}
+
+// CompleteDrain 删除已经 draining 的节点, 删除不会再移动任何 key; 节点不存在或没有在 draining 时返回 false
+func (c *Consistent) CompleteDrain(id int) bool {
+	c.Lock()
+	if !c.draining[id] {
+		c.Unlock()
+		return false
+	}
+
+	prev := c.current()
+	node, _ := c.remove(id)
+	c.notify(prev, CHANGE_LEFT, node)
+	return true
+}
+
+func (c *Consistent) IsDraining(id int) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.draining[id]
+}
+
+// GetIncludingDraining 不跳过 draining 的节点, 返回节点开始 draining 之前 key 的归属
+func (c *Consistent) GetIncludingDraining(key string) (Node, error) {
+	s := c.current()
+	if len(s.ring) == 0 {
+		return Node{}, ErrEmptyRing
+	}
+	return s.owners[s.search(s.hashStr(key))], nil
+}
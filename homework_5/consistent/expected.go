@@ -0,0 +1,89 @@
+package consistent
+
+import (
+	"math"
+)
+
+// LoadWarning 是预期比例偏离目标太多的节点, Deviation 是 (Expected - Target) / Target
+type LoadWarning struct {
+	Node      Node    `json:"node"`
+	Expected  float64 `json:"expected"`
+	Target    float64 `json:"target"`
+	Deviation float64 `json:"deviation"`
+}
+
+// ExpectedLoad 直接按排好序的位置之间的间隔计算每个物理节点拥有的哈希空间比例, 不需要采样 key; 均匀的 key 落到节点上的比例就是它.
+// 结果与 Ownership 相同, 但包含所有成员, draining 和 MarkDown 的节点为 0, 它们的区间算在接管的节点上
+func (c *Consistent) ExpectedLoad() map[int]float64 {
+	c.RLock()
+	s := c.current()
+	shares := make(map[int]float64, len(c.members))
+	for id := range c.members {
+		shares[id] = 0
+	}
+	c.RUnlock()
+
+	space := c.Space()
+	for _, arc := range c.arcs(s) {
+		shares[arc.Node.Id] += arc.Share(space)
+	}
+	return shares
+}
+
+// targets 按虚拟节点数 (ReplicaCount, 没有 Capacity 和 Replicas 时就是按 Weight) 计算每个参与路由的节点应有的比例
+func (c *Consistent) targets() map[int]float64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	skip := c.current().skip
+	total := 0
+	for id, node := range c.members {
+		if !skip[id] {
+			total += c.ReplicaCount(node)
+		}
+	}
+	targets := make(map[int]float64, len(c.members))
+	if total == 0 {
+		return targets
+	}
+	for id, node := range c.members {
+		if !skip[id] {
+			targets[id] = float64(c.ReplicaCount(node)) / float64(total)
+		}
+	}
+	return targets
+}
+
+// LoadWarnings 按 Id 顺序返回预期比例与目标比例相差超过 threshold (0.1 表示 10%) 的节点, draining 和 MarkDown 的节点不参与比较
+func (c *Consistent) LoadWarnings(threshold float64) []LoadWarning {
+	expected, targets := c.ExpectedLoad(), c.targets()
+	members := c.Members()
+
+	warnings := make([]LoadWarning, 0)
+	for _, node := range members {
+		target, ok := targets[node.Id]
+		if !ok || target == 0 {
+			continue
+		}
+		deviation := (expected[node.Id] - target) / target
+		if math.Abs(deviation) > threshold {
+			warnings = append(warnings, LoadWarning{Node: node, Expected: expected[node.Id], Target: target, Deviation: deviation})
+		}
+	}
+	return warnings
+}
+
+// WithLoadWarning 在每次归属变化之后检查一遍 LoadWarnings, 有偏离超过 threshold 的节点时调用 fn.
+// fn 与 OnOwnershipChanged 的回调在同样的时机执行, 规则也相同; 每次检查的代价是 O(V)
+func WithLoadWarning(threshold float64, fn func(warnings []LoadWarning)) Option {
+	return func(c *Consistent) {
+		if threshold <= 0 || fn == nil {
+			return
+		}
+		c.hooks.changed = append(c.hooks.changed, func(changes []RangeChange) {
+			if warnings := c.LoadWarnings(threshold); len(warnings) > 0 {
+				fn(warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package consistent
+
+import (
+	"sort"
+)
+
+// Skipped 是查找时跳过的候选虚拟节点及原因
+type Skipped struct {
+	Index  int    `json:"index"`
+	Point  uint64 `json:"point"`
+	Node   Node   `json:"node"`
+	Reason string `json:"reason"`
+}
+
+// Explanation 记录一次 Get 的完整过程
+type Explanation struct {
+	Key  string `json:"key"`
+	Hash uint64 `json:"hash"`
+	// Found 是第一个 >= Hash 的点的下标, 等于 RingSize 表示越过了末尾
+	Found int `json:"found"`
+	// Index 是 search 回绕规则处理之后真正使用的下标
+	Index    int       `json:"index"`
+	Wrapped  bool      `json:"wrapped"`
+	RingSize int       `json:"ring_size"`
+	Point    uint64    `json:"point"`
+	Label    string    `json:"label"`
+	Replica  int       `json:"replica"`
+	Skipped  []Skipped `json:"skipped"`
+	Owner    Node      `json:"owner"`
+	// Pinned 表示 key 被 Pin 固定, Owner 是固定的节点, 其余字段仍是环上的查找过程
+	Pinned bool `json:"pinned,omitempty"`
+	// Version 是查找时环的版本
+	Version uint64 `json:"version"`
+}
+
+// ExplainGet 与 Get 走同样的查找逻辑, 返回每一步的中间结果, 用于排查 key 的意外归属
+func (c *Consistent) ExplainGet(key string) (*Explanation, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if err := c.current().routable(); err != nil {
+		return nil, err
+	}
+
+	e := &Explanation{
+		Key:      key,
+		Hash:     c.hashStr(key),
+		RingSize: len(c.ring),
+		Skipped:  make([]Skipped, 0),
+		Replica:  -1,
+		Version:  c.current().version,
+	}
+
+	e.Found = sort.Search(len(c.ring), func(i int) bool {
+		return c.ring[i] >= e.Hash
+	})
+	e.Index = c.search(e.Hash)
+	e.Wrapped = e.Index != e.Found
+	e.Point = c.ring[e.Index]
+	e.Owner = c.Nodes[e.Point]
+	for c.current().skip[e.Owner.Id] {
+		reason := "draining"
+		if c.down[e.Owner.Id] {
+			reason = "down"
+		}
+		e.Skipped = append(e.Skipped, Skipped{Index: e.Index, Point: e.Point, Node: e.Owner, Reason: reason})
+		e.Index = (e.Index + 1) % len(c.ring)
+		e.Point = c.ring[e.Index]
+		e.Owner = c.Nodes[e.Point]
+	}
+
+	for i, hash := range c.points[e.Owner.Id] {
+		if hash != e.Point {
+			continue
+		}
+		// 冲突探测过的点, Label 是最终落位时用的 label#k
+		label := c.joinStr(i, &e.Owner)
+		for k := 0; ; k++ {
+			if c.hashStr(probeLabel(label, k)) == hash {
+				e.Label = probeLabel(label, k)
+				break
+			}
+		}
+		e.Replica = i
+		break
+	}
+
+	if node, ok := c.current().pinned(key); ok {
+		e.Owner, e.Pinned = node, true
+	}
+	return e, nil
+}
@@ -0,0 +1,65 @@
+package consistent
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// Fingerprint 对排好序的位置、每个位置路由到的节点 (Id, Ip, Port, Weight)、生效的 Pin 和影响路由的参数做 FNV-1a,
+// 与进程、节点加入的顺序无关; 两个环的 Fingerprint 相同就可以认为所有 key 的路由相同
+func (c *Consistent) Fingerprint() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+	return c.fingerprint()
+}
+
+// fingerprint 需要持有读锁或写锁
+func (c *Consistent) fingerprint() uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	put := func(v uint64) {
+		binary.LittleEndian.PutUint64(buf, v)
+		h.Write(buf)
+	}
+
+	strict := uint64(0)
+	if c.strict {
+		strict = 1
+	}
+	put(uint64(c.bits))
+	put(uint64(c.numReps))
+	put(strict)
+	put(math.Float64bits(c.factor))
+	// 没有 seed 时不写入, 之前的指纹保持不变
+	if c.seed != 0 {
+		put(c.seed)
+	}
+
+	s := c.current()
+	for i := 0; i < len(s.routes); i++ {
+		hash := s.ring[i]
+		node := s.routes[i]
+		put(hash)
+		put(uint64(node.Id))
+		put(uint64(node.Port))
+		put(uint64(node.Weight))
+		h.Write([]byte(node.Ip))
+		h.Write([]byte{0})
+	}
+
+	// 按 key 排序写入生效的 Pin, 没有 Pin 时之前的指纹保持不变
+	keys := make([]string, 0, len(s.pins))
+	for key := range s.pins {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		put(uint64(s.pins[key].Id))
+	}
+
+	return h.Sum64()
+}
@@ -0,0 +1,29 @@
+package consistent
+
+import "testing"
+
+func TestFingerprintPins(t *testing.T) {
+	a, b := newTestRing(3), newTestRing(3)
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Fatal("identical rings have different fingerprints")
+	}
+
+	a.Pin("key", 2)
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Fatal("pinning a key did not change the fingerprint")
+	}
+	b.Pin("key", 3)
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Fatal("pins to different nodes have the same fingerprint")
+	}
+	b.Pin("key", 2)
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Fatal("same pins have different fingerprints")
+	}
+
+	a.Unpin("key")
+	b.Unpin("key")
+	if a.Fingerprint() != newTestRing(3).Fingerprint() {
+		t.Fatal("Unpin did not restore the fingerprint")
+	}
+}
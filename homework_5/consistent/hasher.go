@@ -0,0 +1,217 @@
+package consistent
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math/bits"
+)
+
+// Hasher 决定虚拟节点和 key 在环上的位置
+type Hasher interface {
+	Hash(b []byte) uint64
+}
+
+// HasherFunc 让普通函数满足 Hasher
+type HasherFunc func(b []byte) uint64
+
+func (f HasherFunc) Hash(b []byte) uint64 {
+	return f(b)
+}
+
+// WithHasher 替换默认的 CRC32, 换了哈希函数的环与默认环的分布完全不同
+func WithHasher(h Hasher) Option {
+	return func(c *Consistent) {
+		if h != nil {
+			c.hasher = h
+		}
+	}
+}
+
+// WithSeed 把 seed 混进虚拟节点和 key 的哈希值: 同一个 seed 在任何进程里得到同样的分布, 换一个 seed 整个环重新洗牌.
+// 测试用固定的 seed 复现分布, 部署时改 seed 要像换哈希函数一样迁移数据; 0 表示不加 seed, 与之前的位置完全一致
+func WithSeed(seed uint64) Option {
+	return func(c *Consistent) {
+		c.seed = seed
+	}
+}
+
+// seeded 在哈希函数的输出上异或 seed 再做一次 fmix64, 不同的 seed 之间互不相关;
+// 原来就相同的哈希值加 seed 之后仍然相同
+func seeded(h, seed uint64) uint64 {
+	if seed == 0 {
+		return h
+	}
+	return fmix64(h ^ seed)
+}
+
+var (
+	CRC32   Hasher = HasherFunc(crc32Hash)
+	FNV1a   Hasher = HasherFunc(fnv1a64)
+	XXHash  Hasher = HasherFunc(xxhash64)
+	Murmur3 Hasher = HasherFunc(murmur3)
+)
+
+// Hashers 按名称列出内置的哈希函数, 供命令行和配置文件使用
+var Hashers = map[string]Hasher{
+	"crc32":   CRC32,
+	"fnv1a":   FNV1a,
+	"xxhash":  XXHash,
+	"murmur3": Murmur3,
+}
+
+// fold 把 64 位哈希折成 32 位环上的位置, crc32 的结果不超过 32 位, 折叠后不变
+func fold(h uint64) uint32 {
+	return uint32(h ^ h>>32)
+}
+
+func crc32Hash(b []byte) uint64 {
+	return uint64(crc32.ChecksumIEEE(b))
+}
+
+func fnv1a64(b []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h
+}
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMerge(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// xxhash64 是 seed 为 0 的 XXH64
+func xxhash64(b []byte) uint64 {
+	n := len(b)
+	var h uint64
+
+	if n >= 32 {
+		// 常量运算会溢出, 用变量做回绕的加减
+		p1, p2 := xxPrime1, xxPrime2
+		v1 := p1 + p2
+		v2 := p2
+		v3 := uint64(0)
+		v4 := 0 - p1
+		for len(b) >= 32 {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(b[0:8]))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(b[8:16]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(b[16:24]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(b[24:32]))
+			b = b[32:]
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMerge(h, v1)
+		h = xxMerge(h, v2)
+		h = xxMerge(h, v3)
+		h = xxMerge(h, v4)
+	} else {
+		h = xxPrime5
+	}
+
+	h += uint64(n)
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(b[:8]))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b[:4])) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
+
+// murmur3 返回 seed 为 0 的 MurmurHash3_x64_128 的前 64 位
+func murmur3(b []byte) uint64 {
+	const (
+		c1 = 0x87c37b91114253d5
+		c2 = 0x4cf5ad432745937f
+	)
+
+	n := len(b)
+	var h1, h2 uint64
+	for ; len(b) >= 16; b = b[16:] {
+		k1 := binary.LittleEndian.Uint64(b[0:8])
+		k2 := binary.LittleEndian.Uint64(b[8:16])
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	var k1, k2 uint64
+	for i := len(b) - 1; i >= 8; i-- {
+		k2 ^= uint64(b[i]) << (uint(i-8) * 8)
+	}
+	if len(b) > 8 {
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+	}
+	for i := 0; i < len(b) && i < 8; i++ {
+		k1 ^= uint64(b[i]) << (uint(i) * 8)
+	}
+	if len(b) > 0 {
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	h1 ^= uint64(n)
+	h2 ^= uint64(n)
+	h1 += h2
+	h2 += h1
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+	h1 += h2
+	return h1
+}
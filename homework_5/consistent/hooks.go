@@ -0,0 +1,176 @@
+package consistent
+
+import (
+	"sync"
+)
+
+// RangeChange 表示 [Start, End] 区间上的 key 从 From 移到了 To;
+// 环原来为空时 From 是零值, 环变为空时 To 是零值
+type RangeChange struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	From  Node   `json:"from"`
+	To    Node   `json:"to"`
+}
+
+type hooks struct {
+	sync.Mutex
+	added   []func(node Node)
+	removed []func(node Node)
+	changed []func(changes []RangeChange)
+	subs    map[chan ChangeEvent]struct{}
+	// closed 在 Close 之后为 true, 不再接受新的订阅
+	closed bool
+}
+
+// OnNodeAdded 注册节点加入后的回调. 回调在新的环发布之后、在修改环的 goroutine 里按环的变更顺序执行,
+// 回调里可以读环, 但不能再修改环或注册回调
+func (c *Consistent) OnNodeAdded(fn func(node Node)) {
+	c.hooks.Lock()
+	defer c.hooks.Unlock()
+	c.hooks.added = append(c.hooks.added, fn)
+}
+
+// OnNodeRemoved 注册节点删除后的回调, 执行规则与 OnNodeAdded 相同
+func (c *Consistent) OnNodeRemoved(fn func(node Node)) {
+	c.hooks.Lock()
+	defer c.hooks.Unlock()
+	c.hooks.removed = append(c.hooks.removed, fn)
+}
+
+// OnOwnershipChanged 注册归属变化的回调, 加入、删除和 UpdateWeight 之后都会调用, 没有区间变化时不调用.
+// 注册之后每次变更都要比较新旧两个环, 代价是 O(V)
+func (c *Consistent) OnOwnershipChanged(fn func(changes []RangeChange)) {
+	c.hooks.Lock()
+	defer c.hooks.Unlock()
+	c.hooks.changed = append(c.hooks.changed, fn)
+}
+
+// notify 在写锁内调用, 先拿到回调的锁再释放写锁, 这样回调的顺序与环的版本一致, 回调期间读者不会被阻塞
+func (c *Consistent) notify(prev *snapshot, kind string, node Node) {
+	c.hooks.Lock()
+	defer c.hooks.Unlock()
+	next := c.current()
+	c.Unlock()
+
+	switch kind {
+	case CHANGE_JOINED:
+		for _, fn := range c.hooks.added {
+			fn(node)
+		}
+	case CHANGE_LEFT:
+		for _, fn := range c.hooks.removed {
+			fn(node)
+		}
+	}
+
+	if len(c.hooks.changed) == 0 && len(c.hooks.subs) == 0 && c.logger == nil {
+		return
+	}
+	changes := diffArcs(c.arcs(prev), c.arcs(next))
+	if kind == CHANGE_RESTORED {
+		// Restore 没有单个的节点, vnodes 就是恢复的虚拟节点数
+		c.logChange(prev, next, changes, "change", kind)
+	} else {
+		c.logChange(prev, next, changes, "change", kind, "node", node.Id)
+	}
+	if len(changes) > 0 {
+		for _, fn := range c.hooks.changed {
+			fn(changes)
+		}
+	}
+	c.hooks.send(ChangeEvent{Type: kind, Node: node, Ranges: changes, Version: next.version})
+}
+
+// notifyBatch 与 notify 相同, 用于一次发布了多个节点变化的情况: 对每个加入和删除的节点分别调用回调,
+// 归属变化的回调和订阅只收到一次, 事件的 Node 是零值
+func (c *Consistent) notifyBatch(prev *snapshot, kind string, joined, left []Node) {
+	c.hooks.Lock()
+	defer c.hooks.Unlock()
+	next := c.current()
+	c.Unlock()
+
+	for _, node := range joined {
+		for _, fn := range c.hooks.added {
+			fn(node)
+		}
+	}
+	for _, node := range left {
+		for _, fn := range c.hooks.removed {
+			fn(node)
+		}
+	}
+
+	if len(c.hooks.changed) == 0 && len(c.hooks.subs) == 0 && c.logger == nil {
+		return
+	}
+	changes := diffArcs(c.arcs(prev), c.arcs(next))
+	c.logChange(prev, next, changes, "change", kind, "joined", nodeIds(joined), "left", nodeIds(left))
+	if len(changes) > 0 {
+		for _, fn := range c.hooks.changed {
+			fn(changes)
+		}
+	}
+	c.hooks.send(ChangeEvent{Type: kind, Ranges: changes, Version: next.version})
+}
+
+// diffArcs 同时扫描新旧两组区间, 两组都从 0 开始覆盖到环的末尾; 相邻且 From/To 相同的变化合并成一段
+func diffArcs(old, next []Arc) []RangeChange {
+	changes := make([]RangeChange, 0)
+	if len(old) == 0 && len(next) == 0 {
+		return changes
+	}
+	if len(old) == 0 {
+		for _, arc := range next {
+			changes = appendChange(changes, RangeChange{Start: arc.Start, End: arc.End, To: arc.Node})
+		}
+		return changes
+	}
+	if len(next) == 0 {
+		for _, arc := range old {
+			changes = appendChange(changes, RangeChange{Start: arc.Start, End: arc.End, From: arc.Node})
+		}
+		return changes
+	}
+
+	start := uint64(0)
+	i, j := 0, 0
+	for i < len(old) && j < len(next) {
+		end := old[i].End
+		if next[j].End < end {
+			end = next[j].End
+		}
+		if old[i].Node.Id != next[j].Node.Id {
+			changes = appendChange(changes, RangeChange{Start: start, End: end, From: old[i].Node, To: next[j].Node})
+		}
+
+		if old[i].End == end {
+			i++
+		}
+		if next[j].End == end {
+			j++
+		}
+		start = end + 1
+	}
+
+	return changes
+}
+
+func appendChange(changes []RangeChange, rc RangeChange) []RangeChange {
+	if n := len(changes); n > 0 {
+		last := &changes[n-1]
+		if last.End+1 == rc.Start && last.From.Id == rc.From.Id && last.To.Id == rc.To.Id {
+			last.End = rc.End
+			return changes
+		}
+	}
+	return append(changes, rc)
+}
+
+func nodeIds(nodes []Node) []int {
+	ids := make([]int, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.Id
+	}
+	return ids
+}
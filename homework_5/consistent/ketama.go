@@ -0,0 +1,176 @@
+package consistent
+
+import (
+	"crypto/md5"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	KETAMA_POINTS_PER_DIGEST = 4
+	KETAMA_DIGESTS_PER_NODE  = 40
+)
+
+type ketamaPoint struct {
+	hash uint32
+	node int
+}
+
+// Ketama 与 libketama 生成同样的环: 每个节点 floor(weight/总权重 * 40 * 节点数) 个 "ip:port-i" 标签,
+// 每个标签的 MD5 切成 4 个点, key 取 MD5 前 4 字节; 与 PHP/Python 的 ketama 客户端查到同样的节点
+type Ketama struct {
+	sync.RWMutex
+	nodes  []Node
+	points []ketamaPoint
+}
+
+func NewKetama() *Ketama {
+	return &Ketama{}
+}
+
+func ketamaLabel(node *Node, i int) string {
+	return node.Ip + ":" + strconv.Itoa(node.Port) + "-" + strconv.Itoa(i)
+}
+
+// ketamaHash 是 libketama 的 ketama_hashi, 取 digest 的第 h 组 4 字节, 小端
+func ketamaHash(digest [md5.Size]byte, h int) uint32 {
+	return uint32(digest[3+h*4])<<24 | uint32(digest[2+h*4])<<16 | uint32(digest[1+h*4])<<8 | uint32(digest[h*4])
+}
+
+// rebuild 每个节点的点数取决于总权重和节点数, 所以成员变化时整个环都要重建, 与 libketama 一致
+func (k *Ketama) rebuild() {
+	total := 0
+	for _, node := range k.nodes {
+		if node.Weight > 0 {
+			total += node.Weight
+		}
+	}
+
+	k.points = k.points[:0]
+	for i := range k.nodes {
+		node := &k.nodes[i]
+		if node.Weight <= 0 {
+			continue
+		}
+
+		// libketama 是 floorf(pct * 40.0 * (float)n): 乘积按 double 算, 传给 floorf 时先转成 float 再取整,
+		// 份额 0.7、10 个节点时 double 是 279.99999, 转成 float 是 280
+		pct := float32(node.Weight) / float32(total)
+		digests := int(math.Floor(float64(float32(float64(pct) * KETAMA_DIGESTS_PER_NODE * float64(len(k.nodes))))))
+		for d := 0; d < digests; d++ {
+			digest := md5.Sum([]byte(ketamaLabel(node, d)))
+			for h := 0; h < KETAMA_POINTS_PER_DIGEST; h++ {
+				k.points = append(k.points, ketamaPoint{hash: ketamaHash(digest, h), node: node.Id})
+			}
+		}
+	}
+
+	sort.SliceStable(k.points, func(i, j int) bool {
+		return k.points[i].hash < k.points[j].hash
+	})
+}
+
+func (k *Ketama) find(id int) (int, bool) {
+	i := sort.Search(len(k.nodes), func(i int) bool {
+		return k.nodes[i].Id >= id
+	})
+	return i, i < len(k.nodes) && k.nodes[i].Id == id
+}
+
+func (k *Ketama) Add(node *Node) bool {
+	k.Lock()
+	defer k.Unlock()
+
+	i, ok := k.find(node.Id)
+	if ok {
+		return false
+	}
+
+	k.nodes = append(k.nodes, Node{})
+	copy(k.nodes[i+1:], k.nodes[i:])
+	k.nodes[i] = *node
+	k.rebuild()
+	return true
+}
+
+func (k *Ketama) Remove(node *Node) {
+	k.Lock()
+	defer k.Unlock()
+
+	i, ok := k.find(node.Id)
+	if !ok {
+		return
+	}
+
+	k.nodes = append(k.nodes[:i], k.nodes[i+1:]...)
+	k.rebuild()
+}
+
+// search 返回第一个 >= hash 的点, 越过末尾时回到第一个点
+func (k *Ketama) search(key string) int {
+	digest := md5.Sum([]byte(key))
+	hash := ketamaHash(digest, 0)
+
+	i := sort.Search(len(k.points), func(i int) bool {
+		return k.points[i].hash >= hash
+	})
+	if i == len(k.points) {
+		return 0
+	}
+	return i
+}
+
+func (k *Ketama) node(id int) Node {
+	i, _ := k.find(id)
+	return k.nodes[i]
+}
+
+// Get 没有节点时返回 ErrEmptyRing
+func (k *Ketama) Get(key string) (Node, error) {
+	k.RLock()
+	defer k.RUnlock()
+
+	if len(k.points) == 0 {
+		return Node{}, ErrEmptyRing
+	}
+	return k.node(k.points[k.search(key)].node), nil
+}
+
+// GetN 从 key 的位置顺时针找 n 个不同的节点
+func (k *Ketama) GetN(key string, n int) []Node {
+	k.RLock()
+	defer k.RUnlock()
+
+	if n < 0 {
+		n = 0
+	}
+	nodes := make([]Node, 0, n)
+	if len(k.points) == 0 || n <= 0 {
+		return nodes
+	}
+
+	seen := make(map[int]bool, n)
+	i := k.search(key)
+	for step := 0; step < len(k.points) && len(nodes) < n; step++ {
+		id := k.points[i].node
+		if !seen[id] {
+			seen[id] = true
+			nodes = append(nodes, k.node(id))
+		}
+		i = (i + 1) % len(k.points)
+	}
+
+	return nodes
+}
+
+// Members 按 Id 排序返回所有节点
+func (k *Ketama) Members() []Node {
+	k.RLock()
+	defer k.RUnlock()
+
+	nodes := make([]Node, len(k.nodes))
+	copy(nodes, k.nodes)
+	return nodes
+}
@@ -0,0 +1,83 @@
+package consistent
+
+import (
+	"strconv"
+	"testing"
+)
+
+// 下面的期望值由 libketama 的 ketama_create_continuum / ketama_get_server 生成,
+// 服务器都是 "10.0.0.N:11211", 下标 N-1
+
+type ketamaGolden struct {
+	weights []int
+	digests []int
+	points  []ketamaPoint
+	keys    map[string]int
+}
+
+var ketamaGoldens = []ketamaGolden{
+	{
+		weights: []int{7, 3},
+		digests: []int{56, 24},
+		points: []ketamaPoint{
+			{7234733, 1}, {12697329, 1}, {39361663, 1}, {78990249, 0},
+			{79809050, 1}, {84856909, 0}, {101707043, 1}, {105079953, 1},
+		},
+		keys: map[string]int{
+			"foo": 1, "bar": 0, "baz": 0, "user:1": 0, "user:2": 0, "session:42": 1, "": 1, "a": 0,
+		},
+	},
+	{
+		// 0.7 * 40 * 10 按 double 是 279.99999, libketama 转成 float 之后取整得到 280
+		weights: []int{70, 4, 4, 4, 3, 3, 3, 3, 3, 3},
+		digests: []int{280, 16, 16, 16, 12, 12, 12, 12, 12, 12},
+		points: []ketamaPoint{
+			{791605, 5}, {3813257, 0}, {7182415, 0}, {7234733, 1},
+			{9433661, 0}, {12251735, 0}, {12556522, 0}, {12697329, 1},
+		},
+		keys: map[string]int{
+			"foo": 6, "bar": 0, "baz": 7, "user:1": 5, "user:2": 0, "session:42": 0, "": 0, "a": 0,
+		},
+	},
+}
+
+func TestKetamaGolden(t *testing.T) {
+	for _, g := range ketamaGoldens {
+		k := NewKetama()
+		for i, w := range g.weights {
+			k.Add(NewNode(i+1, "10.0.0."+strconv.Itoa(i+1), 11211, "", w))
+		}
+
+		counts := make(map[int]int)
+		total := 0
+		for _, p := range k.points {
+			counts[p.node]++
+		}
+		for i, d := range g.digests {
+			if got := counts[i+1]; got != d*KETAMA_POINTS_PER_DIGEST {
+				t.Fatalf("weights %v: node %d has %d points, want %d", g.weights, i+1, got, d*KETAMA_POINTS_PER_DIGEST)
+			}
+			total += d * KETAMA_POINTS_PER_DIGEST
+		}
+		if len(k.points) != total {
+			t.Fatalf("weights %v: %d points, want %d", g.weights, len(k.points), total)
+		}
+
+		for i, want := range g.points {
+			got := k.points[i]
+			if got.hash != want.hash || got.node != want.node+1 {
+				t.Fatalf("weights %v: point %d is (%d, %d), want (%d, %d)", g.weights, i, got.hash, got.node, want.hash, want.node+1)
+			}
+		}
+
+		for key, want := range g.keys {
+			node, err := k.Get(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if node.Id != want+1 {
+				t.Fatalf("weights %v: Get(%q) = %d, want %d", g.weights, key, node.Id, want+1)
+			}
+		}
+	}
+}
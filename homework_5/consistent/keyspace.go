@@ -0,0 +1,145 @@
+package consistent
+
+import (
+	"sort"
+	"sync"
+)
+
+type KeyspaceOption func(k *Keyspace)
+
+// WithKeyspaceSeed 给 key 加上前缀再哈希, 不同 seed 的 keyspace 在同一个环上的分布互相独立
+func WithKeyspaceSeed(seed string) KeyspaceOption {
+	return func(k *Keyspace) {
+		k.seed = seed
+	}
+}
+
+// WithKeyspaceReplicas 设置 keyspace 的副本数, 即 GetN 默认返回几个节点
+func WithKeyspaceReplicas(n int) KeyspaceOption {
+	return func(k *Keyspace) {
+		if n > 0 {
+			k.replicas = n
+		}
+	}
+}
+
+// Keyspace 是同一个环上的一类数据, 共用成员和虚拟节点, 但有自己的 seed、副本数和固定映射表
+type Keyspace struct {
+	sync.RWMutex
+	c        *Consistent
+	name     string
+	seed     string
+	replicas int
+	pins     map[string]Node
+}
+
+// Keyspace 返回名为 name 的 keyspace, 不存在时创建; opts 每次调用都会生效
+func (c *Consistent) Keyspace(name string, opts ...KeyspaceOption) *Keyspace {
+	c.Lock()
+	k, ok := c.keyspaces[name]
+	if !ok {
+		k = &Keyspace{c: c, name: name, replicas: 1, pins: make(map[string]Node)}
+		c.keyspaces[name] = k
+	}
+	c.Unlock()
+
+	k.Lock()
+	for _, opt := range opts {
+		opt(k)
+	}
+	k.Unlock()
+
+	return k
+}
+
+// Keyspaces 按名称返回所有已创建的 keyspace
+func (c *Consistent) Keyspaces() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	names := make([]string, 0, len(c.keyspaces))
+	for name := range c.keyspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (k *Keyspace) Name() string {
+	return k.name
+}
+
+func (k *Keyspace) Replicas() int {
+	k.RLock()
+	defer k.RUnlock()
+
+	return k.replicas
+}
+
+func (k *Keyspace) hashKey(key string) string {
+	if k.seed == "" {
+		return key
+	}
+	return k.seed + "\x00" + key
+}
+
+// Pin 把 key 固定到节点上, 节点离开环之后自动失效
+func (k *Keyspace) Pin(key string, node Node) {
+	k.Lock()
+	defer k.Unlock()
+
+	k.pins[key] = node
+}
+
+func (k *Keyspace) Unpin(key string) {
+	k.Lock()
+	defer k.Unlock()
+
+	delete(k.pins, key)
+}
+
+func (k *Keyspace) pinned(key string) (Node, bool) {
+	node, ok := k.pins[key]
+	if !ok {
+		return Node{}, false
+	}
+
+	k.c.RLock()
+	defer k.c.RUnlock()
+
+	return node, k.c.resources[node.Id]
+}
+
+func (k *Keyspace) Get(key string) (Node, error) {
+	nodes := k.GetN(key, 1)
+	if len(nodes) == 0 {
+		return Node{}, ErrEmptyRing
+	}
+	return nodes[0], nil
+}
+
+// GetN 返回 key 的 n 个副本节点, n <= 0 时使用 keyspace 的副本数; 固定映射的节点排在第一个
+func (k *Keyspace) GetN(key string, n int) []Node {
+	k.RLock()
+	if n <= 0 {
+		n = k.replicas
+	}
+	pin, ok := k.pinned(key)
+	hashed := k.hashKey(key)
+	k.RUnlock()
+
+	if !ok {
+		return k.c.GetN(hashed, n)
+	}
+
+	nodes := []Node{pin}
+	for _, node := range k.c.GetN(hashed, n) {
+		if len(nodes) >= n {
+			break
+		}
+		if node.Id != pin.Id {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,38 @@
+package consistent
+
+import (
+	"strconv"
+)
+
+// LabelFunc 生成节点第 replica 个虚拟节点参与哈希的字符串, 不同节点的标签不能相同
+type LabelFunc func(node Node, replica int) string
+
+// Labels 按名称列出内置的标签格式, 供命令行和配置文件使用
+var Labels = map[string]LabelFunc{
+	"default": DefaultLabel,
+	"legacy":  LegacyLabel,
+}
+
+// DefaultLabel 是 ip:port-replica-id, 同一台机器上的多个端口不会冲突, 权重变化也不会改动已有的点
+func DefaultLabel(node Node, replica int) string {
+	return node.Ip + ":" + strconv.Itoa(node.Port) + "-" + strconv.Itoa(replica) + "-" + strconv.Itoa(node.Id)
+}
+
+// LegacyLabel 是最初的 ip*weight-replica-id 格式, 需要保持旧部署的位置不变时用 WithLabel(LegacyLabel)
+func LegacyLabel(node Node, replica int) string {
+	return node.Ip + "*" + strconv.Itoa(node.Weight) + "-" + strconv.Itoa(replica) + "-" + strconv.Itoa(node.Id)
+}
+
+// WithLabel 替换虚拟节点的标签格式, 换了格式的环上所有点都会移动
+func WithLabel(f LabelFunc) Option {
+	return func(c *Consistent) {
+		if f != nil {
+			c.label = f
+		}
+	}
+}
+
+// Label 用环的标签格式返回节点第 i 个虚拟节点的标签
+func (c *Consistent) Label(i int, node *Node) string {
+	return c.joinStr(i, node)
+}
@@ -0,0 +1,101 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrRingClosed = errors.New("consistent: ring is closed")
+
+// lifecycle 管理挂在环上的后台 goroutine, 零值可用, ctx 在第一次用到时创建
+type lifecycle struct {
+	sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	closed bool
+	err    error
+}
+
+// init 在 lifecycle 的锁内调用
+func (l *lifecycle) init() {
+	if l.ctx == nil {
+		l.ctx, l.cancel = context.WithCancel(context.Background())
+	}
+}
+
+// Go 在后台运行 fn, 例如健康检查、服务发现和 wal 压缩的 Run; fn 应当在 ctx 结束时尽快返回.
+// Close 会取消 ctx 并等待所有 fn 返回. fn 在 ctx 结束之前返回的错误由 Close 返回, 其余的错误被忽略; 环已经关闭时返回 ErrRingClosed
+func (c *Consistent) Go(fn func(ctx context.Context) error) error {
+	l := &c.life
+	l.Lock()
+	defer l.Unlock()
+
+	if l.closed {
+		return ErrRingClosed
+	}
+	l.init()
+
+	l.wg.Add(1)
+	go func(ctx context.Context) {
+		defer l.wg.Done()
+		if err := fn(ctx); err != nil && ctx.Err() == nil {
+			l.Lock()
+			if l.err == nil {
+				l.err = err
+			}
+			l.Unlock()
+		}
+	}(l.ctx)
+	return nil
+}
+
+// Closing 返回的 channel 在 Close 时关闭, 没有通过 Go 启动的循环可以用它判断环是否已经关闭
+func (c *Consistent) Closing() <-chan struct{} {
+	l := &c.life
+	l.Lock()
+	defer l.Unlock()
+
+	l.init()
+	return l.ctx.Done()
+}
+
+// Close 停止环上的后台 goroutine 并关闭所有 Subscribe 的 channel, 之后的 Subscribe 直接返回已关闭的 channel, Go 返回 ErrRingClosed.
+// 等待 goroutine 返回的时间受 ctx 控制, ctx 先结束时返回 ctx.Err(), goroutine 仍会在之后退出.
+// 环本身仍然可以读写, 只是不再有后台任务; 重复调用返回 nil
+func (c *Consistent) Close(ctx context.Context) error {
+	l := &c.life
+	l.Lock()
+	if l.closed {
+		l.Unlock()
+		return nil
+	}
+	l.closed = true
+	if l.cancel != nil {
+		l.cancel()
+	}
+	l.Unlock()
+
+	c.hooks.Lock()
+	c.hooks.closed = true
+	for ch := range c.hooks.subs {
+		c.hooks.unsubscribe(ch)
+	}
+	c.hooks.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	return l.err
+}
@@ -0,0 +1,51 @@
+package consistent
+
+// Logger 是环输出结构化日志用的接口, 与 *slog.Logger 的方法相同, 可以直接传入 slog.Default();
+// args 是交替出现的 key 和 value
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// nopLogger 是没有 WithLogger 时使用的 Logger, 什么都不输出
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+// WithLogger 让环在每次成员变化 (加入、删除、权重、draining、MarkDown、Restore 等) 之后输出一条 "ring changed":
+// change 是变化的类型, node 或 joined/left 是涉及的节点, vnodes 是环上虚拟节点数的变化, moved 是换了节点的 key 空间比例, version 是新的版本.
+// 计算 moved 要比较新旧两个环, 代价与 OnOwnershipChanged 相同
+func WithLogger(l Logger) Option {
+	return func(c *Consistent) {
+		if l != nil {
+			c.logger = l
+		}
+	}
+}
+
+// Logger 返回 WithLogger 设置的 Logger, 没有设置时返回不输出的 Logger; health 和 wal 用它输出自己的事件
+func (c *Consistent) Logger() Logger {
+	if c.logger == nil {
+		return nopLogger{}
+	}
+	return c.logger
+}
+
+// logChange 在 hooks 的锁内调用, 日志的顺序与环的版本一致
+func (c *Consistent) logChange(prev, next *snapshot, changes []RangeChange, args ...interface{}) {
+	if c.logger == nil {
+		return
+	}
+
+	space, moved := c.Space(), 0.0
+	for _, ch := range changes {
+		moved += (float64(ch.End-ch.Start) + 1) / space
+	}
+	args = append(args, "vnodes", len(next.ring)-len(prev.ring), "moved", moved, "version", next.version)
+	c.logger.Info("ring changed", args...)
+}
@@ -0,0 +1,198 @@
+package consistent
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	DEFAULT_MAGLEV_TABLE_SIZE = 65537
+)
+
+// Maglev 是 Google Maglev 负载均衡器的查找表: 每个节点按自己的排列轮流占表项, 查找是 O(1).
+// 表的大小取不小于 tableSize 的质数, 应远大于节点数; 权重为 w 的节点每轮占 w 个表项
+type Maglev struct {
+	sync.RWMutex
+	nodes []Node
+	table []int
+	size  uint64
+}
+
+func NewMaglev(nodes []Node, tableSize int) *Maglev {
+	if tableSize <= 0 {
+		tableSize = DEFAULT_MAGLEV_TABLE_SIZE
+	}
+
+	m := &Maglev{size: nextPrime(uint64(tableSize))}
+	for i := range nodes {
+		m.insert(nodes[i])
+	}
+	m.populate()
+	return m
+}
+
+func nextPrime(n uint64) uint64 {
+	if n <= 2 {
+		return 2
+	}
+	if n%2 == 0 {
+		n++
+	}
+	for ; ; n += 2 {
+		prime := true
+		for d := uint64(3); d*d <= n; d += 2 {
+			if n%d == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			return n
+		}
+	}
+}
+
+func (m *Maglev) TableSize() int {
+	return int(m.size)
+}
+
+func (m *Maglev) find(id int) (int, bool) {
+	i := sort.Search(len(m.nodes), func(i int) bool {
+		return m.nodes[i].Id >= id
+	})
+	return i, i < len(m.nodes) && m.nodes[i].Id == id
+}
+
+func (m *Maglev) insert(node Node) bool {
+	i, ok := m.find(node.Id)
+	if ok {
+		return false
+	}
+
+	m.nodes = append(m.nodes, Node{})
+	copy(m.nodes[i+1:], m.nodes[i:])
+	m.nodes[i] = node
+	return true
+}
+
+// populate 按 Maglev 论文的方法重建查找表, 节点按 Id 排序, 同样的成员总是得到同样的表
+func (m *Maglev) populate() {
+	table := make([]int, m.size)
+	for i := range table {
+		table[i] = -1
+	}
+
+	offsets := make([]uint64, len(m.nodes))
+	skips := make([]uint64, len(m.nodes))
+	next := make([]uint64, len(m.nodes))
+	total := 0
+	for i := range m.nodes {
+		name := nodeKey(&m.nodes[i])
+		offsets[i] = fnv1a64([]byte("offset-"+name)) % m.size
+		skips[i] = fnv1a64([]byte("skip-"+name))%(m.size-1) + 1
+		if m.nodes[i].Weight > 0 {
+			total += m.nodes[i].Weight
+		}
+	}
+
+	if total == 0 {
+		m.table = nil
+		return
+	}
+
+	filled := uint64(0)
+	for filled < m.size {
+		for i := range m.nodes {
+			for turn := 0; turn < m.nodes[i].Weight && filled < m.size; turn++ {
+				for {
+					slot := (offsets[i] + next[i]*skips[i]) % m.size
+					next[i]++
+					if table[slot] < 0 {
+						table[slot] = i
+						filled++
+						break
+					}
+				}
+			}
+		}
+	}
+
+	m.table = table
+}
+
+// Add 加入节点并重建查找表
+func (m *Maglev) Add(node *Node) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	if !m.insert(*node) {
+		return false
+	}
+	m.populate()
+	return true
+}
+
+// Remove 删除节点并重建查找表
+func (m *Maglev) Remove(node *Node) {
+	m.Lock()
+	defer m.Unlock()
+
+	i, ok := m.find(node.Id)
+	if !ok {
+		return
+	}
+
+	m.nodes = append(m.nodes[:i], m.nodes[i+1:]...)
+	m.populate()
+}
+
+func (m *Maglev) slot(key string) uint64 {
+	return fnv1a64([]byte(key)) % m.size
+}
+
+// Get 返回 key 所在表项的节点, 没有节点时返回 ErrEmptyRing
+func (m *Maglev) Get(key string) (Node, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	if len(m.table) == 0 {
+		return Node{}, ErrEmptyRing
+	}
+	return m.nodes[m.table[m.slot(key)]], nil
+}
+
+// GetN 从 key 的表项往后找 n 个不同的节点, 第一个与 Get 相同
+func (m *Maglev) GetN(key string, n int) []Node {
+	m.RLock()
+	defer m.RUnlock()
+
+	if n < 0 {
+		n = 0
+	}
+	nodes := make([]Node, 0, n)
+	if len(m.table) == 0 || n <= 0 {
+		return nodes
+	}
+
+	seen := make(map[int]bool, n)
+	slot := m.slot(key)
+	for step := uint64(0); step < m.size && len(nodes) < n && len(seen) < len(m.nodes); step++ {
+		i := m.table[(slot+step)%m.size]
+		if !seen[i] {
+			seen[i] = true
+			nodes = append(nodes, m.nodes[i])
+		}
+	}
+
+	return nodes
+}
+
+// Members 按 Id 排序返回所有节点
+func (m *Maglev) Members() []Node {
+	m.RLock()
+	defer m.RUnlock()
+
+	nodes := make([]Node, len(m.nodes))
+	copy(nodes, m.nodes)
+	return nodes
+}
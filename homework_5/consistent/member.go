@@ -0,0 +1,146 @@
+package consistent
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Member 是可以放到环上的任意成员, Key 在环内唯一并决定虚拟节点的位置
+type Member interface {
+	Key() string
+	Weight() int
+}
+
+// MemberRing 把任意类型的成员放到 Consistent 上, 每个成员在内部对应一个 Node,
+// Node.HostName 存 Key, Node.Id 只是内部编号
+type MemberRing[T Member] struct {
+	sync.RWMutex
+	ring    *Consistent
+	ids     map[string]int
+	members map[int]T
+	next    int
+}
+
+// NewMemberRing 的虚拟节点标签只由 Key 和序号决定, 与加入顺序无关, opts 里的 WithLabel 不生效
+func NewMemberRing[T Member](opts ...Option) *MemberRing[T] {
+	opts = append(opts, WithLabel(memberLabel))
+	return &MemberRing[T]{
+		ring:    NewConsistent(opts...),
+		ids:     make(map[string]int),
+		members: make(map[int]T),
+	}
+}
+
+func memberLabel(node Node, replica int) string {
+	return node.HostName + "-" + strconv.Itoa(replica)
+}
+
+// Add 在 Key 已存在时返回 false
+func (r *MemberRing[T]) Add(m T) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.ids[m.Key()]; ok {
+		return false
+	}
+
+	r.next++
+	node := Node{Id: r.next, HostName: m.Key(), Weight: m.Weight()}
+	if !r.ring.Add(&node) {
+		return false
+	}
+
+	r.ids[m.Key()] = node.Id
+	r.members[node.Id] = m
+	return true
+}
+
+func (r *MemberRing[T]) Remove(key string) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	id, ok := r.ids[key]
+	if !ok {
+		return false
+	}
+
+	r.ring.RemoveByID(id)
+	delete(r.ids, key)
+	delete(r.members, id)
+	return true
+}
+
+// Update 替换 Key 相同的成员, 权重变化时只移动增删的那部分虚拟节点, Key 不存在时返回 false
+func (r *MemberRing[T]) Update(m T) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	id, ok := r.ids[m.Key()]
+	if !ok || !r.ring.UpdateWeight(id, m.Weight()) {
+		return false
+	}
+
+	r.members[id] = m
+	return true
+}
+
+func (r *MemberRing[T]) Get(key string) (T, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	node, err := r.ring.Get(key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return r.members[node.Id], nil
+}
+
+// GetN 与 Consistent.GetN 相同, 返回 n 个不同的成员
+func (r *MemberRing[T]) GetN(key string, n int) []T {
+	r.RLock()
+	defer r.RUnlock()
+
+	nodes := r.ring.GetN(key, n)
+	members := make([]T, len(nodes))
+	for i, node := range nodes {
+		members[i] = r.members[node.Id]
+	}
+	return members
+}
+
+// Members 按 Key 排序返回所有成员
+func (r *MemberRing[T]) Members() []T {
+	r.RLock()
+	defer r.RUnlock()
+
+	members := make([]T, 0, len(r.members))
+	for _, m := range r.members {
+		members = append(members, m)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Key() < members[j].Key()
+	})
+
+	return members
+}
+
+func (r *MemberRing[T]) Contains(key string) bool {
+	r.RLock()
+	defer r.RUnlock()
+	_, ok := r.ids[key]
+	return ok
+}
+
+func (r *MemberRing[T]) Len() int {
+	r.RLock()
+	defer r.RUnlock()
+	return len(r.members)
+}
+
+// Ring 返回底层的 Consistent, 上面的 Node 只有 Id、HostName 和 Weight 有意义
+func (r *MemberRing[T]) Ring() *Consistent {
+	return r.ring
+}
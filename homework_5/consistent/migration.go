@@ -0,0 +1,92 @@
+package consistent
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var ErrBadCutover = errors.New("consistent: cutover must be between 0 and 100")
+
+const (
+	CUTOVER_BUCKETS = 10000
+)
+
+// MigrationRouter 同时持有迁移前后的两个环, 用于 "读两边、写新环" 式的在线迁移.
+// Get 按切换比例决定 key 走哪个环: 每个 key 固定落在 CUTOVER_BUCKETS 个桶中的一个, 桶号小于比例的走新环,
+// 所以比例只增不减时, 已经切到新环的 key 不会再回到旧环
+type MigrationRouter struct {
+	old *Consistent
+	new *Consistent
+	// cutover 是切到新环的桶数
+	cutover int64
+}
+
+func NewMigrationRouter(old, new *Consistent) *MigrationRouter {
+	return &MigrationRouter{old: old, new: new}
+}
+
+func (r *MigrationRouter) GetOld(key string) (Node, error) {
+	return r.old.Get(key)
+}
+
+func (r *MigrationRouter) GetNew(key string) (Node, error) {
+	return r.new.Get(key)
+}
+
+// GetBoth 返回 key 在两个环上的节点, 两者不同说明 key 需要迁移; 任一个环无法路由时返回错误
+func (r *MigrationRouter) GetBoth(key string) (Node, Node, error) {
+	old, err := r.old.Get(key)
+	if err != nil {
+		return Node{}, Node{}, err
+	}
+	new, err := r.new.Get(key)
+	if err != nil {
+		return Node{}, Node{}, err
+	}
+	return old, new, nil
+}
+
+// Moved 判断 key 在两个环上的节点是否不同
+func (r *MigrationRouter) Moved(key string) (bool, error) {
+	old, new, err := r.GetBoth(key)
+	if err != nil {
+		return false, err
+	}
+	return old.Id != new.Id, nil
+}
+
+// Get 按当前的切换比例返回 key 应该使用的节点
+func (r *MigrationRouter) Get(key string) (Node, error) {
+	if r.OnNew(key) {
+		return r.new.Get(key)
+	}
+	return r.old.Get(key)
+}
+
+// OnNew 判断 key 是否已经切到新环. 桶号固定用 fnv1a 加 fmix64 计算, 与两个环的哈希函数和 key 在环上的位置无关
+func (r *MigrationRouter) OnNew(key string) bool {
+	bucket := int64(fmix64(fnv1a64([]byte(key))) % CUTOVER_BUCKETS)
+	return bucket < atomic.LoadInt64(&r.cutover)
+}
+
+// SetCutover 设置切到新环的 key 的百分比, 0 表示全部走旧环, 100 表示全部走新环
+func (r *MigrationRouter) SetCutover(percent float64) error {
+	if percent < 0 || percent > 100 {
+		return ErrBadCutover
+	}
+
+	atomic.StoreInt64(&r.cutover, int64(percent*CUTOVER_BUCKETS/100))
+	return nil
+}
+
+func (r *MigrationRouter) Cutover() float64 {
+	return float64(atomic.LoadInt64(&r.cutover)) * 100 / CUTOVER_BUCKETS
+}
+
+func (r *MigrationRouter) Old() *Consistent {
+	return r.old
+}
+
+func (r *MigrationRouter) New() *Consistent {
+	return r.new
+}
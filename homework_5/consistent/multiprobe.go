@@ -0,0 +1,155 @@
+package consistent
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	DEFAULT_PROBES = 21
+)
+
+type probePoint struct {
+	hash uint64
+	node Node
+}
+
+// MultiProbe 是 multi-probe consistent hashing: 每个节点在环上只有一个点, 查找时把 key 哈希到 probes 个位置,
+// 取离顺时针下一个点最近的那次. probes 为 21 时峰值负载约为平均值的 1.05 倍, 内存和重建时间与节点数成正比. 不考虑权重
+type MultiProbe struct {
+	sync.RWMutex
+	points []probePoint
+	probes int
+	hasher Hasher
+}
+
+func NewMultiProbe(probes int, h Hasher) *MultiProbe {
+	if probes <= 0 {
+		probes = DEFAULT_PROBES
+	}
+	if h == nil {
+		h = CRC32
+	}
+	return &MultiProbe{probes: probes, hasher: h}
+}
+
+func (m *MultiProbe) Probes() int {
+	return m.probes
+}
+
+// position 经过 fmix64 展开到 64 位, 32 位的哈希函数也能用
+func (m *MultiProbe) position(s string) uint64 {
+	return fmix64(m.hasher.Hash([]byte(s)))
+}
+
+func (m *MultiProbe) find(id int) int {
+	for i := range m.points {
+		if m.points[i].node.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *MultiProbe) Add(node *Node) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.find(node.Id) >= 0 {
+		return false
+	}
+
+	p := probePoint{hash: m.position(nodeKey(node)), node: *node}
+	i := sort.Search(len(m.points), func(i int) bool {
+		return m.points[i].hash >= p.hash
+	})
+	m.points = append(m.points, probePoint{})
+	copy(m.points[i+1:], m.points[i:])
+	m.points[i] = p
+	return true
+}
+
+func (m *MultiProbe) Remove(node *Node) {
+	m.Lock()
+	defer m.Unlock()
+
+	if i := m.find(node.Id); i >= 0 {
+		m.points = append(m.points[:i], m.points[i+1:]...)
+	}
+}
+
+// successor 返回第一个 >= hash 的点, 越过末尾时回到第一个点
+func (m *MultiProbe) successor(hash uint64) int {
+	i := sort.Search(len(m.points), func(i int) bool {
+		return m.points[i].hash >= hash
+	})
+	if i == len(m.points) {
+		return 0
+	}
+	return i
+}
+
+// closest 返回所有探测位置中离顺时针下一个点最近的那个点, 距离用无符号减法, 自然处理了回绕
+func (m *MultiProbe) closest(key string) int {
+	h := m.hasher.Hash([]byte(key))
+
+	best, bestDistance := 0, uint64(0)
+	for probe := 0; probe < m.probes; probe++ {
+		hash := fmix64(h ^ uint64(probe)*0x9e3779b97f4a7c15)
+		i := m.successor(hash)
+		if distance := m.points[i].hash - hash; probe == 0 || distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
+
+// Get 没有节点时返回 ErrEmptyRing
+func (m *MultiProbe) Get(key string) (Node, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	if len(m.points) == 0 {
+		return Node{}, ErrEmptyRing
+	}
+	return m.points[m.closest(key)].node, nil
+}
+
+// GetN 第一个与 Get 相同, 其余的是这个点之后顺时针的节点
+func (m *MultiProbe) GetN(key string, n int) []Node {
+	m.RLock()
+	defer m.RUnlock()
+
+	if n > len(m.points) {
+		n = len(m.points)
+	}
+	if n < 0 {
+		n = 0
+	}
+	nodes := make([]Node, 0, n)
+	if n <= 0 {
+		return nodes
+	}
+
+	i := m.closest(key)
+	for len(nodes) < n {
+		nodes = append(nodes, m.points[i].node)
+		i = (i + 1) % len(m.points)
+	}
+	return nodes
+}
+
+// Members 按 Id 排序返回所有节点
+func (m *MultiProbe) Members() []Node {
+	m.RLock()
+	defer m.RUnlock()
+
+	nodes := make([]Node, 0, len(m.points))
+	for _, p := range m.points {
+		nodes = append(nodes, p.node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Id < nodes[j].Id
+	})
+	return nodes
+}
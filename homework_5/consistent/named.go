@@ -0,0 +1,80 @@
+package consistent
+
+type named struct {
+	id     string
+	weight int
+}
+
+func (n named) Key() string {
+	return n.id
+}
+
+func (n named) Weight() int {
+	return n.weight
+}
+
+// StringRing 用字符串标识节点, 例如 "redis-cache-3.prod:6379", 不需要另外维护 int Id
+type StringRing struct {
+	members *MemberRing[named]
+}
+
+func NewStringRing(opts ...Option) *StringRing {
+	return &StringRing{members: NewMemberRing[named](opts...)}
+}
+
+// Add 在 id 已存在时返回 false
+func (r *StringRing) Add(id string, weight int) bool {
+	return r.members.Add(named{id: id, weight: weight})
+}
+
+func (r *StringRing) Remove(id string) bool {
+	return r.members.Remove(id)
+}
+
+func (r *StringRing) UpdateWeight(id string, weight int) bool {
+	return r.members.Update(named{id: id, weight: weight})
+}
+
+func (r *StringRing) Get(key string) (string, error) {
+	m, err := r.members.Get(key)
+	return m.id, err
+}
+
+func (r *StringRing) GetN(key string, n int) []string {
+	members := r.members.GetN(key, n)
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.id
+	}
+	return ids
+}
+
+// Members 按字典序返回所有节点的 id
+func (r *StringRing) Members() []string {
+	members := r.members.Members()
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.id
+	}
+	return ids
+}
+
+func (r *StringRing) Contains(id string) bool {
+	return r.members.Contains(id)
+}
+
+func (r *StringRing) Len() int {
+	return r.members.Len()
+}
+
+// Weight 返回节点当前的权重, 节点不存在时返回 0
+func (r *StringRing) Weight(id string) int {
+	r.members.RLock()
+	defer r.members.RUnlock()
+	return r.members.members[r.members.ids[id]].weight
+}
+
+// Ring 返回底层的 Consistent, Node.HostName 是节点的 id
+func (r *StringRing) Ring() *Consistent {
+	return r.members.Ring()
+}
@@ -0,0 +1,35 @@
+package consistent
+
+// Range 是连续归同一个节点的一段位置 [Start, End], 相邻的 Arc 属于同一个节点时合并成一段
+type Range struct {
+	Start    uint64  `json:"start"`
+	End      uint64  `json:"end"`
+	Node     Node    `json:"node"`
+	Fraction float64 `json:"fraction"`
+}
+
+// OwnershipTable 按位置从小到大列出环上的每一段区间和它的节点, 所有 Fraction 加起来是 1
+func (c *Consistent) OwnershipTable() []Range {
+	space := c.Space()
+	arcs := c.arcs(c.current())
+	table := make([]Range, 0, len(arcs))
+	for _, arc := range arcs {
+		if n := len(table); n > 0 && table[n-1].Node.Id == arc.Node.Id {
+			table[n-1].End = arc.End
+			table[n-1].Fraction += arc.Share(space)
+			continue
+		}
+		table = append(table, Range{Start: arc.Start, End: arc.End, Node: arc.Node, Fraction: arc.Share(space)})
+	}
+	return table
+}
+
+// Ownership 按区间长度算出每个节点拥有的哈希空间比例, 结果是精确值, 不需要抽样 key;
+// 没有虚拟节点的节点不在结果里
+func (c *Consistent) Ownership() map[int]float64 {
+	shares := make(map[int]float64)
+	for _, r := range c.OwnershipTable() {
+		shares[r.Node.Id] += r.Fraction
+	}
+	return shares
+}
@@ -0,0 +1,96 @@
+package consistent
+
+import (
+	"sort"
+)
+
+const (
+	CHANGE_PINNED   = "pinned"
+	CHANGE_UNPINNED = "unpinned"
+)
+
+// Pin 把 key 固定到节点上, Get、GetBytes、GetMany 和 GetN 在查环之前先查固定表, 用于故障期间把个别租户或问题 key 挪到指定节点.
+// 固定的节点不在环上、draining 或 MarkDown 时固定暂时不生效, key 按环路由, 节点恢复后重新生效;
+// 固定表写进 Snapshot. 节点不存在时返回 false
+func (c *Consistent) Pin(key string, id int) bool {
+	c.Lock()
+	node, ok := c.members[id]
+	if !ok {
+		c.Unlock()
+		return false
+	}
+
+	prev := c.current()
+	c.pins[key] = id
+	c.publish(nil)
+
+	c.notify(prev, CHANGE_PINNED, node)
+	return true
+}
+
+// Unpin 取消 Pin, key 没有被固定时返回 false
+func (c *Consistent) Unpin(key string) bool {
+	c.Lock()
+	id, ok := c.pins[key]
+	if !ok {
+		c.Unlock()
+		return false
+	}
+
+	prev := c.current()
+	delete(c.pins, key)
+	c.publish(nil)
+
+	c.notify(prev, CHANGE_UNPINNED, c.members[id])
+	return true
+}
+
+// Pins 返回固定表的副本: key -> 节点 Id, 包括暂时不生效的
+func (c *Consistent) Pins() map[string]int {
+	c.RLock()
+	defer c.RUnlock()
+
+	pins := make(map[string]int, len(c.pins))
+	for key, id := range c.pins {
+		pins[key] = id
+	}
+	return pins
+}
+
+// PinnedKeys 按字典序返回固定到节点上的 key
+func (c *Consistent) PinnedKeys(id int) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	keys := make([]string, 0)
+	for key, pinned := range c.pins {
+		if pinned == id {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pin 在 publish 里调用, 生成快照里当前生效的固定表, 没有生效的固定时为 nil
+func (s *snapshot) pin(members map[int]Node, table map[string]int) {
+	for key, id := range table {
+		node, ok := members[id]
+		if !ok || s.skip[id] {
+			continue
+		}
+		if s.pins == nil {
+			s.pins = make(map[string]Node)
+		}
+		s.pins[key] = node
+	}
+}
+
+// pinned 查快照里的固定表, 不加锁
+func (s *snapshot) pinned(key string) (Node, bool) {
+	if len(s.pins) == 0 {
+		return Node{}, false
+	}
+	node, ok := s.pins[key]
+	return node, ok
+}
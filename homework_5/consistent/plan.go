@@ -0,0 +1,148 @@
+package consistent
+
+import (
+	"errors"
+)
+
+var (
+	ErrStalePlan   = errors.New("consistent: ring changed since the plan was made")
+	ErrNodeExists  = errors.New("consistent: node already on the ring")
+	ErrNodeMissing = errors.New("consistent: node not on the ring")
+)
+
+// Plan 是一次还没执行的成员变化: Moves 是变化之后换了节点的区间 (From 迁到 To) 和对应的 key 比例.
+// Fingerprint 是生成计划时环的指纹, 环在这之后变过的话 Apply 会拒绝执行
+type Plan struct {
+	Type        string     `json:"type"`
+	Node        Node       `json:"node"`
+	Moves       []Movement `json:"moves"`
+	Fingerprint uint64     `json:"fingerprint"`
+}
+
+// Moved 返回计划中需要迁移的 key 比例
+func (p *Plan) Moved() float64 {
+	moved := 0.0
+	for _, m := range p.Moves {
+		moved += m.Fraction
+	}
+	return moved
+}
+
+// PlanAdd 在环的副本上加入 node, 返回迁移计划, 不修改环
+func (c *Consistent) PlanAdd(node *Node) (*Plan, error) {
+	c.RLock()
+	if _, ok := c.members[node.Id]; ok {
+		c.RUnlock()
+		return nil, ErrNodeExists
+	}
+	next, fp := c.clone(), c.fingerprint()
+	c.RUnlock()
+
+	next.add(node)
+	return &Plan{Type: CHANGE_JOINED, Node: *node, Moves: Diff(c, next), Fingerprint: fp}, nil
+}
+
+// PlanRemove 与 PlanAdd 相同, 计划删除 id 对应的节点
+func (c *Consistent) PlanRemove(id int) (*Plan, error) {
+	c.RLock()
+	node, ok := c.members[id]
+	if !ok {
+		c.RUnlock()
+		return nil, ErrNodeMissing
+	}
+	next, fp := c.clone(), c.fingerprint()
+	c.RUnlock()
+
+	next.remove(id)
+	return &Plan{Type: CHANGE_LEFT, Node: node, Moves: Diff(c, next), Fingerprint: fp}, nil
+}
+
+// Apply 执行计划. 检查指纹和执行变化在同一个写锁里, 所以执行的结果与计划中的 Moves 完全一致;
+// 生成计划之后环被修改过时返回 ErrStalePlan, 需要重新生成计划
+func (c *Consistent) Apply(p *Plan) error {
+	c.Lock()
+	if c.fingerprint() != p.Fingerprint {
+		c.Unlock()
+		return ErrStalePlan
+	}
+
+	prev := c.current()
+	switch p.Type {
+	case CHANGE_JOINED:
+		node := p.Node
+		if !c.add(&node) {
+			c.Unlock()
+			return ErrNodeExists
+		}
+	case CHANGE_LEFT:
+		if _, ok := c.remove(p.Node.Id); !ok {
+			c.Unlock()
+			return ErrNodeMissing
+		}
+	default:
+		c.Unlock()
+		return errors.New("consistent: unknown plan type " + p.Type)
+	}
+
+	c.notify(prev, p.Type, p.Node)
+	return nil
+}
+
+// Clone 返回环的一个完全独立的副本: 成员、虚拟节点、draining、MarkDown 和 Pin 都被复制, 之后两边的修改互不影响.
+// 回调、订阅、Keyspace 和有界负载的计数不复制, 版本号与原来的环相同, 但没有保留的旧版本.
+// 用来在副本上试验成员变化, 再用 Diff 或 Stats 和原来的环比较, 不影响线上的路由
+func (c *Consistent) Clone() *Consistent {
+	c.RLock()
+	defer c.RUnlock()
+	return c.clone()
+}
+
+// clone 需要持有读锁, 复制成员和环, 不复制回调、订阅和负载计数
+func (c *Consistent) clone() *Consistent {
+	n := &Consistent{
+		Nodes:        make(map[uint64]Node, len(c.Nodes)),
+		resources:    make(map[int]bool, len(c.resources)),
+		members:      make(map[int]Node, len(c.members)),
+		labelWeights: make(map[int]int, len(c.labelWeights)),
+		points:       make(map[int][]uint64, len(c.points)),
+		draining:     make(map[int]bool, len(c.draining)),
+		down:         make(map[int]bool, len(c.down)),
+		pins:         make(map[string]int, len(c.pins)),
+		collisions:   c.collisions,
+		ring:         c.ring,
+		numReps:      c.numReps,
+		keyspaces:    make(map[string]*Keyspace),
+		hasher:       c.hasher,
+		seed:         c.seed,
+		bits:         c.bits,
+		weights:      c.weights,
+		factor:       c.factor,
+		loads:        newLoads(),
+		strict:       c.strict,
+		label:        c.label,
+		versions:     versions{retain: c.versions.retain},
+	}
+
+	for hash, node := range c.Nodes {
+		n.Nodes[hash] = node
+	}
+	for id, node := range c.members {
+		n.resources[id] = true
+		n.members[id] = node
+		n.labelWeights[id] = c.labelWeights[id]
+		// UpdateWeight 可能在原来的底层数组上 append, 必须拷贝
+		n.points[id] = append([]uint64(nil), c.points[id]...)
+	}
+	for id := range c.draining {
+		n.draining[id] = true
+	}
+	for id := range c.down {
+		n.down[id] = true
+	}
+	for key, id := range c.pins {
+		n.pins[key] = id
+	}
+	n.snap.Store(c.current())
+
+	return n
+}
@@ -0,0 +1,183 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ErrBadWeight      = errors.New("consistent: weight must not be negative")
+	ErrUnknownOp      = errors.New("consistent: unknown change op")
+	ErrProposalClosed = errors.New("consistent: proposal already committed or aborted")
+)
+
+const (
+	CHANGE_COMMITTED = "committed"
+)
+
+// ChangeOp 是提议中的一个操作: Type 为 CHANGE_JOINED 时加入 Node, 为 CHANGE_LEFT 时删除 Node.Id,
+// 为 CHANGE_WEIGHT 时把 Node.Id 的权重改成 Weight
+type ChangeOp struct {
+	Type   string `json:"type"`
+	Node   Node   `json:"node"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+func AddOp(node *Node) ChangeOp {
+	return ChangeOp{Type: CHANGE_JOINED, Node: *node}
+}
+
+func RemoveOp(id int) ChangeOp {
+	return ChangeOp{Type: CHANGE_LEFT, Node: Node{Id: id}}
+}
+
+func WeightOp(id, weight int) ChangeOp {
+	return ChangeOp{Type: CHANGE_WEIGHT, Node: Node{Id: id}, Weight: weight}
+}
+
+// Proposal 是暂存的一组成员变化, 已经在环的副本上按顺序执行过, 但还没有生效.
+// 多个路由器各自 ProposeChange 同样的操作, 比较 Fingerprint 一致之后再一起 Commit, 所有路由器在同一时刻切换到同样的环
+type Proposal struct {
+	Ops []ChangeOp `json:"ops"`
+	// Base 是提议时环的指纹, Fingerprint 是全部操作执行之后的指纹
+	Base        uint64     `json:"base"`
+	Fingerprint uint64     `json:"fingerprint"`
+	Moves       []Movement `json:"moves"`
+
+	ring    *Consistent
+	next    *Consistent
+	version uint64
+	closed  bool
+}
+
+// Moved 返回提议生效之后需要迁移的 key 比例
+func (p *Proposal) Moved() float64 {
+	moved := 0.0
+	for _, m := range p.Moves {
+		moved += m.Fraction
+	}
+	return moved
+}
+
+// ProposeChange 在环的副本上按顺序执行 ops, 任何一个操作不成立时返回带下标的错误, 不修改环.
+// 同一个提议里的操作可以互相依赖, 例如先加入节点再修改它的权重
+func (c *Consistent) ProposeChange(ops ...ChangeOp) (*Proposal, error) {
+	c.RLock()
+	next, base, version := c.clone(), c.fingerprint(), c.current().version
+	c.RUnlock()
+
+	for i, op := range ops {
+		if err := next.stage(op); err != nil {
+			return nil, fmt.Errorf("op %d (%s %d): %w", i, op.Type, op.Node.Id, err)
+		}
+	}
+
+	next.RLock()
+	fp := next.fingerprint()
+	next.RUnlock()
+	return &Proposal{
+		Ops:         append([]ChangeOp(nil), ops...),
+		Base:        base,
+		Fingerprint: fp,
+		Moves:       Diff(c, next),
+		ring:        c,
+		next:        next,
+		version:     version,
+	}, nil
+}
+
+// stage 在副本上执行一个操作, 副本不会被别的 goroutine 访问, 不需要加锁
+func (c *Consistent) stage(op ChangeOp) error {
+	switch op.Type {
+	case CHANGE_JOINED:
+		if op.Node.Weight < 0 {
+			return ErrBadWeight
+		}
+		node := op.Node
+		if !c.add(&node) {
+			return ErrNodeExists
+		}
+	case CHANGE_LEFT:
+		if _, ok := c.remove(op.Node.Id); !ok {
+			return ErrNodeMissing
+		}
+	case CHANGE_WEIGHT:
+		if op.Weight < 0 {
+			return ErrBadWeight
+		}
+		if !c.updateWeight(op.Node.Id, op.Weight) {
+			return ErrNodeMissing
+		}
+	default:
+		return ErrUnknownOp
+	}
+	return nil
+}
+
+// Commit 让提议生效: 环直接换成副本上的结果, 只发布一个新版本, 读者看不到执行了一半的操作.
+// 提议之后环有过任何变化 (包括 MarkDown 和 Pin) 时返回 ErrStalePlan, 需要重新提议; draining、MarkDown 和 Pin 的状态保持不变.
+// OnNodeAdded 和 OnNodeRemoved 对每个节点各调用一次, OnOwnershipChanged 和 Subscribe 只收到一次 CHANGE_COMMITTED
+func (p *Proposal) Commit() error {
+	c := p.ring
+	c.Lock()
+	if p.closed {
+		c.Unlock()
+		return ErrProposalClosed
+	}
+	if c.current().version != p.version {
+		c.Unlock()
+		return ErrStalePlan
+	}
+	p.closed = true
+
+	prev, next := c.current(), p.next
+	joined, left := make([]Node, 0), make([]Node, 0)
+	touched := make(map[uint64]bool)
+	for id, node := range next.members {
+		old, ok := c.members[id]
+		if !ok {
+			joined = append(joined, node)
+		} else if old.Weight != node.Weight || old.Capacity != node.Capacity || old.Replicas != node.Replicas {
+			// 权重变了的节点, 保留下来的位置也要换成新的 Node
+			for _, hash := range next.points[id] {
+				touched[hash] = true
+			}
+		}
+	}
+	c.loads.Lock()
+	for id, node := range c.members {
+		if _, ok := next.members[id]; !ok {
+			left = append(left, node)
+			c.loads.forget(id)
+			delete(c.draining, id)
+			delete(c.down, id)
+		}
+	}
+	c.loads.Unlock()
+	sortNodes(joined)
+	sortNodes(left)
+
+	c.Nodes, c.resources, c.members, c.labelWeights, c.points = next.Nodes, next.resources, next.members, next.labelWeights, next.points
+	c.ring, c.weights, c.collisions = next.ring, next.weights, next.collisions
+	c.publish(touched)
+	p.next = nil
+
+	c.notifyBatch(prev, CHANGE_COMMITTED, joined, left)
+	return nil
+}
+
+// Abort 丢弃提议, 之后的 Commit 返回 ErrProposalClosed
+func (p *Proposal) Abort() {
+	c := p.ring
+	c.Lock()
+	defer c.Unlock()
+	p.closed = true
+	p.next = nil
+}
+
+func sortNodes(nodes []Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Id < nodes[j].Id
+	})
+}
@@ -0,0 +1,177 @@
+package consistent
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Rendezvous 是最高随机权重 (HRW) 哈希: 每次查找给所有节点打分, 取分数最高的节点.
+// 不需要虚拟节点, 查找是 O(节点数), 适合小集群; NewRendezvous 不考虑权重, NewWeightedRendezvous 按 Weight 分配
+type Rendezvous struct {
+	sync.RWMutex
+	nodes    []Node
+	hashes   []uint64
+	weights  []float64
+	hasher   Hasher
+	weighted bool
+}
+
+func NewRendezvous(h Hasher) *Rendezvous {
+	if h == nil {
+		h = CRC32
+	}
+	return &Rendezvous{hasher: h}
+}
+
+// NewWeightedRendezvous 使用对数打分 -Weight/ln(u), u 是 (0, 1) 上均匀分布的哈希值, 每个节点拿到的 key 与 Weight 成正比,
+// 增删节点时同样只有这个节点的 key 会移动. Weight <= 0 的节点只有在所有节点的权重都 <= 0 时才会被选中
+func NewWeightedRendezvous(h Hasher) *Rendezvous {
+	r := NewRendezvous(h)
+	r.weighted = true
+	return r
+}
+
+// nodeKey 是不使用虚拟节点的算法里代表节点的字符串
+func nodeKey(node *Node) string {
+	return node.Ip + ":" + strconv.Itoa(node.Port) + "-" + strconv.Itoa(node.Id)
+}
+
+func (r *Rendezvous) nodeHash(node *Node) uint64 {
+	return r.hasher.Hash([]byte(nodeKey(node)))
+}
+
+func (r *Rendezvous) score(key uint64, i int) uint64 {
+	return fmix64(key ^ r.hashes[i])
+}
+
+// weightedScore 取分数的高 53 位映射到 (0, 1), 不会取到 0 和 1, ln(u) 总是负数
+func (r *Rendezvous) weightedScore(key uint64, i int) float64 {
+	if r.weights[i] <= 0 {
+		return 0
+	}
+	u := (float64(r.score(key, i)>>11) + 0.5) / (1 << 53)
+	return -r.weights[i] / math.Log(u)
+}
+
+// better 判断 key 下第 i 个节点的分数是否高于第 j 个
+func (r *Rendezvous) better(key uint64, i, j int) bool {
+	if r.weighted {
+		return r.weightedScore(key, i) > r.weightedScore(key, j)
+	}
+	return r.score(key, i) > r.score(key, j)
+}
+
+func (r *Rendezvous) Add(node *Node) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	i := sort.Search(len(r.nodes), func(i int) bool {
+		return r.nodes[i].Id >= node.Id
+	})
+	if i < len(r.nodes) && r.nodes[i].Id == node.Id {
+		return false
+	}
+
+	r.nodes = append(r.nodes, Node{})
+	copy(r.nodes[i+1:], r.nodes[i:])
+	r.nodes[i] = *node
+
+	r.hashes = append(r.hashes, 0)
+	copy(r.hashes[i+1:], r.hashes[i:])
+	r.hashes[i] = r.nodeHash(node)
+
+	r.weights = append(r.weights, 0)
+	copy(r.weights[i+1:], r.weights[i:])
+	r.weights[i] = float64(node.Weight)
+	return true
+}
+
+func (r *Rendezvous) Remove(node *Node) {
+	r.Lock()
+	defer r.Unlock()
+
+	i := sort.Search(len(r.nodes), func(i int) bool {
+		return r.nodes[i].Id >= node.Id
+	})
+	if i == len(r.nodes) || r.nodes[i].Id != node.Id {
+		return
+	}
+
+	r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+	r.hashes = append(r.hashes[:i], r.hashes[i+1:]...)
+	r.weights = append(r.weights[:i], r.weights[i+1:]...)
+}
+
+// Get 返回分数最高的节点, 分数相同时取 Id 小的; 没有节点时返回 ErrEmptyRing
+func (r *Rendezvous) Get(key string) (Node, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if len(r.nodes) == 0 {
+		return Node{}, ErrEmptyRing
+	}
+
+	k := r.hasher.Hash([]byte(key))
+	best := 0
+	for i := 1; i < len(r.nodes); i++ {
+		if r.better(k, i, best) {
+			best = i
+		}
+	}
+
+	return r.nodes[best], nil
+}
+
+// GetN 按分数从高到低返回 n 个节点, 第一个与 Get 相同; 去掉 Get 的节点后剩下的顺序不变, 所以副本只会顺延
+func (r *Rendezvous) GetN(key string, n int) []Node {
+	r.RLock()
+	defer r.RUnlock()
+
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+	if n <= 0 {
+		return make([]Node, 0)
+	}
+
+	k := r.hasher.Hash([]byte(key))
+	order := make([]int, len(r.nodes))
+	for i := range order {
+		order[i] = i
+	}
+	if r.weighted {
+		scores := make([]float64, len(r.nodes))
+		for i := range order {
+			scores[i] = r.weightedScore(k, i)
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return scores[order[a]] > scores[order[b]]
+		})
+	} else {
+		scores := make([]uint64, len(r.nodes))
+		for i := range order {
+			scores[i] = r.score(k, i)
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return scores[order[a]] > scores[order[b]]
+		})
+	}
+
+	nodes := make([]Node, n)
+	for i := range nodes {
+		nodes[i] = r.nodes[order[i]]
+	}
+	return nodes
+}
+
+// Members 按 Id 排序返回所有节点
+func (r *Rendezvous) Members() []Node {
+	r.RLock()
+	defer r.RUnlock()
+
+	nodes := make([]Node, len(r.nodes))
+	copy(nodes, r.nodes)
+	return nodes
+}
@@ -0,0 +1,55 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownStrategy = errors.New("consistent: unknown strategy")
+
+const (
+	STRATEGY_RING       = "ring"
+	STRATEGY_RENDEZVOUS = "rendezvous"
+	STRATEGY_MAGLEV     = "maglev"
+	STRATEGY_KETAMA     = "ketama"
+	STRATEGY_ANCHOR     = "anchor"
+	STRATEGY_MULTIPROBE = "multiprobe"
+	STRATEGY_SLOTS      = "slots"
+	// STRATEGY_WEIGHTED_RENDEZVOUS 是按 Weight 打分的 Rendezvous
+	STRATEGY_WEIGHTED_RENDEZVOUS = "weighted-rendezvous"
+)
+
+// Ring 是各种一致性哈希算法共同的接口, *Consistent 也实现了它
+type Ring interface {
+	Add(node *Node) bool
+	Remove(node *Node)
+	Get(key string) (Node, error)
+	GetN(key string, n int) []Node
+	Members() []Node
+}
+
+// New 按 strategy 构造一个空的 Ring, opts 中与该算法无关的选项会被忽略
+func New(strategy string, opts ...Option) (Ring, error) {
+	c := NewConsistent(opts...)
+
+	switch strategy {
+	case STRATEGY_RING, "":
+		return c, nil
+	case STRATEGY_RENDEZVOUS:
+		return NewRendezvous(c.Hasher()), nil
+	case STRATEGY_WEIGHTED_RENDEZVOUS:
+		return NewWeightedRendezvous(c.Hasher()), nil
+	case STRATEGY_MAGLEV:
+		return NewMaglev(nil, DEFAULT_MAGLEV_TABLE_SIZE), nil
+	case STRATEGY_KETAMA:
+		return NewKetama(), nil
+	case STRATEGY_ANCHOR:
+		return NewAnchor(DEFAULT_ANCHOR_CAPACITY), nil
+	case STRATEGY_MULTIPROBE:
+		return NewMultiProbe(DEFAULT_PROBES, c.Hasher()), nil
+	case STRATEGY_SLOTS:
+		return NewSlotRing(DEFAULT_SLOTS), nil
+	}
+
+	return nil, fmt.Errorf("%w %q", ErrUnknownStrategy, strategy)
+}
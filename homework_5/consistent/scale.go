@@ -0,0 +1,49 @@
+package consistent
+
+// ScaleReport 是加入一批节点之前对迁移量和加入之后均衡情况的估计, 所有比例都是占整个哈希空间的比例
+type ScaleReport struct {
+	// Moved 是换了节点的 key 比例
+	Moved float64 `json:"moved"`
+	// Donations 是每个原有节点让出的比例, Received 是每个新节点接收的比例
+	Donations map[int]float64 `json:"donations"`
+	Received  map[int]float64 `json:"received"`
+	// Before 和 After 是加入前后的均衡统计
+	Before Stats `json:"before"`
+	After  Stats `json:"after"`
+	// Skipped 是已经在环上或者重复的 Id, 这些节点不参与估计
+	Skipped []int `json:"skipped,omitempty"`
+}
+
+// EstimateScaleOut 在环的副本上加入 newNodes, 按区间精确计算迁移量和加入之后的均衡, 不修改环.
+// 用来比较不同的扩容方案, 例如加 2 个权重为 2 的节点和加 4 个权重为 1 的节点
+func (c *Consistent) EstimateScaleOut(newNodes []*Node) ScaleReport {
+	// 两边都用副本, 估计期间环的变化不影响结果
+	base := c.Clone()
+	next := base.Clone()
+	r := ScaleReport{Donations: make(map[int]float64), Received: make(map[int]float64), Before: base.Stats()}
+
+	fresh := make([]*Node, 0, len(newNodes))
+	seen := make(map[int]bool, len(newNodes))
+	for _, node := range newNodes {
+		if seen[node.Id] || next.Contains(node.Id) {
+			r.Skipped = append(r.Skipped, node.Id)
+			continue
+		}
+		seen[node.Id] = true
+		fresh = append(fresh, node)
+		r.Received[node.Id] = 0
+	}
+	next.AddBatch(fresh)
+
+	for _, m := range Diff(base, next) {
+		r.Moved += m.Fraction
+		if !seen[m.From.Id] {
+			r.Donations[m.From.Id] += m.Fraction
+		}
+		if seen[m.To.Id] {
+			r.Received[m.To.Id] += m.Fraction
+		}
+	}
+	r.After = next.Stats()
+	return r
+}
@@ -0,0 +1,292 @@
+package consistent
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrBadSlot      = errors.New("consistent: slot out of range")
+	ErrUnknownNode  = errors.New("consistent: node is not a member")
+	ErrSlotNotOwned = errors.New("consistent: slot has no owner")
+)
+
+const (
+	DEFAULT_SLOTS = 16384
+)
+
+// SlotRing 与 Redis Cluster 相同: key 先哈希到固定数量的 slot (CRC16 取模, 支持 {tag}), 再由 slot 表决定节点.
+// 加入和删除节点时只在 slot 之间搬动, 按权重补齐每个节点的目标数量, 超出目标的节点让出编号最大的 slot;
+// 之后可以用 MoveSlot 逐个迁移, 每次只有一个 slot 上的 key 换节点, 迁移的进度和范围完全可控.
+// 与 Anchor 一样, 映射取决于变更的历史, 各实例需要按同样的顺序变更, 或者直接同步 slot 表
+type SlotRing struct {
+	sync.RWMutex
+	owners []int
+	nodes  map[int]Node
+	counts map[int]int
+}
+
+func NewSlotRing(slots int) *SlotRing {
+	if slots <= 0 {
+		slots = DEFAULT_SLOTS
+	}
+
+	s := &SlotRing{owners: make([]int, slots), nodes: make(map[int]Node), counts: make(map[int]int)}
+	for i := range s.owners {
+		s.owners[i] = -1
+	}
+	return s
+}
+
+// crc16 是 Redis Cluster 用的 CRC16-CCITT (XMODEM)
+func crc16(b []byte) uint16 {
+	crc := uint16(0)
+	for _, c := range b {
+		crc ^= uint16(c) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// hashTag 返回 key 里第一对非空花括号中的内容, 没有时返回 key 本身
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+func (s *SlotRing) Slots() int {
+	return len(s.owners)
+}
+
+// KeySlot 返回 key 的 slot, 16384 个 slot 时与 Redis 的 CLUSTER KEYSLOT 相同
+func (s *SlotRing) KeySlot(key string) int {
+	return int(crc16([]byte(hashTag(key)))) % len(s.owners)
+}
+
+// targets 按权重计算每个节点应有的 slot 数, 余数按 Id 顺序每个节点多分一个
+func (s *SlotRing) targets() ([]int, map[int]int) {
+	ids := make([]int, 0, len(s.nodes))
+	total := 0
+	for id, node := range s.nodes {
+		ids = append(ids, id)
+		if node.Weight > 0 {
+			total += node.Weight
+		}
+	}
+	sort.Ints(ids)
+
+	targets := make(map[int]int, len(ids))
+	if total == 0 {
+		return ids, targets
+	}
+	given := 0
+	for _, id := range ids {
+		if w := s.nodes[id].Weight; w > 0 {
+			targets[id] = len(s.owners) * w / total
+			given += targets[id]
+		}
+	}
+	for i := 0; given < len(s.owners); i = (i + 1) % len(ids) {
+		if s.nodes[ids[i]].Weight > 0 {
+			targets[ids[i]]++
+			given++
+		}
+	}
+	return ids, targets
+}
+
+// rebalance 在写锁内调用: 没有 owner 的 slot 和超出目标的 slot 按编号顺序分给不足目标的节点, 返回换了节点的 slot 数
+func (s *SlotRing) rebalance() int {
+	ids, targets := s.targets()
+
+	free := make([]int, 0)
+	surplus := make(map[int]int, len(ids))
+	for _, id := range ids {
+		surplus[id] = s.counts[id] - targets[id]
+	}
+	for slot := len(s.owners) - 1; slot >= 0; slot-- {
+		id := s.owners[slot]
+		if id < 0 {
+			free = append(free, slot)
+		} else if surplus[id] > 0 {
+			surplus[id]--
+			free = append(free, slot)
+		}
+	}
+	sort.Ints(free)
+
+	moved := 0
+	for _, id := range ids {
+		for s.counts[id] < targets[id] && len(free) > 0 {
+			slot := free[0]
+			free = free[1:]
+			if old := s.owners[slot]; old >= 0 {
+				s.counts[old]--
+				moved++
+			}
+			s.owners[slot] = id
+			s.counts[id]++
+		}
+	}
+	return moved
+}
+
+// Add 加入节点并从其它节点搬来它应有的 slot
+func (s *SlotRing) Add(node *Node) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.nodes[node.Id]; ok {
+		return false
+	}
+	s.nodes[node.Id] = *node
+	s.rebalance()
+	return true
+}
+
+// Remove 删除节点, 它的 slot 分给其余不足目标的节点
+func (s *SlotRing) Remove(node *Node) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.nodes[node.Id]; !ok {
+		return
+	}
+	delete(s.nodes, node.Id)
+	delete(s.counts, node.Id)
+	for slot, id := range s.owners {
+		if id == node.Id {
+			s.owners[slot] = -1
+		}
+	}
+	s.rebalance()
+}
+
+// Rebalance 把手工 MoveSlot 之后的分布重新调整到按权重的目标, 返回移动的 slot 数
+func (s *SlotRing) Rebalance() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.rebalance()
+}
+
+// MoveSlot 把一个 slot 交给节点, 只有这个 slot 上的 key 换节点; 之后的 Add/Remove 可能会再调整它
+func (s *SlotRing) MoveSlot(slot, id int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if slot < 0 || slot >= len(s.owners) {
+		return ErrBadSlot
+	}
+	if _, ok := s.nodes[id]; !ok {
+		return ErrUnknownNode
+	}
+	if old := s.owners[slot]; old >= 0 {
+		s.counts[old]--
+	}
+	s.owners[slot] = id
+	s.counts[id]++
+	return nil
+}
+
+// SlotOwner 返回 slot 所在的节点
+func (s *SlotRing) SlotOwner(slot int) (Node, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if slot < 0 || slot >= len(s.owners) {
+		return Node{}, ErrBadSlot
+	}
+	if s.owners[slot] < 0 {
+		return Node{}, ErrSlotNotOwned
+	}
+	return s.nodes[s.owners[slot]], nil
+}
+
+// SlotsOf 按编号返回节点的 slot
+func (s *SlotRing) SlotsOf(id int) []int {
+	s.RLock()
+	defer s.RUnlock()
+
+	slots := make([]int, 0, s.counts[id])
+	for slot, owner := range s.owners {
+		if owner == id {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+// SlotCounts 返回每个节点的 slot 数
+func (s *SlotRing) SlotCounts() map[int]int {
+	s.RLock()
+	defer s.RUnlock()
+
+	counts := make(map[int]int, len(s.counts))
+	for id := range s.nodes {
+		counts[id] = s.counts[id]
+	}
+	return counts
+}
+
+// Get 没有节点时返回 ErrEmptyRing
+func (s *SlotRing) Get(key string) (Node, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	id := s.owners[s.KeySlot(key)]
+	if id < 0 {
+		return Node{}, ErrEmptyRing
+	}
+	return s.nodes[id], nil
+}
+
+// GetN 从 key 的 slot 往后找 n 个不同的节点, 第一个与 Get 相同
+func (s *SlotRing) GetN(key string, n int) []Node {
+	s.RLock()
+	defer s.RUnlock()
+
+	if n < 0 {
+		n = 0
+	}
+	nodes := make([]Node, 0, n)
+	seen := make(map[int]bool, n)
+	slot := s.KeySlot(key)
+	for step := 0; step < len(s.owners) && len(nodes) < n && len(seen) < len(s.counts); step++ {
+		id := s.owners[(slot+step)%len(s.owners)]
+		if id >= 0 && !seen[id] {
+			seen[id] = true
+			nodes = append(nodes, s.nodes[id])
+		}
+	}
+	return nodes
+}
+
+// Members 按 Id 排序返回所有节点
+func (s *SlotRing) Members() []Node {
+	s.RLock()
+	defer s.RUnlock()
+
+	nodes := make([]Node, 0, len(s.nodes))
+	for _, node := range s.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Id < nodes[j].Id
+	})
+	return nodes
+}
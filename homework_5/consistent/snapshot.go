@@ -0,0 +1,119 @@
+package consistent
+
+import (
+	"math"
+	"sync"
+)
+
+// snapshot 是发布给读者的只读视图, ring 与 owners 按下标一一对应, 发布之后不再修改.
+// Get 和 GetN 原子地读出当前快照, 不加锁, 也不会被正在进行的 Add/Remove 阻塞.
+// routes[i] 是跳过 skip 中的节点 (draining 和 MarkDown) 之后下标 i 实际路由到的节点,
+// 没有这样的节点时与 owners 是同一个切片, 所有节点都被跳过时为 nil.
+// tagged 缓存 GetTagged 用到的子环 (tag -> *subRing), 第一次查询某个 tag 时生成; pins 是当前生效的 Pin.
+// ringParams 是发布时环的参数, 不加锁的读者只用快照里的参数计算位置, Restore 换参数时不会读到一半
+type snapshot struct {
+	ringParams
+	ring    HashRing
+	owners  []Node
+	routes  []Node
+	members int
+	skip    map[int]bool
+	pins    map[string]Node
+	tagged  sync.Map
+	// version 每发布一个快照加一, 见 Version
+	version uint64
+}
+
+// ringParams 是查找时用到的环参数, 只有 Restore 会在环上改变它们
+type ringParams struct {
+	hasher  Hasher
+	seed    uint64
+	bits    int
+	numReps int
+	strict  bool
+	factor  float64
+}
+
+// ringParams 在锁内调用, 复制当前的参数放进新快照
+func (c *Consistent) ringParams() ringParams {
+	return ringParams{hasher: c.hasher, seed: c.seed, bits: c.bits, numReps: c.numReps, strict: c.strict, factor: c.factor}
+}
+
+func (p *ringParams) hashStr(key string) uint64 {
+	return p.position(p.hash([]byte(key)))
+}
+
+func (p *ringParams) hash(b []byte) uint64 {
+	return seeded(p.hasher.Hash(b), p.seed)
+}
+
+func (p *ringParams) position(h uint64) uint64 {
+	if p.bits == 64 {
+		return h
+	}
+	return uint64(fold(h))
+}
+
+func (p *ringParams) maxPosition() uint64 {
+	if p.bits == 64 {
+		return math.MaxUint64
+	}
+	return math.MaxUint32
+}
+
+// search 按快照的回绕规则在快照的环上查找
+func (s *snapshot) search(hash uint64) int {
+	return s.ring.search(hash, s.strict)
+}
+
+func (c *Consistent) current() *snapshot {
+	return c.snap.Load().(*snapshot)
+}
+
+// publish 在写锁内调用, 用 c.ring 生成新快照; 上一个快照里还在环上且不在 touched 中的位置沿用原来的 owner,
+// 其余的从 c.Nodes 取, 所以代价是 O(V) 次拷贝加上变化的位置数次 map 查找
+func (c *Consistent) publish(touched map[uint64]bool) {
+	prev := c.current()
+	owners := make([]Node, len(c.ring))
+
+	j := 0
+	for i, hash := range c.ring {
+		for j < len(prev.ring) && prev.ring[j] < hash {
+			j++
+		}
+		if j < len(prev.ring) && prev.ring[j] == hash && !touched[hash] {
+			owners[i] = prev.owners[j]
+		} else {
+			owners[i] = c.Nodes[hash]
+		}
+	}
+
+	s := &snapshot{ringParams: c.ringParams(), ring: c.ring, owners: owners, members: len(c.resources), version: prev.version + 1}
+	s.route(c.unroutable())
+	s.pin(c.members, c.pins)
+	c.snap.Store(s)
+	c.versions.keep(prev)
+}
+
+// arcs 按快照生成区间, 与 Arcs 相同, 区间归路由到的节点, 所有节点都被跳过时没有区间
+func (c *Consistent) arcs(s *snapshot) []Arc {
+	arcs := make([]Arc, 0, len(s.ring)+1)
+	if len(s.routes) == 0 {
+		return arcs
+	}
+
+	start := uint64(0)
+	for i, hash := range s.ring {
+		if i > 0 {
+			start = s.ring[i-1] + 1
+		}
+		arcs = append(arcs, Arc{Start: start, End: hash, Node: s.routes[s.search(hash)]})
+	}
+
+	last, top := s.ring[len(s.ring)-1], s.maxPosition()
+	if last != top {
+		arcs = append(arcs, Arc{Start: last + 1, End: top, Node: s.routes[s.search(top)]})
+	}
+
+	return arcs
+}
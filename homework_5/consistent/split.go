@@ -0,0 +1,95 @@
+package consistent
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+const (
+	SPLIT_POINTS = 16
+)
+
+// Bucket 是实验分桶, Weight 是相对比例, 例如 90 和 10
+type Bucket struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+type splitPoint struct {
+	hash   uint64
+	bucket int
+}
+
+// Splitter 把 key 按权重稳定地分到桶里. 每个桶有 Weight*SPLIT_POINTS 个点, 标签只和桶名、序号有关,
+// 所以调整权重时只有新增或删掉的点附近的 key 会换桶, 例如 90/10 改成 80/20 只移动约 10% 的 key
+type Splitter struct {
+	hasher  Hasher
+	seed    string
+	buckets []Bucket
+	points  []splitPoint
+}
+
+// NewSplitter 使用 SplitHasher, 与环的哈希函数无关
+func NewSplitter(seed string, buckets []Bucket) *Splitter {
+	return NewSplitterWithHasher(SplitHasher, seed, buckets)
+}
+
+// NewSplitterWithHasher 用 h 计算点和 key 的位置, h 为 nil 时使用 SplitHasher
+func NewSplitterWithHasher(h Hasher, seed string, buckets []Bucket) *Splitter {
+	if h == nil {
+		h = SplitHasher
+	}
+	s := &Splitter{hasher: h, seed: seed, buckets: buckets}
+	for i, b := range buckets {
+		for j := 0; j < b.Weight*SPLIT_POINTS; j++ {
+			h := s.hasher.Hash([]byte(b.Name + "-" + strconv.Itoa(j)))
+			s.points = append(s.points, splitPoint{hash: h, bucket: i})
+		}
+	}
+
+	sort.Slice(s.points, func(i, j int) bool {
+		if s.points[i].hash != s.points[j].hash {
+			return s.points[i].hash < s.points[j].hash
+		}
+		return s.buckets[s.points[i].bucket].Name < s.buckets[s.points[j].bucket].Name
+	})
+	return s
+}
+
+// Splitter 使用环的哈希函数和 seed (WithHasher、WithSeed), 同样配置的环得到同样的分桶
+func (c *Consistent) Splitter(seed string, buckets []Bucket) *Splitter {
+	return NewSplitterWithHasher(c.Hasher(), seed, buckets)
+}
+
+// SplitHasher 在 crc32 之后加 murmur3 的 fmix32, crc32 是线性的, 相似的 key 和标签会挤在一起
+var SplitHasher = HasherFunc(func(b []byte) uint64 {
+	h := crc32.ChecksumIEEE(b)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return uint64(h)
+})
+
+// Split 返回 key 所在的桶, 没有权重大于 0 的桶时返回零值
+func (s *Splitter) Split(key string) Bucket {
+	if len(s.points) == 0 {
+		return Bucket{}
+	}
+
+	h := s.hasher.Hash([]byte(s.seed + key))
+	i := sort.Search(len(s.points), func(i int) bool {
+		return s.points[i].hash >= h
+	})
+	if i == len(s.points) {
+		i = 0
+	}
+	return s.buckets[s.points[i].bucket]
+}
+
+// Split 不带 seed 的便捷写法, 每次调用都会重新建表, 高频调用请复用 Splitter
+func Split(key string, buckets []Bucket) Bucket {
+	return NewSplitter("", buckets).Split(key)
+}
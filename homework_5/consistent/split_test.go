@@ -0,0 +1,36 @@
+package consistent
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSplitterUsesRingHasher(t *testing.T) {
+	buckets := []Bucket{{Name: "a", Weight: 90}, {Name: "b", Weight: 10}}
+
+	// 默认的 Splitter 与环无关, 环的 Splitter 跟着 WithHasher 和 WithSeed 变化
+	def := NewSplitter("exp", buckets)
+	crc := NewConsistent().Splitter("exp", buckets)
+	seeded := NewConsistent(WithSeed(42)).Splitter("exp", buckets)
+	same := NewConsistent(WithSeed(42)).Splitter("exp", buckets)
+
+	differs, seedDiffers := false, false
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		if def.Split(key) != crc.Split(key) {
+			differs = true
+		}
+		if crc.Split(key) != seeded.Split(key) {
+			seedDiffers = true
+		}
+		if seeded.Split(key) != same.Split(key) {
+			t.Fatalf("%s: rings with the same seed split differently", key)
+		}
+	}
+	if !differs {
+		t.Fatal("ring Splitter ignores the ring hasher")
+	}
+	if !seedDiffers {
+		t.Fatal("ring Splitter ignores the seed")
+	}
+}
@@ -0,0 +1,255 @@
+package consistent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ErrRingNotEmpty = errors.New("consistent: restore needs an empty ring")
+	ErrBadSnapshot  = errors.New("consistent: snapshot does not match this ring")
+)
+
+const (
+	SNAPSHOT_VERSION = 1
+)
+
+const (
+	CHANGE_RESTORED = "restored"
+)
+
+// stateNode 是快照里的节点, LabelWeight 只在与 Weight 不同时写出,
+// Probes 记录冲突探测过的虚拟节点: 序号 -> 探测次数
+type stateNode struct {
+	Node
+	LabelWeight int         `json:"label_weight,omitempty"`
+	Probes      map[int]int `json:"probes,omitempty"`
+}
+
+// ringState 是 Snapshot 的 JSON 格式. 自定义的哈希函数和标签格式没有名字, 对应的字段为空,
+// Restore 时沿用接收者自己的设置
+type ringState struct {
+	Version    int            `json:"version"`
+	Hasher     string         `json:"hasher,omitempty"`
+	Seed       uint64         `json:"seed,omitempty"`
+	Label      string         `json:"label,omitempty"`
+	RingBits   int            `json:"ring_bits"`
+	Replicas   int            `json:"replicas"`
+	StrictWrap bool           `json:"strict_wrap,omitempty"`
+	LoadFactor float64        `json:"load_factor,omitempty"`
+	Collisions int            `json:"collisions,omitempty"`
+	Nodes      []stateNode    `json:"nodes"`
+	Draining   []int          `json:"draining,omitempty"`
+	Pins       map[string]int `json:"pins,omitempty"`
+}
+
+// Snapshot 把节点、权重和环的参数编码成 JSON, 同样的快照在任何进程里 Restore 出来的环完全相同,
+// 包括冲突探测之后的位置
+func (c *Consistent) Snapshot() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	st := ringState{
+		Version:    SNAPSHOT_VERSION,
+		Hasher:     hasherName(c.hasher),
+		Seed:       c.seed,
+		Label:      labelName(c.label),
+		RingBits:   c.bits,
+		Replicas:   c.numReps,
+		StrictWrap: c.strict,
+		LoadFactor: c.factor,
+		Collisions: c.collisions,
+		Nodes:      make([]stateNode, 0, len(c.members)),
+	}
+
+	for id, node := range c.members {
+		sn := stateNode{Node: node}
+		if w := c.labelWeights[id]; w != node.Weight {
+			sn.LabelWeight = w
+		}
+		for i, hash := range c.points[id] {
+			label := c.joinStr(i, &node)
+			for k := 0; c.hashStr(probeLabel(label, k)) != hash; k++ {
+				if sn.Probes == nil {
+					sn.Probes = make(map[int]int)
+				}
+				sn.Probes[i] = k + 1
+			}
+		}
+		st.Nodes = append(st.Nodes, sn)
+	}
+
+	sort.Slice(st.Nodes, func(i, j int) bool {
+		return st.Nodes[i].Id < st.Nodes[j].Id
+	})
+
+	for id := range c.draining {
+		st.Draining = append(st.Draining, id)
+	}
+	sort.Ints(st.Draining)
+
+	if len(c.pins) > 0 {
+		st.Pins = make(map[string]int, len(c.pins))
+		for key, id := range c.pins {
+			st.Pins[key] = id
+		}
+	}
+
+	return json.Marshal(st)
+}
+
+// Restore 用 Snapshot 的结果重建环, 只能在空环上调用; 完成后 OnOwnershipChanged 和 Subscribe 会收到一次 CHANGE_RESTORED
+func (c *Consistent) Restore(data []byte) error {
+	st := ringState{}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	if st.Version != SNAPSHOT_VERSION {
+		return fmt.Errorf("consistent: unsupported snapshot version %d", st.Version)
+	}
+
+	hasher, label := c.hasher, c.label
+	if st.Hasher != "" {
+		h, ok := Hashers[st.Hasher]
+		if !ok {
+			return fmt.Errorf("consistent: unknown hasher %q in snapshot", st.Hasher)
+		}
+		hasher = h
+	}
+	if st.Label != "" {
+		f, ok := Labels[st.Label]
+		if !ok {
+			return fmt.Errorf("consistent: unknown label %q in snapshot", st.Label)
+		}
+		label = f
+	}
+	if st.RingBits != 32 && st.RingBits != 64 {
+		return fmt.Errorf("consistent: unsupported ring bits %d in snapshot", st.RingBits)
+	}
+	if st.Replicas <= 0 {
+		return fmt.Errorf("consistent: bad replicas %d in snapshot", st.Replicas)
+	}
+
+	c.Lock()
+	if len(c.members) != 0 {
+		c.Unlock()
+		return ErrRingNotEmpty
+	}
+
+	oldHasher, oldSeed, oldLabel, oldBits, oldReps, oldStrict, oldFactor := c.hasher, c.seed, c.label, c.bits, c.numReps, c.strict, c.factor
+	c.hasher, c.seed, c.label, c.bits, c.numReps, c.strict, c.factor = hasher, st.Seed, label, st.RingBits, st.Replicas, st.StrictWrap, st.LoadFactor
+	prev := c.current()
+	if err := c.restore(st); err != nil {
+		c.hasher, c.seed, c.label, c.bits, c.numReps, c.strict, c.factor = oldHasher, oldSeed, oldLabel, oldBits, oldReps, oldStrict, oldFactor
+		c.reset()
+		c.Unlock()
+		return err
+	}
+
+	// 恢复可能换了哈希函数和回绕规则, 之前保留的版本无法再按新的参数查找
+	c.versions.clear()
+	c.notify(prev, CHANGE_RESTORED, Node{})
+	return nil
+}
+
+func (c *Consistent) restore(st ringState) error {
+	added := make(HashRing, 0)
+	for _, sn := range st.Nodes {
+		node := sn.Node
+		if _, ok := c.members[node.Id]; ok || node.Weight < 0 {
+			return ErrBadSnapshot
+		}
+
+		c.labelWeights[node.Id] = node.Weight
+		if sn.LabelWeight != 0 {
+			c.labelWeights[node.Id] = sn.LabelWeight
+		}
+
+		points := make(HashRing, c.replicaCount(node))
+		for i := range points {
+			hash := c.hashStr(probeLabel(c.joinStr(i, &node), sn.Probes[i]))
+			if _, ok := c.Nodes[hash]; ok {
+				return ErrBadSnapshot
+			}
+			c.Nodes[hash] = node
+			points[i] = hash
+		}
+
+		c.resources[node.Id] = true
+		c.members[node.Id] = node
+		c.points[node.Id] = points
+		c.weights += node.Weight
+		added = append(added, points...)
+	}
+
+	for _, id := range st.Draining {
+		if _, ok := c.members[id]; !ok {
+			return ErrBadSnapshot
+		}
+		c.draining[id] = true
+	}
+	// 固定到已经删除的节点的 key 也原样恢复, 与 Snapshot 之前一样暂时不生效
+	for key, id := range st.Pins {
+		c.pins[key] = id
+	}
+
+	c.collisions = st.Collisions
+	c.insertPoints(added)
+	c.publish(nil)
+	return nil
+}
+
+// reset 在写锁内调用, 清掉 restore 失败时留下的一半状态
+func (c *Consistent) reset() {
+	c.Nodes = make(map[uint64]Node)
+	c.resources = make(map[int]bool)
+	c.members = make(map[int]Node)
+	c.labelWeights = make(map[int]int)
+	c.points = make(map[int][]uint64)
+	c.draining = make(map[int]bool)
+	c.down = make(map[int]bool)
+	c.pins = make(map[string]int)
+	c.ring = HashRing{}
+	c.weights = 0
+	c.collisions = 0
+	// 失败时还没有发布过快照, 版本号保持不变, 不能倒退
+	c.snap.Store(&snapshot{ringParams: c.ringParams(), version: c.current().version})
+}
+
+// hasherName 用几组固定的输入比较输出, 判断 h 是不是 Hashers 里的某一个, 自定义的哈希函数返回空串
+func hasherName(h Hasher) string {
+	inputs := []string{"", "consistent", "192.168.1.1:8080-0-1"}
+	for name, builtin := range Hashers {
+		same := true
+		for _, in := range inputs {
+			if h.Hash([]byte(in)) != builtin.Hash([]byte(in)) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return name
+		}
+	}
+	return ""
+}
+
+// labelName 与 hasherName 相同, 比较几个测试节点上的标签
+func labelName(f LabelFunc) string {
+	nodes := []Node{{Id: 1, Ip: "192.168.1.1", Port: 8080, Weight: 2}, {Id: 7, Ip: "10.0.0.1", Port: 6379, Weight: 1}}
+	for name, builtin := range Labels {
+		same := true
+		for i, node := range nodes {
+			if f(node, i) != builtin(node, i) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return name
+		}
+	}
+	return ""
+}
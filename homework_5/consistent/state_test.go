@@ -0,0 +1,55 @@
+package consistent
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// TestRestoreConcurrentGet 在 Restore 和 RemoveByID 循环的同时不加锁地查找, 用 go test -race 检查快照里的参数
+func TestRestoreConcurrentGet(t *testing.T) {
+	src := newTestRing(5, WithHasher(FNV1a), WithSeed(7), WithRingBits(64), WithStrictWrap())
+	data, err := src.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := src.Get("key")
+
+	c := NewConsistent()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				key := "key" + strconv.Itoa(i)
+				c.Get(key)
+				c.GetBytes([]byte(key))
+				c.GetN(key, 2)
+				c.Position(key)
+				c.Hasher().Hash([]byte(key))
+				c.Arcs()
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		if err := c.Restore(data); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := c.Get("key"); err != nil || got.Id != want.Id {
+			t.Fatalf("after Restore: got %v %v, want node %d", got.Id, err, want.Id)
+		}
+		for id := 1; id <= 5; id++ {
+			c.RemoveByID(id)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
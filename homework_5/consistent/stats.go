@@ -0,0 +1,62 @@
+package consistent
+
+import (
+	"math"
+	"sort"
+)
+
+// Stats 是每个节点拥有的哈希空间比例的统计, 按区间长度精确计算, 不需要抽样 key
+type Stats struct {
+	Nodes  int             `json:"nodes"`
+	Shares map[int]float64 `json:"shares"`
+	Mean   float64         `json:"mean"`
+	StdDev float64         `json:"stddev"`
+	Min    float64         `json:"min"`
+	Max    float64         `json:"max"`
+	// MinNode 和 MaxNode 是比例最小和最大的节点, 比例相同时取 Id 小的
+	MinNode int `json:"min_node"`
+	MaxNode int `json:"max_node"`
+	// CV 是变异系数 StdDev / Mean, 与节点数无关, 可以直接比较不同规模的环
+	CV float64 `json:"cv"`
+}
+
+// Stats 统计所有参与路由的节点, 没有虚拟节点的节点按 0 计入; draining 和 down 的节点不计入. 环为空时返回零值
+func (c *Consistent) Stats() Stats {
+	shares := c.Ownership()
+	for _, node := range c.Members() {
+		if _, ok := shares[node.Id]; !ok && !c.IsDraining(node.Id) && !c.IsDown(node.Id) {
+			shares[node.Id] = 0
+		}
+	}
+
+	st := Stats{Nodes: len(shares), Shares: shares}
+	if len(shares) == 0 {
+		return st
+	}
+
+	ids := make([]int, 0, len(shares))
+	for id := range shares {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	st.Min, st.Max = math.Inf(1), math.Inf(-1)
+	for _, id := range ids {
+		s := shares[id]
+		st.Mean += s
+		if s < st.Min {
+			st.Min, st.MinNode = s, id
+		}
+		if s > st.Max {
+			st.Max, st.MaxNode = s, id
+		}
+	}
+	st.Mean /= float64(len(ids))
+
+	for _, s := range shares {
+		st.StdDev += (s - st.Mean) * (s - st.Mean)
+	}
+	st.StdDev = math.Sqrt(st.StdDev / float64(len(ids)))
+	st.CV = st.StdDev / st.Mean
+	return st
+}
@@ -0,0 +1,63 @@
+package consistent
+
+const (
+	SUBSCRIBE_BUFFER = 64
+)
+
+const (
+	CHANGE_JOINED = "joined"
+	CHANGE_LEFT   = "left"
+	CHANGE_WEIGHT = "weight"
+)
+
+// ChangeEvent 是一次成员变化, Ranges 是这次变化中归属改变的区间, 与 OnOwnershipChanged 收到的相同
+type ChangeEvent struct {
+	Type   string        `json:"type"`
+	Node   Node          `json:"node"`
+	Ranges []RangeChange `json:"ranges"`
+	// Version 是变更之后环的版本
+	Version uint64 `json:"version"`
+}
+
+// Subscribe 返回按环的变更顺序到达的事件, 调用返回的函数取消订阅.
+// channel 有 SUBSCRIBE_BUFFER 的缓冲, 缓冲满了说明消费者跟不上, channel 会被直接关闭,
+// 消费者应当重新 Subscribe 并按 Members 和 Arcs 重建自己的状态
+func (c *Consistent) Subscribe() (<-chan ChangeEvent, func()) {
+	ch := make(chan ChangeEvent, SUBSCRIBE_BUFFER)
+
+	c.hooks.Lock()
+	if c.hooks.closed {
+		c.hooks.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if c.hooks.subs == nil {
+		c.hooks.subs = make(map[chan ChangeEvent]struct{})
+	}
+	c.hooks.subs[ch] = struct{}{}
+	c.hooks.Unlock()
+
+	return ch, func() {
+		c.hooks.Lock()
+		defer c.hooks.Unlock()
+		c.hooks.unsubscribe(ch)
+	}
+}
+
+// send 持有 hooks 的锁时调用, 不会阻塞修改环的 goroutine
+func (h *hooks) send(e ChangeEvent) {
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+			h.unsubscribe(ch)
+		}
+	}
+}
+
+func (h *hooks) unsubscribe(ch chan ChangeEvent) {
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,43 @@
+package consistent
+
+import (
+	"math/rand"
+)
+
+// Subset 按 SRE 书里的确定性子集算法给客户端分配后端: clientID 是客户端从 0 开始连续编号的序号,
+// 每 len/size 个客户端是一轮, 成员按 Id 排好后用轮次作为种子打乱, 切成 len/size 个互不重叠的子集,
+// 同一轮的客户端各取一个, 正好均分所有后端, 每个后端的客户端数最多相差一轮. 成员不变时结果不变.
+// clientID 为负时返回空
+func (c *Consistent) Subset(clientID int, size int) []Node {
+	members := c.Members()
+	if size <= 0 || clientID < 0 {
+		return []Node{}
+	}
+	if size >= len(members) {
+		return members
+	}
+
+	count := len(members) / size
+	round, index := clientID/count, clientID%count
+
+	rnd := rand.New(rand.NewSource(int64(round)))
+	rnd.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+
+	start := index * size
+	subset := make([]Node, size)
+	copy(subset, members[start:start+size])
+	return subset
+}
+
+// SubsetRing 用 Subset 的结果建一个新环, 客户端在自己的子集里再做一致性哈希
+func (c *Consistent) SubsetRing(clientID int, size int) *Consistent {
+	p := c.current().ringParams
+	ring := NewConsistent(WithReplicas(p.numReps), WithHasher(p.hasher), WithSeed(p.seed), WithRingBits(p.bits))
+	for _, node := range c.Subset(clientID, size) {
+		node := node
+		ring.Add(&node)
+	}
+	return ring
+}
@@ -0,0 +1,73 @@
+package consistent
+
+import "testing"
+
+func newTestRing(n int, opts ...Option) *Consistent {
+	c := NewConsistent(opts...)
+	for i := 1; i <= n; i++ {
+		c.Add(NewNode(i, "10.0.0.1", 8000+i, "", 1))
+	}
+	return c
+}
+
+func TestSubsetBalance(t *testing.T) {
+	const backends, size, clients = 12, 3, 42
+	c := newTestRing(backends)
+
+	counts := make(map[int]int)
+	for client := 0; client < clients; client++ {
+		subset := c.Subset(client, size)
+		if len(subset) != size {
+			t.Fatalf("client %d: got %d backends, want %d", client, len(subset), size)
+		}
+		seen := make(map[int]bool)
+		for _, node := range subset {
+			if seen[node.Id] {
+				t.Fatalf("client %d: backend %d appears twice", client, node.Id)
+			}
+			seen[node.Id] = true
+			counts[node.Id]++
+		}
+	}
+
+	// 每轮 backends/size 个客户端正好覆盖每个后端一次, 不满一轮的客户端最多让计数相差 1
+	rounds := clients / (backends / size)
+	for id := 1; id <= backends; id++ {
+		if counts[id] < rounds || counts[id] > rounds+1 {
+			t.Fatalf("backend %d has %d clients, want %d or %d", id, counts[id], rounds, rounds+1)
+		}
+	}
+}
+
+func TestSubsetRoundCoversAll(t *testing.T) {
+	c := newTestRing(10)
+	for round := 0; round < 5; round++ {
+		seen := make(map[int]bool)
+		for index := 0; index < 10/3; index++ {
+			for _, node := range c.Subset(round*(10/3)+index, 3) {
+				if seen[node.Id] {
+					t.Fatalf("round %d: backend %d in two subsets", round, node.Id)
+				}
+				seen[node.Id] = true
+			}
+		}
+		if len(seen) != 10/3*3 {
+			t.Fatalf("round %d covers %d backends, want %d", round, len(seen), 10/3*3)
+		}
+	}
+}
+
+func TestSubsetStable(t *testing.T) {
+	a, b := newTestRing(9), newTestRing(9)
+	for client := 0; client < 20; client++ {
+		x, y := a.Subset(client, 3), b.Subset(client, 3)
+		for i := range x {
+			if x[i].Id != y[i].Id {
+				t.Fatalf("client %d: subsets differ between identical rings", client)
+			}
+		}
+	}
+	if len(a.Subset(-1, 3)) != 0 {
+		t.Fatal("negative client id returned backends")
+	}
+}
@@ -0,0 +1,83 @@
+package consistent
+
+import (
+	"errors"
+	"sort"
+)
+
+var ErrNoMatch = errors.New("consistent: no routable node matches")
+
+// HasTag 判断节点是否带有 tag
+func (n Node) HasTag(tag string) bool {
+	for _, t := range n.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWithFilter 从 key 的位置顺时针找第一个满足 pred 的节点, 跳过 draining 和 MarkDown 的节点;
+// pred 总是返回 true 时与 Get 相同. 最坏情况要扫描整个环, 固定的 tag 用 GetTagged
+func (c *Consistent) GetWithFilter(key string, pred func(node Node) bool) (Node, error) {
+	s := c.current()
+	if err := s.routable(); err != nil {
+		return Node{}, err
+	}
+
+	i := s.search(s.hashStr(key))
+	for step := 0; step < len(s.ring); step++ {
+		node := s.owners[i]
+		if !s.skip[node.Id] && pred(node) {
+			return node, nil
+		}
+		i = (i + 1) % len(s.ring)
+	}
+	return Node{}, ErrNoMatch
+}
+
+// subRing 是只包含带某个 tag 且参与路由的节点的位置
+type subRing struct {
+	ring   HashRing
+	owners []Node
+}
+
+func (s *snapshot) sub(tag string) *subRing {
+	if sr, ok := s.tagged.Load(tag); ok {
+		return sr.(*subRing)
+	}
+
+	sr := &subRing{ring: HashRing{}, owners: make([]Node, 0)}
+	for i, node := range s.owners {
+		if !s.skip[node.Id] && node.HasTag(tag) {
+			sr.ring = append(sr.ring, s.ring[i])
+			sr.owners = append(sr.owners, node)
+		}
+	}
+	actual, _ := s.tagged.LoadOrStore(tag, sr)
+	return actual.(*subRing)
+}
+
+// GetTagged 与 GetWithFilter(key, 带 tag) 的结果相同, 但用每个 tag 的子环二分查找, 是 O(log V).
+// 子环跟着环的快照走, 每次成员变化之后第一次查询某个 tag 时重新生成
+func (c *Consistent) GetTagged(key, tag string) (Node, error) {
+	s := c.current()
+	if err := s.routable(); err != nil {
+		return Node{}, err
+	}
+
+	sr := s.sub(tag)
+	if len(sr.ring) == 0 {
+		return Node{}, ErrNoMatch
+	}
+
+	// 先按环的回绕规则找到 key 的起点, 再在子环上找顺时针第一个位置, 与 GetWithFilter 的扫描一致
+	start := s.ring[s.search(s.hashStr(key))]
+	j := sort.Search(len(sr.ring), func(j int) bool {
+		return sr.ring[j] >= start
+	})
+	if j == len(sr.ring) {
+		j = 0
+	}
+	return sr.owners[j], nil
+}
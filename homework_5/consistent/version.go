@@ -0,0 +1,161 @@
+package consistent
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrVersionGone = errors.New("consistent: version is not retained")
+
+const (
+	DEFAULT_RETAINED_VERSIONS = 16
+)
+
+// versions 保存最近的 retain 个旧快照, 按版本从旧到新排列; 有自己的锁, AtVersion 不需要环的锁
+type versions struct {
+	sync.Mutex
+	retain int
+	snaps  []*snapshot
+}
+
+// keep 在写锁内调用, 保存刚被替换的快照
+func (v *versions) keep(s *snapshot) {
+	if v.retain <= 0 {
+		return
+	}
+
+	v.Lock()
+	defer v.Unlock()
+	if len(v.snaps) >= v.retain {
+		// 拷贝到新切片, 旧的底层数组不再引用被淘汰的快照
+		v.snaps = append([]*snapshot(nil), v.snaps[len(v.snaps)-v.retain+1:]...)
+	}
+	v.snaps = append(v.snaps, s)
+}
+
+func (v *versions) clear() {
+	v.Lock()
+	defer v.Unlock()
+	v.snaps = nil
+}
+
+func (v *versions) find(version uint64) (*snapshot, bool) {
+	v.Lock()
+	defer v.Unlock()
+	for _, s := range v.snaps {
+		if s.version == version {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// WithRetainedVersions 设置 AtVersion 能查到的旧版本个数, 0 表示只保留当前版本; 默认 DEFAULT_RETAINED_VERSIONS
+func WithRetainedVersions(n int) Option {
+	return func(c *Consistent) {
+		if n >= 0 {
+			c.versions.retain = n
+		}
+	}
+}
+
+// View 是环在某个版本上的只读视图, 快照本身不会再变, 可以在任意 goroutine 里使用
+type View struct {
+	c *Consistent
+	s *snapshot
+}
+
+// Version 返回环的当前版本. 每次成员、权重、draining、MarkDown 和 Pin 的变化都会加一, 没有实际变化的调用不加;
+// 版本只在进程内有意义, 不同实例的同一个版本号不一定是同样的环
+func (c *Consistent) Version() uint64 {
+	return c.current().version
+}
+
+// Current 返回当前版本的视图, 之后的变更不影响它
+func (c *Consistent) Current() *View {
+	return &View{c: c, s: c.current()}
+}
+
+// AtVersion 返回版本 v 的视图, v 太旧已经被淘汰或者还不存在时返回 ErrVersionGone.
+// 客户端可以用它判断按旧版本做出的路由决定在新版本上是否仍然成立
+func (c *Consistent) AtVersion(v uint64) (*View, error) {
+	if s := c.current(); s.version == v {
+		return &View{c: c, s: s}, nil
+	}
+	s, ok := c.versions.find(v)
+	if !ok {
+		return nil, ErrVersionGone
+	}
+	return &View{c: c, s: s}, nil
+}
+
+// GetVersioned 与 Get 相同, 同时返回做出决定时环的版本
+func (c *Consistent) GetVersioned(key string) (Node, uint64, error) {
+	if c.current().factor > 0 {
+		c.RLock()
+		defer c.RUnlock()
+
+		s := c.current()
+		if node, ok := s.pinned(key); ok {
+			return node, s.version, nil
+		}
+		if err := s.routable(); err != nil {
+			return Node{}, s.version, err
+		}
+
+		c.loads.Lock()
+		defer c.loads.Unlock()
+		return c.bounded(c.search(c.hashStr(key))), s.version, nil
+	}
+
+	v := c.Current()
+	node, err := v.Get(key)
+	return node, v.Version(), err
+}
+
+// GetNVersioned 与 GetN 相同, 同时返回做出决定时环的版本
+func (c *Consistent) GetNVersioned(key string, n int) ([]Node, uint64) {
+	s := c.current()
+	return c.getN(s, key, n), s.version
+}
+
+func (v *View) Version() uint64 {
+	return v.s.version
+}
+
+// Get 按这个版本的环查找, 与 Get 相同但不考虑有界负载, 有界负载的结果取决于查找时的负载, 不属于任何版本
+func (v *View) Get(key string) (Node, error) {
+	if node, ok := v.s.pinned(key); ok {
+		return node, nil
+	}
+	if err := v.s.routable(); err != nil {
+		return Node{}, err
+	}
+	return v.s.routes[v.s.search(v.s.hashStr(key))], nil
+}
+
+func (v *View) GetN(key string, n int) []Node {
+	return v.c.getN(v.s, key, n)
+}
+
+// Owns 判断这个版本上 key 是否归 id, 用来检查按旧版本做出的决定: 例如 v.Owns(key, id) 而 c.Current().Owns(key, id) 为 false 时,
+// 说明决定之后 key 已经换了节点
+func (v *View) Owns(key string, id int) bool {
+	node, err := v.Get(key)
+	return err == nil && node.Id == id
+}
+
+// Stale 判断按版本 v 做出的 key 的决定在当前版本上是否已经换了节点; v 不再保留时返回 true, 调用方应当重新查找
+func (c *Consistent) Stale(key string, v uint64) bool {
+	cur := c.Current()
+	if cur.Version() == v {
+		return false
+	}
+	old, err := c.AtVersion(v)
+	if err != nil {
+		return true
+	}
+	a, errA := old.Get(key)
+	b, errB := cur.Get(key)
+	return errA != nil || errB != nil || a.Id != b.Id
+}
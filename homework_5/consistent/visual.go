@@ -0,0 +1,220 @@
+package consistent
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"sort"
+)
+
+const (
+	SVG_SIZE   = 640
+	SVG_LEGEND = 280
+)
+
+// PALETTE 按 Id 顺序给物理节点配色, 节点比颜色多时循环使用
+var PALETTE = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948",
+	"#b07aa1", "#ff9da7", "#9c755f", "#bab0ac", "#1f77b4", "#17becf",
+}
+
+// visualNode 是图里的一个物理节点
+type visualNode struct {
+	Node
+	color    string
+	share    float64
+	draining bool
+	down     bool
+}
+
+// visual 是导出图用的一份数据, 来自同一个版本的环
+type visual struct {
+	nodes  []*visualNode
+	byId   map[int]*visualNode
+	ranges []Range
+	points HashRing
+	owners []Node
+	space  float64
+}
+
+func (c *Consistent) visual() *visual {
+	c.RLock()
+	s := c.current()
+	v := &visual{byId: make(map[int]*visualNode, len(c.members)), points: s.ring, owners: s.owners, space: c.Space()}
+	for id, node := range c.members {
+		vn := &visualNode{Node: node, draining: c.draining[id], down: c.down[id]}
+		v.nodes = append(v.nodes, vn)
+		v.byId[id] = vn
+	}
+	c.RUnlock()
+
+	sort.Slice(v.nodes, func(i, j int) bool {
+		return v.nodes[i].Id < v.nodes[j].Id
+	})
+	for i, vn := range v.nodes {
+		vn.color = PALETTE[i%len(PALETTE)]
+	}
+
+	for _, arc := range c.arcs(s) {
+		share := arc.Share(v.space)
+		if vn, ok := v.byId[arc.Node.Id]; ok {
+			vn.share += share
+		}
+		if n := len(v.ranges); n > 0 && v.ranges[n-1].Node.Id == arc.Node.Id {
+			v.ranges[n-1].End = arc.End
+			v.ranges[n-1].Fraction += share
+			continue
+		}
+		v.ranges = append(v.ranges, Range{Start: arc.Start, End: arc.End, Node: arc.Node, Fraction: share})
+	}
+	return v
+}
+
+func (vn *visualNode) state() string {
+	switch {
+	case vn.down:
+		return "down"
+	case vn.draining:
+		return "draining"
+	}
+	return "up"
+}
+
+// ExportDOT 输出 Graphviz 的 DOT, 用 circo 布局画成一个环: 每段连续归同一个节点的区间是一个点, 大小与 key 比例成正比,
+// 颜色是区间所属的物理节点; 旁边每个物理节点一个方框, 写着比例和状态, draining 的是虚线框, MarkDown 的是灰色
+func (c *Consistent) ExportDOT(w io.Writer) error {
+	v := c.visual()
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "graph ring {")
+	fmt.Fprintln(bw, "\tlayout=circo;")
+	fmt.Fprintln(bw, "\tnode [shape=circle, style=filled, fontname=\"sans-serif\", fontsize=9, fixedsize=true];")
+	for i, r := range v.ranges {
+		color := "#cccccc"
+		if vn, ok := v.byId[r.Node.Id]; ok {
+			color = vn.color
+		}
+		// 最小的区间也要能看见, 直径按比例的平方根缩放, 面积与比例成正比
+		size := 0.2 + 2*math.Sqrt(r.Fraction)
+		fmt.Fprintf(bw, "\tr%d [label=\"%d\\n%.1f%%\", fillcolor=\"%s\", width=%.2f, tooltip=\"[%d, %d]\"];\n",
+			i, r.Node.Id, r.Fraction*100, color, size, r.Start, r.End)
+	}
+	for i := range v.ranges {
+		if len(v.ranges) > 1 {
+			fmt.Fprintf(bw, "\tr%d -- r%d;\n", i, (i+1)%len(v.ranges))
+		}
+	}
+
+	fmt.Fprintln(bw, "\tsubgraph cluster_nodes {")
+	fmt.Fprintln(bw, "\t\tlabel=\"nodes\";")
+	for _, vn := range v.nodes {
+		style, color := "filled", vn.color
+		if vn.draining {
+			style = "filled,dashed"
+		}
+		if vn.down {
+			color = "#999999"
+		}
+		fmt.Fprintf(bw, "\t\tn%d [shape=box, fixedsize=false, style=\"%s\", fillcolor=\"%s\", label=\"%d %s:%d\\nweight %d, %.2f%%, %s\"];\n",
+			vn.Id, style, color, vn.Id, vn.Ip, vn.Port, vn.Weight, vn.share*100, vn.state())
+	}
+	fmt.Fprintln(bw, "\t}")
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// polar 把环上的位置换成圆上的坐标, 0 在正上方, 顺时针增大
+func (v *visual) polar(pos float64, radius float64) (float64, float64) {
+	angle := pos/v.space*2*math.Pi - math.Pi/2
+	center := float64(SVG_SIZE) / 2
+	return center + radius*math.Cos(angle), center + radius*math.Sin(angle)
+}
+
+// ExportSVG 输出一张 SVG: 内圈的环按区间分段, 每段的弧长就是它的 key 比例, 颜色是路由到的节点 (draining 和 MarkDown 的节点的区间
+// 画成接管它的节点的颜色); 外圈每条刻度是一个虚拟节点, 颜色是它所属的节点, MarkDown 的节点的刻度是灰色; 右边是图例
+func (c *Consistent) ExportSVG(w io.Writer) error {
+	v := c.visual()
+	bw := bufio.NewWriter(w)
+
+	height := SVG_SIZE
+	if h := 60 + 20*len(v.nodes); h > height {
+		height = h
+	}
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"12\">\n", SVG_SIZE+SVG_LEGEND, height)
+
+	outer, inner := float64(SVG_SIZE)/2-40, float64(SVG_SIZE)/2-110
+	center := float64(SVG_SIZE) / 2
+	if len(v.ranges) == 0 {
+		fmt.Fprintf(bw, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"%.1f\" fill=\"none\" stroke=\"#cccccc\" stroke-width=\"%.1f\"/>\n", center, center, (outer+inner)/2, outer-inner)
+		fmt.Fprintf(bw, "<text x=\"%.1f\" y=\"%.1f\" text-anchor=\"middle\">empty ring</text>\n", center, center)
+	}
+	for _, r := range v.ranges {
+		color := "#cccccc"
+		if vn, ok := v.byId[r.Node.Id]; ok {
+			color = vn.color
+		}
+		title := fmt.Sprintf("node %d [%d, %d] %.3f%%", r.Node.Id, r.Start, r.End, r.Fraction*100)
+		if r.Fraction >= 1 {
+			fmt.Fprintf(bw, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"%.1f\" fill=\"none\" stroke=\"%s\" stroke-width=\"%.1f\"><title>%s</title></circle>\n",
+				center, center, (outer+inner)/2, color, outer-inner, html.EscapeString(title))
+			continue
+		}
+
+		large := 0
+		if r.Fraction > 0.5 {
+			large = 1
+		}
+		start, end := float64(r.Start), float64(r.End)+1
+		x1, y1 := v.polar(start, outer)
+		x2, y2 := v.polar(end, outer)
+		x3, y3 := v.polar(end, inner)
+		x4, y4 := v.polar(start, inner)
+		fmt.Fprintf(bw, "<path d=\"M%.2f %.2f A%.1f %.1f 0 %d 1 %.2f %.2f L%.2f %.2f A%.1f %.1f 0 %d 0 %.2f %.2f Z\" fill=\"%s\"><title>%s</title></path>\n",
+			x1, y1, outer, outer, large, x2, y2, x3, y3, inner, inner, large, x4, y4, color, html.EscapeString(title))
+	}
+
+	for i, point := range v.points {
+		color := "#cccccc"
+		if vn, ok := v.byId[v.owners[i].Id]; ok {
+			color = vn.color
+			if vn.down {
+				color = "#999999"
+			}
+		}
+		x1, y1 := v.polar(float64(point), outer+4)
+		x2, y2 := v.polar(float64(point), outer+16)
+		fmt.Fprintf(bw, "<line x1=\"%.2f\" y1=\"%.2f\" x2=\"%.2f\" y2=\"%.2f\" stroke=\"%s\" stroke-width=\"1\"/>\n", x1, y1, x2, y2, color)
+	}
+
+	fmt.Fprintf(bw, "<text x=\"%d\" y=\"30\" font-weight=\"bold\">%d nodes, %d virtual nodes</text>\n", SVG_SIZE, len(v.nodes), len(v.points))
+	for i, vn := range v.nodes {
+		y := 50 + 20*i
+		fill, dash := vn.color, ""
+		if vn.down {
+			fill = "#999999"
+		}
+		if vn.draining {
+			dash = " stroke=\"#333333\" stroke-dasharray=\"3,2\""
+		}
+		fmt.Fprintf(bw, "<rect x=\"%d\" y=\"%d\" width=\"14\" height=\"14\" fill=\"%s\"%s/>\n", SVG_SIZE, y, fill, dash)
+		label := fmt.Sprintf("%d %s:%d w=%d %.2f%% %s", vn.Id, vn.Ip, vn.Port, vn.Weight, vn.share*100, vn.state())
+		fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\">%s</text>\n", SVG_SIZE+20, y+12, html.EscapeString(label))
+	}
+
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// ExportHTML 把 ExportSVG 的图放进一个独立的 HTML 页面, 鼠标停在区间上可以看到范围和比例
+func (c *Consistent) ExportHTML(w io.Writer) error {
+	if _, err := io.WriteString(w, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>ring</title></head>\n<body>\n"); err != nil {
+		return err
+	}
+	if err := c.ExportSVG(w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "</body></html>\n")
+	return err
+}
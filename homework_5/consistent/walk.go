@@ -0,0 +1,49 @@
+package consistent
+
+// Successors 从 key 的位置顺时针依次把不同的物理节点交给 fn, fn 返回 false 或者所有节点都给过一遍时停止.
+// 顺序与 GetN 相同: 被 Pin 固定的节点最先, draining 和 MarkDown 的节点被跳过; 跳过不健康的、已满的节点,
+// 或者凑够法定数量这类选择规则可以写在 fn 里, 不需要 GetN 先取出固定数量的节点. 整个遍历看到的是同一个版本的环
+func (c *Consistent) Successors(key string, fn func(node Node) bool) {
+	c.successors(c.current(), key, fn)
+}
+
+func (c *Consistent) successors(s *snapshot, key string, fn func(node Node) bool) {
+	if len(s.ring) == 0 {
+		return
+	}
+
+	seen := make(map[int]bool)
+	if node, ok := s.pinned(key); ok {
+		seen[node.Id] = true
+		if !fn(node) {
+			return
+		}
+	}
+	i := s.search(s.hashStr(key))
+	for step := 0; step < len(s.ring) && len(seen) < s.members; step++ {
+		node := s.owners[i]
+		if !seen[node.Id] {
+			seen[node.Id] = true
+			if !s.skip[node.Id] && !fn(node) {
+				return
+			}
+		}
+		i = (i + 1) % len(s.ring)
+	}
+}
+
+// Successors 与 Consistent.Successors 相同, 按这个版本的环遍历
+func (v *View) Successors(key string, fn func(node Node) bool) {
+	v.c.successors(v.s, key, fn)
+}
+
+// Walk 从环的起点按位置顺序把每个虚拟节点和它所属的物理节点交给 fn, fn 返回 false 时停止;
+// 给出的是位置的 owner, 不考虑 draining 和 MarkDown
+func (c *Consistent) Walk(fn func(point uint64, node Node) bool) {
+	s := c.current()
+	for i, point := range s.ring {
+		if !fn(point, s.owners[i]) {
+			return
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package consistent
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	DEFAULT_WARMUP_START = 0.1
+	DEFAULT_WARMUP_STEPS = 10
+)
+
+const (
+	CHANGE_WARMUP = "warmup"
+)
+
+// warmup 是 WithSlowStart 的参数和正在预热的节点, since 在 Consistent 的锁内读写
+type warmup struct {
+	duration time.Duration
+	start    float64
+	since    map[int]time.Time
+	running  bool
+}
+
+// WithSlowStart 让加入非空的环的节点逐步接收流量: 虚拟节点数从 start 比例开始, 在 d 内分 DEFAULT_WARMUP_STEPS 步线性增加到 ReplicaCount,
+// 新节点后面的缓存不会一下子承担全部份额. start 不在 (0, 1) 之间时使用 DEFAULT_WARMUP_START.
+// 每一步只加上缺少的虚拟节点, 只有 key 移到新节点上, 结束后的位置与直接加入完全相同.
+// 空环上加入的节点, 包括 AddBatch 一起加入的, 直接使用全部权重; Snapshot 恢复时也按全部权重
+func WithSlowStart(d time.Duration, start float64) Option {
+	return func(c *Consistent) {
+		if d <= 0 {
+			return
+		}
+		if start <= 0 || start >= 1 {
+			start = DEFAULT_WARMUP_START
+		}
+		c.warm = warmup{duration: d, start: start, since: make(map[int]time.Time)}
+	}
+}
+
+// fraction 返回节点当前的预热比例, 不在预热的节点是 1
+func (w *warmup) fraction(id int, now time.Time) float64 {
+	since, ok := w.since[id]
+	if !ok {
+		return 1
+	}
+	elapsed := now.Sub(since)
+	if elapsed >= w.duration {
+		return 1
+	}
+	return w.start + (1-w.start)*float64(elapsed)/float64(w.duration)
+}
+
+// pointCount 在锁内调用, 返回节点现在应有的虚拟节点数: 预热中的节点按比例减少, 至少保留一个
+func (c *Consistent) pointCount(node Node) int {
+	count := c.replicaCount(node)
+	f := c.warm.fraction(node.Id, time.Now())
+	if f >= 1 || count == 0 {
+		return count
+	}
+	return int(math.Ceil(float64(count) * f))
+}
+
+// startWarmUp 在写锁内、register 之前调用. 预热由环上的后台任务推进, 环已经 Close 时没有任务可以推进, 节点直接使用全部权重
+func (c *Consistent) startWarmUp(id int) {
+	if c.warm.duration <= 0 {
+		return
+	}
+	if !c.warm.running {
+		if err := c.Go(c.warmUp); err != nil {
+			return
+		}
+		c.warm.running = true
+	}
+	c.warm.since[id] = time.Now()
+}
+
+// warmUp 每一步调整一次预热中的节点, 没有预热中的节点时返回; 环关闭时把它们直接恢复到全部权重
+func (c *Consistent) warmUp(ctx context.Context) error {
+	interval := c.warm.duration / DEFAULT_WARMUP_STEPS
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.stepWarmUp(true)
+			return ctx.Err()
+		case <-ticker.C:
+			if !c.stepWarmUp(false) {
+				return nil
+			}
+		}
+	}
+}
+
+// stepWarmUp 把每个预热中的节点调整到当前的比例, 每个节点单独发布一个版本; 返回是否还有节点在预热
+func (c *Consistent) stepWarmUp(finish bool) bool {
+	for _, id := range c.warmingIds() {
+		c.Lock()
+		c.advance(id, finish)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if len(c.warm.since) > 0 {
+		return true
+	}
+	c.warm.running = false
+	return false
+}
+
+func (c *Consistent) warmingIds() []int {
+	c.RLock()
+	defer c.RUnlock()
+
+	ids := make([]int, 0, len(c.warm.since))
+	for id := range c.warm.since {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// advance 在写锁内调用并释放写锁; finish 为 true 时结束预热
+func (c *Consistent) advance(id int, finish bool) bool {
+	node, ok := c.members[id]
+	if !ok {
+		delete(c.warm.since, id)
+		c.Unlock()
+		return false
+	}
+	if _, ok := c.warm.since[id]; !ok {
+		c.Unlock()
+		return false
+	}
+	if finish || c.warm.fraction(id, time.Now()) >= 1 {
+		delete(c.warm.since, id)
+	}
+	if c.pointCount(node) == len(c.points[id]) {
+		c.Unlock()
+		return true
+	}
+
+	prev := c.current()
+	c.resize(node)
+	c.notify(prev, CHANGE_WARMUP, node)
+	return true
+}
+
+// Warming 返回正在预热的节点和它们当前的比例
+func (c *Consistent) Warming() map[int]float64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	now := time.Now()
+	fractions := make(map[int]float64, len(c.warm.since))
+	for id := range c.warm.since {
+		fractions[id] = c.warm.fraction(id, now)
+	}
+	return fractions
+}
+
+// CompleteWarmUp 立即结束节点的预热, 虚拟节点数恢复到 ReplicaCount; 节点不在预热时返回 false
+func (c *Consistent) CompleteWarmUp(id int) bool {
+	c.Lock()
+	return c.advance(id, true)
+}
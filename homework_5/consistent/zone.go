@@ -0,0 +1,75 @@
+package consistent
+
+import (
+	"strconv"
+)
+
+// GetNZoneAware 与 GetN 一样从 key 的位置顺时针找 n 个不同的节点, 但优先选择还没有用到的 zone:
+// 第一轮只接受新 zone 的节点, zone 用完之后第二轮接受新 rack 的节点, 最后按顺时针顺序补齐, 所以 zone 不够时仍然返回 n 个节点.
+// Zone 为空的节点各自算一个 zone, Rack 为空的节点各自算一个 rack. 第一个节点与 Get 的结果相同
+func (c *Consistent) GetNZoneAware(key string, n int) []Node {
+	s := c.current()
+
+	if n > s.members-len(s.skip) {
+		n = s.members - len(s.skip)
+	}
+
+	if n < 0 {
+		n = 0
+	}
+	nodes := make([]Node, 0, n)
+	if n <= 0 || len(s.ring) == 0 {
+		return nodes
+	}
+
+	// order 是顺时针遇到的不同节点, 每个节点只看第一次出现的位置
+	order := make([]Node, 0, s.members)
+	seen := make(map[int]bool, s.members)
+	i := s.search(s.hashStr(key))
+	for step := 0; step < len(s.ring) && len(order) < s.members-len(s.skip); step++ {
+		node := s.owners[i]
+		if !seen[node.Id] && !s.skip[node.Id] {
+			seen[node.Id] = true
+			order = append(order, node)
+		}
+		i = (i + 1) % len(s.ring)
+	}
+
+	zones := make(map[string]bool, n)
+	racks := make(map[string]bool, n)
+	picked := make(map[int]bool, n)
+	pick := func(accept func(node Node) bool) {
+		for _, node := range order {
+			if len(nodes) == n {
+				return
+			}
+			if picked[node.Id] || !accept(node) {
+				continue
+			}
+			picked[node.Id] = true
+			zones[zoneKey(node)] = true
+			racks[rackKey(node)] = true
+			nodes = append(nodes, node)
+		}
+	}
+
+	pick(func(node Node) bool { return !zones[zoneKey(node)] })
+	pick(func(node Node) bool { return !racks[rackKey(node)] })
+	pick(func(node Node) bool { return true })
+	return nodes
+}
+
+// zoneKey 和 rackKey 给没有 zone 或 rack 的节点一个只属于自己的值; rack 名只在 zone 内唯一
+func zoneKey(node Node) string {
+	if node.Zone == "" {
+		return "\x00" + strconv.Itoa(node.Id)
+	}
+	return node.Zone
+}
+
+func rackKey(node Node) string {
+	if node.Rack == "" {
+		return "\x00" + strconv.Itoa(node.Id)
+	}
+	return node.Zone + "/" + node.Rack
+}
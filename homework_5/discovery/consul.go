@@ -0,0 +1,121 @@
+package discovery
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	CONSUL_WAIT  = 5 * time.Minute
+	CONSUL_RETRY = time.Second
+)
+
+// ConsulHealth 是 (*api.Client).Health() 中用到的部分
+type ConsulHealth interface {
+	Service(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error)
+}
+
+// ConsulSource 用阻塞查询监听 Service 的健康实例.
+// 节点 Id 取 Meta["id"], 没有时用服务实例 ID 的 FNV 哈希, 所有网关算出的结果相同;
+// 权重依次取 Meta["weight"]、"weight=N" 形式的 tag 和 Weights.Passing, 都没有时为 1
+type ConsulSource struct {
+	Health  ConsulHealth
+	Service string
+	Tag     string
+	Ring    *consistent.Consistent
+	OnSync  func(r Result)
+	// Logger 为空时使用环的 Logger, 查询出错时输出一条日志
+	Logger consistent.Logger
+}
+
+func (s *ConsulSource) logger() consistent.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return s.Ring.Logger()
+}
+
+// Run 一直做阻塞查询直到 ctx 结束, 出错时等 CONSUL_RETRY 后从头开始
+func (s *ConsulSource) Run(ctx context.Context) error {
+	index := uint64(0)
+	for {
+		q := (&api.QueryOptions{WaitIndex: index, WaitTime: CONSUL_WAIT}).WithContext(ctx)
+		entries, meta, err := s.Health.Service(s.Service, s.Tag, true, q)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			s.logger().Warn("consul query failed", "service", s.Service, "error", err)
+			index = 0
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(CONSUL_RETRY):
+			}
+			continue
+		}
+
+		// index 变小说明 Consul 重置了状态, 按文档从 0 重新查询
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+
+		nodes := make([]consistent.Node, 0, len(entries))
+		for _, e := range entries {
+			nodes = append(nodes, consulNode(e))
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].Id < nodes[j].Id
+		})
+
+		if r := Sync(s.Ring, nodes); !r.Empty() && s.OnSync != nil {
+			s.OnSync(r)
+		}
+	}
+}
+
+func consulNode(e *api.ServiceEntry) consistent.Node {
+	svc := e.Service
+	node := consistent.Node{Ip: svc.Address, Port: svc.Port, HostName: svc.ID, Weight: 1}
+	if node.Ip == "" && e.Node != nil {
+		node.Ip = e.Node.Address
+	}
+
+	if id, err := strconv.Atoi(svc.Meta["id"]); err == nil {
+		node.Id = id
+	} else {
+		h := fnv.New32a()
+		h.Write([]byte(svc.ID))
+		node.Id = int(h.Sum32() & 0x7fffffff)
+	}
+
+	if w, ok := consulWeight(svc); ok {
+		node.Weight = w
+	} else if svc.Weights.Passing > 0 {
+		node.Weight = svc.Weights.Passing
+	}
+	return node
+}
+
+func consulWeight(svc *api.AgentService) (int, bool) {
+	if w, err := strconv.Atoi(svc.Meta["weight"]); err == nil && w > 0 {
+		return w, true
+	}
+	for _, tag := range svc.Tags {
+		if v, ok := strings.CutPrefix(tag, "weight="); ok {
+			if w, err := strconv.Atoi(v); err == nil && w > 0 {
+				return w, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,123 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	ETCD_RETRY = time.Second
+)
+
+// EtcdClient 是 *clientv3.Client 中用到的部分
+type EtcdClient interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+}
+
+// EtcdSource 监听 Prefix 下的 key, 每个 key 的值是一个节点的 JSON (与 topology 文件中的节点格式相同),
+// 权重直接写在 weight 字段里. 注册方通常把 key 绑定到带 TTL 的 lease 上, 实例退出后 key 自动消失
+type EtcdSource struct {
+	Client EtcdClient
+	Prefix string
+	Ring   *consistent.Consistent
+	// OnSync 在每次修改环之后调用
+	OnSync func(r Result)
+	// Logger 为空时使用环的 Logger, Watch 出错和节点解析失败时输出一条日志
+	Logger consistent.Logger
+}
+
+func (s *EtcdSource) logger() consistent.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return s.Ring.Logger()
+}
+
+// Run 先全量读取一次, 再从读取时的 revision 开始 Watch, 直到 ctx 结束; Watch 断开或 revision 被压缩时重新全量读取
+func (s *EtcdSource) Run(ctx context.Context) error {
+	for {
+		err := s.watch(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.logger().Warn("etcd watch failed", "prefix", s.Prefix, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(ETCD_RETRY):
+		}
+	}
+}
+
+func (s *EtcdSource) watch(ctx context.Context) error {
+	resp, err := s.Client.Get(ctx, s.Prefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	nodes := make(map[string]consistent.Node, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if node, ok := s.parseNode(kv.Key, kv.Value); ok {
+			nodes[string(kv.Key)] = node
+		}
+	}
+	s.sync(nodes)
+
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for wr := range s.Client.Watch(wctx, s.Prefix, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1)) {
+		if err := wr.Err(); err != nil {
+			return err
+		}
+
+		for _, e := range wr.Events {
+			key := string(e.Kv.Key)
+			if e.Type == clientv3.EventTypeDelete {
+				delete(nodes, key)
+				continue
+			}
+			if node, ok := s.parseNode(e.Kv.Key, e.Kv.Value); ok {
+				nodes[key] = node
+			} else {
+				delete(nodes, key)
+			}
+		}
+		s.sync(nodes)
+	}
+
+	return ctx.Err()
+}
+
+func (s *EtcdSource) sync(nodes map[string]consistent.Node) {
+	list := make([]consistent.Node, 0, len(nodes))
+	for _, node := range nodes {
+		list = append(list, node)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+
+	if r := Sync(s.Ring, list); !r.Empty() && s.OnSync != nil {
+		s.OnSync(r)
+	}
+}
+
+func (s *EtcdSource) parseNode(key, value []byte) (consistent.Node, bool) {
+	node := consistent.Node{}
+	if err := json.Unmarshal(value, &node); err != nil {
+		s.logger().Warn("etcd bad node", "key", string(key), "error", err)
+		return node, false
+	}
+	if node.Weight <= 0 {
+		node.Weight = 1
+	}
+	return node, true
+}
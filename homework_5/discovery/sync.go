@@ -0,0 +1,62 @@
+package discovery
+
+import (
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// Result 是一次 Sync 对环做的修改
+type Result struct {
+	Added   []consistent.Node `json:"added"`
+	Removed []consistent.Node `json:"removed"`
+	Updated []consistent.Node `json:"updated"`
+}
+
+func (r Result) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Updated) == 0
+}
+
+// Sync 让环的成员与 nodes 一致: 多出来的删除, 缺少的加入, 只有权重变化的用 UpdateWeight,
+// 其它字段 (地址、名称、Capacity、Replicas、Zone、Rack、Tags) 变了的先删除再加入. 所有网关对同样的 nodes 调用 Sync 之后得到同样的环
+func Sync(ring *consistent.Consistent, nodes []consistent.Node) Result {
+	r := Result{}
+	want := make(map[int]consistent.Node, len(nodes))
+	for _, node := range nodes {
+		want[node.Id] = node
+	}
+
+	have := make(map[int]consistent.Node)
+	for _, node := range ring.Members() {
+		have[node.Id] = node
+		if _, ok := want[node.Id]; !ok && ring.RemoveByID(node.Id) {
+			r.Removed = append(r.Removed, node)
+		}
+	}
+
+	for _, node := range nodes {
+		old, ok := have[node.Id]
+		switch {
+		case !ok:
+			n := node
+			if ring.Add(&n) {
+				r.Added = append(r.Added, node)
+			}
+		case old.Equal(node):
+		case sameExceptWeight(old, node):
+			if ring.UpdateWeight(node.Id, node.Weight) {
+				r.Updated = append(r.Updated, node)
+			}
+		default:
+			n := node
+			ring.RemoveByID(node.Id)
+			ring.Add(&n)
+			r.Updated = append(r.Updated, node)
+		}
+	}
+
+	return r
+}
+
+func sameExceptWeight(a, b consistent.Node) bool {
+	a.Weight = b.Weight
+	return a.Equal(b)
+}
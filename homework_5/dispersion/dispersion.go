@@ -0,0 +1,253 @@
+package dispersion
+
+import (
+	"hash/crc32"
+	"hash/fnv"
+	"math"
+	"sort"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_BUCKETS = 1024
+	DEFAULT_TOP     = 5
+)
+
+type HashFunc func(b []byte) uint32
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Hashers 可用于对比的 32 位哈希函数, crc32 是 Consistent 的默认值, fnv1a/xxhash/murmur3 对应 consistent.Hashers
+var Hashers = map[string]HashFunc{
+	"crc32": crc32.ChecksumIEEE,
+	"crc32c": func(b []byte) uint32 {
+		return crc32.Checksum(b, castagnoli)
+	},
+	"fnv32": func(b []byte) uint32 {
+		h := fnv.New32()
+		h.Write(b)
+		return h.Sum32()
+	},
+	"fnv32a": func(b []byte) uint32 {
+		h := fnv.New32a()
+		h.Write(b)
+		return h.Sum32()
+	},
+	"fnv1a":   fold(consistent.FNV1a),
+	"xxhash":  fold(consistent.XXHash),
+	"murmur3": fold(consistent.Murmur3),
+}
+
+// fold 与 Consistent 把 64 位哈希放到 32 位环上的方式相同
+func fold(h consistent.Hasher) HashFunc {
+	return func(b []byte) uint32 {
+		v := h.Hash(b)
+		return uint32(v ^ v>>32)
+	}
+}
+
+type Collision struct {
+	Hash  uint32 `json:"hash"`
+	Nodes []int  `json:"nodes"`
+	Label string `json:"label"`
+}
+
+// Gap 是两个相邻虚拟节点之间没有任何点的区间, Owner 是区间之后的点的节点
+type Gap struct {
+	Start uint32  `json:"start"`
+	End   uint32  `json:"end"`
+	Ratio float64 `json:"ratio"`
+	Owner int     `json:"owner"`
+}
+
+// Cluster 是点数明显多于平均值的桶
+type Cluster struct {
+	Start  uint32  `json:"start"`
+	End    uint32  `json:"end"`
+	Points int     `json:"points"`
+	Ratio  float64 `json:"ratio"`
+}
+
+type Config struct {
+	Replicas int
+	Buckets  int
+	Top      int
+	// Label 为空时使用 consistent.DefaultLabel
+	Label consistent.LabelFunc
+}
+
+type Analysis struct {
+	Hasher     string      `json:"hasher"`
+	Points     int         `json:"points"`
+	Distinct   int         `json:"distinct"`
+	Collisions []Collision `json:"collisions"`
+	// ExpectedCollisions 是均匀哈希下的生日冲突期望值
+	ExpectedCollisions float64   `json:"expected_collisions"`
+	MaxGapRatio        float64   `json:"max_gap_ratio"`
+	ExpectedMaxGap     float64   `json:"expected_max_gap_ratio"`
+	Gaps               []Gap     `json:"gaps"`
+	Clusters           []Cluster `json:"clusters"`
+	// ChiSquare 是桶计数的卡方值除以自由度, 均匀分布时接近 1
+	ChiSquare float64  `json:"chi_square"`
+	Poor      bool     `json:"poor"`
+	Reasons   []string `json:"reasons,omitempty"`
+}
+
+type point struct {
+	hash uint32
+	node int
+}
+
+// Analyze 按 cfg.Label 的虚拟节点格式计算所有点, 检查冲突、最大空隙和聚集
+func Analyze(name string, hash HashFunc, nodes []consistent.Node, cfg Config) *Analysis {
+	if cfg.Replicas <= 0 {
+		cfg.Replicas = consistent.DEFAULT_REPLICAS
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = DEFAULT_BUCKETS
+	}
+	if cfg.Top <= 0 {
+		cfg.Top = DEFAULT_TOP
+	}
+	if cfg.Label == nil {
+		cfg.Label = consistent.DefaultLabel
+	}
+
+	a := &Analysis{Hasher: name}
+	owners := make(map[uint32][]int)
+	labels := make(map[uint32]string)
+	points := make([]point, 0)
+	for i := range nodes {
+		node := nodes[i]
+		for j := 0; j < cfg.Replicas*node.Weight; j++ {
+			label := cfg.Label(node, j)
+			h := hash([]byte(label))
+			a.Points++
+
+			if ids, ok := owners[h]; ok {
+				owners[h] = append(ids, node.Id)
+				continue
+			}
+			owners[h] = []int{node.Id}
+			labels[h] = label
+			points = append(points, point{hash: h, node: node.Id})
+		}
+	}
+
+	a.Distinct = len(points)
+	a.ExpectedCollisions = float64(a.Points) * float64(a.Points-1) / (2 * (1 << 32))
+	for h, ids := range owners {
+		if len(ids) > 1 {
+			a.Collisions = append(a.Collisions, Collision{Hash: h, Nodes: ids, Label: labels[h]})
+		}
+	}
+	sort.Slice(a.Collisions, func(i, j int) bool {
+		return a.Collisions[i].Hash < a.Collisions[j].Hash
+	})
+
+	if len(points) == 0 {
+		return a
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+
+	a.gaps(points, cfg.Top)
+	a.clusters(points, cfg.Buckets, cfg.Top)
+	a.judge()
+	return a
+}
+
+func (a *Analysis) gaps(points []point, top int) {
+	mean := float64(1<<32) / float64(len(points))
+	gaps := make([]Gap, 0, len(points))
+	for i, p := range points {
+		prev := points[(i+len(points)-1)%len(points)]
+		start := prev.hash + 1
+		size := uint64(p.hash) - uint64(prev.hash)
+		if i == 0 {
+			size = uint64(p.hash) + (1 << 32) - uint64(prev.hash)
+		}
+		gaps = append(gaps, Gap{Start: start, End: p.hash, Ratio: float64(size) / mean, Owner: p.node})
+	}
+
+	sort.Slice(gaps, func(i, j int) bool {
+		return gaps[i].Ratio > gaps[j].Ratio
+	})
+	if len(gaps) > top {
+		gaps = gaps[:top]
+	}
+
+	a.Gaps = gaps
+	a.MaxGapRatio = gaps[0].Ratio
+	// n 个均匀点的最大间隔约为平均间隔的 ln(n) 倍
+	a.ExpectedMaxGap = math.Max(math.Log(float64(len(points))), 1)
+}
+
+func (a *Analysis) clusters(points []point, buckets, top int) {
+	counts := make([]int, buckets)
+	width := uint64(1<<32) / uint64(buckets)
+	for _, p := range points {
+		b := int(uint64(p.hash) / width)
+		if b >= buckets {
+			b = buckets - 1
+		}
+		counts[b]++
+	}
+
+	mean := float64(len(points)) / float64(buckets)
+	chi := 0.0
+	clusters := make([]Cluster, 0, buckets)
+	for i, n := range counts {
+		d := float64(n) - mean
+		chi += d * d / mean
+
+		end := uint64(i+1)*width - 1
+		if i == buckets-1 {
+			end = math.MaxUint32
+		}
+		clusters = append(clusters, Cluster{Start: uint32(uint64(i) * width), End: uint32(end), Points: n, Ratio: float64(n) / mean})
+	}
+	if buckets > 1 {
+		a.ChiSquare = chi / float64(buckets-1)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].Points > clusters[j].Points
+	})
+	if len(clusters) > top {
+		clusters = clusters[:top]
+	}
+	a.Clusters = clusters
+}
+
+// judge 与均匀哈希的期望值比较, 明显偏离时标记为离散度差
+func (a *Analysis) judge() {
+	if float64(len(a.Collisions)) > 2*a.ExpectedCollisions+2 {
+		a.Reasons = append(a.Reasons, "more collisions than a uniform hash would produce")
+	}
+	if a.MaxGapRatio > 2*a.ExpectedMaxGap {
+		a.Reasons = append(a.Reasons, "largest gap is far above what a uniform hash would leave")
+	}
+	if a.ChiSquare > 2 {
+		a.Reasons = append(a.Reasons, "points cluster in parts of the ring")
+	}
+	a.Poor = len(a.Reasons) > 0
+}
+
+// Compare 用 Hashers 中的每个哈希函数分析同一组节点, 按名称排序
+func Compare(nodes []consistent.Node, cfg Config) []*Analysis {
+	names := make([]string, 0, len(Hashers))
+	for name := range Hashers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := make([]*Analysis, 0, len(names))
+	for _, name := range names {
+		res = append(res, Analyze(name, Hashers[name], nodes, cfg))
+	}
+	return res
+}
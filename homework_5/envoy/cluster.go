@@ -0,0 +1,135 @@
+package envoy
+
+import (
+	"encoding/json"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	CLUSTER_TYPE_URL = "type.googleapis.com/envoy.config.cluster.v3.Cluster"
+
+	LB_RING_HASH = "RING_HASH"
+	LB_MAGLEV    = "MAGLEV"
+
+	DEFAULT_MAGLEV_TABLE_SIZE = 65537
+	DEFAULT_CONNECT_TIMEOUT   = "1s"
+)
+
+// 下面的结构体只覆盖导出需要的字段, 字段名与 envoy v3 proto 的 JSON 形式一致
+
+type SocketAddress struct {
+	Address   string `json:"address"`
+	PortValue int    `json:"port_value"`
+}
+
+type Address struct {
+	SocketAddress SocketAddress `json:"socket_address"`
+}
+
+type Endpoint struct {
+	Address  Address `json:"address"`
+	Hostname string  `json:"hostname,omitempty"`
+}
+
+type LbEndpoint struct {
+	Endpoint            Endpoint `json:"endpoint"`
+	LoadBalancingWeight int      `json:"load_balancing_weight"`
+}
+
+type LocalityLbEndpoints struct {
+	LbEndpoints []LbEndpoint `json:"lb_endpoints"`
+}
+
+type ClusterLoadAssignment struct {
+	ClusterName string                `json:"cluster_name"`
+	Endpoints   []LocalityLbEndpoints `json:"endpoints"`
+}
+
+type RingHashLbConfig struct {
+	MinimumRingSize uint64 `json:"minimum_ring_size"`
+	HashFunction    string `json:"hash_function,omitempty"`
+}
+
+type MaglevLbConfig struct {
+	TableSize uint64 `json:"table_size"`
+}
+
+type Cluster struct {
+	Type             string                `json:"@type,omitempty"`
+	Name             string                `json:"name"`
+	ConnectTimeout   string                `json:"connect_timeout"`
+	DiscoveryType    string                `json:"type"`
+	LbPolicy         string                `json:"lb_policy"`
+	RingHashLbConfig *RingHashLbConfig     `json:"ring_hash_lb_config,omitempty"`
+	MaglevLbConfig   *MaglevLbConfig       `json:"maglev_lb_config,omitempty"`
+	LoadAssignment   ClusterLoadAssignment `json:"load_assignment"`
+}
+
+type Options struct {
+	Name            string
+	LbPolicy        string
+	ConnectTimeout  string
+	MaglevTableSize uint64
+}
+
+// NewCluster 把环的成员导出为 envoy 的 cluster, load_balancing_weight 是节点的虚拟节点数 (ReplicaCount),
+// 与 Replicas、Capacity 覆盖权重时环上的份额一致, RING_HASH 的最小环大小是虚拟节点总数.
+// envoy 要求 load_balancing_weight 至少为 1, 虚拟节点数为 0 的节点和 draining、MarkDown 的节点不导出
+func NewCluster(c *consistent.Consistent, opts Options) *Cluster {
+	if opts.LbPolicy == "" {
+		opts.LbPolicy = LB_RING_HASH
+	}
+	if opts.ConnectTimeout == "" {
+		opts.ConnectTimeout = DEFAULT_CONNECT_TIMEOUT
+	}
+	if opts.MaglevTableSize == 0 {
+		opts.MaglevTableSize = DEFAULT_MAGLEV_TABLE_SIZE
+	}
+
+	members := c.Members()
+	endpoints := make([]LbEndpoint, 0, len(members))
+	totalReplicas := 0
+	for _, node := range members {
+		replicas := c.ReplicaCount(node)
+		if replicas <= 0 || c.IsDraining(node.Id) || c.IsDown(node.Id) {
+			continue
+		}
+		totalReplicas += replicas
+		endpoints = append(endpoints, LbEndpoint{
+			Endpoint: Endpoint{
+				Address:  Address{SocketAddress{Address: node.Ip, PortValue: node.Port}},
+				Hostname: node.HostName,
+			},
+			LoadBalancingWeight: replicas,
+		})
+	}
+
+	cluster := &Cluster{
+		Type:           CLUSTER_TYPE_URL,
+		Name:           opts.Name,
+		ConnectTimeout: opts.ConnectTimeout,
+		DiscoveryType:  "STATIC",
+		LbPolicy:       opts.LbPolicy,
+		LoadAssignment: ClusterLoadAssignment{
+			ClusterName: opts.Name,
+			Endpoints:   []LocalityLbEndpoints{{LbEndpoints: endpoints}},
+		},
+	}
+
+	switch opts.LbPolicy {
+	case LB_MAGLEV:
+		cluster.MaglevLbConfig = &MaglevLbConfig{TableSize: opts.MaglevTableSize}
+	default:
+		cluster.RingHashLbConfig = &RingHashLbConfig{
+			MinimumRingSize: uint64(totalReplicas),
+			HashFunction:    "XX_HASH",
+		}
+	}
+
+	return cluster
+}
+
+func (cl *Cluster) MarshalIndent() ([]byte, error) {
+	return json.MarshalIndent(cl, "", "  ")
+}
@@ -0,0 +1,47 @@
+package envoy
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+)
+
+type DiscoveryResponse struct {
+	VersionInfo string     `json:"version_info"`
+	Resources   []*Cluster `json:"resources"`
+}
+
+// WriteCDS 按 envoy 文件订阅 (path_config_source) 的格式写出 cluster 列表.
+// 文件先写到临时文件再 rename, envoy 只在文件被整体替换时才重新加载.
+func WriteCDS(path string, clusters ...*Cluster) error {
+	resp := &DiscoveryResponse{Resources: clusters}
+
+	body, err := json.Marshal(clusters)
+	if err != nil {
+		return err
+	}
+	resp.VersionInfo = fmt.Sprintf("%08x", crc32.ChecksumIEEE(body))
+
+	data, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cds-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
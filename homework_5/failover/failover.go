@@ -0,0 +1,165 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// Health 是健康/隔离系统, breaker.Router 满足这个接口
+type Health interface {
+	Allow(node consistent.Node) bool
+	Done(node consistent.Node, err error)
+}
+
+type Config struct {
+	// Attempts 是最多尝试的不同节点数
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Retryable 为 nil 时除了 Permanent 和 ctx 的错误都重试
+	Retryable func(err error) bool
+	Health    Health
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Attempts:   3,
+		Backoff:    10 * time.Millisecond,
+		MaxBackoff: time.Second,
+	}
+}
+
+type permanent struct {
+	err error
+}
+
+func (p *permanent) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanent) Unwrap() error {
+	return p.err
+}
+
+// Permanent 标记不应该换节点重试的错误, 例如参数错误
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanent{err}
+}
+
+type Attempt struct {
+	Node consistent.Node
+	Err  error
+}
+
+// Error 是所有尝试都失败后的错误, Unwrap 返回最后一次的错误
+type Error struct {
+	Key      string
+	Attempts []Attempt
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, 0, len(e.Attempts))
+	for _, a := range e.Attempts {
+		parts = append(parts, "node "+strconv.Itoa(a.Node.Id)+": "+a.Err.Error())
+	}
+	return "failover: key " + strconv.Quote(e.Key) + " failed on " + strconv.Itoa(len(e.Attempts)) + " nodes: " + strings.Join(parts, "; ")
+}
+
+func (e *Error) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+var ErrNoCandidates = errors.New("failover: no healthy candidate")
+
+type Executor struct {
+	ring *consistent.Consistent
+	cfg  Config
+}
+
+func NewExecutor(ring *consistent.Consistent, cfg Config) *Executor {
+	if cfg.Attempts <= 0 {
+		cfg.Attempts = 1
+	}
+	return &Executor{ring: ring, cfg: cfg}
+}
+
+func (e *Executor) retryable(err error) bool {
+	var p *permanent
+	if errors.As(err, &p) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if e.cfg.Retryable != nil {
+		return e.cfg.Retryable(err)
+	}
+	return true
+}
+
+// Do 先在 owner 上调用 fn, 遇到可重试的错误按退避时间依次换到环上的下一个不同节点.
+// Health 拒绝的节点直接跳过, 不占用尝试次数
+func (e *Executor) Do(ctx context.Context, key string, fn func(node consistent.Node) error) error {
+	candidates := e.ring.GetN(key, math.MaxInt32)
+	if len(candidates) == 0 {
+		return consistent.ErrEmptyRing
+	}
+
+	res := &Error{Key: key}
+	backoff := e.cfg.Backoff
+	for _, node := range candidates {
+		if len(res.Attempts) >= e.cfg.Attempts {
+			break
+		}
+		if e.cfg.Health != nil && !e.cfg.Health.Allow(node) {
+			continue
+		}
+
+		if len(res.Attempts) > 0 && backoff > 0 {
+			t := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				res.Attempts = append(res.Attempts, Attempt{Node: node, Err: ctx.Err()})
+				return res
+			case <-t.C:
+			}
+			if backoff *= 2; e.cfg.MaxBackoff > 0 && backoff > e.cfg.MaxBackoff {
+				backoff = e.cfg.MaxBackoff
+			}
+		}
+
+		// 不可重试的错误说明节点本身能正常应答, 对健康系统记为成功
+		err := fn(node)
+		retry := err != nil && e.retryable(err)
+		if e.cfg.Health != nil {
+			if retry {
+				e.cfg.Health.Done(node, err)
+			} else {
+				e.cfg.Health.Done(node, nil)
+			}
+		}
+		if err == nil {
+			return nil
+		}
+
+		res.Attempts = append(res.Attempts, Attempt{Node: node, Err: err})
+		if !retry {
+			return res
+		}
+	}
+
+	if len(res.Attempts) == 0 {
+		return ErrNoCandidates
+	}
+	return res
+}
@@ -0,0 +1,135 @@
+package filesd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_INTERVAL = 5 * time.Second
+	DEFAULT_STATE    = "active"
+	STATE_DRAINING   = "draining"
+	STATE_DOWN       = "down"
+)
+
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// Labeler 给节点补充 zone, state 等环本身不知道的标签
+type Labeler func(node consistent.Node) map[string]string
+
+type Writer struct {
+	Path     string
+	Ring     *consistent.Consistent
+	Labels   Labeler
+	Interval time.Duration
+	last     []byte
+}
+
+func NewWriter(path string, ring *consistent.Consistent) *Writer {
+	return &Writer{
+		Path:     path,
+		Ring:     ring,
+		Interval: DEFAULT_INTERVAL,
+	}
+}
+
+// state 是节点的 state 标签, MarkDown 优先于 draining, 都不是时为 DEFAULT_STATE
+func (w *Writer) state(node consistent.Node) string {
+	if w.Ring.IsDown(node.Id) {
+		return STATE_DOWN
+	}
+	if w.Ring.IsDraining(node.Id) {
+		return STATE_DRAINING
+	}
+	return DEFAULT_STATE
+}
+
+func (w *Writer) TargetGroups() []TargetGroup {
+	members := w.Ring.Members()
+	groups := make([]TargetGroup, 0, len(members))
+
+	for _, node := range members {
+		labels := map[string]string{
+			"ring_node_id": strconv.Itoa(node.Id),
+			"ring_host":    node.HostName,
+			"weight":       strconv.Itoa(node.Weight),
+			"state":        w.state(node),
+		}
+		if w.Labels != nil {
+			for k, v := range w.Labels(node) {
+				labels[k] = v
+			}
+		}
+
+		groups = append(groups, TargetGroup{
+			Targets: []string{net.JoinHostPort(node.Ip, strconv.Itoa(node.Port))},
+			Labels:  labels,
+		})
+	}
+
+	return groups
+}
+
+// Write 内容没有变化时不改动文件, 避免 prometheus 无意义地重新加载
+func (w *Writer) Write() (bool, error) {
+	data, err := json.MarshalIndent(w.TargetGroups(), "", "  ")
+	if err != nil {
+		return false, err
+	}
+
+	if bytes.Equal(data, w.last) {
+		return false, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(w.Path), ".file_sd-*.json")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp.Name(), w.Path); err != nil {
+		return false, err
+	}
+
+	w.last = data
+	return true, nil
+}
+
+// Run 定时检查环的成员, 有变化时重写文件, 直到 ctx 结束
+func (w *Writer) Run(ctx context.Context) error {
+	if _, err := w.Write(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := w.Write(); err != nil {
+				return err
+			}
+		}
+	}
+}
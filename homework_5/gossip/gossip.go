@@ -0,0 +1,185 @@
+package gossip
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/hashicorp/memberlist"
+)
+
+const (
+	LEAVE_TIMEOUT  = 5 * time.Second
+	UPDATE_TIMEOUT = 5 * time.Second
+)
+
+// GossipRing 用 memberlist (SWIM) 维护成员: 每个进程把自己的 Node 作为元数据广播出去,
+// 加入、离开和被判定失败的消息传播到所有进程, 各自更新本地的环. 不需要 etcd 之类的外部存储
+type GossipRing struct {
+	*consistent.Consistent
+	// mu 保护 self, 不能嵌入, 否则会遮住 Consistent 的 Lock
+	mu   sync.Mutex
+	self consistent.Node
+	list *memberlist.Memberlist
+}
+
+// NewGossipRing 在 bindAddr (host:port) 上监听 gossip, 通过 seeds 中任意一个可达的地址加入集群.
+// self 是本进程在环上的节点, 权重为 0 时只观察成员变化、不承担 key; seeds 为空时作为第一个节点启动.
+// gossip 自己的错误 (离开失败、元数据无法解析或过大) 输出到 opts 中 consistent.WithLogger 设置的 Logger
+func NewGossipRing(bindAddr string, seeds []string, self consistent.Node, opts ...consistent.Option) (*GossipRing, error) {
+	host, port, err := net.SplitHostPort(bindAddr)
+	if err != nil {
+		return nil, err
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &GossipRing{Consistent: consistent.NewConsistent(opts...), self: self}
+
+	conf := memberlist.DefaultLANConfig()
+	conf.Name = "node-" + strconv.Itoa(self.Id)
+	conf.BindAddr = host
+	conf.BindPort = p
+	conf.AdvertisePort = p
+	conf.Events = &events{g}
+	conf.Delegate = &delegate{g}
+
+	list, err := memberlist.Create(conf)
+	if err != nil {
+		return nil, err
+	}
+	g.list = list
+
+	if len(seeds) > 0 {
+		if _, err := list.Join(seeds); err != nil {
+			list.Shutdown()
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// SetWeight 修改本节点的权重并广播给其它进程
+func (g *GossipRing) SetWeight(weight int) error {
+	g.mu.Lock()
+	g.self.Weight = weight
+	g.mu.Unlock()
+
+	g.UpdateWeight(g.self.Id, weight)
+	return g.list.UpdateNode(UPDATE_TIMEOUT)
+}
+
+// Peers 返回 gossip 层看到的存活成员数, 包括本进程
+func (g *GossipRing) Peers() int {
+	return g.list.NumMembers()
+}
+
+// Leave 通知其它进程本节点主动离开, 然后关闭 gossip; 其它进程会立刻删除本节点, 不用等失败检测超时
+func (g *GossipRing) Leave() error {
+	if err := g.list.Leave(LEAVE_TIMEOUT); err != nil {
+		g.Logger().Warn("gossip leave failed", "node", g.self.Id, "error", err)
+	}
+	return g.list.Shutdown()
+}
+
+func (g *GossipRing) meta() []byte {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	data, _ := json.Marshal(g.self)
+	return data
+}
+
+func (g *GossipRing) parseMeta(n *memberlist.Node) (consistent.Node, bool) {
+	node := consistent.Node{}
+	if err := json.Unmarshal(n.Meta, &node); err != nil {
+		g.Logger().Warn("gossip bad meta", "member", n.Name, "error", err)
+		return node, false
+	}
+	return node, true
+}
+
+// events 把 memberlist 的成员事件应用到环上, memberlist 对本进程自己也会调用 NotifyJoin
+type events struct {
+	g *GossipRing
+}
+
+func (e *events) NotifyJoin(n *memberlist.Node) {
+	if node, ok := e.g.parseMeta(n); ok {
+		e.g.Add(&node)
+	}
+}
+
+func (e *events) NotifyLeave(n *memberlist.Node) {
+	if node, ok := e.g.parseMeta(n); ok {
+		e.g.RemoveByID(node.Id)
+	}
+}
+
+// NotifyUpdate 元数据没变时什么都不做; 只改了 Weight、Capacity、Replicas 时用对应的 Update 方法, 只增删变化的虚拟节点;
+// 地址、Zone、Tags 等其它字段变化时删除后重新加入
+func (e *events) NotifyUpdate(n *memberlist.Node) {
+	node, ok := e.g.parseMeta(n)
+	if !ok {
+		return
+	}
+
+	for _, old := range e.g.Members() {
+		if old.Id != node.Id {
+			continue
+		}
+		if old.Equal(node) {
+			return
+		}
+
+		o := old
+		o.Weight, o.Capacity, o.Replicas = node.Weight, node.Capacity, node.Replicas
+		if o.Equal(node) {
+			if old.Replicas != node.Replicas {
+				e.g.UpdateReplicas(node.Id, node.Replicas)
+			}
+			if old.Capacity != node.Capacity {
+				e.g.UpdateCapacity(node.Id, node.Capacity)
+			}
+			if old.Weight != node.Weight {
+				e.g.UpdateWeight(node.Id, node.Weight)
+			}
+			return
+		}
+		e.g.RemoveByID(node.Id)
+		break
+	}
+	e.g.Add(&node)
+}
+
+// delegate 只用来携带节点元数据, 不发送其它消息
+type delegate struct {
+	g *GossipRing
+}
+
+func (d *delegate) NodeMeta(limit int) []byte {
+	meta := d.g.meta()
+	if len(meta) > limit {
+		d.g.Logger().Error("gossip node meta too large", "bytes", len(meta), "limit", limit)
+		return nil
+	}
+	return meta
+}
+
+func (d *delegate) NotifyMsg([]byte) {}
+
+func (d *delegate) GetBroadcasts(overhead, limit int) [][]byte {
+	return nil
+}
+
+func (d *delegate) LocalState(join bool) []byte {
+	return nil
+}
+
+func (d *delegate) MergeRemoteState(buf []byte, join bool) {}
@@ -0,0 +1,63 @@
+package haproxy
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_BALANCE = "uri"
+	MAX_WEIGHT      = 256
+)
+
+type Backend struct {
+	Name    string
+	Balance string
+	Check   bool
+	Ring    *consistent.Consistent
+}
+
+func NewBackend(name string, ring *consistent.Consistent) *Backend {
+	return &Backend{
+		Name:    name,
+		Balance: DEFAULT_BALANCE,
+		Check:   true,
+		Ring:    ring,
+	}
+}
+
+func ServerName(node consistent.Node) string {
+	if node.HostName != "" {
+		return node.HostName
+	}
+	return "node_" + strconv.Itoa(node.Id)
+}
+
+// haproxy 的权重范围是 0-256
+func serverWeight(node consistent.Node) int {
+	if node.Weight > MAX_WEIGHT {
+		return MAX_WEIGHT
+	}
+	return node.Weight
+}
+
+func (b *Backend) Render() []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "backend %s\n", b.Name)
+	fmt.Fprintf(&buf, "    balance %s\n", b.Balance)
+	buf.WriteString("    hash-type consistent\n")
+
+	for _, node := range b.Ring.Members() {
+		fmt.Fprintf(&buf, "    server %s %s:%d weight %d", ServerName(node), node.Ip, node.Port, serverWeight(node))
+		if b.Check {
+			buf.WriteString(" check")
+		}
+		buf.WriteString("\n")
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,167 @@
+package haproxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_TIMEOUT = 2 * time.Second
+)
+
+// Runtime 通过 stats socket 执行 runtime API 命令, 例如 Network="unix" Address="/var/run/haproxy.sock"
+type Runtime struct {
+	Network string
+	Address string
+	Timeout time.Duration
+}
+
+func (r *Runtime) Exec(cmd string) (string, error) {
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = DEFAULT_TIMEOUT
+	}
+
+	conn, err := net.DialTimeout(r.Network, r.Address, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := io.WriteString(conn, cmd+"\n"); err != nil {
+		return "", err
+	}
+
+	out, err := io.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Updater 把环的变化同步到运行中的 haproxy, 不需要 reload; 从环上删除的节点只设为 maint, server 仍然定义在 haproxy 里,
+// 重新加入时改地址、权重之后设回 ready, 不再 add server
+type Updater struct {
+	Backend *Backend
+	Runtime *Runtime
+	known   map[int]consistent.Node
+	maint   map[int]bool
+}
+
+// command 是一条 runtime 命令和它执行成功之后 server 的状态
+type command struct {
+	cmd   string
+	node  consistent.Node
+	maint bool
+}
+
+// haproxy 执行成功时输出的提示, 其他输出都当作错误
+var okReplies = []string{"New server registered", "IP changed from", "port changed from", "no need to change"}
+
+func NewUpdater(b *Backend, r *Runtime) *Updater {
+	known := make(map[int]consistent.Node)
+	for _, node := range b.Ring.Members() {
+		known[node.Id] = node
+	}
+
+	return &Updater{
+		Backend: b,
+		Runtime: r,
+		known:   known,
+		maint:   make(map[int]bool),
+	}
+}
+
+func (u *Updater) commands() []command {
+	b := u.Backend.Name
+	current := make(map[int]bool)
+	cmds := make([]command, 0)
+
+	for _, node := range u.Backend.Ring.Members() {
+		current[node.Id] = true
+		server := b + "/" + ServerName(node)
+
+		old, ok := u.known[node.Id]
+		if !ok {
+			cmds = append(cmds,
+				command{fmt.Sprintf("add server %s %s:%d weight %d", server, node.Ip, node.Port, serverWeight(node)), node, true},
+				command{fmt.Sprintf("enable server %s", server), node, false})
+			continue
+		}
+
+		maint := u.maint[node.Id]
+		if old.Ip != node.Ip || old.Port != node.Port {
+			old.Ip, old.Port = node.Ip, node.Port
+			cmds = append(cmds, command{fmt.Sprintf("set server %s addr %s port %d", server, node.Ip, node.Port), old, maint})
+		}
+		if old.Weight != node.Weight {
+			cmds = append(cmds, command{fmt.Sprintf("set server %s weight %d", server, serverWeight(node)), node, maint})
+		}
+		if maint {
+			cmds = append(cmds, command{fmt.Sprintf("set server %s state ready", server), node, false})
+		}
+	}
+
+	for id, node := range u.known {
+		if !current[id] && !u.maint[id] {
+			cmds = append(cmds, command{fmt.Sprintf("set server %s/%s state maint", b, ServerName(node)), node, true})
+		}
+	}
+
+	return cmds
+}
+
+// Commands 计算从上次同步到现在需要执行的命令
+func (u *Updater) Commands() []string {
+	cmds := u.commands()
+	out := make([]string, 0, len(cmds))
+	for _, c := range cmds {
+		out = append(out, c.cmd)
+	}
+	return out
+}
+
+func okReply(out string) bool {
+	if out == "" {
+		return true
+	}
+	for _, prefix := range okReplies {
+		if strings.HasPrefix(out, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sync 依次执行命令, 每条命令成功后就记下 server 的状态, 中途失败时下次 Sync 从失败的命令继续; 返回已经执行成功的命令
+func (u *Updater) Sync() ([]string, error) {
+	cmds := u.commands()
+	done := make([]string, 0, len(cmds))
+
+	for _, c := range cmds {
+		out, err := u.Runtime.Exec(c.cmd)
+		if err != nil {
+			return done, err
+		}
+		if !okReply(out) {
+			return done, fmt.Errorf("haproxy: %s: %s", c.cmd, out)
+		}
+
+		u.known[c.node.Id] = c.node
+		if c.maint {
+			u.maint[c.node.Id] = true
+		} else {
+			delete(u.maint, c.node.Id)
+		}
+		done = append(done, c.cmd)
+	}
+
+	return done, nil
+}
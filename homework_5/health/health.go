@@ -0,0 +1,260 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+var ErrNodeExists = errors.New("health: node is already on the ring")
+
+const (
+	DEFAULT_INTERVAL       = 5 * time.Second
+	DEFAULT_TIMEOUT        = time.Second
+	DEFAULT_FAIL_THRESHOLD = 3
+	DEFAULT_RISE_THRESHOLD = 2
+)
+
+// Probe 检查一个节点, 返回 nil 表示健康
+type Probe func(ctx context.Context, node consistent.Node) error
+
+// TCPProbe 能在 Timeout 内建立到 ip:port 的 TCP 连接就算健康
+func TCPProbe(ctx context.Context, node consistent.Node) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr(node))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Func 把 func(Node) error 包装成 Probe, f 拿不到 ctx, 超时需要自己控制
+func Func(f func(node consistent.Node) error) Probe {
+	return func(ctx context.Context, node consistent.Node) error {
+		return f(node)
+	}
+}
+
+type Config struct {
+	Interval time.Duration
+	// Timeout 是每次检查的超时, 通过 ctx 传给 Probe
+	Timeout time.Duration
+	// 连续失败 FailThreshold 次后 MarkDown, 之后连续成功 RiseThreshold 次再 MarkUp
+	FailThreshold int
+	RiseThreshold int
+	// Probe 为空时使用 TCPProbe
+	Probe Probe
+	// OnChange 在节点被摘除 (up 为 false) 或恢复之后调用
+	OnChange func(node consistent.Node, up bool, err error)
+	// Logger 为空时使用环的 Logger, 节点被摘除和恢复、AddNode 的检查失败时输出一条日志
+	Logger consistent.Logger
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Interval:      DEFAULT_INTERVAL,
+		Timeout:       DEFAULT_TIMEOUT,
+		FailThreshold: DEFAULT_FAIL_THRESHOLD,
+		RiseThreshold: DEFAULT_RISE_THRESHOLD,
+		Probe:         TCPProbe,
+	}
+}
+
+type state struct {
+	fails    int
+	passes   int
+	down     bool
+	lastErr  error
+	lastSeen time.Time
+}
+
+// Status 是一个节点最近一次检查的结果
+type Status struct {
+	Node      consistent.Node `json:"node"`
+	Up        bool            `json:"up"`
+	Fails     int             `json:"fails"`
+	LastError string          `json:"last_error,omitempty"`
+	LastCheck time.Time       `json:"last_check"`
+}
+
+// Checker 定时检查环上的每个节点, 通过 MarkDown/MarkUp 把不健康的节点从路由中摘掉和恢复, 虚拟节点始终留在环上
+// 环的 MarkDown 状态由 Checker 维护, 手工 MarkDown 的节点检查通过后会被 MarkUp
+type Checker struct {
+	sync.Mutex
+	ring   *consistent.Consistent
+	cfg    Config
+	states map[int]*state
+}
+
+func NewChecker(ring *consistent.Consistent, cfg Config) *Checker {
+	def := DefaultConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = def.Interval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.FailThreshold <= 0 {
+		cfg.FailThreshold = def.FailThreshold
+	}
+	if cfg.RiseThreshold <= 0 {
+		cfg.RiseThreshold = def.RiseThreshold
+	}
+	if cfg.Probe == nil {
+		cfg.Probe = def.Probe
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = ring.Logger()
+	}
+
+	return &Checker{ring: ring, cfg: cfg, states: make(map[int]*state)}
+}
+
+// Run 每个 Interval 检查一轮, 直到 ctx 结束
+func (c *Checker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckOnce 并发检查当前所有成员, 等全部检查完成后返回; 已经离开环的节点的状态会被清掉
+func (c *Checker) CheckOnce(ctx context.Context) {
+	members := c.ring.Members()
+
+	var wg sync.WaitGroup
+	for _, node := range members {
+		wg.Add(1)
+		go func(node consistent.Node) {
+			defer wg.Done()
+
+			pctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+			err := c.cfg.Probe(pctx, node)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			c.record(node, err)
+		}(node)
+	}
+	wg.Wait()
+
+	c.Lock()
+	defer c.Unlock()
+
+	seen := make(map[int]bool, len(members))
+	for _, node := range members {
+		seen[node.Id] = true
+	}
+	for id := range c.states {
+		if !seen[id] {
+			delete(c.states, id)
+		}
+	}
+}
+
+// Check 立即检查一次节点, 超时取 ctx 和 Timeout 中较早的一个; 结果不计入节点的状态
+func (c *Checker) Check(ctx context.Context, node consistent.Node) error {
+	pctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	return c.cfg.Probe(pctx, node)
+}
+
+// AddNode 先检查节点, 检查通过后才把它加入环, 新节点不会在第一轮检查之前就接收流量; 检查失败或 ctx 结束时返回错误, 环不变
+func (c *Checker) AddNode(ctx context.Context, node *consistent.Node) error {
+	if err := c.Check(ctx, *node); err != nil {
+		c.cfg.Logger.Warn("node rejected", "node", node.Id, "addr", addr(*node), "error", err)
+		return fmt.Errorf("health: node %d: %w", node.Id, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !c.ring.Add(node) {
+		return ErrNodeExists
+	}
+
+	c.Lock()
+	c.states[node.Id] = &state{passes: 1, lastSeen: time.Now()}
+	c.Unlock()
+	return nil
+}
+
+func addr(node consistent.Node) string {
+	return net.JoinHostPort(node.Ip, strconv.Itoa(node.Port))
+}
+
+func (c *Checker) record(node consistent.Node, err error) {
+	c.Lock()
+	s, ok := c.states[node.Id]
+	if !ok {
+		s = &state{}
+		c.states[node.Id] = s
+	}
+	s.lastErr, s.lastSeen = err, time.Now()
+
+	if err != nil {
+		s.fails++
+		s.passes = 0
+		if s.fails >= c.cfg.FailThreshold {
+			s.down = true
+		}
+	} else {
+		s.passes++
+		if !s.down || s.passes >= c.cfg.RiseThreshold {
+			s.down, s.fails = false, 0
+		}
+	}
+	down, fails := s.down, s.fails
+	c.Unlock()
+
+	// 与环上的状态比较而不是与上一次的结果比较, 节点被删除后重新加入时也能重新摘除
+	if down == c.ring.IsDown(node.Id) {
+		return
+	}
+	if down {
+		c.ring.MarkDown(node.Id)
+		c.cfg.Logger.Warn("node down", "node", node.Id, "addr", addr(node), "fails", fails, "error", err)
+	} else {
+		c.ring.MarkUp(node.Id)
+		c.cfg.Logger.Info("node up", "node", node.Id, "addr", addr(node))
+	}
+	if c.cfg.OnChange != nil {
+		c.cfg.OnChange(node, !down, err)
+	}
+}
+
+// Statuses 按 Id 顺序返回每个节点最近一次的检查结果, 还没检查过的节点不在其中
+func (c *Checker) Statuses() []Status {
+	members := c.ring.Members()
+
+	c.Lock()
+	defer c.Unlock()
+
+	statuses := make([]Status, 0, len(members))
+	for _, node := range members {
+		s, ok := c.states[node.Id]
+		if !ok {
+			continue
+		}
+		st := Status{Node: node, Up: !s.down, Fails: s.fails, LastCheck: s.lastSeen}
+		if s.lastErr != nil {
+			st.LastError = s.lastErr.Error()
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
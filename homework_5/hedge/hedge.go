@@ -0,0 +1,94 @@
+package hedge
+
+import (
+	"context"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_DELAY  = 50 * time.Millisecond
+	DEFAULT_HEDGES = 1
+)
+
+type Config struct {
+	// Delay 是发出下一个对冲请求前等待的时间, 通常取 p95 延迟
+	Delay time.Duration
+	// Hedges 是除了 owner 之外最多再发几个请求
+	Hedges int
+}
+
+func DefaultConfig() Config {
+	return Config{Delay: DEFAULT_DELAY, Hedges: DEFAULT_HEDGES}
+}
+
+type result[T any] struct {
+	value T
+	node  consistent.Node
+	err   error
+}
+
+// Do 先请求 owner, 超过 Delay 没有返回 (或者已经失败) 就按 GetN 的顺序请求下一个副本,
+// 这些节点最可能持有数据. 第一个成功的结果返回后取消其余请求; 全部失败时返回最后一个错误.
+func Do[T any](ctx context.Context, ring *consistent.Consistent, cfg Config, key string, fn func(ctx context.Context, node consistent.Node) (T, error)) (T, consistent.Node, error) {
+	var zero T
+
+	candidates := ring.GetN(key, cfg.Hedges+1)
+	if len(candidates) == 0 {
+		return zero, consistent.Node{}, consistent.ErrEmptyRing
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result[T], len(candidates))
+	launch := func(node consistent.Node) {
+		go func() {
+			v, err := fn(ctx, node)
+			results <- result[T]{value: v, node: node, err: err}
+		}()
+	}
+
+	launch(candidates[0])
+	next, pending := 1, 1
+
+	timer := time.NewTimer(cfg.Delay)
+	defer timer.Stop()
+
+	var last result[T]
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if next < len(candidates) {
+				launch(candidates[next])
+				next++
+				pending++
+				timer.Reset(cfg.Delay)
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.value, r.node, nil
+			}
+			last = r
+			// 失败了不必再等 Delay, 直接请求下一个副本
+			if next < len(candidates) {
+				launch(candidates[next])
+				next++
+				pending++
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(cfg.Delay)
+			}
+		case <-ctx.Done():
+			return zero, consistent.Node{}, ctx.Err()
+		}
+	}
+
+	return zero, last.node, last.err
+}
@@ -0,0 +1,279 @@
+package hotkey
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_WINDOW    = 10 * time.Second
+	DEFAULT_THRESHOLD = 1000
+	DEFAULT_SPREAD    = 3
+	DEFAULT_WIDTH     = 2048
+	DEFAULT_DEPTH     = 4
+	DEFAULT_TOP_K     = 64
+)
+
+type Config struct {
+	// Threshold 是每秒的请求数, 一个窗口内的估计速率超过它的 key 被认为是热点
+	Threshold float64
+	// Window 是统计窗口, 每个窗口结束时计数清零, 热点在下一个窗口速率回落到阈值以下后才解除
+	Window time.Duration
+	// Spread 是热点 key 分散到的节点数, 包括原来的 owner
+	Spread int
+	// Width 和 Depth 是 count-min sketch 的大小, 误差大约是窗口内总请求数的 e/Width
+	Width int
+	Depth int
+	// TopK 是最多同时记住的热点数, 超过时保留速率最高的
+	TopK int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Threshold: DEFAULT_THRESHOLD,
+		Window:    DEFAULT_WINDOW,
+		Spread:    DEFAULT_SPREAD,
+		Width:     DEFAULT_WIDTH,
+		Depth:     DEFAULT_DEPTH,
+		TopK:      DEFAULT_TOP_K,
+	}
+}
+
+// HotKey 是当前的一个热点, Rate 是最近一个窗口的估计速率 (次/秒), Nodes 是它被分散到的节点
+type HotKey struct {
+	Key   string            `json:"key"`
+	Rate  float64           `json:"rate"`
+	Since time.Time         `json:"since"`
+	Nodes []consistent.Node `json:"nodes"`
+}
+
+type hot struct {
+	count int64
+	last  int64
+	since time.Time
+	next  uint64
+}
+
+// Tracker 在 Get 的路径上用 count-min sketch 统计 key 的访问次数, 超过阈值的 key 之后的请求轮流用加盐的 key
+// (key#1, key#2 ...) 在环上查找, 分散到最多 Spread 个节点; 不热的 key 与 ring.Get 的结果完全相同.
+// 热点 key 的数据需要在这几个节点上都能读到 (只读缓存或者多写), Tracker 只负责选节点
+type Tracker struct {
+	sync.Mutex
+	ring   *consistent.Consistent
+	cfg    Config
+	sketch [][]int64
+	hot    map[string]*hot
+	start  time.Time
+	now    func() time.Time
+}
+
+func NewTracker(ring *consistent.Consistent, cfg Config) *Tracker {
+	def := DefaultConfig()
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = def.Threshold
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = def.Window
+	}
+	if cfg.Spread <= 0 {
+		cfg.Spread = def.Spread
+	}
+	if cfg.Width <= 0 {
+		cfg.Width = def.Width
+	}
+	if cfg.Depth <= 0 {
+		cfg.Depth = def.Depth
+	}
+	if cfg.TopK <= 0 {
+		cfg.TopK = def.TopK
+	}
+
+	t := &Tracker{ring: ring, cfg: cfg, sketch: make([][]int64, cfg.Depth), hot: make(map[string]*hot), now: time.Now}
+	for i := range t.sketch {
+		t.sketch[i] = make([]int64, cfg.Width)
+	}
+	t.start = t.now()
+	return t
+}
+
+// salted 是热点 key 的第 i 份, 第 0 份就是 key 本身, 所以原来的 owner 仍然分到一份
+func salted(key string, i int) string {
+	if i == 0 {
+		return key
+	}
+	return key + "#" + strconv.Itoa(i)
+}
+
+// Get 记录一次访问并返回节点
+func (t *Tracker) Get(key string) (consistent.Node, error) {
+	salt := t.record(key)
+	return t.ring.Get(salted(key, salt))
+}
+
+// record 计数, 返回这次请求用的盐, 不热的 key 返回 0
+func (t *Tracker) record(key string) int {
+	t.Lock()
+	defer t.Unlock()
+
+	t.roll()
+
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := uint32(sum), uint32(sum>>32)
+
+	// 每行用 h1+i*h2 作为独立的哈希, 只增加最小的计数 (conservative update), 估计值仍然不小于真实次数
+	est := int64(-1)
+	cells := make([]*int64, len(t.sketch))
+	for i, row := range t.sketch {
+		cells[i] = &row[(h1+uint32(i)*h2)%uint32(len(row))]
+		if est < 0 || *cells[i] < est {
+			est = *cells[i]
+		}
+	}
+	est++
+	for _, cell := range cells {
+		if *cell < est {
+			*cell = est
+		}
+	}
+
+	if e, ok := t.hot[key]; ok {
+		e.count = est
+		e.next++
+		return int(e.next % uint64(t.cfg.Spread))
+	}
+
+	if float64(est) > t.cfg.Threshold*t.cfg.Window.Seconds() {
+		t.promote(key, est)
+	}
+	return 0
+}
+
+// promote 把 key 加入热点, 热点已满时替换掉计数最小的那个, 新 key 比它还小时不加入
+func (t *Tracker) promote(key string, count int64) {
+	if len(t.hot) >= t.cfg.TopK {
+		min, minKey := int64(-1), ""
+		for k, e := range t.hot {
+			if c := e.count + e.last; min < 0 || c < min {
+				min, minKey = c, k
+			}
+		}
+		if count <= min {
+			return
+		}
+		delete(t.hot, minKey)
+	}
+	t.hot[key] = &hot{count: count, since: t.now()}
+}
+
+// roll 在窗口结束时清零, 上一个窗口里没有超过阈值的热点被解除
+func (t *Tracker) roll() {
+	now := t.now()
+	if now.Sub(t.start) < t.cfg.Window {
+		return
+	}
+
+	limit := t.cfg.Threshold * t.cfg.Window.Seconds()
+	for key, e := range t.hot {
+		if float64(e.count) <= limit {
+			delete(t.hot, key)
+			continue
+		}
+		e.last, e.count = e.count, 0
+	}
+	for _, row := range t.sketch {
+		for i := range row {
+			row[i] = 0
+		}
+	}
+	t.start = now
+}
+
+// rate 是热点最近一个窗口的速率, 当前窗口刚开始时用上一个窗口的计数
+func (t *Tracker) rate(e *hot) float64 {
+	elapsed := t.now().Sub(t.start)
+	if e.last > 0 && elapsed < t.cfg.Window/2 {
+		return float64(e.last) / t.cfg.Window.Seconds()
+	}
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(e.count) / elapsed.Seconds()
+}
+
+// IsHot 判断 key 当前是否被分散
+func (t *Tracker) IsHot(key string) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	t.roll()
+	_, ok := t.hot[key]
+	return ok
+}
+
+// Nodes 返回热点 key 被分散到的不同节点, 不热的 key 只有 owner 一个
+func (t *Tracker) Nodes(key string) []consistent.Node {
+	n := 1
+	if t.IsHot(key) {
+		n = t.cfg.Spread
+	}
+	return t.nodes(key, n)
+}
+
+func (t *Tracker) nodes(key string, n int) []consistent.Node {
+	seen := make(map[int]bool, n)
+	nodes := make([]consistent.Node, 0, n)
+	for i := 0; i < n; i++ {
+		node, err := t.ring.Get(salted(key, i))
+		if err != nil {
+			break
+		}
+		if !seen[node.Id] {
+			seen[node.Id] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Hot 按速率从高到低返回当前的热点
+func (t *Tracker) Hot() []HotKey {
+	t.Lock()
+	t.roll()
+	keys := make([]HotKey, 0, len(t.hot))
+	for key, e := range t.hot {
+		keys = append(keys, HotKey{Key: key, Rate: t.rate(e), Since: e.since})
+	}
+	t.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Rate != keys[j].Rate {
+			return keys[i].Rate > keys[j].Rate
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	for i := range keys {
+		keys[i].Nodes = t.nodes(keys[i].Key, t.cfg.Spread)
+	}
+	return keys
+}
+
+// Reset 清除所有计数和热点
+func (t *Tracker) Reset() {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, row := range t.sketch {
+		for i := range row {
+			row[i] = 0
+		}
+	}
+	t.hot = make(map[string]*hot)
+	t.start = t.now()
+}
@@ -0,0 +1,41 @@
+package httproute
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+var ErrNoRoute = errors.New("httproute: no node in request context")
+
+func Addr(node consistent.Node) string {
+	return net.JoinHostPort(node.Ip, strconv.Itoa(node.Port))
+}
+
+// Dial 连接 Middleware 选中的节点
+func Dial(ctx context.Context, network string) (net.Conn, error) {
+	node, ok := NodeFromContext(ctx)
+	if !ok {
+		return nil, ErrNoRoute
+	}
+
+	var d net.Dialer
+	return d.DialContext(ctx, network, Addr(node))
+}
+
+// URL 把 ref 指向选中的节点, 比如 URL(ctx, "http", r.URL)
+func URL(ctx context.Context, scheme string, ref *url.URL) (*url.URL, error) {
+	node, ok := NodeFromContext(ctx)
+	if !ok {
+		return nil, ErrNoRoute
+	}
+
+	u := *ref
+	u.Scheme = scheme
+	u.Host = Addr(node)
+	return &u, nil
+}
@@ -0,0 +1,81 @@
+package httproute
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// KeyFunc 从请求中取路由 key, 返回空字符串表示这个请求不需要路由
+type KeyFunc func(r *http.Request) string
+
+func Header(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+func Cookie(name string) KeyFunc {
+	return func(r *http.Request) string {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return cookie.Value
+	}
+}
+
+func Query(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	}
+}
+
+func Path() KeyFunc {
+	return func(r *http.Request) string {
+		return r.URL.Path
+	}
+}
+
+type contextKey struct{}
+
+type route struct {
+	key  string
+	node consistent.Node
+}
+
+func NewContext(ctx context.Context, key string, node consistent.Node) context.Context {
+	return context.WithValue(ctx, contextKey{}, route{key: key, node: node})
+}
+
+func NodeFromContext(ctx context.Context) (consistent.Node, bool) {
+	r, ok := ctx.Value(contextKey{}).(route)
+	return r.node, ok
+}
+
+func KeyFromContext(ctx context.Context) (string, bool) {
+	r, ok := ctx.Value(contextKey{}).(route)
+	return r.key, ok
+}
+
+// Middleware 只负责选节点并写入 context, 请求仍交给 next 处理, 可以挂在任意 mux 上
+func Middleware(ring *consistent.Consistent, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			nodes := ring.GetN(k, 1)
+			if len(nodes) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), k, nodes[0])))
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package httproute
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// RemoteAddr 用客户端的 IP 作为 key, 一般放在 First 的最后兜底
+func RemoteAddr() KeyFunc {
+	return func(r *http.Request) string {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+}
+
+// First 依次尝试 keys, 返回第一个非空的 key
+func First(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, key := range keys {
+			if k := key(r); k != "" {
+				return k
+			}
+		}
+		return ""
+	}
+}
+
+// Director 把请求改写到选中的节点, 可以直接赋给已有的 httputil.ReverseProxy.Director;
+// 请求经过了 Middleware 时用 context 里的节点, 否则自己用 key 查环. 没有节点时不改写 Host, 由 ReverseProxy 报错
+func Director(ring *consistent.Consistent, key KeyFunc) func(r *http.Request) {
+	return func(r *http.Request) {
+		node, ok := NodeFromContext(r.Context())
+		if !ok {
+			k := key(r)
+			if k == "" {
+				return
+			}
+			nodes := ring.GetN(k, 1)
+			if len(nodes) == 0 {
+				return
+			}
+			node = nodes[0]
+		}
+
+		r.URL.Scheme = "http"
+		r.URL.Host = Addr(node)
+		if _, ok := r.Header["User-Agent"]; !ok {
+			r.Header.Set("User-Agent", "")
+		}
+	}
+}
+
+// ReverseProxy 按 key 在环上选上游并转发, 取不到 key 时返回 400, 没有可用节点时返回 503, 不会转发到空地址.
+// 上游用 http 访问, 需要 https 或者改写路径时在 Rewrite 里修改
+type ReverseProxy struct {
+	*httputil.ReverseProxy
+	key KeyFunc
+	// Lookup 默认是 ring.Get, 可以换成 metrics.Metrics.Get 之类带统计的版本
+	Lookup func(key string) (consistent.Node, error)
+	// Rewrite 在请求改写到选中的节点之后调用
+	Rewrite func(r *http.Request, node consistent.Node)
+}
+
+func NewReverseProxy(ring *consistent.Consistent, key KeyFunc) *ReverseProxy {
+	p := &ReverseProxy{key: key, Lookup: ring.Get}
+	director := Director(ring, key)
+	p.ReverseProxy = &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			director(r)
+			if p.Rewrite != nil {
+				node, _ := NodeFromContext(r.Context())
+				p.Rewrite(r, node)
+			}
+		},
+	}
+	return p
+}
+
+func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k := p.key(r)
+	if k == "" {
+		http.Error(w, "no routing key", http.StatusBadRequest)
+		return
+	}
+
+	node, err := p.Lookup(k)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	p.ReverseProxy.ServeHTTP(w, r.WithContext(NewContext(r.Context(), k, node)))
+}
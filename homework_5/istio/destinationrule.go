@@ -0,0 +1,131 @@
+package istio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	API_VERSION = "networking.istio.io/v1beta1"
+)
+
+type Metadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type HTTPCookie struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+	Ttl  string `json:"ttl"`
+}
+
+type ConsistentHash struct {
+	HttpHeaderName  string      `json:"httpHeaderName,omitempty"`
+	HttpCookie      *HTTPCookie `json:"httpCookie,omitempty"`
+	MinimumRingSize uint64      `json:"minimumRingSize"`
+}
+
+type LoadBalancer struct {
+	ConsistentHash ConsistentHash `json:"consistentHash"`
+}
+
+type TrafficPolicy struct {
+	LoadBalancer LoadBalancer `json:"loadBalancer"`
+}
+
+type Spec struct {
+	Host          string        `json:"host"`
+	TrafficPolicy TrafficPolicy `json:"trafficPolicy"`
+}
+
+type DestinationRule struct {
+	ApiVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Metadata   Metadata `json:"metadata"`
+	Spec       Spec     `json:"spec"`
+}
+
+// Options 中 Header 和 Cookie 二选一, Cookie 优先
+type Options struct {
+	Name       string
+	Namespace  string
+	Host       string
+	Header     string
+	Cookie     string
+	CookiePath string
+	CookieTtl  string
+}
+
+// NewDestinationRule 的 minimumRingSize 取参与路由的节点 (不含 draining、MarkDown) 的虚拟节点数 (ReplicaCount) 之和,
+// 与进程内环的虚拟节点数一致
+func NewDestinationRule(c *consistent.Consistent, opts Options) *DestinationRule {
+	totalReplicas := 0
+	for _, node := range c.Members() {
+		if c.IsDraining(node.Id) || c.IsDown(node.Id) {
+			continue
+		}
+		totalReplicas += c.ReplicaCount(node)
+	}
+
+	hash := ConsistentHash{
+		MinimumRingSize: uint64(totalReplicas),
+	}
+	if opts.Cookie != "" {
+		ttl := opts.CookieTtl
+		if ttl == "" {
+			ttl = "0s"
+		}
+		hash.HttpCookie = &HTTPCookie{Name: opts.Cookie, Path: opts.CookiePath, Ttl: ttl}
+	} else {
+		hash.HttpHeaderName = opts.Header
+	}
+
+	return &DestinationRule{
+		ApiVersion: API_VERSION,
+		Kind:       "DestinationRule",
+		Metadata:   Metadata{Name: opts.Name, Namespace: opts.Namespace},
+		Spec: Spec{
+			Host:          opts.Host,
+			TrafficPolicy: TrafficPolicy{LoadBalancer: LoadBalancer{ConsistentHash: hash}},
+		},
+	}
+}
+
+func (d *DestinationRule) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+func (d *DestinationRule) YAML() []byte {
+	var buf bytes.Buffer
+	hash := d.Spec.TrafficPolicy.LoadBalancer.ConsistentHash
+
+	fmt.Fprintf(&buf, "apiVersion: %s\n", d.ApiVersion)
+	fmt.Fprintf(&buf, "kind: %s\n", d.Kind)
+	buf.WriteString("metadata:\n")
+	fmt.Fprintf(&buf, "  name: %s\n", d.Metadata.Name)
+	if d.Metadata.Namespace != "" {
+		fmt.Fprintf(&buf, "  namespace: %s\n", d.Metadata.Namespace)
+	}
+	buf.WriteString("spec:\n")
+	fmt.Fprintf(&buf, "  host: %s\n", d.Spec.Host)
+	buf.WriteString("  trafficPolicy:\n")
+	buf.WriteString("    loadBalancer:\n")
+	buf.WriteString("      consistentHash:\n")
+	if hash.HttpCookie != nil {
+		buf.WriteString("        httpCookie:\n")
+		fmt.Fprintf(&buf, "          name: %s\n", hash.HttpCookie.Name)
+		if hash.HttpCookie.Path != "" {
+			fmt.Fprintf(&buf, "          path: %s\n", hash.HttpCookie.Path)
+		}
+		fmt.Fprintf(&buf, "          ttl: %s\n", hash.HttpCookie.Ttl)
+	} else {
+		fmt.Fprintf(&buf, "        httpHeaderName: %s\n", hash.HttpHeaderName)
+	}
+	fmt.Fprintf(&buf, "        minimumRingSize: %d\n", hash.MinimumRingSize)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,49 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// KgoPartitioner 用于 kgo.RecordPartitioner(kafka.NewKgoPartitioner())
+type KgoPartitioner struct {
+	sync.Mutex
+	topics map[string]*Partitioner
+}
+
+func NewKgoPartitioner() *KgoPartitioner {
+	return &KgoPartitioner{
+		topics: make(map[string]*Partitioner),
+	}
+}
+
+func (k *KgoPartitioner) ForTopic(topic string) kgo.TopicPartitioner {
+	k.Lock()
+	defer k.Unlock()
+
+	p, ok := k.topics[topic]
+	if !ok {
+		p = NewPartitioner(topic)
+		k.topics[topic] = p
+	}
+
+	return &kgoTopicPartitioner{p}
+}
+
+type kgoTopicPartitioner struct {
+	*Partitioner
+}
+
+func (t *kgoTopicPartitioner) RequiresConsistency(r *kgo.Record) bool {
+	return r.Key != nil
+}
+
+func (t *kgoTopicPartitioner) Partition(r *kgo.Record, n int) int {
+	partition, err := t.Partitioner.Partition(r.Key, int32(n))
+	if err != nil {
+		return 0
+	}
+
+	return int(partition)
+}
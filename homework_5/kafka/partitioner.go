@@ -0,0 +1,116 @@
+package kafka
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+var ErrNoPartitions = errors.New("kafka: topic has no partitions")
+
+// Partitioner 把分区号当作环上的节点, 分区数增加时只有新分区会接走一部分 key.
+// 分区数变化时建一个新环整体替换, 查找总是落在某个分区数对应的完整的环上
+type Partitioner struct {
+	sync.Mutex
+	topic string
+	// ring 存的是 *partitionRing, 建好之后不再修改
+	ring atomic.Value
+}
+
+type partitionRing struct {
+	count int32
+	ring  *consistent.Consistent
+}
+
+func NewPartitioner(topic string) *Partitioner {
+	return &Partitioner{topic: topic}
+}
+
+func (p *Partitioner) Topic() string {
+	return p.topic
+}
+
+func (p *Partitioner) Partition(key []byte, numPartitions int32) (int32, error) {
+	if numPartitions <= 0 {
+		return -1, ErrNoPartitions
+	}
+
+	if key == nil {
+		return rand.Int31n(numPartitions), nil
+	}
+
+	node, err := p.load(numPartitions).ring.GetBytes(key)
+	if err != nil {
+		return -1, err
+	}
+	return int32(node.Id), nil
+}
+
+// load 返回 numPartitions 个分区的环, 分区数变了时在锁内建好新环再替换, 同时变化的调用只建一次
+func (p *Partitioner) load(numPartitions int32) *partitionRing {
+	if r, ok := p.ring.Load().(*partitionRing); ok && r.count == numPartitions {
+		return r
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if r, ok := p.ring.Load().(*partitionRing); ok && r.count == numPartitions {
+		return r
+	}
+
+	nodes := make([]*consistent.Node, 0, numPartitions)
+	for i := int32(0); i < numPartitions; i++ {
+		nodes = append(nodes, partitionNode(i))
+	}
+	r := &partitionRing{count: numPartitions, ring: consistent.NewConsistent()}
+	r.ring.AddBatch(nodes)
+	p.ring.Store(r)
+	return r
+}
+
+func partitionNode(partition int32) *consistent.Node {
+	id := int(partition)
+	return consistent.NewNode(id, "partition", 0, "partition-"+strconv.Itoa(id), 1)
+}
+
+// PartitionMap 预先计算 key 到分区的映射, 用于回填数据
+func PartitionMap(keys []string, numPartitions int32) (map[string]int32, error) {
+	p := NewPartitioner("")
+	m := make(map[string]int32, len(keys))
+
+	for _, key := range keys {
+		partition, err := p.Partition([]byte(key), numPartitions)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = partition
+	}
+
+	return m, nil
+}
+
+// MovedKeys 返回分区数从 from 变为 to 时会换分区的 key
+func MovedKeys(keys []string, from, to int32) ([]string, error) {
+	before, err := PartitionMap(keys, from)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := PartitionMap(keys, to)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := make([]string, 0)
+	for _, key := range keys {
+		if before[key] != after[key] {
+			moved = append(moved, key)
+		}
+	}
+
+	return moved, nil
+}
@@ -0,0 +1,31 @@
+package kafka
+
+import (
+	"github.com/IBM/sarama"
+)
+
+type saramaPartitioner struct {
+	*Partitioner
+}
+
+// NewSaramaPartitioner 可以直接赋值给 sarama.Config.Producer.Partitioner
+func NewSaramaPartitioner(topic string) sarama.Partitioner {
+	return &saramaPartitioner{NewPartitioner(topic)}
+}
+
+func (s *saramaPartitioner) Partition(message *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if message.Key == nil {
+		return s.Partitioner.Partition(nil, numPartitions)
+	}
+
+	key, err := message.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+
+	return s.Partitioner.Partition(key, numPartitions)
+}
+
+func (s *saramaPartitioner) RequiresConsistency() bool {
+	return true
+}
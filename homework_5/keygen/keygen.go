@@ -0,0 +1,170 @@
+package keygen
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+const (
+	DEFAULT_ZIPF_S        = 1.1
+	DEFAULT_HOT_KEYS      = 10
+	DEFAULT_HOT_FRACTION  = 0.5
+	DEFAULT_KEYSPACE_SIZE = 100_0000
+	DEFAULT_SEED          = 1
+)
+
+// Generator 产生压测用的 key, 所有实现都可以被多个 goroutine 同时调用
+type Generator interface {
+	Next() string
+}
+
+// seeded 给 rand.Rand 加锁, 同一个 seed 在单 goroutine 下产生同样的序列
+type seeded struct {
+	sync.Mutex
+	rnd  *rand.Rand
+	next func(r *rand.Rand) string
+}
+
+func (g *seeded) Next() string {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.next(g.rnd)
+}
+
+// Func 用调用方提供的函数生成 key
+func Func(seed int64, fn func(r *rand.Rand) string) Generator {
+	return &seeded{rnd: rand.New(rand.NewSource(seed)), next: fn}
+}
+
+func key(i uint64) string {
+	return fmt.Sprintf("key%d", i)
+}
+
+// Uniform 在 key0..key(n-1) 中均匀随机取, n 必须大于 0
+func Uniform(seed int64, n int) (Generator, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("keygen: uniform needs n > 0, got %d", n)
+	}
+
+	return Func(seed, func(r *rand.Rand) string {
+		return key(uint64(r.Intn(n)))
+	}), nil
+}
+
+// Zipf 按 Zipf 分布取 key, s 越大越倾斜, 必须大于 1; n 必须大于 1
+func Zipf(seed int64, s float64, n uint64) (Generator, error) {
+	if !(s > 1) {
+		return nil, fmt.Errorf("keygen: zipf needs s > 1, got %v", s)
+	}
+	if n <= 1 {
+		return nil, fmt.Errorf("keygen: zipf needs n > 1, got %d", n)
+	}
+
+	g := &seeded{rnd: rand.New(rand.NewSource(seed))}
+	zipf := rand.NewZipf(g.rnd, s, 1, n-1)
+	g.next = func(r *rand.Rand) string {
+		return key(zipf.Uint64())
+	}
+	return g, nil
+}
+
+// Hotspot 有 fraction 比例的请求落在前 hot 个 key 上, 其余在剩下的 key 中均匀分布; 需要 0 < hot < n, fraction 在 [0, 1] 之间
+func Hotspot(seed int64, n, hot int, fraction float64) (Generator, error) {
+	if hot <= 0 || n <= hot {
+		return nil, fmt.Errorf("keygen: hotspot needs 0 < hot < n, got hot %d, n %d", hot, n)
+	}
+	if !(fraction >= 0 && fraction <= 1) {
+		return nil, fmt.Errorf("keygen: hotspot needs fraction in [0, 1], got %v", fraction)
+	}
+
+	return Func(seed, func(r *rand.Rand) string {
+		if r.Float64() < fraction {
+			return key(uint64(r.Intn(hot)))
+		}
+		return key(uint64(hot + r.Intn(n-hot)))
+	}), nil
+}
+
+type sequential struct {
+	sync.Mutex
+	i uint64
+}
+
+// Sequential 依次产生 key0, key1, ..., 与 main() 里的做法一样
+func Sequential() Generator {
+	return &sequential{}
+}
+
+func (g *sequential) Next() string {
+	g.Lock()
+	defer g.Unlock()
+
+	k := key(g.i)
+	g.i++
+	return k
+}
+
+func Sample(g Generator, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = g.Next()
+	}
+	return keys
+}
+
+// Source 描述一组样本 key 的来源, 记录在报告里, 在别的机器上可以还原出完全相同的 key
+type Source struct {
+	Name string `json:"name"`
+	Seed int64  `json:"seed"`
+	Keys int    `json:"keys"`
+}
+
+// SequentialSource 是 key0..key(n-1), 与各包里旧的 Key(i) 一致
+func SequentialSource(n int) Source {
+	return Source{Name: "sequential", Seed: DEFAULT_SEED, Keys: n}
+}
+
+// Validate 检查命令行传入的 -keys 和 -keygen, 命令行至少要一个样本 key
+func (s Source) Validate() error {
+	if s.Keys <= 0 {
+		return fmt.Errorf("keygen: keys must be > 0, got %d", s.Keys)
+	}
+	switch s.Name {
+	case "sequential", "", "uniform", "zipf", "hotspot":
+		return nil
+	}
+	return fmt.Errorf("keygen: unknown generator %q", s.Name)
+}
+
+func (s Source) Sample() ([]string, error) {
+	if s.Keys < 0 {
+		return nil, fmt.Errorf("keygen: keys must be >= 0, got %d", s.Keys)
+	}
+	g, err := ByName(s.Name, s.Seed, s.Keys)
+	if err != nil {
+		return nil, err
+	}
+	return Sample(g, s.Keys), nil
+}
+
+// ByName 供命令行使用: sequential, uniform, zipf, hotspot
+func ByName(name string, seed int64, n int) (Generator, error) {
+	if n <= DEFAULT_HOT_KEYS {
+		n = DEFAULT_KEYSPACE_SIZE
+	}
+
+	switch name {
+	case "sequential", "":
+		return Sequential(), nil
+	case "uniform":
+		return Uniform(seed, n)
+	case "zipf":
+		return Zipf(seed, DEFAULT_ZIPF_S, uint64(n))
+	case "hotspot":
+		return Hotspot(seed, n, DEFAULT_HOT_KEYS, DEFAULT_HOT_FRACTION)
+	}
+
+	return nil, fmt.Errorf("keygen: unknown generator %q", name)
+}
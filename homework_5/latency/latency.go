@@ -0,0 +1,139 @@
+package latency
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_ALPHA      = 0.3
+	DEFAULT_CANDIDATES = 3
+	DEFAULT_TOLERANCE  = 1.2
+)
+
+var ErrNoHealthy = errors.New("latency: no healthy candidate")
+
+// Tracker 记录每个节点响应时间的指数加权平均值
+type Tracker struct {
+	sync.RWMutex
+	alpha float64
+	ewma  map[int]float64
+}
+
+func NewTracker(alpha float64) *Tracker {
+	if alpha <= 0 || alpha > 1 {
+		alpha = DEFAULT_ALPHA
+	}
+	return &Tracker{alpha: alpha, ewma: make(map[int]float64)}
+}
+
+func (t *Tracker) ReportLatency(id int, d time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+
+	v := float64(d)
+	if old, ok := t.ewma[id]; ok {
+		v = t.alpha*v + (1-t.alpha)*old
+	}
+	t.ewma[id] = v
+}
+
+// Latency 返回节点的平均响应时间, 没有数据时第二个返回值为 false
+func (t *Tracker) Latency(id int) (time.Duration, bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	v, ok := t.ewma[id]
+	return time.Duration(v), ok
+}
+
+func (t *Tracker) Forget(id int) {
+	t.Lock()
+	defer t.Unlock()
+
+	delete(t.ewma, id)
+}
+
+// Health 过滤不健康的节点, breaker.Router 满足这个接口; 被放行的节点请求结束后由调用方负责 Done
+type Health interface {
+	Allow(node consistent.Node) bool
+}
+
+type Config struct {
+	// Candidates 是参与比较的环上候选数
+	Candidates int
+	// Tolerance 是离开 owner 的门槛: owner 的延迟超过最快候选的 Tolerance 倍才换节点, 尽量保持一致性
+	Tolerance float64
+	Health    Health
+}
+
+func DefaultConfig() Config {
+	return Config{Candidates: DEFAULT_CANDIDATES, Tolerance: DEFAULT_TOLERANCE}
+}
+
+type Router struct {
+	*Tracker
+	ring *consistent.Consistent
+	cfg  Config
+}
+
+func NewRouter(ring *consistent.Consistent, tracker *Tracker, cfg Config) *Router {
+	if cfg.Candidates <= 0 {
+		cfg.Candidates = DEFAULT_CANDIDATES
+	}
+	if cfg.Tolerance < 1 {
+		cfg.Tolerance = 1
+	}
+	return &Router{Tracker: tracker, ring: ring, cfg: cfg}
+}
+
+// Get 在 key 的前 Candidates 个候选中选延迟最低的健康节点.
+// 没有延迟数据的节点视为最快, 这样新节点也能收到请求并积累数据
+func (r *Router) Get(key string) (consistent.Node, error) {
+	candidates := r.ring.GetN(key, r.cfg.Candidates)
+	if len(candidates) == 0 {
+		return consistent.Node{}, consistent.ErrEmptyRing
+	}
+
+	latencies := make(map[int]float64, len(candidates))
+	for _, node := range candidates {
+		d, _ := r.Latency(node.Id)
+		latencies[node.Id] = float64(d)
+	}
+
+	ordered := make([]consistent.Node, len(candidates))
+	copy(ordered, candidates)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return latencies[ordered[i].Id] < latencies[ordered[j].Id]
+	})
+
+	// owner 没有明显变慢时仍然优先使用 owner
+	owner := candidates[0]
+	if latencies[owner.Id] <= latencies[ordered[0].Id]*r.cfg.Tolerance {
+		copy(ordered[1:], without(ordered, owner.Id))
+		ordered[0] = owner
+	}
+
+	// 按顺序询问健康状态, 避免占用没被选中的节点的 half-open 探测名额
+	for _, node := range ordered {
+		if r.cfg.Health == nil || r.cfg.Health.Allow(node) {
+			return node, nil
+		}
+	}
+
+	return consistent.Node{}, ErrNoHealthy
+}
+
+func without(nodes []consistent.Node, id int) []consistent.Node {
+	res := make([]consistent.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Id != id {
+			res = append(res, node)
+		}
+	}
+	return res
+}
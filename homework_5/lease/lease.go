@@ -0,0 +1,126 @@
+package lease
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_TTL = 15 * time.Second
+)
+
+// Registry 把环当作轻量的成员注册表: 节点注册时带 TTL, 需要在 TTL 内 Heartbeat, 过期的节点由 Reap 从环上删除.
+// 只有通过 Registry 注册的节点有租约, 直接 Add 到环上的节点不会过期
+type Registry struct {
+	sync.Mutex
+	ring    *consistent.Consistent
+	ttl     time.Duration
+	expires map[int]time.Time
+	now     func() time.Time
+	// OnExpire 在过期的节点从环上删除之后调用
+	OnExpire func(node consistent.Node)
+}
+
+func NewRegistry(ring *consistent.Consistent, ttl time.Duration) *Registry {
+	if ttl <= 0 {
+		ttl = DEFAULT_TTL
+	}
+	return &Registry{ring: ring, ttl: ttl, expires: make(map[int]time.Time), now: time.Now}
+}
+
+func (r *Registry) TTL() time.Duration {
+	return r.ttl
+}
+
+// Register 把节点加入环并开始租约, 节点已经在环上时返回 false
+func (r *Registry) Register(node *consistent.Node) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if !r.ring.Add(node) {
+		return false
+	}
+	r.expires[node.Id] = r.now().Add(r.ttl)
+	return true
+}
+
+// Heartbeat 把租约延长到现在起的一个 TTL; 没有租约 (从未注册或已经过期被删除) 时返回 false, 需要重新 Register
+func (r *Registry) Heartbeat(id int) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.expires[id]; !ok {
+		return false
+	}
+	r.expires[id] = r.now().Add(r.ttl)
+	return true
+}
+
+// Deregister 主动删除节点, 不触发 OnExpire
+func (r *Registry) Deregister(id int) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.expires[id]; !ok {
+		return false
+	}
+	delete(r.expires, id)
+	return r.ring.RemoveByID(id)
+}
+
+// Expires 返回节点租约的到期时间
+func (r *Registry) Expires(id int) (time.Time, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	t, ok := r.expires[id]
+	return t, ok
+}
+
+// Reap 删除所有已经过期的节点并返回它们; 被别人直接从环上删掉的节点的租约也一起清掉
+func (r *Registry) Reap() []consistent.Node {
+	r.Lock()
+	now := r.now()
+	members := r.ring.Members()
+	seen := make(map[int]bool, len(members))
+	expired := make([]consistent.Node, 0)
+	for _, node := range members {
+		seen[node.Id] = true
+		if t, ok := r.expires[node.Id]; ok && !now.Before(t) {
+			delete(r.expires, node.Id)
+			r.ring.RemoveByID(node.Id)
+			expired = append(expired, node)
+		}
+	}
+	for id := range r.expires {
+		if !seen[id] {
+			delete(r.expires, id)
+		}
+	}
+	r.Unlock()
+
+	if r.OnExpire != nil {
+		for _, node := range expired {
+			r.OnExpire(node)
+		}
+	}
+	return expired
+}
+
+// Run 每 TTL/2 调用一次 Reap, 直到 ctx 结束; 节点过期之后最多再过半个 TTL 就会被删除
+func (r *Registry) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.ttl / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			r.Reap()
+		}
+	}
+}
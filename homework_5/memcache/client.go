@@ -0,0 +1,216 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/connpool"
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_TIMEOUT        = 500 * time.Millisecond
+	DEFAULT_MAX_IDLE       = 4
+	DEFAULT_FAIL_THRESHOLD = 3
+)
+
+type Config struct {
+	// Timeout 是建立连接和每条命令的超时
+	Timeout time.Duration
+	// MaxIdle 和 MaxOpen 是每个服务器的连接池大小, 见 connpool.Config
+	MaxIdle int
+	MaxOpen int
+	// Ketama 为 true 时用 consistent.Ketama 选服务器, 与 libmemcached 等 ketama 客户端查到同样的服务器,
+	// 可以和其它语言的客户端共用一组 memcached
+	Ketama bool
+	// 连续 FailThreshold 次网络错误后 MarkDown 这个服务器, 原来归它的 key 落到其它服务器;
+	// 和 health.Checker (Probe 用本包的 Probe) 一起使用时, 检查通过后由 Checker MarkUp
+	FailThreshold int
+}
+
+func DefaultConfig() Config {
+	return Config{Timeout: DEFAULT_TIMEOUT, MaxIdle: DEFAULT_MAX_IDLE, FailThreshold: DEFAULT_FAIL_THRESHOLD}
+}
+
+// Client 把环上的每个节点当作一个 memcached 服务器, Get/Set/Delete 按 key 路由到对应的服务器,
+// 每个服务器一个连接池. 成员和 MarkDown 都以环为准, 环变化之后自动生效
+type Client struct {
+	sync.Mutex
+	ring   *consistent.Consistent
+	cfg    Config
+	pools  *connpool.Manager[*conn]
+	ketama *consistent.Ketama
+	fails  map[int]int
+	closed bool
+}
+
+func NewClient(ring *consistent.Consistent, cfg Config) *Client {
+	def := DefaultConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = def.MaxIdle
+	}
+	if cfg.FailThreshold <= 0 {
+		cfg.FailThreshold = def.FailThreshold
+	}
+
+	c := &Client{ring: ring, cfg: cfg, fails: make(map[int]int)}
+	c.pools = connpool.NewManager(ring, connpool.Config[*conn]{
+		Dial: func(ctx context.Context, node consistent.Node) (*conn, error) {
+			return dial(ctx, node, cfg.Timeout)
+		},
+		Close: func(cn *conn) error {
+			return cn.Close()
+		},
+		MaxIdle: cfg.MaxIdle,
+		MaxOpen: cfg.MaxOpen,
+	})
+	if cfg.Ketama {
+		c.rebuild()
+	}
+	ring.OnOwnershipChanged(func(changes []consistent.RangeChange) {
+		c.Lock()
+		closed := c.closed
+		c.Unlock()
+		if closed {
+			return
+		}
+
+		c.pools.Sync()
+		if cfg.Ketama {
+			c.rebuild()
+		}
+	})
+	return c
+}
+
+// rebuild 用环上可以路由的节点重建 ketama 环, 与 libmemcached 的 auto eject 一样, 被摘除的服务器不在环上
+func (c *Client) rebuild() {
+	k := consistent.NewKetama()
+	for _, node := range c.ring.Members() {
+		if !c.ring.IsDown(node.Id) && !c.ring.IsDraining(node.Id) {
+			n := node
+			k.Add(&n)
+		}
+	}
+
+	c.Lock()
+	c.ketama = k
+	c.Unlock()
+}
+
+// Server 返回 key 所在的服务器
+func (c *Client) Server(key string) (consistent.Node, error) {
+	if !c.cfg.Ketama {
+		return c.ring.Get(key)
+	}
+
+	c.Lock()
+	k := c.ketama
+	c.Unlock()
+	return k.Get(key)
+}
+
+// do 在 key 所在服务器的一个连接上执行 fn, 网络错误时丢弃连接并计数
+func (c *Client) do(ctx context.Context, key string, fn func(cn *conn) error) error {
+	if !validKey(key) {
+		return ErrBadKey
+	}
+	node, err := c.Server(key)
+	if err != nil {
+		return err
+	}
+	p, ok := c.pools.Pool(node.Id)
+	if !ok {
+		c.pools.Sync()
+		if p, ok = c.pools.Pool(node.Id); !ok {
+			return connpool.ErrPoolClosed
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	cn, err := p.Get(ctx)
+	if err != nil {
+		c.failed(node, err)
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	cn.nc.SetDeadline(deadline)
+
+	err = fn(cn)
+	if resumable(err) {
+		p.Put(cn)
+		c.failed(node, nil)
+		return err
+	}
+	p.Discard(cn)
+	c.failed(node, err)
+	return err
+}
+
+// failed 记录服务器连续的网络错误, 达到 FailThreshold 时 MarkDown; err 为 nil 时清零
+func (c *Client) failed(node consistent.Node, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, connpool.ErrPoolExhausted) {
+		return
+	}
+
+	c.Lock()
+	if err == nil {
+		delete(c.fails, node.Id)
+		c.Unlock()
+		return
+	}
+	c.fails[node.Id]++
+	eject := c.fails[node.Id] >= c.cfg.FailThreshold
+	if eject {
+		delete(c.fails, node.Id)
+	}
+	c.Unlock()
+
+	if eject {
+		c.ring.MarkDown(node.Id)
+	}
+}
+
+// Get 没有这个 key 时返回 ErrCacheMiss
+func (c *Client) Get(ctx context.Context, key string) (*Item, error) {
+	var item *Item
+	err := c.do(ctx, key, func(cn *conn) error {
+		var err error
+		item, err = cn.get(key)
+		return err
+	})
+	return item, err
+}
+
+func (c *Client) Set(ctx context.Context, item *Item) error {
+	return c.do(ctx, item.Key, func(cn *conn) error {
+		return cn.set(item)
+	})
+}
+
+// Delete 没有这个 key 时返回 ErrCacheMiss
+func (c *Client) Delete(ctx context.Context, key string) error {
+	return c.do(ctx, key, func(cn *conn) error {
+		return cn.delete(key)
+	})
+}
+
+func (c *Client) Stats() map[int]connpool.Stats {
+	return c.pools.Stats()
+}
+
+// Close 关闭所有连接池, 之后环的变化不再重建连接池
+func (c *Client) Close() {
+	c.Lock()
+	c.closed = true
+	c.Unlock()
+
+	c.pools.Close()
+}
@@ -0,0 +1,201 @@
+package memcache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+var (
+	ErrCacheMiss = errors.New("memcache: cache miss")
+	ErrNotStored = errors.New("memcache: item not stored")
+	ErrBadKey    = errors.New("memcache: key is too long or contains invalid characters")
+	ErrBadReply  = errors.New("memcache: unexpected reply")
+)
+
+const (
+	MAX_KEY_BYTES = 250
+)
+
+// ServerError 是服务器返回的 SERVER_ERROR 或 CLIENT_ERROR, 连接本身仍然可用
+type ServerError string
+
+func (e ServerError) Error() string {
+	return "memcache: " + string(e)
+}
+
+// Item 是一个缓存项, Expiration 是秒数或 unix 时间戳, 与 memcached 协议相同
+type Item struct {
+	Key        string
+	Value      []byte
+	Flags      uint32
+	Expiration int32
+}
+
+// conn 是一个文本协议的连接
+type conn struct {
+	nc net.Conn
+	rw *bufio.ReadWriter
+}
+
+func dial(ctx context.Context, node consistent.Node, timeout time.Duration) (*conn, error) {
+	d := net.Dialer{Timeout: timeout}
+	nc, err := d.DialContext(ctx, "tcp", net.JoinHostPort(node.Ip, strconv.Itoa(node.Port)))
+	if err != nil {
+		return nil, err
+	}
+	return &conn{nc: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))}, nil
+}
+
+func (c *conn) Close() error {
+	return c.nc.Close()
+}
+
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > MAX_KEY_BYTES {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// resumable 判断出错之后连接还能不能放回池里, 只有协议层面的错误可以
+func resumable(err error) bool {
+	var se ServerError
+	return err == nil || err == ErrCacheMiss || err == ErrNotStored || errors.As(err, &se)
+}
+
+func (c *conn) line() (string, error) {
+	s, err := c.rw.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	s = strings.TrimSuffix(s, "\r\n")
+	if strings.HasPrefix(s, "SERVER_ERROR ") || strings.HasPrefix(s, "CLIENT_ERROR ") || s == "ERROR" {
+		return "", ServerError(s)
+	}
+	return s, nil
+}
+
+func (c *conn) get(key string) (*Item, error) {
+	fmt.Fprintf(c.rw, "get %s\r\n", key)
+	if err := c.rw.Flush(); err != nil {
+		return nil, err
+	}
+
+	var item *Item
+	for {
+		s, err := c.line()
+		if err != nil {
+			return nil, err
+		}
+		if s == "END" {
+			break
+		}
+
+		// VALUE <key> <flags> <bytes>
+		f := strings.Fields(s)
+		if len(f) < 4 || f[0] != "VALUE" {
+			return nil, ErrBadReply
+		}
+		flags, err1 := strconv.ParseUint(f[2], 10, 32)
+		size, err2 := strconv.Atoi(f[3])
+		if err1 != nil || err2 != nil || size < 0 {
+			return nil, ErrBadReply
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.rw, buf); err != nil {
+			return nil, err
+		}
+		if !bytes.HasSuffix(buf, []byte("\r\n")) {
+			return nil, ErrBadReply
+		}
+		item = &Item{Key: f[1], Value: buf[:size], Flags: uint32(flags)}
+	}
+
+	if item == nil {
+		return nil, ErrCacheMiss
+	}
+	return item, nil
+}
+
+func (c *conn) set(item *Item) error {
+	fmt.Fprintf(c.rw, "set %s %d %d %d\r\n", item.Key, item.Flags, item.Expiration, len(item.Value))
+	c.rw.Write(item.Value)
+	c.rw.WriteString("\r\n")
+	if err := c.rw.Flush(); err != nil {
+		return err
+	}
+
+	s, err := c.line()
+	switch {
+	case err != nil:
+		return err
+	case s == "STORED":
+		return nil
+	case s == "NOT_STORED":
+		return ErrNotStored
+	}
+	return ErrBadReply
+}
+
+func (c *conn) delete(key string) error {
+	fmt.Fprintf(c.rw, "delete %s\r\n", key)
+	if err := c.rw.Flush(); err != nil {
+		return err
+	}
+
+	s, err := c.line()
+	switch {
+	case err != nil:
+		return err
+	case s == "DELETED":
+		return nil
+	case s == "NOT_FOUND":
+		return ErrCacheMiss
+	}
+	return ErrBadReply
+}
+
+func (c *conn) version() error {
+	fmt.Fprintf(c.rw, "version\r\n")
+	if err := c.rw.Flush(); err != nil {
+		return err
+	}
+
+	s, err := c.line()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(s, "VERSION ") {
+		return ErrBadReply
+	}
+	return nil
+}
+
+// Probe 是给 health.Checker 用的检查, 发送 version 命令
+func Probe(ctx context.Context, node consistent.Node) error {
+	c, err := dial(ctx, node, 0)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		c.nc.SetDeadline(deadline)
+	}
+	return c.version()
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics 导出环的状态和路由统计. 节点数、虚拟节点数和均衡系数在抓取时从环上现算,
+// 查询次数和延迟只统计经过 Get/GetN 的查询, 直接调用环的查询不计入
+type Metrics struct {
+	ring *consistent.Consistent
+
+	nodes   *prometheus.Desc
+	vnodes  *prometheus.Desc
+	balance *prometheus.Desc
+
+	lookups    *prometheus.CounterVec
+	rebalances prometheus.Counter
+	moved      prometheus.Counter
+	latency    prometheus.Histogram
+}
+
+// New 在 ring 上注册回调统计归属变化, 指标名都以 namespace_ring_ 开头
+func New(ring *consistent.Consistent, namespace string) *Metrics {
+	m := &Metrics{
+		ring: ring,
+		nodes: prometheus.NewDesc(prometheus.BuildFQName(namespace, "ring", "nodes"),
+			"Number of nodes on the ring.", nil, nil),
+		vnodes: prometheus.NewDesc(prometheus.BuildFQName(namespace, "ring", "virtual_nodes"),
+			"Number of virtual nodes on the ring.", nil, nil),
+		balance: prometheus.NewDesc(prometheus.BuildFQName(namespace, "ring", "balance_coefficient"),
+			"Standard deviation divided by mean of the keyspace owned by each routable node.", nil, nil),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "ring",
+			Name:      "lookups_total",
+			Help:      "Lookups routed to each node.",
+		}, []string{"node"}),
+		rebalances: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "ring",
+			Name:      "rebalances_total",
+			Help:      "Membership changes that moved part of the keyspace.",
+		}),
+		moved: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "ring",
+			Name:      "moved_keyspace_total",
+			Help:      "Fraction of the keyspace moved by rebalances, summed over all rebalances.",
+		}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "ring",
+			Name:      "lookup_duration_seconds",
+			Help:      "Latency of ring lookups.",
+			Buckets:   prometheus.ExponentialBuckets(1e-7, 4, 10),
+		}),
+	}
+
+	ring.OnOwnershipChanged(func(changes []consistent.RangeChange) {
+		space := ring.Space()
+		moved := 0.0
+		for _, rc := range changes {
+			moved += (float64(rc.End-rc.Start) + 1) / space
+		}
+		m.rebalances.Inc()
+		m.moved.Add(moved)
+	})
+	// 删除的节点不再出现在查询次数里, 避免 label 只增不减
+	ring.OnNodeRemoved(func(node consistent.Node) {
+		m.lookups.DeleteLabelValues(strconv.Itoa(node.Id))
+	})
+
+	return m
+}
+
+// Register 把全部指标注册到 reg 上, 可以是 prometheus.DefaultRegisterer 或自己的 Registry
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	return reg.Register(m)
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.nodes
+	ch <- m.vnodes
+	ch <- m.balance
+	m.lookups.Describe(ch)
+	m.rebalances.Describe(ch)
+	m.moved.Describe(ch)
+	m.latency.Describe(ch)
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(m.nodes, prometheus.GaugeValue, float64(m.ring.NodeCount()))
+	ch <- prometheus.MustNewConstMetric(m.vnodes, prometheus.GaugeValue, float64(m.ring.VirtualNodeCount()))
+	ch <- prometheus.MustNewConstMetric(m.balance, prometheus.GaugeValue, m.ring.Stats().CV)
+	m.lookups.Collect(ch)
+	m.rebalances.Collect(ch)
+	m.moved.Collect(ch)
+	m.latency.Collect(ch)
+}
+
+// Get 与 Consistent.Get 相同, 同时记录延迟和命中的节点
+func (m *Metrics) Get(key string) (consistent.Node, error) {
+	start := time.Now()
+	node, err := m.ring.Get(key)
+	m.latency.Observe(time.Since(start).Seconds())
+	if err == nil {
+		m.lookups.WithLabelValues(strconv.Itoa(node.Id)).Inc()
+	}
+	return node, err
+}
+
+// GetN 只把第一个节点计入查询次数, 其余的是副本
+func (m *Metrics) GetN(key string, n int) []consistent.Node {
+	start := time.Now()
+	nodes := m.ring.GetN(key, n)
+	m.latency.Observe(time.Since(start).Seconds())
+	if len(nodes) > 0 {
+		m.lookups.WithLabelValues(strconv.Itoa(nodes[0].Id)).Inc()
+	}
+	return nodes
+}
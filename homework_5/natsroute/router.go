@@ -0,0 +1,157 @@
+package natsroute
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	DEFAULT_PREFIX = "work.shard"
+	// SYNC_RETRY 是订阅同步失败后重试的间隔
+	SYNC_RETRY = time.Second
+)
+
+var ErrNoMembers = errors.New("natsroute: ring has no members")
+
+// Router 把 key 映射到 <prefix>.<nodeID> 这样的 subject
+type Router struct {
+	sync.RWMutex
+	prefix      string
+	ring        *consistent.Consistent
+	members     map[int]consistent.Node
+	subscribers []*Subscriber
+	// Logger 为 nil 时使用环的 Logger
+	Logger consistent.Logger
+	// OnError 在成员变化后某个 Subscriber 同步订阅失败时调用, 之后每 SYNC_RETRY 重试直到成功
+	OnError func(s *Subscriber, err error)
+}
+
+func NewRouter(prefix string) *Router {
+	if prefix == "" {
+		prefix = DEFAULT_PREFIX
+	}
+
+	return &Router{
+		prefix:  prefix,
+		ring:    consistent.NewConsistent(),
+		members: make(map[int]consistent.Node),
+	}
+}
+
+func (r *Router) logger() consistent.Logger {
+	if r.Logger != nil {
+		return r.Logger
+	}
+	return r.ring.Logger()
+}
+
+func (r *Router) Add(node *consistent.Node) bool {
+	r.Lock()
+	if !r.ring.Add(node) {
+		r.Unlock()
+		return false
+	}
+	r.members[node.Id] = *node
+	r.Unlock()
+
+	r.resync()
+	return true
+}
+
+func (r *Router) Remove(node *consistent.Node) {
+	r.Lock()
+	if _, ok := r.members[node.Id]; !ok {
+		r.Unlock()
+		return
+	}
+	r.ring.Remove(node)
+	delete(r.members, node.Id)
+	r.Unlock()
+
+	r.resync()
+}
+
+func (r *Router) Get(key string) (consistent.Node, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if len(r.members) == 0 {
+		return consistent.Node{}, false
+	}
+
+	node, err := r.ring.Get(key)
+	return node, err == nil
+}
+
+func (r *Router) Subject(key string) (string, bool) {
+	node, ok := r.Get(key)
+	if !ok {
+		return "", false
+	}
+
+	return r.SubjectFor(node), true
+}
+
+func (r *Router) SubjectFor(node consistent.Node) string {
+	return r.prefix + "." + strconv.Itoa(node.Id)
+}
+
+func (r *Router) QueueGroup(node consistent.Node) string {
+	return r.prefix + ".q." + strconv.Itoa(node.Id)
+}
+
+func (r *Router) Publish(nc *nats.Conn, key string, data []byte) error {
+	subject, ok := r.Subject(key)
+	if !ok {
+		return ErrNoMembers
+	}
+
+	return nc.Publish(subject, data)
+}
+
+// Resync 让所有 Subscriber 按当前的成员重新同步订阅, 返回所有失败的错误; 失败的 Subscriber 会在后台重试
+func (r *Router) Resync() error {
+	return r.resync()
+}
+
+func (r *Router) resync() error {
+	r.RLock()
+	subscribers := append([]*Subscriber(nil), r.subscribers...)
+	r.RUnlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if err := s.Sync(); err != nil {
+			r.syncFailed(s, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Router) syncFailed(s *Subscriber, err error) {
+	r.logger().Warn("natsroute sync failed", "prefix", r.prefix, "error", err)
+	if r.OnError != nil {
+		r.OnError(s, err)
+	}
+	s.scheduleRetry()
+}
+
+func (r *Router) owned(owns func(consistent.Node) bool) map[int]consistent.Node {
+	r.RLock()
+	defer r.RUnlock()
+
+	nodes := make(map[int]consistent.Node)
+	for id, node := range r.members {
+		if owns(node) {
+			nodes[id] = node
+		}
+	}
+
+	return nodes
+}
@@ -0,0 +1,135 @@
+package natsroute
+
+import (
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/nats-io/nats.go"
+)
+
+// Subscriber 订阅本进程负责的分片, 环的成员变化后自动增减订阅
+type Subscriber struct {
+	sync.Mutex
+	conn    *nats.Conn
+	router  *Router
+	owns    func(consistent.Node) bool
+	handler nats.MsgHandler
+	subs    map[int]*nats.Subscription
+	retry   *time.Timer
+	closed  bool
+}
+
+func (r *Router) NewSubscriber(nc *nats.Conn, owns func(consistent.Node) bool, handler nats.MsgHandler) (*Subscriber, error) {
+	s := &Subscriber{
+		conn:    nc,
+		router:  r,
+		owns:    owns,
+		handler: handler,
+		subs:    make(map[int]*nats.Subscription),
+	}
+
+	if err := s.Sync(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	r.Lock()
+	r.subscribers = append(r.subscribers, s)
+	r.Unlock()
+
+	return s, nil
+}
+
+func (s *Subscriber) Sync() error {
+	want := s.router.owned(s.owns)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	for id, sub := range s.subs {
+		if _, ok := want[id]; !ok {
+			sub.Drain()
+			delete(s.subs, id)
+		}
+	}
+
+	for id, node := range want {
+		if _, ok := s.subs[id]; ok {
+			continue
+		}
+
+		sub, err := s.conn.QueueSubscribe(s.router.SubjectFor(node), s.router.QueueGroup(node), s.handler)
+		if err != nil {
+			return err
+		}
+		s.subs[id] = sub
+	}
+
+	return nil
+}
+
+// scheduleRetry 在 SYNC_RETRY 之后再同步一次, 仍然失败时由 syncFailed 继续安排; 已经在等待重试时不重复安排
+func (s *Subscriber) scheduleRetry() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed || s.retry != nil {
+		return
+	}
+	s.retry = time.AfterFunc(SYNC_RETRY, func() {
+		s.Lock()
+		s.retry = nil
+		s.Unlock()
+
+		if err := s.Sync(); err != nil {
+			s.router.syncFailed(s, err)
+		}
+	})
+}
+
+func (s *Subscriber) Subjects() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	subjects := make([]string, 0, len(s.subs))
+	for _, sub := range s.subs {
+		subjects = append(subjects, sub.Subject)
+	}
+
+	return subjects
+}
+
+func (s *Subscriber) Close() error {
+	s.router.Lock()
+	for i, sub := range s.router.subscribers {
+		if sub == s {
+			s.router.subscribers = append(s.router.subscribers[:i], s.router.subscribers[i+1:]...)
+			break
+		}
+	}
+	s.router.Unlock()
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.retry != nil {
+		s.retry.Stop()
+		s.retry = nil
+	}
+
+	var err error
+	for id, sub := range s.subs {
+		if e := sub.Unsubscribe(); e != nil && err == nil {
+			err = e
+		}
+		delete(s.subs, id)
+	}
+	s.closed = true
+
+	return err
+}
@@ -0,0 +1,90 @@
+package nginx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+var (
+	DefaultTestCmd   = []string{"nginx", "-t"}
+	DefaultReloadCmd = []string{"nginx", "-s", "reload"}
+)
+
+// Syncer 把 upstream 写到 nginx 的 include 文件里, 内容变化时先 nginx -t 再 reload
+type Syncer struct {
+	Upstream  *Upstream
+	Path      string
+	TestCmd   []string
+	ReloadCmd []string
+}
+
+func NewSyncer(u *Upstream, path string) *Syncer {
+	return &Syncer{
+		Upstream:  u,
+		Path:      path,
+		TestCmd:   DefaultTestCmd,
+		ReloadCmd: DefaultReloadCmd,
+	}
+}
+
+func (s *Syncer) Sync(ctx context.Context) (bool, error) {
+	data := s.Upstream.Render()
+
+	old, err := os.ReadFile(s.Path)
+	if err == nil && bytes.Equal(old, data) {
+		return false, nil
+	}
+
+	if err := writeFile(s.Path, data); err != nil {
+		return false, err
+	}
+
+	if err := run(ctx, s.TestCmd); err != nil {
+		// 新配置不合法时恢复旧文件, 不去 reload
+		if old != nil {
+			writeFile(s.Path, old)
+		}
+		return false, err
+	}
+
+	if err := run(ctx, s.ReloadCmd); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("nginx: %v: %s", err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
+
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upstream-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
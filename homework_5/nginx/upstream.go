@@ -0,0 +1,59 @@
+package nginx
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_HASH_KEY = "$request_uri"
+)
+
+type Upstream struct {
+	Name    string
+	HashKey string
+	Ring    *consistent.Consistent
+}
+
+func NewUpstream(name string, ring *consistent.Consistent) *Upstream {
+	return &Upstream{
+		Name:    name,
+		HashKey: DEFAULT_HASH_KEY,
+		Ring:    ring,
+	}
+}
+
+// Render 生成 upstream 块, 空环时输出一个 down 的占位 server, 保证配置仍能通过 nginx -t;
+// weight 是节点的虚拟节点数 (ReplicaCount), 与 Replicas、Capacity 覆盖权重时环上的份额一致;
+// nginx 不接受 weight=0, 虚拟节点数为 0 的节点和 draining、MarkDown 的节点标记为 down
+func (u *Upstream) Render() []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "upstream %s {\n", u.Name)
+	fmt.Fprintf(&buf, "    hash %s consistent;\n", u.HashKey)
+
+	members := u.Ring.Members()
+	for _, node := range members {
+		replicas := u.Ring.ReplicaCount(node)
+		if replicas <= 0 || u.Ring.IsDraining(node.Id) || u.Ring.IsDown(node.Id) {
+			fmt.Fprintf(&buf, "    server %s:%d down;\n", node.Ip, node.Port)
+			continue
+		}
+		fmt.Fprintf(&buf, "    server %s:%d weight=%d;\n", node.Ip, node.Port, replicas)
+	}
+	if len(members) == 0 {
+		buf.WriteString("    server 127.0.0.1:1 down;\n")
+	}
+
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// ServeHTTP 让 nginx 侧的脚本可以轮询当前的 upstream 配置
+func (u *Upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(u.Render())
+}
@@ -0,0 +1,246 @@
+package outlier
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	REASON_CONSECUTIVE_ERRORS = "consecutive_errors"
+	REASON_LATENCY            = "latency"
+)
+
+var ErrAllEjected = errors.New("outlier: every candidate node is ejected")
+
+// Config 的含义与 Envoy 的 outlier_detection 对应
+type Config struct {
+	ConsecutiveErrors int
+	// LatencyZScore 是 Evaluate 时平均延迟超过所有节点均值多少个标准差会被摘除, 0 表示关闭
+	LatencyZScore float64
+	// MinRequests 是一个周期内至少多少个请求才参与延迟比较
+	MinRequests int
+	// 第 n 次摘除持续 BaseEjection * 2^(n-1), 不超过 MaxEjection
+	BaseEjection time.Duration
+	MaxEjection  time.Duration
+	// MaxEjectedPercent 限制同时被摘除的节点比例, 避免把整个集群都摘掉
+	MaxEjectedPercent int
+	OnEject           func(Ejection)
+}
+
+func DefaultConfig() Config {
+	return Config{
+		ConsecutiveErrors: 5,
+		LatencyZScore:     3,
+		MinRequests:       20,
+		BaseEjection:      30 * time.Second,
+		MaxEjection:       5 * time.Minute,
+		MaxEjectedPercent: 10,
+	}
+}
+
+type Ejection struct {
+	Node   consistent.Node `json:"node"`
+	Reason string          `json:"reason"`
+	Count  int             `json:"count"`
+	Until  time.Time       `json:"until"`
+}
+
+type host struct {
+	node        consistent.Node
+	consecutive int
+	requests    int
+	latency     time.Duration
+	ejections   int
+	until       time.Time
+	ejectedNow  bool
+	reason      string
+}
+
+func (h *host) ejected(now time.Time) bool {
+	return now.Before(h.until)
+}
+
+// Detector 根据调用方上报的结果摘除异常节点, 到期后自动恢复
+type Detector struct {
+	sync.Mutex
+	ring  *consistent.Consistent
+	cfg   Config
+	hosts map[int]*host
+	now   func() time.Time
+}
+
+func NewDetector(ring *consistent.Consistent, cfg Config) *Detector {
+	if cfg.BaseEjection <= 0 {
+		cfg.BaseEjection = time.Second
+	}
+	return &Detector{ring: ring, cfg: cfg, hosts: make(map[int]*host), now: time.Now}
+}
+
+func (d *Detector) host(node consistent.Node) *host {
+	h, ok := d.hosts[node.Id]
+	if !ok {
+		h = &host{}
+		d.hosts[node.Id] = h
+	}
+	h.node = node
+	return h
+}
+
+// Report 上报一次请求的结果和延迟, err 为 nil 表示成功
+func (d *Detector) Report(node consistent.Node, err error, latency time.Duration) {
+	d.Lock()
+	defer d.Unlock()
+
+	now := d.now()
+	h := d.host(node)
+	h.requests++
+	h.latency += latency
+
+	if err == nil {
+		h.consecutive = 0
+		return
+	}
+
+	h.consecutive++
+	if d.cfg.ConsecutiveErrors > 0 && h.consecutive >= d.cfg.ConsecutiveErrors {
+		h.consecutive = 0
+		d.eject(h, REASON_CONSECUTIVE_ERRORS, now)
+	}
+}
+
+// Done 让 Detector 可以作为 failover.Health 使用, 不带延迟
+func (d *Detector) Done(node consistent.Node, err error) {
+	d.Report(node, err, 0)
+}
+
+func (d *Detector) Allow(node consistent.Node) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	h, ok := d.hosts[node.Id]
+	return !ok || !h.ejected(d.now())
+}
+
+func (d *Detector) eject(h *host, reason string, now time.Time) {
+	if h.ejected(now) {
+		return
+	}
+
+	total := len(d.ring.Members())
+	ejected := 0
+	for _, other := range d.hosts {
+		if other.ejected(now) {
+			ejected++
+		}
+	}
+	// 与 Envoy 一样, 不论比例多少都至少允许摘除一个节点
+	if ejected > 0 && (ejected+1)*100 > total*d.cfg.MaxEjectedPercent {
+		return
+	}
+
+	h.ejections++
+	h.ejectedNow = true
+	duration := time.Duration(float64(d.cfg.BaseEjection) * math.Pow(2, float64(h.ejections-1)))
+	if d.cfg.MaxEjection > 0 && duration > d.cfg.MaxEjection {
+		duration = d.cfg.MaxEjection
+	}
+	h.until = now.Add(duration)
+	h.reason = reason
+
+	if d.cfg.OnEject != nil {
+		d.cfg.OnEject(Ejection{Node: h.node, Reason: reason, Count: h.ejections, Until: h.until})
+	}
+}
+
+// Evaluate 按周期调用: 比较各节点本周期的平均延迟, 摘除 z-score 过高的节点,
+// 并让一整个周期都没有被摘除的节点的退避次数减一
+func (d *Detector) Evaluate() {
+	d.Lock()
+	defer d.Unlock()
+
+	now := d.now()
+	means := make(map[int]float64)
+	for id, h := range d.hosts {
+		if !h.ejected(now) && h.requests >= d.cfg.MinRequests && h.requests > 0 {
+			means[id] = float64(h.latency) / float64(h.requests)
+		}
+	}
+
+	if d.cfg.LatencyZScore > 0 && len(means) >= 2 {
+		sum := 0.0
+		for _, v := range means {
+			sum += v
+		}
+		mean := sum / float64(len(means))
+		variance := 0.0
+		for _, v := range means {
+			variance += (v - mean) * (v - mean)
+		}
+		stddev := math.Sqrt(variance / float64(len(means)))
+
+		ids := make([]int, 0, len(means))
+		for id := range means {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			if stddev > 0 && (means[id]-mean)/stddev >= d.cfg.LatencyZScore {
+				d.eject(d.hosts[id], REASON_LATENCY, now)
+			}
+		}
+	}
+
+	for _, h := range d.hosts {
+		if !h.ejectedNow && !h.ejected(now) && h.ejections > 0 {
+			h.ejections--
+		}
+		h.ejectedNow = false
+		h.requests, h.latency = 0, 0
+	}
+}
+
+// Ejected 返回当前被摘除的节点
+func (d *Detector) Ejected() []Ejection {
+	d.Lock()
+	defer d.Unlock()
+
+	now := d.now()
+	res := make([]Ejection, 0)
+	for _, h := range d.hosts {
+		if h.ejected(now) {
+			res = append(res, Ejection{Node: h.node, Reason: h.reason, Count: h.ejections, Until: h.until})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Node.Id < res[j].Node.Id
+	})
+	return res
+}
+
+// Get 顺时针跳过被摘除的节点
+func (d *Detector) Get(key string) (consistent.Node, error) {
+	candidates := d.ring.GetN(key, math.MaxInt32)
+	if len(candidates) == 0 {
+		return consistent.Node{}, consistent.ErrEmptyRing
+	}
+
+	for _, node := range candidates {
+		if d.Allow(node) {
+			return node, nil
+		}
+	}
+	return consistent.Node{}, ErrAllEjected
+}
+
+// Forget 删除节点的统计, 节点下线后调用
+func (d *Detector) Forget(id int) {
+	d.Lock()
+	defer d.Unlock()
+
+	delete(d.hosts, id)
+}
@@ -0,0 +1,133 @@
+package pressure
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_HALF_LIFE  = 10 * time.Second
+	DEFAULT_MAX_QUEUE  = 100
+	DEFAULT_MIN_FACTOR = 0.05
+)
+
+// Signal 是节点上报的压力, 两项取较大的一个换算成 0~1 的压力值
+type Signal struct {
+	QueueDepth int     `json:"queue_depth"`
+	CPU        float64 `json:"cpu"`
+}
+
+type Config struct {
+	// MaxQueue 是压力达到 1 时的队列长度
+	MaxQueue int
+	// HalfLife 是没有新上报时压力减半的时间, 节点恢复后不需要显式清除
+	HalfLife time.Duration
+	// MinFactor 是压力最大时节点保留的份额比例
+	MinFactor float64
+}
+
+func DefaultConfig() Config {
+	return Config{MaxQueue: DEFAULT_MAX_QUEUE, HalfLife: DEFAULT_HALF_LIFE, MinFactor: DEFAULT_MIN_FACTOR}
+}
+
+type report struct {
+	level float64
+	at    time.Time
+}
+
+type Tracker struct {
+	sync.RWMutex
+	cfg     Config
+	reports map[int]report
+	now     func() time.Time
+}
+
+func NewTracker(cfg Config) *Tracker {
+	if cfg.MaxQueue <= 0 {
+		cfg.MaxQueue = DEFAULT_MAX_QUEUE
+	}
+	if cfg.HalfLife <= 0 {
+		cfg.HalfLife = DEFAULT_HALF_LIFE
+	}
+	return &Tracker{cfg: cfg, reports: make(map[int]report), now: time.Now}
+}
+
+func (t *Tracker) Report(id int, s Signal) {
+	level := math.Max(float64(s.QueueDepth)/float64(t.cfg.MaxQueue), s.CPU)
+	t.ReportLevel(id, level)
+}
+
+// ReportLevel 直接上报 0~1 的压力值, 超出范围的值会被截断
+func (t *Tracker) ReportLevel(id int, level float64) {
+	level = math.Min(math.Max(level, 0), 1)
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.reports[id] = report{level: level, at: t.now()}
+}
+
+// Level 返回按半衰期衰减之后的压力
+func (t *Tracker) Level(id int) float64 {
+	t.RLock()
+	defer t.RUnlock()
+
+	r, ok := t.reports[id]
+	if !ok {
+		return 0
+	}
+	age := t.now().Sub(r.at)
+	return r.level * math.Pow(0.5, float64(age)/float64(t.cfg.HalfLife))
+}
+
+// Factor 是节点应保留的份额比例, 压力为 p 时为 1-p, 不低于 MinFactor
+func (t *Tracker) Factor(id int) float64 {
+	return math.Max(1-t.Level(id), t.cfg.MinFactor)
+}
+
+func (t *Tracker) Forget(id int) {
+	t.Lock()
+	defer t.Unlock()
+
+	delete(t.reports, id)
+}
+
+// Router 在环上按压力缩小节点的份额: 压力为 p 的 owner 上有 p 比例的 key 顺延到下一个候选.
+// 哪些 key 被顺延由 key 的哈希决定, 压力不变时同一个 key 总是落在同一个节点.
+type Router struct {
+	*Tracker
+	ring *consistent.Consistent
+	// Candidates 是最多顺延的候选数
+	Candidates int
+}
+
+func NewRouter(ring *consistent.Consistent, tracker *Tracker) *Router {
+	return &Router{Tracker: tracker, ring: ring, Candidates: 3}
+}
+
+// fraction 把 key 映射到 [0, 1), 每个候选用不同的 i, 让各候选的顺延互相独立
+func fraction(key string, i int) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{byte(i)})
+	return float64(h.Sum64()>>11) / float64(1<<53)
+}
+
+func (r *Router) Get(key string) (consistent.Node, error) {
+	candidates := r.ring.GetN(key, r.Candidates)
+	if len(candidates) == 0 {
+		return consistent.Node{}, consistent.ErrEmptyRing
+	}
+
+	// 压力越大保留的 key 越少, 最后一个候选兜底
+	for i, node := range candidates[:len(candidates)-1] {
+		if fraction(key, i) < r.Factor(node.Id) {
+			return node, nil
+		}
+	}
+	return candidates[len(candidates)-1], nil
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_MAX_SHARE   = 0.2
+	DEFAULT_CALLER_IDLE = time.Minute
+)
+
+var ErrOverCapacity = errors.New("ratelimit: over capacity")
+
+// OverCapacityError 说明请求被拒绝的原因: 总预算用完, 或者调用方超过了自己的份额
+type OverCapacityError struct {
+	Caller string
+	Global bool
+}
+
+func (e *OverCapacityError) Error() string {
+	if e.Global {
+		return "ratelimit: global budget exhausted, rejected " + strconv.Quote(e.Caller)
+	}
+	return "ratelimit: caller " + strconv.Quote(e.Caller) + " exceeded its share"
+}
+
+func (e *OverCapacityError) Is(target error) bool {
+	return target == ErrOverCapacity
+}
+
+type AdmissionConfig struct {
+	// Rate 和 Burst 是整个路由层每秒的总预算
+	Rate  float64
+	Burst int
+	// MaxShare 是单个调用方最多能用掉的总预算比例, 防止一个调用方挤掉其他调用方
+	MaxShare float64
+	// Idle 之后没有请求的调用方状态会被清理
+	Idle time.Duration
+}
+
+type caller struct {
+	bucket   *TokenBucket
+	lastSeen time.Time
+}
+
+// Admission 在查找之前做准入控制, 超出容量时尽早拒绝, 不把压力转嫁给后端节点
+type Admission struct {
+	sync.Mutex
+	cfg     AdmissionConfig
+	ring    *consistent.Consistent
+	global  *TokenBucket
+	callers map[string]*caller
+	now     func() time.Time
+}
+
+// NewAdmission 的 ring 可以为 nil, 此时只能使用 Admit
+func NewAdmission(ring *consistent.Consistent, cfg AdmissionConfig) *Admission {
+	if cfg.MaxShare <= 0 || cfg.MaxShare > 1 {
+		cfg.MaxShare = DEFAULT_MAX_SHARE
+	}
+	if cfg.Idle <= 0 {
+		cfg.Idle = DEFAULT_CALLER_IDLE
+	}
+	return &Admission{
+		cfg:     cfg,
+		ring:    ring,
+		global:  NewTokenBucket(cfg.Rate, cfg.Burst),
+		callers: make(map[string]*caller),
+		now:     time.Now,
+	}
+}
+
+// Admit 先检查调用方的份额再扣总预算, 被份额拒绝的请求不消耗总预算
+func (a *Admission) Admit(name string) error {
+	now := a.now()
+
+	a.Lock()
+	c, ok := a.callers[name]
+	if !ok {
+		burst := int(float64(a.cfg.Burst) * a.cfg.MaxShare)
+		if burst < 1 {
+			burst = 1
+		}
+		c = &caller{bucket: NewTokenBucket(a.cfg.Rate*a.cfg.MaxShare, burst)}
+		a.callers[name] = c
+	}
+	c.lastSeen = now
+	a.Unlock()
+
+	if !c.bucket.Allow(now) {
+		return &OverCapacityError{Caller: name}
+	}
+	if !a.global.Allow(now) {
+		return &OverCapacityError{Caller: name, Global: true}
+	}
+	return nil
+}
+
+// Get 准入通过之后再查找 key 的 owner
+func (a *Admission) Get(name, key string) (consistent.Node, error) {
+	if err := a.Admit(name); err != nil {
+		return consistent.Node{}, err
+	}
+
+	owners := a.ring.GetN(key, 1)
+	if len(owners) == 0 {
+		return consistent.Node{}, consistent.ErrEmptyRing
+	}
+	return owners[0], nil
+}
+
+// Sweep 清理长时间没有请求的调用方, 返回清理的数量
+func (a *Admission) Sweep() int {
+	a.Lock()
+	defer a.Unlock()
+
+	now, n := a.now(), 0
+	for name, c := range a.callers {
+		if now.Sub(c.lastSeen) > a.cfg.Idle {
+			delete(a.callers, name)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// Limiter 决定当前是否还能向节点发一个请求
+type Limiter interface {
+	Allow(now time.Time) bool
+}
+
+// Factory 为节点创建限流器, 通常按权重换算出节点的容量
+type Factory func(node consistent.Node) Limiter
+
+// TokenBucket 每秒补充 rate 个令牌, 最多攒 burst 个
+type TokenBucket struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+func (b *TokenBucket) Allow(now time.Time) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// SlidingWindow 用上一个窗口和当前窗口的计数近似滑动窗口, 任意 window 长度内最多约 limit 个请求
+type SlidingWindow struct {
+	sync.Mutex
+	limit  int
+	window time.Duration
+	start  time.Time
+	prev   int
+	cur    int
+}
+
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{limit: limit, window: window}
+}
+
+func (w *SlidingWindow) Allow(now time.Time) bool {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.start.IsZero() {
+		w.start = now
+	}
+	if elapsed := now.Sub(w.start); elapsed >= w.window {
+		if elapsed < 2*w.window {
+			w.prev = w.cur
+		} else {
+			w.prev = 0
+		}
+		w.cur = 0
+		w.start = w.start.Add(elapsed / w.window * w.window)
+	}
+
+	weight := 1 - float64(now.Sub(w.start))/float64(w.window)
+	if float64(w.prev)*weight+float64(w.cur) >= float64(w.limit) {
+		return false
+	}
+	w.cur++
+	return true
+}
+
+// PerWeightTokenBucket 按权重线性放大速率和突发量, 权重为 1 的节点每秒 rate 个请求
+func PerWeightTokenBucket(rate float64, burst int) Factory {
+	return func(node consistent.Node) Limiter {
+		return NewTokenBucket(rate*float64(node.Weight), burst*node.Weight)
+	}
+}
+
+// PerWeightSlidingWindow 权重为 1 的节点在 window 内最多 limit 个请求
+func PerWeightSlidingWindow(limit int, window time.Duration) Factory {
+	return func(node consistent.Node) Limiter {
+		return NewSlidingWindow(limit*node.Weight, window)
+	}
+}
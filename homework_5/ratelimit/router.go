@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+var ErrThrottled = errors.New("ratelimit: node throttled")
+
+// ThrottleError 列出查找时尝试过并且都已限流的节点
+type ThrottleError struct {
+	Key   string
+	Nodes []consistent.Node
+}
+
+func (e *ThrottleError) Error() string {
+	ids := make([]string, 0, len(e.Nodes))
+	for _, node := range e.Nodes {
+		ids = append(ids, strconv.Itoa(node.Id))
+	}
+	return "ratelimit: key " + strconv.Quote(e.Key) + " throttled on nodes " + strings.Join(ids, ",")
+}
+
+func (e *ThrottleError) Is(target error) bool {
+	return target == ErrThrottled
+}
+
+type Config struct {
+	New Factory
+	// Spill 是 owner 限流后最多再顺时针尝试几个节点, 0 表示直接返回 ThrottleError
+	Spill int
+}
+
+type limited struct {
+	weight  int
+	limiter Limiter
+}
+
+// Router 在一致性哈希的查找结果上加限流, 防止热点 key 把小节点打满
+type Router struct {
+	sync.Mutex
+	ring     *consistent.Consistent
+	cfg      Config
+	limiters map[int]limited
+	now      func() time.Time
+}
+
+func NewRouter(ring *consistent.Consistent, cfg Config) *Router {
+	return &Router{ring: ring, cfg: cfg, limiters: make(map[int]limited), now: time.Now}
+}
+
+// limiter 节点权重变化后重新创建限流器
+func (r *Router) limiter(node consistent.Node) Limiter {
+	r.Lock()
+	defer r.Unlock()
+
+	l, ok := r.limiters[node.Id]
+	if !ok || l.weight != node.Weight {
+		l = limited{weight: node.Weight, limiter: r.cfg.New(node)}
+		r.limiters[node.Id] = l
+	}
+	return l.limiter
+}
+
+// Get 返回第一个未被限流的候选节点, 第一个候选与 ring.Get 一致
+func (r *Router) Get(key string) (consistent.Node, error) {
+	candidates := r.ring.GetN(key, r.cfg.Spill+1)
+	if len(candidates) == 0 {
+		return consistent.Node{}, consistent.ErrEmptyRing
+	}
+
+	now := r.now()
+	for _, node := range candidates {
+		if r.limiter(node).Allow(now) {
+			return node, nil
+		}
+	}
+
+	return consistent.Node{}, &ThrottleError{Key: key, Nodes: candidates}
+}
+
+// Forget 删除节点的限流状态, 节点下线后调用
+func (r *Router) Forget(id int) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.limiters, id)
+}
@@ -0,0 +1,268 @@
+package redisshard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrClosed  = errors.New("redisshard: client is closed")
+	ErrOddArgs = errors.New("redisshard: MSet needs key value pairs")
+	ErrBadKey  = errors.New("redisshard: MSet keys must be strings")
+)
+
+// HashTag 与 Redis Cluster 相同: key 里有非空的 {...} 时只用第一对花括号里的内容路由,
+// 例如 {user:1}:profile 和 {user:1}:cart 总在同一个分片上, 可以一起用 MGET/MSET
+func HashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+// Client 给环上的每个节点开一个 go-redis 客户端, 按 key 把命令发到对应的分片.
+// 节点离开环时关闭它的客户端, 地址变了 (删除后重新加入) 时重新连接, 之后的命令自动按新的环路由;
+// 已经在旧分片上的数据不会被迁移
+type Client struct {
+	sync.Mutex
+	ring    *consistent.Consistent
+	clients map[int]*redis.Client
+	closed  bool
+	// NewClient 为节点创建客户端, 默认只设置 Addr; 需要密码、连接池大小等参数时替换它
+	NewClient func(node consistent.Node) *redis.Client
+}
+
+func NewClient(ring *consistent.Consistent) *Client {
+	c := &Client{
+		ring:    ring,
+		clients: make(map[int]*redis.Client),
+		NewClient: func(node consistent.Node) *redis.Client {
+			return redis.NewClient(&redis.Options{Addr: net.JoinHostPort(node.Ip, strconv.Itoa(node.Port))})
+		},
+	}
+	ring.OnNodeRemoved(c.forget)
+	return c
+}
+
+func (c *Client) forget(node consistent.Node) {
+	c.Lock()
+	rc, ok := c.clients[node.Id]
+	delete(c.clients, node.Id)
+	c.Unlock()
+
+	if ok {
+		rc.Close()
+	}
+}
+
+func (c *Client) client(node consistent.Node) (*redis.Client, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.closed {
+		return nil, ErrClosed
+	}
+	rc, ok := c.clients[node.Id]
+	if !ok {
+		rc = c.NewClient(node)
+		c.clients[node.Id] = rc
+	}
+	return rc, nil
+}
+
+// Shard 返回 key 所在分片的客户端, 可以用来执行这里没有包装的单 key 命令
+func (c *Client) Shard(key string) (*redis.Client, error) {
+	node, err := c.ring.Get(HashTag(key))
+	if err != nil {
+		return nil, err
+	}
+	return c.client(node)
+}
+
+func (c *Client) Get(ctx context.Context, key string) *redis.StringCmd {
+	rc, err := c.Shard(key)
+	if err != nil {
+		return redis.NewStringResult("", err)
+	}
+	return rc.Get(ctx, key)
+}
+
+func (c *Client) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	rc, err := c.Shard(key)
+	if err != nil {
+		return redis.NewStatusResult("", err)
+	}
+	return rc.Set(ctx, key, value, expiration)
+}
+
+// group 按分片把 keys 的下标分组
+func (c *Client) group(keys []string) (map[int][]int, map[int]consistent.Node, error) {
+	routing := make([]string, len(keys))
+	for i, key := range keys {
+		routing[i] = HashTag(key)
+	}
+	nodes, err := c.ring.GetMany(routing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups := make(map[int][]int)
+	shards := make(map[int]consistent.Node)
+	for i, node := range nodes {
+		groups[node.Id] = append(groups[node.Id], i)
+		shards[node.Id] = node
+	}
+	return groups, shards, nil
+}
+
+// fanout 对每个分片并发执行 fn, 返回第一个错误
+func (c *Client) fanout(groups map[int][]int, shards map[int]consistent.Node, fn func(node consistent.Node, rc *redis.Client, idx []int) error) error {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+	for id, idx := range groups {
+		rc, err := c.client(shards[id])
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func(node consistent.Node, rc *redis.Client, idx []int) {
+			defer wg.Done()
+			if err := fn(node, rc, idx); err != nil {
+				mu.Lock()
+				if first == nil {
+					first = err
+				}
+				mu.Unlock()
+			}
+		}(shards[id], rc, idx)
+	}
+	wg.Wait()
+	return first
+}
+
+func pick(keys []string, idx []int) []string {
+	sub := make([]string, len(idx))
+	for j, i := range idx {
+		sub[j] = keys[i]
+	}
+	return sub
+}
+
+// MGet 按分片拆成多个 MGET 并发执行, 结果按 keys 的顺序合并, 不存在的 key 是 nil; 任何一个分片出错都返回错误
+func (c *Client) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	groups, shards, err := c.group(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(keys))
+	err = c.fanout(groups, shards, func(node consistent.Node, rc *redis.Client, idx []int) error {
+		vals, err := rc.MGet(ctx, pick(keys, idx)...).Result()
+		if err != nil {
+			return err
+		}
+		for j, v := range vals {
+			values[idx[j]] = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// MSet 的参数是 key1, value1, key2, value2 ..., 按分片拆成多个 MSET 并发执行.
+// 每个分片内是原子的, 跨分片不是: 出错时可能只有一部分分片写入了
+func (c *Client) MSet(ctx context.Context, pairs ...interface{}) error {
+	if len(pairs)%2 != 0 {
+		return ErrOddArgs
+	}
+
+	keys := make([]string, len(pairs)/2)
+	for i := range keys {
+		key, ok := pairs[2*i].(string)
+		if !ok {
+			return ErrBadKey
+		}
+		keys[i] = key
+	}
+
+	groups, shards, err := c.group(keys)
+	if err != nil {
+		return err
+	}
+	return c.fanout(groups, shards, func(node consistent.Node, rc *redis.Client, idx []int) error {
+		args := make([]interface{}, 0, 2*len(idx))
+		for _, i := range idx {
+			args = append(args, pairs[2*i], pairs[2*i+1])
+		}
+		return rc.MSet(ctx, args...).Err()
+	})
+}
+
+// Del 按分片拆开执行, 返回所有分片删除的 key 数之和
+func (c *Client) Del(ctx context.Context, keys ...string) (int64, error) {
+	groups, shards, err := c.group(keys)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		mu    sync.Mutex
+		total int64
+	)
+	err = c.fanout(groups, shards, func(node consistent.Node, rc *redis.Client, idx []int) error {
+		n, err := rc.Del(ctx, pick(keys, idx)...).Result()
+		mu.Lock()
+		total += n
+		mu.Unlock()
+		return err
+	})
+	return total, err
+}
+
+// ForEachShard 对环上的每个节点并发执行 fn, 用于 PING、FLUSHDB 这类要发到所有分片的命令
+func (c *Client) ForEachShard(ctx context.Context, fn func(ctx context.Context, node consistent.Node, rc *redis.Client) error) error {
+	groups, shards := make(map[int][]int), make(map[int]consistent.Node)
+	for _, node := range c.ring.Members() {
+		groups[node.Id], shards[node.Id] = nil, node
+	}
+	return c.fanout(groups, shards, func(node consistent.Node, rc *redis.Client, idx []int) error {
+		return fn(ctx, node, rc)
+	})
+}
+
+// Close 关闭所有分片的客户端, 之后的命令返回 ErrClosed
+func (c *Client) Close() error {
+	c.Lock()
+	clients := c.clients
+	c.clients = make(map[int]*redis.Client)
+	c.closed = true
+	c.Unlock()
+
+	var first error
+	for _, rc := range clients {
+		if err := rc.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
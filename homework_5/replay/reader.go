@@ -0,0 +1,116 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FORMAT_AUTO  = "auto"
+	FORMAT_LINES = "lines"
+	FORMAT_JSONL = "jsonl"
+
+	DEFAULT_KEY_FIELD  = "key"
+	DEFAULT_TIME_FIELD = "ts"
+)
+
+type Record struct {
+	Key  string
+	Time time.Time
+}
+
+// Reader 逐条读取 key, 每行一个 key 或者每行一个 JSON 对象
+type Reader struct {
+	scanner   *bufio.Scanner
+	format    string
+	KeyField  string
+	TimeField string
+	line      int
+}
+
+func NewReader(r io.Reader, format string) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	return &Reader{
+		scanner:   scanner,
+		format:    format,
+		KeyField:  DEFAULT_KEY_FIELD,
+		TimeField: DEFAULT_TIME_FIELD,
+	}
+}
+
+// Next 读到结尾时返回 io.EOF, 空行直接跳过
+func (r *Reader) Next() (Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		format := r.format
+		if format == FORMAT_AUTO || format == "" {
+			format = FORMAT_LINES
+			if strings.HasPrefix(line, "{") {
+				format = FORMAT_JSONL
+			}
+		}
+
+		if format == FORMAT_LINES {
+			return Record{Key: line}, nil
+		}
+
+		rec, err := r.parseJSON(line)
+		if err != nil {
+			return Record{}, fmt.Errorf("replay: line %d: %v", r.line, err)
+		}
+		return rec, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+
+	return Record{}, io.EOF
+}
+
+func (r *Reader) parseJSON(line string) (Record, error) {
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return Record{}, err
+	}
+
+	key, ok := fields[r.KeyField]
+	if !ok {
+		return Record{}, fmt.Errorf("missing field %q", r.KeyField)
+	}
+
+	rec := Record{Key: fmt.Sprint(key)}
+	switch ts := fields[r.TimeField].(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			rec.Time = t
+		} else if sec, err := strconv.ParseFloat(ts, 64); err == nil {
+			rec.Time = unix(sec)
+		}
+	case float64:
+		rec.Time = unix(ts)
+	}
+
+	return rec, nil
+}
+
+// unix 同时兼容秒和毫秒时间戳
+func unix(v float64) time.Time {
+	if v > 1e12 {
+		return time.UnixMilli(int64(v))
+	}
+	sec := int64(v)
+	return time.Unix(sec, int64((v-float64(sec))*1e9))
+}
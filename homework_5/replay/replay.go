@@ -0,0 +1,95 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+var ErrEmptyRing = errors.New("replay: ring is empty")
+
+type NodeCount struct {
+	Id     int     `json:"id"`
+	Name   string  `json:"name"`
+	Weight int     `json:"weight"`
+	Count  int     `json:"count"`
+	Share  float64 `json:"share"`
+}
+
+type Result struct {
+	Total      int         `json:"total"`
+	Nodes      []NodeCount `json:"nodes"`
+	StdDev     float64     `json:"stddev"`
+	PeakToMean float64     `json:"peak_to_mean"`
+	HotKeys    []KeyCount  `json:"hot_keys"`
+	First      time.Time   `json:"first,omitempty"`
+	Last       time.Time   `json:"last,omitempty"`
+}
+
+// Replay 把读到的每个 key 都过一遍环, 统计节点分布和 top 个最热的 key
+func Replay(c *consistent.Consistent, r *Reader, top int) (*Result, error) {
+	members := c.Members()
+	if len(members) == 0 {
+		return nil, ErrEmptyRing
+	}
+
+	counts := make(map[int]int, len(members))
+	hot := newTopK(top * 10)
+	res := &Result{}
+
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		node, err := c.Get(rec.Key)
+		if err != nil {
+			return nil, err
+		}
+		id := node.Id
+		counts[id]++
+		hot.add(rec.Key, id)
+		res.Total++
+
+		if !rec.Time.IsZero() {
+			if res.First.IsZero() || rec.Time.Before(res.First) {
+				res.First = rec.Time
+			}
+			if rec.Time.After(res.Last) {
+				res.Last = rec.Time
+			}
+		}
+	}
+
+	if res.Total == 0 {
+		return res, nil
+	}
+
+	mean := float64(res.Total) / float64(len(members))
+	variance, peak := 0.0, 0.0
+	for _, node := range members {
+		v := counts[node.Id]
+		variance += math.Pow(float64(v)-mean, 2)
+		peak = math.Max(peak, float64(v))
+
+		res.Nodes = append(res.Nodes, NodeCount{
+			Id:     node.Id,
+			Name:   node.HostName,
+			Weight: node.Weight,
+			Count:  v,
+			Share:  float64(v) / float64(res.Total),
+		})
+	}
+
+	res.StdDev = math.Sqrt(variance / float64(len(members)))
+	res.PeakToMean = peak / mean
+	res.HotKeys = hot.top(top)
+	return res, nil
+}
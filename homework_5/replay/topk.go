@@ -0,0 +1,71 @@
+package replay
+
+import (
+	"sort"
+)
+
+type KeyCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+	// Error 是 space-saving 算法的最大高估值, 真实次数在 [Count-Error, Count] 之间
+	Error int `json:"error"`
+	Node  int `json:"node"`
+}
+
+// topK 用 space-saving 算法在固定内存里统计高频 key
+type topK struct {
+	capacity int
+	counts   map[string]*KeyCount
+}
+
+func newTopK(capacity int) *topK {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &topK{
+		capacity: capacity,
+		counts:   make(map[string]*KeyCount, capacity),
+	}
+}
+
+func (t *topK) add(key string, node int) {
+	if kc, ok := t.counts[key]; ok {
+		kc.Count++
+		return
+	}
+
+	if len(t.counts) < t.capacity {
+		t.counts[key] = &KeyCount{Key: key, Count: 1, Node: node}
+		return
+	}
+
+	var min *KeyCount
+	for _, kc := range t.counts {
+		if min == nil || kc.Count < min.Count {
+			min = kc
+		}
+	}
+
+	delete(t.counts, min.Key)
+	t.counts[key] = &KeyCount{Key: key, Count: min.Count + 1, Error: min.Count, Node: node}
+}
+
+func (t *topK) top(n int) []KeyCount {
+	keys := make([]KeyCount, 0, len(t.counts))
+	for _, kc := range t.counts {
+		keys = append(keys, *kc)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count != keys[j].Count {
+			return keys[i].Count > keys[j].Count
+		}
+		return keys[i].Key < keys[j].Key
+	})
+
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
@@ -0,0 +1,118 @@
+package report
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+)
+
+// WriteHTML 输出单个 HTML 文件, 数据内嵌在页面里, 图表用原生 SVG + JS 绘制, 不依赖外部资源
+func (r *DistributionReport) WriteHTML(w io.Writer) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return page.Execute(w, struct {
+		Title string
+		Data  template.JS
+	}{r.Title, template.JS(data)})
+}
+
+var page = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h2 { margin-top: 1.5em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+.bar:hover { opacity: 0.7; }
+#tip { position: fixed; background: #333; color: #fff; padding: 4px 8px; border-radius: 3px; display: none; font-size: 12px; }
+button { margin-right: 4px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p id="summary"></p>
+<h2>节点份额</h2>
+<div><button onclick="draw('share')">抽样份额</button><button onclick="draw('arc_share')">区间份额</button><button onclick="draw('target')">权重目标</button></div>
+<svg id="shares" width="900" height="320"></svg>
+<h2>变更后的移动比例</h2>
+<svg id="movement" width="900" height="240"></svg>
+<h2>明细</h2>
+<table id="detail"><tr><th>id</th><th>name</th><th>weight</th><th>keys</th><th>share</th><th>arc share</th><th>target</th></tr></table>
+<div id="tip"></div>
+<script>
+const report = {{.Data}};
+const NS = "http://www.w3.org/2000/svg";
+const tip = document.getElementById("tip");
+
+function el(name, attrs, parent) {
+  const e = document.createElementNS(NS, name);
+  for (const k in attrs) e.setAttribute(k, attrs[k]);
+  parent.appendChild(e);
+  return e;
+}
+
+function hover(e, text) {
+  e.addEventListener("mousemove", ev => {
+    tip.style.display = "block";
+    tip.style.left = (ev.clientX + 12) + "px";
+    tip.style.top = (ev.clientY + 12) + "px";
+    tip.textContent = text;
+  });
+  e.addEventListener("mouseleave", () => { tip.style.display = "none"; });
+}
+
+function bars(svg, items, value, label, color) {
+  while (svg.firstChild) svg.removeChild(svg.firstChild);
+  const w = +svg.getAttribute("width"), h = +svg.getAttribute("height") - 30;
+  const max = Math.max(...items.map(value), 1e-9);
+  const bw = w / Math.max(items.length, 1);
+  items.forEach((it, i) => {
+    const bh = value(it) / max * (h - 10);
+    const r = el("rect", {x: i * bw + 2, y: h - bh, width: Math.max(bw - 4, 1), height: bh, fill: color, class: "bar"}, svg);
+    hover(r, label(it));
+    if (items.length <= 40) {
+      el("text", {x: i * bw + bw / 2, y: h + 16, "text-anchor": "middle", "font-size": 11}, svg).textContent = it.id !== undefined ? it.id : i + 1;
+    }
+  });
+}
+
+function pct(v) { return (v * 100).toFixed(2) + "%"; }
+
+function draw(field) {
+  bars(document.getElementById("shares"), report.nodes || [], n => n[field],
+    n => "node " + n.id + " " + (n.name || "") + ": " + pct(n[field]) + " (target " + pct(n.target) + ")", "#4a7ebb");
+}
+
+draw("share");
+
+const steps = report.movement || [];
+if (steps.length) {
+  bars(document.getElementById("movement"), steps, s => s.moved_fraction,
+    s => s.event.op + " node " + s.event.node.id + ": moved " + pct(s.moved_fraction) + ", peak/mean " + s.balance.peak_to_mean.toFixed(3), "#c0504d");
+} else {
+  document.getElementById("movement").style.display = "none";
+}
+
+document.getElementById("summary").textContent =
+  (report.nodes || []).length + " nodes, " + report.arcs + " arcs, " + report.keys + " sample keys, stddev " + report.stddev.toFixed(1) + ", generated " + report.generated;
+
+const table = document.getElementById("detail");
+(report.nodes || []).forEach(n => {
+  const tr = document.createElement("tr");
+  [n.id, n.name, n.weight, n.keys, pct(n.share), pct(n.arc_share), pct(n.target)].forEach(v => {
+    const td = document.createElement("td");
+    td.textContent = v;
+    tr.appendChild(td);
+  });
+  table.appendChild(tr);
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,100 @@
+package report
+
+import (
+	"math"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+	"github.com/axiusilihao/geek_homework/homework_5/simulate"
+)
+
+type NodeShare struct {
+	Id       int     `json:"id"`
+	Name     string  `json:"name"`
+	Weight   int     `json:"weight"`
+	Keys     int     `json:"keys"`
+	Share    float64 `json:"share"`
+	ArcShare float64 `json:"arc_share"`
+	Target   float64 `json:"target"`
+}
+
+// DistributionReport 同时给出抽样统计的份额和按环上区间长度算出的份额
+type DistributionReport struct {
+	Title     string          `json:"title"`
+	Generated time.Time       `json:"generated"`
+	Keys      int             `json:"keys"`
+	Source    keygen.Source   `json:"source"`
+	Nodes     []NodeShare     `json:"nodes"`
+	StdDev    float64         `json:"stddev"`
+	Movement  []simulate.Step `json:"movement,omitempty"`
+	Arcs      int             `json:"arcs"`
+}
+
+func NewDistributionReport(c *consistent.Consistent, keys int) *DistributionReport {
+	r, _ := NewDistributionReportSource(c, keygen.SequentialSource(keys))
+	return r
+}
+
+// NewDistributionReportSource 用 src 生成样本 key, src 会写进报告以便复现
+func NewDistributionReportSource(c *consistent.Consistent, src keygen.Source) (*DistributionReport, error) {
+	sample, err := src.Sample()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := len(sample)
+	members := c.Members()
+	r := &DistributionReport{
+		Title:     "一致性哈希数据分布",
+		Generated: time.Now(),
+		Keys:      keys,
+		Source:    src,
+	}
+	if len(members) == 0 {
+		return r, nil
+	}
+
+	owners, err := c.GetMany(sample)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int, len(members))
+	for _, node := range owners {
+		counts[node.Id]++
+	}
+
+	r.Arcs = len(c.Arcs())
+	owned := c.Ownership()
+
+	totalWeight := 0
+	for _, node := range members {
+		totalWeight += node.Weight
+	}
+
+	mean := float64(keys) / float64(len(members))
+	variance := 0.0
+	for _, node := range members {
+		v := counts[node.Id]
+		variance += math.Pow(float64(v)-mean, 2)
+
+		r.Nodes = append(r.Nodes, NodeShare{
+			Id:       node.Id,
+			Name:     node.HostName,
+			Weight:   node.Weight,
+			Keys:     v,
+			Share:    float64(v) / float64(keys),
+			ArcShare: owned[node.Id],
+			Target:   float64(node.Weight) / float64(totalWeight),
+		})
+	}
+	r.StdDev = math.Sqrt(variance / float64(len(members)))
+
+	return r, nil
+}
+
+// WithMovement 附上一次模拟变更的结果, 报告里会画出每一步的 key 移动比例
+func (r *DistributionReport) WithMovement(steps []simulate.Step) *DistributionReport {
+	r.Movement = steps
+	return r
+}
@@ -0,0 +1,141 @@
+package ringcheck
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	FUZZ_MAX_NODES = 32
+	FUZZ_MAX_KEY   = 16
+)
+
+// fuzzInput 按顺序读出字节, 读完之后一直返回 0, 任何输入都能解码
+type fuzzInput struct {
+	data []byte
+}
+
+func (in *fuzzInput) byte() byte {
+	if len(in.data) == 0 {
+		return 0
+	}
+	b := in.data[0]
+	in.data = in.data[1:]
+	return b
+}
+
+// Fuzz 把任意字节解码成一串加节点、删节点和查询, 每一步之后检查不变量, 返回第一个违反的:
+// Get 不 panic、结果是当前成员、删除的节点没有留下虚拟节点、同一个环上的查询是确定的.
+// 签名与 go-fuzz 的 func([]byte) 一致, 可以直接接到 fuzz 驱动上, FuzzRandom 用随机输入跑同样的检查
+func Fuzz(newRing func() *consistent.Consistent, data []byte) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	in := &fuzzInput{data: data}
+	c := newRing()
+	members := make(map[int]consistent.Node)
+	keys := []string{""}
+
+	for len(in.data) > 0 {
+		switch in.byte() % 4 {
+		case 0, 1:
+			id := int(in.byte() % FUZZ_MAX_NODES)
+			node := consistent.NewNode(id, fmt.Sprintf("10.0.0.%d", id), 8080+int(in.byte()%3), fmt.Sprintf("host_%d", id), int(in.byte()%4))
+			_, exists := members[id]
+			if added := c.Add(node); added == exists {
+				return fmt.Errorf("Add(%d) returned %v, member before: %v", id, added, exists)
+			}
+			if !exists {
+				members[id] = *node
+			}
+		case 2:
+			id := int(in.byte() % FUZZ_MAX_NODES)
+			_, exists := members[id]
+			if removed := c.RemoveByID(id); removed != exists {
+				return fmt.Errorf("RemoveByID(%d) returned %v, member before: %v", id, removed, exists)
+			}
+			delete(members, id)
+			if err := checkGone(c, id); err != nil {
+				return err
+			}
+		case 3:
+			key := make([]byte, in.byte()%FUZZ_MAX_KEY)
+			for i := range key {
+				key[i] = in.byte()
+			}
+			keys = append(keys, string(key))
+		}
+
+		if err := checkLookups(c, members, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGone 删除之后环上不能再有属于 id 的位置, 虚拟节点数与剩下的成员的权重一致
+func checkGone(c *consistent.Consistent, id int) error {
+	if c.Contains(id) {
+		return fmt.Errorf("node %d still a member after RemoveByID", id)
+	}
+	for _, arc := range c.Arcs() {
+		if arc.Node.Id == id {
+			return fmt.Errorf("node %d still owns [%x, %x] after RemoveByID", id, arc.Start, arc.End)
+		}
+	}
+
+	want := 0
+	for _, node := range c.Members() {
+		want += c.ReplicaCount(node)
+	}
+	if got := c.VirtualNodeCount(); got != want {
+		return fmt.Errorf("%d virtual nodes after removing %d, want %d", got, id, want)
+	}
+	return nil
+}
+
+func checkLookups(c *consistent.Consistent, members map[int]consistent.Node, keys []string) error {
+	weights := 0
+	for _, node := range members {
+		weights += node.Weight
+	}
+
+	for _, key := range keys {
+		node, err := c.Get(key)
+		if weights == 0 {
+			if !errors.Is(err, consistent.ErrEmptyRing) {
+				return fmt.Errorf("key %q: Get on a ring without virtual nodes returned %v, want ErrEmptyRing", key, err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("key %q: %v", key, err)
+		}
+		if _, ok := members[node.Id]; !ok {
+			return fmt.Errorf("key %q resolved to node %d which is not a member", key, node.Id)
+		}
+		if again, _ := c.Get(key); again.Id != node.Id {
+			return fmt.Errorf("key %q resolved to %d then %d", key, node.Id, again.Id)
+		}
+	}
+	return nil
+}
+
+// FuzzRandom 用 seed 生成 runs 个随机输入交给 Fuzz, 返回第一个失败的输入, 方便复现
+func FuzzRandom(newRing func() *consistent.Consistent, seed int64, runs int) ([]byte, error) {
+	rnd := rand.New(rand.NewSource(seed))
+	for i := 0; i < runs; i++ {
+		data := make([]byte, rnd.Intn(512))
+		rnd.Read(data)
+		if err := Fuzz(newRing, data); err != nil {
+			return data, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,50 @@
+package ringcheck
+
+import (
+	"testing"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+func newDefault() *consistent.Consistent {
+	return consistent.NewConsistent()
+}
+
+func newStrict() *consistent.Consistent {
+	return consistent.NewConsistent(consistent.WithStrictWrap())
+}
+
+// FuzzRingOps 用 go test -fuzz=FuzzRingOps 运行, 每个输入在默认和 WithStrictWrap 的环上各跑一遍 Fuzz
+func FuzzRingOps(f *testing.F) {
+	seeds := [][]byte{
+		{},
+		// 加入一个节点后查询
+		{0, 1, 0, 1, 3, 3, 'a', 'b', 'c'},
+		// 加入再删除同一个节点
+		{0, 5, 1, 2, 2, 5, 3, 1, 'x'},
+		// 权重为 0 的节点, 环上没有虚拟节点
+		{0, 7, 0, 0, 3, 2, 'k', 'v'},
+		// 重复加入, 删除不存在的节点
+		{0, 3, 0, 1, 1, 3, 0, 1, 2, 9, 3, 4, 0xff, 0, 0xff, 0},
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := Fuzz(newDefault, data); err != nil {
+			t.Fatalf("default ring: %v", err)
+		}
+		if err := Fuzz(newStrict, data); err != nil {
+			t.Fatalf("strict ring: %v", err)
+		}
+	})
+}
+
+func TestFuzzRandom(t *testing.T) {
+	for _, newRing := range []func() *consistent.Consistent{newDefault, newStrict} {
+		if data, err := FuzzRandom(newRing, 1, 50); err != nil {
+			t.Fatalf("input %x: %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package ringcheck
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// Ring 是被检查的对象, *consistent.Consistent 以及在它外面包了一层的类型都可以
+type Ring interface {
+	Add(node *consistent.Node) bool
+	Remove(node *consistent.Node)
+	Get(key string) (consistent.Node, error)
+}
+
+type Factory func() Ring
+
+// owner 查询出错时返回 -1, 不是任何节点的 Id
+func owner(r Ring, key string) int {
+	node, err := r.Get(key)
+	if err != nil {
+		return -1
+	}
+	return node.Id
+}
+
+func Owners(r Ring, keys []string) map[string]int {
+	owners := make(map[string]int, len(keys))
+	for _, key := range keys {
+		owners[key] = owner(r, key)
+	}
+	return owners
+}
+
+// CheckDeterminism 同一个 key 连续两次查询必须得到同一个节点
+func CheckDeterminism(r Ring, keys []string) error {
+	for _, key := range keys {
+		a, b := owner(r, key), owner(r, key)
+		if a != b {
+			return fmt.Errorf("key %q resolved to %d then %d", key, a, b)
+		}
+	}
+	return nil
+}
+
+// CheckRebuild 用同样的节点重新建一个环, 查询结果必须和原来完全一致
+func CheckRebuild(newRing Factory, members []consistent.Node, owners map[string]int) error {
+	r := newRing()
+	for i := range members {
+		node := members[i]
+		r.Add(&node)
+	}
+
+	for key, want := range owners {
+		if got := owner(r, key); got != want {
+			return fmt.Errorf("key %q resolved to %d on a rebuilt ring, want %d", key, got, want)
+		}
+	}
+	return nil
+}
+
+// CheckMembers 查询结果必须是当前成员, 不能落到已经删除的节点上
+func CheckMembers(owners map[string]int, members map[int]bool) error {
+	for key, id := range owners {
+		if !members[id] {
+			return fmt.Errorf("key %q resolved to node %d which is not a member", key, id)
+		}
+	}
+	return nil
+}
+
+// CheckRemoval 删除节点后只有原来属于它的 key 可以换节点, 所以移动量不会超过它的份额
+func CheckRemoval(before, after map[string]int, removed int) error {
+	moved, owned := 0, 0
+	for key, from := range before {
+		if from == removed {
+			owned++
+		}
+		if to := after[key]; to != from {
+			moved++
+			if from != removed {
+				return fmt.Errorf("key %q moved from %d to %d although node %d was removed", key, from, to, removed)
+			}
+		}
+	}
+
+	if moved > owned {
+		return fmt.Errorf("%d keys moved but removed node %d owned only %d", moved, removed, owned)
+	}
+	return nil
+}
+
+// CheckAddition 加入节点后换了节点的 key 只能移到新节点上, 原有节点之间不能互相移动 key
+func CheckAddition(before, after map[string]int, added int) error {
+	for key, from := range before {
+		if to := after[key]; to != from && to != added {
+			return fmt.Errorf("key %q moved from %d to %d although only node %d was added", key, from, to, added)
+		}
+	}
+	return nil
+}
+
+// CheckOrder 同样的节点按 orders 里的每种顺序加入, 查询结果都必须与 owners 一致
+func CheckOrder(newRing Factory, orders [][]consistent.Node, owners map[string]int) error {
+	for _, order := range orders {
+		if err := CheckRebuild(newRing, order, owners); err != nil {
+			ids := make([]int, len(order))
+			for i, node := range order {
+				ids[i] = node.Id
+			}
+			return fmt.Errorf("insertion order %v: %v", ids, err)
+		}
+	}
+	return nil
+}
+
+// CheckGetN GetN 必须返回 min(n, 成员数) 个不同的节点, 第一个与 Get 相同, n <= 0 时返回空切片且不 panic;
+// keys 之外再加上 WRAP_KEYS 个落在最后一个虚拟节点之后的 key, 它们会回绕到环的开头, 同样要满足
+func CheckGetN(c *consistent.Consistent, keys []string, n int) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("GetN(%d) panicked: %v", n, p)
+		}
+	}()
+
+	want := n
+	if members := len(c.Members()); members < want {
+		want = members
+	}
+	if want < 0 {
+		want = 0
+	}
+
+	for _, key := range append(WrapKeys(c, WRAP_KEYS), keys...) {
+		nodes := c.GetN(key, n)
+		if len(nodes) != want {
+			return fmt.Errorf("key %q: GetN(%d) returned %d nodes, want %d", key, n, len(nodes), want)
+		}
+		if len(nodes) > 0 && nodes[0].Id != owner(c, key) {
+			return fmt.Errorf("key %q: GetN starts at %d but Get returned %d", key, nodes[0].Id, owner(c, key))
+		}
+
+		seen := make(map[int]bool, len(nodes))
+		for _, node := range nodes {
+			if seen[node.Id] {
+				return fmt.Errorf("key %q: GetN returned node %d twice", key, node.Id)
+			}
+			seen[node.Id] = true
+		}
+	}
+	return nil
+}
+
+// WrapKeys 最多找 n 个哈希值大于最后一个虚拟节点的 key, 这些 key 要回绕才能找到节点; 环越大越难找到, 最多尝试 WRAP_TRIES 次
+func WrapKeys(c *consistent.Consistent, n int) []string {
+	keys := make([]string, 0, n)
+	for i := 0; i < WRAP_TRIES && len(keys) < n; i++ {
+		key := fmt.Sprintf("wrap-%d", i)
+		e, err := c.ExplainGet(key)
+		if err != nil {
+			return keys
+		}
+		if e.Found == e.RingSize {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// CheckEmpty 没有成员的环上 Get 必须返回 ErrEmptyRing, 不能 panic
+func CheckEmpty(r Ring, keys []string) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("Get on an empty ring panicked: %v", p)
+		}
+	}()
+
+	for _, key := range keys {
+		if _, err := r.Get(key); !errors.Is(err, consistent.ErrEmptyRing) {
+			return fmt.Errorf("key %q: Get on an empty ring returned %v, want ErrEmptyRing", key, err)
+		}
+	}
+	return nil
+}
+
+// CheckSuccessor WithStrictWrap 的环上 key 必须归第一个 >= 它的位置的点, 越过末尾时归第一个点
+func CheckSuccessor(c *consistent.Consistent, keys []string) error {
+	for _, key := range keys {
+		e, err := c.ExplainGet(key)
+		if err != nil {
+			return err
+		}
+		if want := e.Found % e.RingSize; e.Index != want {
+			return fmt.Errorf("key %q at %x resolved to index %d, want clockwise successor %d", key, e.Hash, e.Index, want)
+		}
+	}
+	return nil
+}
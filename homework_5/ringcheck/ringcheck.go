@@ -0,0 +1,255 @@
+package ringcheck
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	OP_ADD    = "add"
+	OP_REMOVE = "remove"
+
+	GETN_REPLICAS = 3
+	// WRAP_KEYS 是 CheckGetN 额外检查的越过末尾的 key 数, WRAP_TRIES 是找这些 key 的尝试次数
+	WRAP_KEYS  = 4
+	WRAP_TRIES = 1 << 16
+	// ORDER_SHUFFLES 是每一步检查插入顺序无关时随机打乱的次数
+	ORDER_SHUFFLES = 2
+)
+
+type Config struct {
+	Seed     int64
+	Ops      int
+	Keys     int
+	MaxNodes int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Seed:     1,
+		Ops:      100,
+		Keys:     2000,
+		MaxNodes: 16,
+	}
+}
+
+type Op struct {
+	Kind string          `json:"kind"`
+	Node consistent.Node `json:"node"`
+}
+
+type Violation struct {
+	Step      int    `json:"step"`
+	Op        Op     `json:"op"`
+	Invariant string `json:"invariant"`
+	Detail    string `json:"detail"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("step %d (%s node %d): %s: %s", v.Step, v.Op.Kind, v.Op.Node.Id, v.Invariant, v.Detail)
+}
+
+type Report struct {
+	Seed       int64       `json:"seed"`
+	Ops        []Op        `json:"ops"`
+	Violations []Violation `json:"violations"`
+}
+
+func (r *Report) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// Run 用 cfg.Seed 生成随机的增删序列, 每一步之后检查全部不变量, 同样的 seed 得到同样的序列
+func Run(newRing Factory, cfg Config) *Report {
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	keys := make([]string, cfg.Keys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("check-%d-%d", cfg.Seed, rnd.Int63())
+	}
+
+	report := &Report{Seed: cfg.Seed}
+	ring := newRing()
+	members := make(map[int]consistent.Node)
+	nextId := 0
+	var owners map[string]int
+	var tail map[string]bool
+
+	for step := 0; step < cfg.Ops; step++ {
+		op := nextOp(rnd, members, &nextId, cfg.MaxNodes)
+		report.Ops = append(report.Ops, op)
+
+		fail := func(invariant string, err error) {
+			report.Violations = append(report.Violations, Violation{Step: step, Op: op, Invariant: invariant, Detail: err.Error()})
+		}
+
+		if op.Kind == OP_ADD {
+			node := op.Node
+			ring.Add(&node)
+			members[node.Id] = node
+		} else {
+			node := members[op.Node.Id]
+			ring.Remove(&node)
+			delete(members, node.Id)
+		}
+
+		if len(members) == 0 {
+			if err := CheckEmpty(ring, keys); err != nil {
+				fail("empty", err)
+			}
+			owners, tail = nil, nil
+			continue
+		}
+
+		next := Owners(ring, keys)
+		ids := make(map[int]bool, len(members))
+		for id := range members {
+			ids[id] = true
+		}
+
+		if err := CheckDeterminism(ring, keys); err != nil {
+			fail("determinism", err)
+		}
+		if err := CheckMembers(next, ids); err != nil {
+			fail("members", err)
+		}
+		if err := CheckRebuild(newRing, sortedMembers(members), next); err != nil {
+			fail("rebuild", err)
+		}
+		if c, ok := ring.(*consistent.Consistent); ok {
+			if err := CheckGetN(c, keys, GETN_REPLICAS); err != nil {
+				fail("getn", err)
+			}
+			if c.StrictWrap() {
+				if err := CheckSuccessor(c, keys); err != nil {
+					fail("successor", err)
+				}
+			}
+		}
+		if err := CheckOrder(newRing, shuffled(rnd, sortedMembers(members), ORDER_SHUFFLES), next); err != nil {
+			fail("order", err)
+		}
+		// 默认回绕规则下末尾的 key 不满足单调性, 前后两个环上落在末尾的 key 都不参与 removal 和 addition
+		nextTail := legacyTail(ring, keys)
+		before, after := without(owners, tail, nextTail), without(next, tail, nextTail)
+		if op.Kind == OP_REMOVE && owners != nil {
+			if err := CheckRemoval(before, after, op.Node.Id); err != nil {
+				fail("removal", err)
+			}
+		}
+		if op.Kind == OP_ADD && owners != nil {
+			if err := CheckAddition(before, after, op.Node.Id); err != nil {
+				fail("addition", err)
+			}
+		}
+		tail = nextTail
+
+		owners = next
+	}
+
+	return report
+}
+
+func nextOp(rnd *rand.Rand, members map[int]consistent.Node, nextId *int, maxNodes int) Op {
+	if len(members) == 0 || (len(members) < maxNodes && rnd.Intn(2) == 0) {
+		id := *nextId
+		*nextId++
+		ip := fmt.Sprintf("10.%d.%d.%d", id/65536%256, id/256%256, id%256)
+		return Op{Kind: OP_ADD, Node: *consistent.NewNode(id, ip, 8080+rnd.Intn(3), fmt.Sprintf("host_%d", id), 1+rnd.Intn(3))}
+	}
+
+	ids := make([]int, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	return Op{Kind: OP_REMOVE, Node: members[ids[rnd.Intn(len(ids))]]}
+}
+
+func sortedMembers(members map[int]consistent.Node) []consistent.Node {
+	nodes := make([]consistent.Node, 0, len(members))
+	for _, node := range members {
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Id < nodes[j].Id
+	})
+	return nodes
+}
+
+// legacyTail 找出默认回绕规则下落在最后一个点上或越过末尾的 key, 它们的归属与顺时针后继不同;
+// 不是 *consistent.Consistent 或使用 WithStrictWrap 时返回 nil
+func legacyTail(r Ring, keys []string) map[string]bool {
+	c, ok := r.(*consistent.Consistent)
+	if !ok || c.StrictWrap() {
+		return nil
+	}
+
+	tail := make(map[string]bool)
+	for _, key := range keys {
+		if e, err := c.ExplainGet(key); err == nil && e.Found >= e.RingSize-1 {
+			tail[key] = true
+		}
+	}
+	return tail
+}
+
+func without(owners map[string]int, skips ...map[string]bool) map[string]int {
+	if owners == nil {
+		return nil
+	}
+
+	kept := make(map[string]int, len(owners))
+	for key, id := range owners {
+		skipped := false
+		for _, skip := range skips {
+			skipped = skipped || skip[key]
+		}
+		if !skipped {
+			kept[key] = id
+		}
+	}
+	return kept
+}
+
+// shuffled 返回 nodes 的 n 种随机排列, 总是包括倒序
+func shuffled(rnd *rand.Rand, nodes []consistent.Node, n int) [][]consistent.Node {
+	reversed := make([]consistent.Node, len(nodes))
+	for i, node := range nodes {
+		reversed[len(nodes)-1-i] = node
+	}
+
+	orders := [][]consistent.Node{reversed}
+	for i := 0; i < n; i++ {
+		order := append([]consistent.Node(nil), nodes...)
+		rnd.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+// Consistent 是检查 *consistent.Consistent 本身用的 Factory
+func Consistent() Ring {
+	return consistent.NewConsistent()
+}
+
+// Strict 是检查 WithStrictWrap 的 *consistent.Consistent 用的 Factory
+func Strict() Ring {
+	return consistent.NewConsistent(consistent.WithStrictWrap())
+}
+
+// Rendezvous 是检查 *consistent.Rendezvous 用的 Factory
+func Rendezvous() Ring {
+	return consistent.NewRendezvous(nil)
+}
+
+// WeightedRendezvous 是检查按 Weight 打分的 *consistent.Rendezvous 用的 Factory
+func WeightedRendezvous() Ring {
+	return consistent.NewWeightedRendezvous(nil)
+}
@@ -0,0 +1,128 @@
+package ringcheck
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+func strategy(name string) Factory {
+	return func() Ring {
+		r, err := consistent.New(name)
+		if err != nil {
+			panic(err)
+		}
+		return r
+	}
+}
+
+// TestInvariants 对每种算法跑几组随机的增删序列; skip 是算法本身不保证的不变量:
+// maglev 和 ketama 加减节点时已有节点之间也会换 key, anchor 和 slots 的结果取决于节点加入的顺序
+func TestInvariants(t *testing.T) {
+	cases := []struct {
+		name    string
+		factory Factory
+		skip    []string
+	}{
+		{"ring", Consistent, nil},
+		{"strict", Strict, nil},
+		{"rendezvous", Rendezvous, nil},
+		{"weighted-rendezvous", WeightedRendezvous, nil},
+		{"multiprobe", strategy(consistent.STRATEGY_MULTIPROBE), nil},
+		{"maglev", strategy(consistent.STRATEGY_MAGLEV), []string{"addition", "removal"}},
+		{"ketama", strategy(consistent.STRATEGY_KETAMA), []string{"addition", "removal"}},
+		{"anchor", strategy(consistent.STRATEGY_ANCHOR), []string{"order", "rebuild"}},
+		{"slots", strategy(consistent.STRATEGY_SLOTS), []string{"order", "rebuild"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			skip := make(map[string]bool, len(tc.skip))
+			for _, inv := range tc.skip {
+				skip[inv] = true
+			}
+
+			for seed := int64(1); seed <= 3; seed++ {
+				cfg := DefaultConfig()
+				cfg.Seed = seed
+				cfg.Ops = 40
+				cfg.Keys = 1000
+				for _, v := range Run(tc.factory, cfg).Violations {
+					if !skip[v.Invariant] {
+						t.Errorf("seed %d: %s", seed, v)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestOrderIndependence 同一组节点按不同顺序加入, 查询结果必须相同
+func TestOrderIndependence(t *testing.T) {
+	nodes := make([]consistent.Node, 12)
+	for i := range nodes {
+		nodes[i] = *consistent.NewNode(i, "10.0.0.1", 8000+i, "", 1+i%3)
+	}
+	reversed := make([]consistent.Node, len(nodes))
+	for i, node := range nodes {
+		reversed[len(nodes)-1-i] = node
+	}
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("order-%d", i)
+	}
+
+	for _, factory := range []Factory{Consistent, Strict, Rendezvous, WeightedRendezvous} {
+		ring := factory()
+		for i := range nodes {
+			ring.Add(&nodes[i])
+		}
+		if err := CheckOrder(factory, [][]consistent.Node{reversed}, Owners(ring, keys)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCheckGetN(t *testing.T) {
+	ring := consistent.NewConsistent()
+	for i := 1; i <= 3; i++ {
+		ring.Add(consistent.NewNode(i, "10.0.0.1", 8000+i, "", 1))
+	}
+	if len(WrapKeys(ring, 1)) == 0 {
+		t.Fatal("no key hashes past the last virtual node")
+	}
+	keys := []string{"", "a", "key-1", "key-2"}
+
+	cases := []struct {
+		name string
+		n    int
+		want int
+	}{
+		{"negative", -1, 0},
+		{"zero", 0, 0},
+		{"one", 1, 1},
+		{"members", 3, 3},
+		{"more than members", 10, 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := CheckGetN(ring, keys, tc.n); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range append(WrapKeys(ring, WRAP_KEYS), keys...) {
+				if got := len(ring.GetN(key, tc.n)); got != tc.want {
+					t.Fatalf("key %q: GetN(%d) returned %d nodes, want %d", key, tc.n, got, tc.want)
+				}
+			}
+		})
+	}
+
+	strict := consistent.NewConsistent(consistent.WithStrictWrap())
+	for i := 1; i <= 3; i++ {
+		strict.Add(consistent.NewNode(i, "10.0.0.1", 8000+i, "", 1))
+	}
+	if err := CheckGetN(strict, keys, GETN_REPLICAS); err != nil {
+		t.Fatalf("strict ring: %v", err)
+	}
+}
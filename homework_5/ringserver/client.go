@@ -0,0 +1,157 @@
+package ringserver
+
+import (
+	"context"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/ringserver/ringpb"
+	"google.golang.org/grpc"
+)
+
+// Client 包装生成的 ringpb.RingClient, 参数和结果使用 consistent 的类型
+type Client struct {
+	rc ringpb.RingClient
+}
+
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{rc: ringpb.NewRingClient(cc)}
+}
+
+func (c *Client) Lookup(ctx context.Context, key string) (consistent.Node, error) {
+	out, err := c.rc.Lookup(ctx, &ringpb.LookupRequest{Key: key})
+	if err != nil {
+		return consistent.Node{}, err
+	}
+
+	return NodeFromProto(out.GetNode()), nil
+}
+
+func (c *Client) LookupN(ctx context.Context, key string, n int) ([]consistent.Node, error) {
+	out, err := c.rc.LookupN(ctx, &ringpb.LookupNRequest{Key: key, N: int32(n)})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodesFromProto(out.GetNodes()), nil
+}
+
+// Get 与 Lookup 相同, 调用服务端的 Get 方法
+func (c *Client) Get(ctx context.Context, key string) (consistent.Node, error) {
+	out, err := c.rc.Get(ctx, &ringpb.LookupRequest{Key: key})
+	if err != nil {
+		return consistent.Node{}, err
+	}
+
+	return NodeFromProto(out.GetNode()), nil
+}
+
+func (c *Client) GetN(ctx context.Context, key string, n int) ([]consistent.Node, error) {
+	out, err := c.rc.GetN(ctx, &ringpb.LookupNRequest{Key: key, N: int32(n)})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodesFromProto(out.GetNodes()), nil
+}
+
+func (c *Client) AddNode(ctx context.Context, node consistent.Node) (bool, error) {
+	out, err := c.rc.AddNode(ctx, &ringpb.AddNodeRequest{Node: NodeToProto(node)})
+	if err != nil {
+		return false, err
+	}
+
+	return out.GetAdded(), nil
+}
+
+func (c *Client) RemoveNode(ctx context.Context, id int) (bool, error) {
+	out, err := c.rc.RemoveNode(ctx, &ringpb.RemoveNodeRequest{Id: int64(id)})
+	if err != nil {
+		return false, err
+	}
+
+	return out.GetRemoved(), nil
+}
+
+func (c *Client) DrainNode(ctx context.Context, id int) (bool, error) {
+	out, err := c.rc.DrainNode(ctx, &ringpb.DrainNodeRequest{Id: int64(id)})
+	if err != nil {
+		return false, err
+	}
+
+	return out.GetDrained(), nil
+}
+
+func (c *Client) Members(ctx context.Context, includeDraining bool) (*Members, error) {
+	out, err := c.rc.Members(ctx, &ringpb.MembersRequest{IncludeDraining: includeDraining})
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Members{Version: out.GetVersion(), Nodes: nodesFromProto(out.GetNodes())}
+	for _, id := range out.GetDraining() {
+		m.Draining = append(m.Draining, int(id))
+	}
+	return m, nil
+}
+
+// Watch 第一个事件是当前拓扑的快照, 之后是增量事件, ctx 取消后 channel 关闭
+func (c *Client) Watch(ctx context.Context) (<-chan *TopologyEvent, <-chan error, error) {
+	stream, err := c.rc.Watch(ctx, &ringpb.WatchRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := make(chan *TopologyEvent)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(events)
+
+		for {
+			e, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case events <- topologyFromProto(e):
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return events, errs, nil
+}
+
+// WatchChanges 返回环的变更事件, 不带初始快照, 需要初始状态时先调用 Members
+func (c *Client) WatchChanges(ctx context.Context) (<-chan *consistent.ChangeEvent, <-chan error, error) {
+	stream, err := c.rc.WatchChanges(ctx, &ringpb.WatchChangesRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := make(chan *consistent.ChangeEvent)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(events)
+
+		for {
+			e, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case events <- changeFromProto(e):
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return events, errs, nil
+}
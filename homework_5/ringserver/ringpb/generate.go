@@ -0,0 +1,4 @@
+// Package ringpb 是 ring.proto 生成的消息和 gRPC 接口, 修改 ring.proto 之后执行 go generate 重新生成
+package ringpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ring.proto
@@ -0,0 +1,1142 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: ring.proto
+
+package ringpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Node 与 consistent.Node 的字段一一对应
+type Node struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Ip            string                 `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	HostName      string                 `protobuf:"bytes,4,opt,name=host_name,json=hostName,proto3" json:"host_name,omitempty"`
+	Weight        int32                  `protobuf:"varint,5,opt,name=weight,proto3" json:"weight,omitempty"`
+	Zone          string                 `protobuf:"bytes,6,opt,name=zone,proto3" json:"zone,omitempty"`
+	Rack          string                 `protobuf:"bytes,7,opt,name=rack,proto3" json:"rack,omitempty"`
+	Capacity      float64                `protobuf:"fixed64,8,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	Replicas      int32                  `protobuf:"varint,9,opt,name=replicas,proto3" json:"replicas,omitempty"`
+	Tags          []string               `protobuf:"bytes,10,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Node) Reset() {
+	*x = Node{}
+	mi := &file_ring_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Node) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Node) ProtoMessage() {}
+
+func (x *Node) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Node.ProtoReflect.Descriptor instead.
+func (*Node) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Node) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Node) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *Node) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Node) GetHostName() string {
+	if x != nil {
+		return x.HostName
+	}
+	return ""
+}
+
+func (x *Node) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *Node) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *Node) GetRack() string {
+	if x != nil {
+		return x.Rack
+	}
+	return ""
+}
+
+func (x *Node) GetCapacity() float64 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *Node) GetReplicas() int32 {
+	if x != nil {
+		return x.Replicas
+	}
+	return 0
+}
+
+func (x *Node) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type LookupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupRequest) Reset() {
+	*x = LookupRequest{}
+	mi := &file_ring_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupRequest) ProtoMessage() {}
+
+func (x *LookupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupRequest.ProtoReflect.Descriptor instead.
+func (*LookupRequest) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LookupRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type LookupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          *Node                  `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupResponse) Reset() {
+	*x = LookupResponse{}
+	mi := &file_ring_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupResponse) ProtoMessage() {}
+
+func (x *LookupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupResponse.ProtoReflect.Descriptor instead.
+func (*LookupResponse) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LookupResponse) GetNode() *Node {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+type LookupNRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	N             int32                  `protobuf:"varint,2,opt,name=n,proto3" json:"n,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupNRequest) Reset() {
+	*x = LookupNRequest{}
+	mi := &file_ring_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupNRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupNRequest) ProtoMessage() {}
+
+func (x *LookupNRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupNRequest.ProtoReflect.Descriptor instead.
+func (*LookupNRequest) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LookupNRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *LookupNRequest) GetN() int32 {
+	if x != nil {
+		return x.N
+	}
+	return 0
+}
+
+type LookupNResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Nodes         []*Node                `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupNResponse) Reset() {
+	*x = LookupNResponse{}
+	mi := &file_ring_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupNResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupNResponse) ProtoMessage() {}
+
+func (x *LookupNResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupNResponse.ProtoReflect.Descriptor instead.
+func (*LookupNResponse) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LookupNResponse) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type AddNodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          *Node                  `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddNodeRequest) Reset() {
+	*x = AddNodeRequest{}
+	mi := &file_ring_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddNodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddNodeRequest) ProtoMessage() {}
+
+func (x *AddNodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddNodeRequest.ProtoReflect.Descriptor instead.
+func (*AddNodeRequest) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AddNodeRequest) GetNode() *Node {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+type AddNodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Added         bool                   `protobuf:"varint,1,opt,name=added,proto3" json:"added,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddNodeResponse) Reset() {
+	*x = AddNodeResponse{}
+	mi := &file_ring_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddNodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddNodeResponse) ProtoMessage() {}
+
+func (x *AddNodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddNodeResponse.ProtoReflect.Descriptor instead.
+func (*AddNodeResponse) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AddNodeResponse) GetAdded() bool {
+	if x != nil {
+		return x.Added
+	}
+	return false
+}
+
+type RemoveNodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveNodeRequest) Reset() {
+	*x = RemoveNodeRequest{}
+	mi := &file_ring_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveNodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveNodeRequest) ProtoMessage() {}
+
+func (x *RemoveNodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveNodeRequest.ProtoReflect.Descriptor instead.
+func (*RemoveNodeRequest) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RemoveNodeRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type RemoveNodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Removed       bool                   `protobuf:"varint,1,opt,name=removed,proto3" json:"removed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveNodeResponse) Reset() {
+	*x = RemoveNodeResponse{}
+	mi := &file_ring_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveNodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveNodeResponse) ProtoMessage() {}
+
+func (x *RemoveNodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveNodeResponse.ProtoReflect.Descriptor instead.
+func (*RemoveNodeResponse) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RemoveNodeResponse) GetRemoved() bool {
+	if x != nil {
+		return x.Removed
+	}
+	return false
+}
+
+// DrainNodeRequest 让节点不再接收新的 key, 但仍然留在成员列表里直到 RemoveNode
+type DrainNodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainNodeRequest) Reset() {
+	*x = DrainNodeRequest{}
+	mi := &file_ring_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainNodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainNodeRequest) ProtoMessage() {}
+
+func (x *DrainNodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainNodeRequest.ProtoReflect.Descriptor instead.
+func (*DrainNodeRequest) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DrainNodeRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DrainNodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Drained       bool                   `protobuf:"varint,1,opt,name=drained,proto3" json:"drained,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainNodeResponse) Reset() {
+	*x = DrainNodeResponse{}
+	mi := &file_ring_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainNodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainNodeResponse) ProtoMessage() {}
+
+func (x *DrainNodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainNodeResponse.ProtoReflect.Descriptor instead.
+func (*DrainNodeResponse) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DrainNodeResponse) GetDrained() bool {
+	if x != nil {
+		return x.Drained
+	}
+	return false
+}
+
+// MembersRequest 默认只返回参与路由的节点, include_draining 为 true 时包括正在下线的节点
+type MembersRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	IncludeDraining bool                   `protobuf:"varint,1,opt,name=include_draining,json=includeDraining,proto3" json:"include_draining,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *MembersRequest) Reset() {
+	*x = MembersRequest{}
+	mi := &file_ring_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MembersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MembersRequest) ProtoMessage() {}
+
+func (x *MembersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MembersRequest.ProtoReflect.Descriptor instead.
+func (*MembersRequest) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *MembersRequest) GetIncludeDraining() bool {
+	if x != nil {
+		return x.IncludeDraining
+	}
+	return false
+}
+
+type MembersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       uint64                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Nodes         []*Node                `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Draining      []int64                `protobuf:"varint,3,rep,packed,name=draining,proto3" json:"draining,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MembersResponse) Reset() {
+	*x = MembersResponse{}
+	mi := &file_ring_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MembersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MembersResponse) ProtoMessage() {}
+
+func (x *MembersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MembersResponse.ProtoReflect.Descriptor instead.
+func (*MembersResponse) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *MembersResponse) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *MembersResponse) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *MembersResponse) GetDraining() []int64 {
+	if x != nil {
+		return x.Draining
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_ring_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{13}
+}
+
+// TopologyEvent 中 snapshot 事件带上全部参与路由的节点, added/removed/draining 只带变化的节点
+type TopologyEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Version       uint64                 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Node          *Node                  `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	Nodes         []*Node                `protobuf:"bytes,4,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopologyEvent) Reset() {
+	*x = TopologyEvent{}
+	mi := &file_ring_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopologyEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopologyEvent) ProtoMessage() {}
+
+func (x *TopologyEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopologyEvent.ProtoReflect.Descriptor instead.
+func (*TopologyEvent) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TopologyEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *TopologyEvent) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *TopologyEvent) GetNode() *Node {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *TopologyEvent) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type WatchChangesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchChangesRequest) Reset() {
+	*x = WatchChangesRequest{}
+	mi := &file_ring_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchChangesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchChangesRequest) ProtoMessage() {}
+
+func (x *WatchChangesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchChangesRequest.ProtoReflect.Descriptor instead.
+func (*WatchChangesRequest) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{15}
+}
+
+type RangeChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         uint64                 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           uint64                 `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	From          *Node                  `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	To            *Node                  `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RangeChange) Reset() {
+	*x = RangeChange{}
+	mi := &file_ring_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RangeChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangeChange) ProtoMessage() {}
+
+func (x *RangeChange) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RangeChange.ProtoReflect.Descriptor instead.
+func (*RangeChange) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RangeChange) GetStart() uint64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *RangeChange) GetEnd() uint64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *RangeChange) GetFrom() *Node {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *RangeChange) GetTo() *Node {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+// ChangeEvent 与 consistent.ChangeEvent 相同, version 是变更之后环的版本
+type ChangeEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Node          *Node                  `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	Ranges        []*RangeChange         `protobuf:"bytes,3,rep,name=ranges,proto3" json:"ranges,omitempty"`
+	Version       uint64                 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeEvent) Reset() {
+	*x = ChangeEvent{}
+	mi := &file_ring_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeEvent) ProtoMessage() {}
+
+func (x *ChangeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_ring_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeEvent.ProtoReflect.Descriptor instead.
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return file_ring_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ChangeEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetNode() *Node {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetRanges() []*RangeChange {
+	if x != nil {
+		return x.Ranges
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+var File_ring_proto protoreflect.FileDescriptor
+
+const file_ring_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"ring.proto\x12\n" +
+	"ringserver\"\xe3\x01\n" +
+	"\x04Node\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x0e\n" +
+	"\x02ip\x18\x02 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12\x1b\n" +
+	"\thost_name\x18\x04 \x01(\tR\bhostName\x12\x16\n" +
+	"\x06weight\x18\x05 \x01(\x05R\x06weight\x12\x12\n" +
+	"\x04zone\x18\x06 \x01(\tR\x04zone\x12\x12\n" +
+	"\x04rack\x18\a \x01(\tR\x04rack\x12\x1a\n" +
+	"\bcapacity\x18\b \x01(\x01R\bcapacity\x12\x1a\n" +
+	"\breplicas\x18\t \x01(\x05R\breplicas\x12\x12\n" +
+	"\x04tags\x18\n" +
+	" \x03(\tR\x04tags\"!\n" +
+	"\rLookupRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"6\n" +
+	"\x0eLookupResponse\x12$\n" +
+	"\x04node\x18\x01 \x01(\v2\x10.ringserver.NodeR\x04node\"0\n" +
+	"\x0eLookupNRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\f\n" +
+	"\x01n\x18\x02 \x01(\x05R\x01n\"9\n" +
+	"\x0fLookupNResponse\x12&\n" +
+	"\x05nodes\x18\x01 \x03(\v2\x10.ringserver.NodeR\x05nodes\"6\n" +
+	"\x0eAddNodeRequest\x12$\n" +
+	"\x04node\x18\x01 \x01(\v2\x10.ringserver.NodeR\x04node\"'\n" +
+	"\x0fAddNodeResponse\x12\x14\n" +
+	"\x05added\x18\x01 \x01(\bR\x05added\"#\n" +
+	"\x11RemoveNodeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\".\n" +
+	"\x12RemoveNodeResponse\x12\x18\n" +
+	"\aremoved\x18\x01 \x01(\bR\aremoved\"\"\n" +
+	"\x10DrainNodeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"-\n" +
+	"\x11DrainNodeResponse\x12\x18\n" +
+	"\adrained\x18\x01 \x01(\bR\adrained\";\n" +
+	"\x0eMembersRequest\x12)\n" +
+	"\x10include_draining\x18\x01 \x01(\bR\x0fincludeDraining\"o\n" +
+	"\x0fMembersResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x12&\n" +
+	"\x05nodes\x18\x02 \x03(\v2\x10.ringserver.NodeR\x05nodes\x12\x1a\n" +
+	"\bdraining\x18\x03 \x03(\x03R\bdraining\"\x0e\n" +
+	"\fWatchRequest\"\x8b\x01\n" +
+	"\rTopologyEvent\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x04R\aversion\x12$\n" +
+	"\x04node\x18\x03 \x01(\v2\x10.ringserver.NodeR\x04node\x12&\n" +
+	"\x05nodes\x18\x04 \x03(\v2\x10.ringserver.NodeR\x05nodes\"\x15\n" +
+	"\x13WatchChangesRequest\"}\n" +
+	"\vRangeChange\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x04R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x04R\x03end\x12$\n" +
+	"\x04from\x18\x03 \x01(\v2\x10.ringserver.NodeR\x04from\x12 \n" +
+	"\x02to\x18\x04 \x01(\v2\x10.ringserver.NodeR\x02to\"\x92\x01\n" +
+	"\vChangeEvent\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12$\n" +
+	"\x04node\x18\x02 \x01(\v2\x10.ringserver.NodeR\x04node\x12/\n" +
+	"\x06ranges\x18\x03 \x03(\v2\x17.ringserver.RangeChangeR\x06ranges\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x04R\aversion2\xb5\x05\n" +
+	"\x04Ring\x12?\n" +
+	"\x06Lookup\x12\x19.ringserver.LookupRequest\x1a\x1a.ringserver.LookupResponse\x12B\n" +
+	"\aLookupN\x12\x1a.ringserver.LookupNRequest\x1a\x1b.ringserver.LookupNResponse\x12<\n" +
+	"\x03Get\x12\x19.ringserver.LookupRequest\x1a\x1a.ringserver.LookupResponse\x12?\n" +
+	"\x04GetN\x12\x1a.ringserver.LookupNRequest\x1a\x1b.ringserver.LookupNResponse\x12B\n" +
+	"\aAddNode\x12\x1a.ringserver.AddNodeRequest\x1a\x1b.ringserver.AddNodeResponse\x12K\n" +
+	"\n" +
+	"RemoveNode\x12\x1d.ringserver.RemoveNodeRequest\x1a\x1e.ringserver.RemoveNodeResponse\x12H\n" +
+	"\tDrainNode\x12\x1c.ringserver.DrainNodeRequest\x1a\x1d.ringserver.DrainNodeResponse\x12B\n" +
+	"\aMembers\x12\x1a.ringserver.MembersRequest\x1a\x1b.ringserver.MembersResponse\x12>\n" +
+	"\x05Watch\x12\x18.ringserver.WatchRequest\x1a\x19.ringserver.TopologyEvent0\x01\x12J\n" +
+	"\fWatchChanges\x12\x1f.ringserver.WatchChangesRequest\x1a\x17.ringserver.ChangeEvent0\x01BCZAgithub.com/axiusilihao/geek_homework/homework_5/ringserver/ringpbb\x06proto3"
+
+var (
+	file_ring_proto_rawDescOnce sync.Once
+	file_ring_proto_rawDescData []byte
+)
+
+func file_ring_proto_rawDescGZIP() []byte {
+	file_ring_proto_rawDescOnce.Do(func() {
+		file_ring_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ring_proto_rawDesc), len(file_ring_proto_rawDesc)))
+	})
+	return file_ring_proto_rawDescData
+}
+
+var file_ring_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_ring_proto_goTypes = []any{
+	(*Node)(nil),                // 0: ringserver.Node
+	(*LookupRequest)(nil),       // 1: ringserver.LookupRequest
+	(*LookupResponse)(nil),      // 2: ringserver.LookupResponse
+	(*LookupNRequest)(nil),      // 3: ringserver.LookupNRequest
+	(*LookupNResponse)(nil),     // 4: ringserver.LookupNResponse
+	(*AddNodeRequest)(nil),      // 5: ringserver.AddNodeRequest
+	(*AddNodeResponse)(nil),     // 6: ringserver.AddNodeResponse
+	(*RemoveNodeRequest)(nil),   // 7: ringserver.RemoveNodeRequest
+	(*RemoveNodeResponse)(nil),  // 8: ringserver.RemoveNodeResponse
+	(*DrainNodeRequest)(nil),    // 9: ringserver.DrainNodeRequest
+	(*DrainNodeResponse)(nil),   // 10: ringserver.DrainNodeResponse
+	(*MembersRequest)(nil),      // 11: ringserver.MembersRequest
+	(*MembersResponse)(nil),     // 12: ringserver.MembersResponse
+	(*WatchRequest)(nil),        // 13: ringserver.WatchRequest
+	(*TopologyEvent)(nil),       // 14: ringserver.TopologyEvent
+	(*WatchChangesRequest)(nil), // 15: ringserver.WatchChangesRequest
+	(*RangeChange)(nil),         // 16: ringserver.RangeChange
+	(*ChangeEvent)(nil),         // 17: ringserver.ChangeEvent
+}
+var file_ring_proto_depIdxs = []int32{
+	0,  // 0: ringserver.LookupResponse.node:type_name -> ringserver.Node
+	0,  // 1: ringserver.LookupNResponse.nodes:type_name -> ringserver.Node
+	0,  // 2: ringserver.AddNodeRequest.node:type_name -> ringserver.Node
+	0,  // 3: ringserver.MembersResponse.nodes:type_name -> ringserver.Node
+	0,  // 4: ringserver.TopologyEvent.node:type_name -> ringserver.Node
+	0,  // 5: ringserver.TopologyEvent.nodes:type_name -> ringserver.Node
+	0,  // 6: ringserver.RangeChange.from:type_name -> ringserver.Node
+	0,  // 7: ringserver.RangeChange.to:type_name -> ringserver.Node
+	0,  // 8: ringserver.ChangeEvent.node:type_name -> ringserver.Node
+	16, // 9: ringserver.ChangeEvent.ranges:type_name -> ringserver.RangeChange
+	1,  // 10: ringserver.Ring.Lookup:input_type -> ringserver.LookupRequest
+	3,  // 11: ringserver.Ring.LookupN:input_type -> ringserver.LookupNRequest
+	1,  // 12: ringserver.Ring.Get:input_type -> ringserver.LookupRequest
+	3,  // 13: ringserver.Ring.GetN:input_type -> ringserver.LookupNRequest
+	5,  // 14: ringserver.Ring.AddNode:input_type -> ringserver.AddNodeRequest
+	7,  // 15: ringserver.Ring.RemoveNode:input_type -> ringserver.RemoveNodeRequest
+	9,  // 16: ringserver.Ring.DrainNode:input_type -> ringserver.DrainNodeRequest
+	11, // 17: ringserver.Ring.Members:input_type -> ringserver.MembersRequest
+	13, // 18: ringserver.Ring.Watch:input_type -> ringserver.WatchRequest
+	15, // 19: ringserver.Ring.WatchChanges:input_type -> ringserver.WatchChangesRequest
+	2,  // 20: ringserver.Ring.Lookup:output_type -> ringserver.LookupResponse
+	4,  // 21: ringserver.Ring.LookupN:output_type -> ringserver.LookupNResponse
+	2,  // 22: ringserver.Ring.Get:output_type -> ringserver.LookupResponse
+	4,  // 23: ringserver.Ring.GetN:output_type -> ringserver.LookupNResponse
+	6,  // 24: ringserver.Ring.AddNode:output_type -> ringserver.AddNodeResponse
+	8,  // 25: ringserver.Ring.RemoveNode:output_type -> ringserver.RemoveNodeResponse
+	10, // 26: ringserver.Ring.DrainNode:output_type -> ringserver.DrainNodeResponse
+	12, // 27: ringserver.Ring.Members:output_type -> ringserver.MembersResponse
+	14, // 28: ringserver.Ring.Watch:output_type -> ringserver.TopologyEvent
+	17, // 29: ringserver.Ring.WatchChanges:output_type -> ringserver.ChangeEvent
+	20, // [20:30] is the sub-list for method output_type
+	10, // [10:20] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_ring_proto_init() }
+func file_ring_proto_init() {
+	if File_ring_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ring_proto_rawDesc), len(file_ring_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ring_proto_goTypes,
+		DependencyIndexes: file_ring_proto_depIdxs,
+		MessageInfos:      file_ring_proto_msgTypes,
+	}.Build()
+	File_ring_proto = out.File
+	file_ring_proto_goTypes = nil
+	file_ring_proto_depIdxs = nil
+}
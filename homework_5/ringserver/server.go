@@ -0,0 +1,203 @@
+package ringserver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/ringserver/ringpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	WATCH_BUFFER = 64
+)
+
+// Server 持有唯一权威的一致性哈希环, 实现 ringpb.RingServer; 成员、draining 和版本号都直接取自环,
+// 写锁只用来让环的修改和 Watch 事件的顺序一致
+type Server struct {
+	ringpb.UnimplementedRingServer
+	sync.RWMutex
+	ring     *consistent.Consistent
+	watchers map[chan *TopologyEvent]struct{}
+}
+
+// NewServer 用 opts 构造环, 所有客户端看到的虚拟节点密度和哈希函数都以服务端为准
+func NewServer(opts ...consistent.Option) *Server {
+	return &Server{
+		ring:     consistent.NewConsistent(opts...),
+		watchers: make(map[chan *TopologyEvent]struct{}),
+	}
+}
+
+// Lookup 在空环或所有节点都在 draining 时返回 FailedPrecondition
+func (s *Server) Lookup(ctx context.Context, in *ringpb.LookupRequest) (*ringpb.LookupResponse, error) {
+	node, err := s.ring.Get(in.GetKey())
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &ringpb.LookupResponse{Node: NodeToProto(node)}, nil
+}
+
+func (s *Server) LookupN(ctx context.Context, in *ringpb.LookupNRequest) (*ringpb.LookupNResponse, error) {
+	if in.GetN() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "n must be positive")
+	}
+
+	nodes := s.ring.GetN(in.GetKey(), int(in.GetN()))
+	if len(nodes) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "ring is empty")
+	}
+	return &ringpb.LookupNResponse{Nodes: nodesToProto(nodes)}, nil
+}
+
+// Get 和 GetN 是 Lookup 和 LookupN 的别名, 与 Consistent 的方法同名
+func (s *Server) Get(ctx context.Context, in *ringpb.LookupRequest) (*ringpb.LookupResponse, error) {
+	return s.Lookup(ctx, in)
+}
+
+func (s *Server) GetN(ctx context.Context, in *ringpb.LookupNRequest) (*ringpb.LookupNResponse, error) {
+	return s.LookupN(ctx, in)
+}
+
+func (s *Server) AddNode(ctx context.Context, in *ringpb.AddNodeRequest) (*ringpb.AddNodeResponse, error) {
+	node := NodeFromProto(in.GetNode())
+	if node.Weight <= 0 && node.Capacity <= 0 && node.Replicas <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "weight, capacity or replicas must be positive")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.ring.Add(&node) {
+		return &ringpb.AddNodeResponse{Added: false}, nil
+	}
+
+	s.publish(&TopologyEvent{Type: EVENT_ADDED, Node: &node})
+	return &ringpb.AddNodeResponse{Added: true}, nil
+}
+
+func (s *Server) RemoveNode(ctx context.Context, in *ringpb.RemoveNodeRequest) (*ringpb.RemoveNodeResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	node, ok := s.ring.Member(int(in.GetId()))
+	if !ok || !s.ring.RemoveByID(node.Id) {
+		return &ringpb.RemoveNodeResponse{Removed: false}, nil
+	}
+
+	s.publish(&TopologyEvent{Type: EVENT_REMOVED, Node: &node})
+	return &ringpb.RemoveNodeResponse{Removed: true}, nil
+}
+
+// DrainNode 让环不再把新的 key 路由到节点, 但保留成员身份, 节点处理完手上的请求后再调用 RemoveNode
+func (s *Server) DrainNode(ctx context.Context, in *ringpb.DrainNodeRequest) (*ringpb.DrainNodeResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	node, ok := s.ring.Member(int(in.GetId()))
+	if !ok || !s.ring.Drain(node.Id) {
+		return &ringpb.DrainNodeResponse{Drained: false}, nil
+	}
+
+	s.publish(&TopologyEvent{Type: EVENT_DRAINING, Node: &node})
+	return &ringpb.DrainNodeResponse{Drained: true}, nil
+}
+
+func (s *Server) Members(ctx context.Context, in *ringpb.MembersRequest) (*ringpb.MembersResponse, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	out := &ringpb.MembersResponse{Version: s.ring.Version()}
+	if !in.GetIncludeDraining() {
+		out.Nodes = nodesToProto(s.members())
+		return out, nil
+	}
+
+	// Members 已经按 Id 排序
+	nodes := s.ring.Members()
+	for _, node := range nodes {
+		if s.ring.IsDraining(node.Id) {
+			out.Draining = append(out.Draining, int64(node.Id))
+		}
+	}
+	out.Nodes = nodesToProto(nodes)
+	return out, nil
+}
+
+func (s *Server) Watch(in *ringpb.WatchRequest, stream ringpb.Ring_WatchServer) error {
+	ch := make(chan *TopologyEvent, WATCH_BUFFER)
+
+	s.Lock()
+	ch <- &TopologyEvent{Type: EVENT_SNAPSHOT, Version: s.ring.Version(), Nodes: s.members()}
+	s.watchers[ch] = struct{}{}
+	s.Unlock()
+
+	defer func() {
+		s.Lock()
+		delete(s.watchers, ch)
+		s.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case e, ok := <-ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind")
+			}
+			if err := stream.Send(topologyToProto(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WatchChanges 把环的 ChangeEvent 原样转发给客户端, 环上的订阅被关闭 (客户端跟不上) 时返回 ResourceExhausted,
+// 客户端应当调用 Members 重建状态后重新订阅
+func (s *Server) WatchChanges(in *ringpb.WatchChangesRequest, stream ringpb.Ring_WatchChangesServer) error {
+	ch, cancel := s.ring.Subscribe()
+	defer cancel()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case e, ok := <-ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind")
+			}
+			if err := stream.Send(changeToProto(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// publish 需要持有写锁, 在修改环之后调用, 事件带上修改后环的版本号; 跟不上的 watcher 直接断开, 由客户端重新 Watch 拿快照
+func (s *Server) publish(e *TopologyEvent) {
+	e.Version = s.ring.Version()
+
+	for ch := range s.watchers {
+		select {
+		case ch <- e:
+		default:
+			close(ch)
+			delete(s.watchers, ch)
+		}
+	}
+}
+
+// members 是参与路由的节点, 按 Id 排序
+func (s *Server) members() []consistent.Node {
+	all := s.ring.Members()
+	nodes := make([]consistent.Node, 0, len(all))
+	for _, node := range all {
+		if !s.ring.IsDraining(node.Id) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,121 @@
+package ringserver
+
+import (
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/ringserver/ringpb"
+	"google.golang.org/grpc"
+)
+
+// 消息和 gRPC 接口定义在 ringpb/ring.proto, 其他语言的客户端用同一个 .proto 生成代码
+
+const (
+	EVENT_SNAPSHOT = "snapshot"
+	EVENT_ADDED    = "added"
+	EVENT_REMOVED  = "removed"
+	EVENT_DRAINING = "draining"
+)
+
+// TopologyEvent 是 Client.Watch 收到的事件, snapshot 事件带上全部参与路由的节点, added/removed/draining 只带变化的节点
+type TopologyEvent struct {
+	Type    string            `json:"type"`
+	Version uint64            `json:"version"`
+	Node    *consistent.Node  `json:"node,omitempty"`
+	Nodes   []consistent.Node `json:"nodes,omitempty"`
+}
+
+// Members 是 Client.Members 的结果, Draining 只在 includeDraining 为 true 时有值
+type Members struct {
+	Version  uint64            `json:"version"`
+	Nodes    []consistent.Node `json:"nodes"`
+	Draining []int             `json:"draining,omitempty"`
+}
+
+func RegisterRingServer(s grpc.ServiceRegistrar, srv *Server) {
+	ringpb.RegisterRingServer(s, srv)
+}
+
+// NodeToProto 和 NodeFromProto 在 consistent.Node 和 ringpb.Node 之间转换, 所有字段一一对应
+func NodeToProto(node consistent.Node) *ringpb.Node {
+	return &ringpb.Node{
+		Id:       int64(node.Id),
+		Ip:       node.Ip,
+		Port:     int32(node.Port),
+		HostName: node.HostName,
+		Weight:   int32(node.Weight),
+		Zone:     node.Zone,
+		Rack:     node.Rack,
+		Capacity: node.Capacity,
+		Replicas: int32(node.Replicas),
+		Tags:     node.Tags,
+	}
+}
+
+func NodeFromProto(pb *ringpb.Node) consistent.Node {
+	return consistent.Node{
+		Id:       int(pb.GetId()),
+		Ip:       pb.GetIp(),
+		Port:     int(pb.GetPort()),
+		HostName: pb.GetHostName(),
+		Weight:   int(pb.GetWeight()),
+		Zone:     pb.GetZone(),
+		Rack:     pb.GetRack(),
+		Capacity: pb.GetCapacity(),
+		Replicas: int(pb.GetReplicas()),
+		Tags:     pb.GetTags(),
+	}
+}
+
+func nodesToProto(nodes []consistent.Node) []*ringpb.Node {
+	out := make([]*ringpb.Node, 0, len(nodes))
+	for _, node := range nodes {
+		out = append(out, NodeToProto(node))
+	}
+	return out
+}
+
+func nodesFromProto(nodes []*ringpb.Node) []consistent.Node {
+	out := make([]consistent.Node, 0, len(nodes))
+	for _, pb := range nodes {
+		out = append(out, NodeFromProto(pb))
+	}
+	return out
+}
+
+func topologyToProto(e *TopologyEvent) *ringpb.TopologyEvent {
+	out := &ringpb.TopologyEvent{Type: e.Type, Version: e.Version}
+	if e.Node != nil {
+		out.Node = NodeToProto(*e.Node)
+	}
+	if len(e.Nodes) > 0 {
+		out.Nodes = nodesToProto(e.Nodes)
+	}
+	return out
+}
+
+func topologyFromProto(pb *ringpb.TopologyEvent) *TopologyEvent {
+	e := &TopologyEvent{Type: pb.GetType(), Version: pb.GetVersion()}
+	if pb.Node != nil {
+		node := NodeFromProto(pb.Node)
+		e.Node = &node
+	}
+	if len(pb.Nodes) > 0 {
+		e.Nodes = nodesFromProto(pb.Nodes)
+	}
+	return e
+}
+
+func changeToProto(e consistent.ChangeEvent) *ringpb.ChangeEvent {
+	out := &ringpb.ChangeEvent{Type: e.Type, Node: NodeToProto(e.Node), Version: e.Version}
+	for _, r := range e.Ranges {
+		out.Ranges = append(out.Ranges, &ringpb.RangeChange{Start: r.Start, End: r.End, From: NodeToProto(r.From), To: NodeToProto(r.To)})
+	}
+	return out
+}
+
+func changeFromProto(pb *ringpb.ChangeEvent) *consistent.ChangeEvent {
+	e := &consistent.ChangeEvent{Type: pb.GetType(), Node: NodeFromProto(pb.Node), Version: pb.GetVersion()}
+	for _, r := range pb.Ranges {
+		e.Ranges = append(e.Ranges, consistent.RangeChange{Start: r.GetStart(), End: r.GetEnd(), From: NodeFromProto(r.From), To: NodeFromProto(r.To)})
+	}
+	return e
+}
@@ -0,0 +1,56 @@
+package shardsql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// FanOut 在所有分片上并发执行同一条查询, fn 会被并发调用, 返回第一个出现的错误
+func (r *Router) FanOut(ctx context.Context, fn func(id int, rows *sql.Rows) error, query string, args ...interface{}) error {
+	dbs := r.shards()
+	if len(dbs) == 0 {
+		return ErrNoShards
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for id, db := range dbs {
+		wg.Add(1)
+		go func(id int, db *sql.DB) {
+			defer wg.Done()
+
+			err := queryShard(ctx, db, id, fn, query, args...)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(id, db)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+func queryShard(ctx context.Context, db *sql.DB, id int, fn func(id int, rows *sql.Rows) error, query string, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if err := fn(id, rows); err != nil {
+		return err
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,120 @@
+package shardsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+var ErrNoShards = errors.New("shardsql: no shard registered")
+
+// Router 每个节点对应一个 *sql.DB, 按 key (比如 userID) 选库
+type Router struct {
+	sync.RWMutex
+	ring  *consistent.Consistent
+	dbs   map[int]*sql.DB
+	nodes map[int]consistent.Node
+}
+
+func NewRouter() *Router {
+	return &Router{
+		ring:  consistent.NewConsistent(),
+		dbs:   make(map[int]*sql.DB),
+		nodes: make(map[int]consistent.Node),
+	}
+}
+
+func (r *Router) Register(node *consistent.Node, db *sql.DB) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if !r.ring.Add(node) {
+		return false
+	}
+
+	r.dbs[node.Id] = db
+	r.nodes[node.Id] = *node
+	return true
+}
+
+// Unregister 只从环上摘掉节点, 返回的 *sql.DB 由调用方关闭
+func (r *Router) Unregister(node *consistent.Node) *sql.DB {
+	r.Lock()
+	defer r.Unlock()
+
+	db, ok := r.dbs[node.Id]
+	if !ok {
+		return nil
+	}
+
+	r.ring.Remove(node)
+	delete(r.dbs, node.Id)
+	delete(r.nodes, node.Id)
+	return db
+}
+
+func (r *Router) NodeFor(key string) (consistent.Node, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if len(r.dbs) == 0 {
+		return consistent.Node{}, false
+	}
+
+	node, err := r.ring.Get(key)
+	return node, err == nil
+}
+
+func (r *Router) ShardFor(key string) *sql.DB {
+	r.RLock()
+	defer r.RUnlock()
+
+	node, err := r.ring.Get(key)
+	if err != nil {
+		return nil
+	}
+
+	return r.dbs[node.Id]
+}
+
+func (r *Router) ExecOnShard(ctx context.Context, key string, query string, args ...interface{}) (sql.Result, error) {
+	db := r.ShardFor(key)
+	if db == nil {
+		return nil, ErrNoShards
+	}
+
+	return db.ExecContext(ctx, query, args...)
+}
+
+func (r *Router) QueryOnShard(ctx context.Context, key string, query string, args ...interface{}) (*sql.Rows, error) {
+	db := r.ShardFor(key)
+	if db == nil {
+		return nil, ErrNoShards
+	}
+
+	return db.QueryContext(ctx, query, args...)
+}
+
+func (r *Router) QueryRowOnShard(ctx context.Context, key string, query string, args ...interface{}) (*sql.Row, error) {
+	db := r.ShardFor(key)
+	if db == nil {
+		return nil, ErrNoShards
+	}
+
+	return db.QueryRowContext(ctx, query, args...), nil
+}
+
+func (r *Router) shards() map[int]*sql.DB {
+	r.RLock()
+	defer r.RUnlock()
+
+	dbs := make(map[int]*sql.DB, len(r.dbs))
+	for id, db := range r.dbs {
+		dbs[id] = db
+	}
+
+	return dbs
+}
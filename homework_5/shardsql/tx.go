@@ -0,0 +1,52 @@
+package shardsql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// Tx 固定在 key 所在的分片上, 即使事务期间环发生变化也不会切库
+type Tx struct {
+	*sql.Tx
+	Key  string
+	Node consistent.Node
+}
+
+func (r *Router) BeginTx(ctx context.Context, key string, opts *sql.TxOptions) (*Tx, error) {
+	r.RLock()
+	if len(r.dbs) == 0 {
+		r.RUnlock()
+		return nil, ErrNoShards
+	}
+	node, err := r.ring.Get(key)
+	db := r.dbs[node.Id]
+	r.RUnlock()
+
+	if err != nil {
+		return nil, ErrNoShards
+	}
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{Tx: tx, Key: key, Node: node}, nil
+}
+
+// WithTx 在 key 所在分片上执行 fn, fn 返回错误时回滚, 否则提交
+func (r *Router) WithTx(ctx context.Context, key string, opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	tx, err := r.BeginTx(ctx, key, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
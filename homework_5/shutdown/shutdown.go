@@ -0,0 +1,134 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_POLL    = 100 * time.Millisecond
+	DEFAULT_TIMEOUT = 30 * time.Second
+)
+
+var ErrNotMember = errors.New("shutdown: node is not a member")
+
+// Membership 是节点修改自己在环上状态的接口, ringserver.Client 满足这个接口
+type Membership interface {
+	DrainNode(ctx context.Context, id int) (bool, error)
+	RemoveNode(ctx context.Context, id int) (bool, error)
+}
+
+// Load 报告节点上还没处理完的 key 数
+type Load interface {
+	Inflight() int
+}
+
+// Counter 是最简单的 Load, 处理 key 前 Inc, 处理完 Done
+type Counter struct {
+	sync.Mutex
+	keys map[string]int
+	n    int
+}
+
+func NewCounter() *Counter {
+	return &Counter{keys: make(map[string]int)}
+}
+
+func (c *Counter) Inc(key string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.keys[key]++
+	c.n++
+}
+
+func (c *Counter) Done(key string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.keys[key] == 0 {
+		return
+	}
+	if c.keys[key]--; c.keys[key] == 0 {
+		delete(c.keys, key)
+	}
+	c.n--
+}
+
+func (c *Counter) Inflight() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.n
+}
+
+type Coordinator struct {
+	Id         int
+	Membership Membership
+	Load       Load
+	// Timeout 是等待在途请求的最长时间, 到期后不再等待直接下线
+	Timeout time.Duration
+	Poll    time.Duration
+	Logf    func(format string, args ...interface{})
+}
+
+func NewCoordinator(id int, m Membership, load Load) *Coordinator {
+	return &Coordinator{Id: id, Membership: m, Load: load, Timeout: DEFAULT_TIMEOUT, Poll: DEFAULT_POLL}
+}
+
+func (c *Coordinator) logf(format string, args ...interface{}) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
+
+// Shutdown 依次: 标记为 draining 不再接收新 key, 等在途的 key 处理完或者超时, 最后把自己从环上删除.
+// 超时不算错误, 返回值只反映 draining 和删除是否成功
+func (c *Coordinator) Shutdown(ctx context.Context) error {
+	drained, err := c.Membership.DrainNode(ctx, c.Id)
+	if err != nil {
+		return err
+	}
+	if !drained {
+		c.logf("shutdown: node %d was already draining or unknown", c.Id)
+	}
+
+	c.wait(ctx)
+
+	removed, err := c.Membership.RemoveNode(ctx, c.Id)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotMember
+	}
+	return nil
+}
+
+func (c *Coordinator) wait(ctx context.Context) {
+	if c.Load == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(c.Poll)
+	defer ticker.Stop()
+
+	for {
+		n := c.Load.Inflight()
+		if n == 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			c.logf("shutdown: node %d leaving with %d keys still in flight", c.Id, n)
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package simulate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/axiusilihao/geek_homework/homework_5/bench"
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+// ChurnAlgorithm 用 bench.Builders 里的算法执行同样的事件, 每一步用当时的成员重新构造算法,
+// 所以统计的是算法本身的移动量; 对不看权重的算法 (hrw、jump、modulo) weight 事件不会移动 key
+func ChurnAlgorithm(name string, initial []consistent.Node, events []Event, sample []string) ([]Step, error) {
+	build, ok := bench.Builders[name]
+	if !ok {
+		return nil, fmt.Errorf("simulate: unknown algorithm %q", name)
+	}
+
+	members := make(map[int]consistent.Node)
+	for _, node := range initial {
+		if _, ok := members[node.Id]; !ok {
+			members[node.Id] = node
+		}
+	}
+
+	owners, err := assignStrategy(build, members, sample)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]Step, 0, len(events))
+	cumulative := 0
+
+	for _, e := range events {
+		switch e.Op {
+		case OP_ADD:
+			if _, ok := members[e.Node.Id]; !ok {
+				members[e.Node.Id] = e.Node
+			}
+		case OP_REMOVE:
+			if node, ok := members[e.Node.Id]; ok {
+				delete(members, node.Id)
+				e.Node = node
+			}
+		case OP_WEIGHT:
+			if node, ok := members[e.Node.Id]; ok && e.Node.Weight >= 0 {
+				node.Weight = e.Node.Weight
+				members[node.Id] = node
+				e.Node = node
+			}
+		default:
+			return nil, ErrUnknownOp
+		}
+
+		next, err := assignStrategy(build, members, sample)
+		if err != nil {
+			return nil, err
+		}
+		moved := 0
+		for i := range next {
+			if next[i] != owners[i] {
+				moved++
+			}
+		}
+		owners = next
+		cumulative += moved
+
+		steps = append(steps, Step{
+			Event:         e,
+			Nodes:         len(members),
+			Moved:         moved,
+			MovedFraction: float64(moved) / float64(len(sample)),
+			Cumulative:    cumulative,
+			Balance:       balance(owners, members, weightShare),
+		})
+	}
+
+	return steps, nil
+}
+
+// ChurnCompare 对每个算法执行一次 ChurnAlgorithm, "ring" 使用 ChurnKeys, 与增量修改的环完全一致
+func ChurnCompare(names []string, initial []consistent.Node, events []Event, sample []string) (map[string][]Step, error) {
+	res := make(map[string][]Step, len(names))
+	for _, name := range names {
+		var steps []Step
+		var err error
+		if name == "ring" {
+			steps, err = ChurnKeys(initial, events, sample)
+		} else {
+			steps, err = ChurnAlgorithm(name, initial, events, sample)
+		}
+		if err != nil {
+			return nil, err
+		}
+		res[name] = steps
+	}
+	return res, nil
+}
+
+// assignStrategy 按 Id 顺序构造, 同样的成员得到同样的结果; 没有成员时 owner 都是 -1
+func assignStrategy(build bench.Builder, members map[int]consistent.Node, sample []string) ([]int, error) {
+	owners := make([]int, len(sample))
+	if len(members) == 0 {
+		for i := range owners {
+			owners[i] = -1
+		}
+		return owners, nil
+	}
+
+	nodes := make([]consistent.Node, 0, len(members))
+	for _, node := range members {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Id < nodes[j].Id
+	})
+
+	s, err := build(nodes)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range sample {
+		owners[i] = s.Get(key)
+	}
+	return owners, nil
+}
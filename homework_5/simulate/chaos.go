@@ -0,0 +1,218 @@
+package simulate
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+type ChaosConfig struct {
+	Seed  int64 `json:"seed"`
+	Ticks int   `json:"ticks"`
+	Keys  int   `json:"keys"`
+	// 每个 tick 发生各种故障的概率
+	KillProb    float64 `json:"kill_prob"`
+	RestoreProb float64 `json:"restore_prob"`
+	LatencyProb float64 `json:"latency_prob"`
+	// 健康检查的延迟模型, 超过 ProbeTimeout 算失败
+	BaseLatency  time.Duration `json:"base_latency"`
+	LatencySpike time.Duration `json:"latency_spike"`
+	SpikeTicks   int           `json:"spike_ticks"`
+	ProbeTimeout time.Duration `json:"probe_timeout"`
+	// 连续失败 FailThreshold 次摘除, 连续成功 RecoverThreshold 次恢复
+	FailThreshold    int `json:"fail_threshold"`
+	RecoverThreshold int `json:"recover_threshold"`
+	// 主节点不可用时最多再尝试几个后继节点
+	Failover int `json:"failover"`
+}
+
+func DefaultChaosConfig() ChaosConfig {
+	return ChaosConfig{
+		Seed:             1,
+		Ticks:            60,
+		Keys:             2_0000,
+		KillProb:         0.1,
+		RestoreProb:      0.2,
+		LatencyProb:      0.1,
+		BaseLatency:      5 * time.Millisecond,
+		LatencySpike:     500 * time.Millisecond,
+		SpikeTicks:       3,
+		ProbeTimeout:     200 * time.Millisecond,
+		FailThreshold:    3,
+		RecoverThreshold: 2,
+		Failover:         1,
+	}
+}
+
+type ChaosTick struct {
+	Tick       int      `json:"tick"`
+	Events     []string `json:"events"`
+	Down       []int    `json:"down"`
+	Evicted    []int    `json:"evicted"`
+	Failed     float64  `json:"failed"`
+	FailedOver float64  `json:"failed_over"`
+	Moved      float64  `json:"moved"`
+}
+
+type ChaosReport struct {
+	Config ChaosConfig `json:"config"`
+	Ticks  []ChaosTick `json:"ticks"`
+	// 汇总: 请求失败率, 故障转移比例, 从宕机到被摘除平均用了多少个 tick
+	Failed         float64 `json:"failed"`
+	FailedOver     float64 `json:"failed_over"`
+	MeanDetection  float64 `json:"mean_detection_ticks"`
+	FalseEvictions int     `json:"false_evictions"`
+}
+
+type chaosNode struct {
+	node      consistent.Node
+	down      bool
+	downSince int
+	spike     int
+	fails     int
+	oks       int
+	evicted   bool
+}
+
+// Chaos 按 cfg.Seed 生成的故障序列运行模拟, 同一个 seed 的结果完全一致
+func Chaos(initial []consistent.Node, cfg ChaosConfig) *ChaosReport {
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	report := &ChaosReport{Config: cfg}
+
+	nodes := make([]*chaosNode, 0, len(initial))
+	ring := consistent.NewConsistent()
+	for i := range initial {
+		n := &chaosNode{node: initial[i]}
+		if ring.Add(&n.node) {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].node.Id < nodes[j].node.Id
+	})
+
+	sample := make([]string, cfg.Keys)
+	for i := range sample {
+		sample[i] = Key(i)
+	}
+
+	byId := make(map[int]*chaosNode, len(nodes))
+	for _, n := range nodes {
+		byId[n.node.Id] = n
+	}
+
+	var owners []int
+	detections, detected := 0, 0
+	failedTotal, failedOverTotal := 0, 0
+	routable := len(nodes)
+
+	for tick := 0; tick < cfg.Ticks; tick++ {
+		t := ChaosTick{Tick: tick, Events: []string{}, Down: []int{}, Evicted: []int{}}
+
+		// 1. 注入故障
+		for _, n := range nodes {
+			switch {
+			case !n.down && rnd.Float64() < cfg.KillProb:
+				n.down, n.downSince = true, tick
+				t.Events = append(t.Events, fmt.Sprintf("kill %d", n.node.Id))
+			case n.down && rnd.Float64() < cfg.RestoreProb:
+				n.down = false
+				t.Events = append(t.Events, fmt.Sprintf("restore %d", n.node.Id))
+			case !n.down && n.spike == 0 && rnd.Float64() < cfg.LatencyProb:
+				n.spike = cfg.SpikeTicks
+				t.Events = append(t.Events, fmt.Sprintf("latency %d", n.node.Id))
+			}
+		}
+
+		// 2. 健康检查, 决定节点是否留在路由环上
+		for _, n := range nodes {
+			latency := cfg.BaseLatency
+			if n.spike > 0 {
+				latency += cfg.LatencySpike
+				n.spike--
+			}
+
+			if n.down || latency > cfg.ProbeTimeout {
+				n.fails++
+				n.oks = 0
+			} else {
+				n.oks++
+				n.fails = 0
+			}
+
+			if !n.evicted && n.fails >= cfg.FailThreshold && routable > 1 {
+				ring.Remove(&n.node)
+				n.evicted = true
+				routable--
+				t.Events = append(t.Events, fmt.Sprintf("evict %d", n.node.Id))
+				if n.down {
+					detections += tick - n.downSince
+					detected++
+				} else {
+					report.FalseEvictions++
+				}
+			} else if n.evicted && n.oks >= cfg.RecoverThreshold {
+				ring.Add(&n.node)
+				n.evicted = false
+				routable++
+				t.Events = append(t.Events, fmt.Sprintf("readmit %d", n.node.Id))
+			}
+
+			if n.down {
+				t.Down = append(t.Down, n.node.Id)
+			}
+			if n.evicted {
+				t.Evicted = append(t.Evicted, n.node.Id)
+			}
+		}
+
+		// 3. 路由样本 key, 主节点不可用时沿环找后继
+		next := make([]int, len(sample))
+		failed, failedOver, moved := 0, 0, 0
+		for i, key := range sample {
+			candidates := ring.GetN(key, cfg.Failover+1)
+			next[i] = candidates[0].Id
+
+			served := false
+			for j, c := range candidates {
+				if !byId[c.Id].down {
+					served = true
+					if j > 0 {
+						failedOver++
+					}
+					break
+				}
+			}
+			if !served {
+				failed++
+			}
+
+			if owners != nil && owners[i] != next[i] {
+				moved++
+			}
+		}
+		owners = next
+
+		t.Failed = float64(failed) / float64(len(sample))
+		t.FailedOver = float64(failedOver) / float64(len(sample))
+		t.Moved = float64(moved) / float64(len(sample))
+		failedTotal += failed
+		failedOverTotal += failedOver
+
+		report.Ticks = append(report.Ticks, t)
+	}
+
+	requests := float64(len(sample) * cfg.Ticks)
+	if requests > 0 {
+		report.Failed = float64(failedTotal) / requests
+		report.FailedOver = float64(failedOverTotal) / requests
+	}
+	if detected > 0 {
+		report.MeanDetection = float64(detections) / float64(detected)
+	}
+
+	return report
+}
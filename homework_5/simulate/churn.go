@@ -0,0 +1,198 @@
+package simulate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+	"github.com/axiusilihao/geek_homework/homework_5/stats"
+)
+
+const (
+	OP_ADD    = "add"
+	OP_REMOVE = "remove"
+	OP_WEIGHT = "weight"
+)
+
+var ErrUnknownOp = errors.New("simulate: unknown event op")
+
+// Event 是一次成员变化, remove 只需要填 Node.Id, weight 只需要填 Node.Id 和新的 Node.Weight
+type Event struct {
+	Op   string          `json:"op"`
+	Node consistent.Node `json:"node"`
+}
+
+type Balance struct {
+	StdDev     float64 `json:"stddev"`
+	PeakToMean float64 `json:"peak_to_mean"`
+}
+
+type Step struct {
+	Event         Event   `json:"event"`
+	Nodes         int     `json:"nodes"`
+	Moved         int     `json:"moved"`
+	MovedFraction float64 `json:"moved_fraction"`
+	Cumulative    int     `json:"cumulative"`
+	Balance       Balance `json:"balance"`
+}
+
+func Key(i int) string {
+	return fmt.Sprintf("key%d", i)
+}
+
+// Churn 从 initial 开始依次执行 events, 每一步统计有多少 key 换了节点以及当时的均衡度
+func Churn(initial []consistent.Node, events []Event, keys int) ([]Step, error) {
+	sample := make([]string, keys)
+	for i := range sample {
+		sample[i] = Key(i)
+	}
+
+	return ChurnKeys(initial, events, sample)
+}
+
+// ChurnReport 记录样本 key 的来源, 同样的 Source 和事件得到同样的结果
+type ChurnReport struct {
+	Source keygen.Source `json:"source"`
+	Steps  []Step        `json:"steps"`
+}
+
+func ChurnSource(initial []consistent.Node, events []Event, src keygen.Source, opts ...consistent.Option) (*ChurnReport, error) {
+	sample, err := src.Sample()
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := ChurnKeys(initial, events, sample, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChurnReport{Source: src, Steps: steps}, nil
+}
+
+// ChurnKeys 使用给定的 key 样本, 样本里重复的 key 按出现次数计入移动量, 可以配合 keygen 模拟倾斜流量;
+// opts 用来比较不同的虚拟节点数和哈希函数
+func ChurnKeys(initial []consistent.Node, events []Event, sample []string, opts ...consistent.Option) ([]Step, error) {
+	keys := len(sample)
+	c := consistent.NewConsistent(opts...)
+	members := make(map[int]consistent.Node)
+	for i := range initial {
+		node := initial[i]
+		if c.Add(&node) {
+			members[node.Id] = node
+		}
+	}
+
+	owners := assign(c, members, sample)
+	steps := make([]Step, 0, len(events))
+	cumulative := 0
+
+	for _, e := range events {
+		switch e.Op {
+		case OP_ADD:
+			node := e.Node
+			if c.Add(&node) {
+				members[node.Id] = node
+			}
+		case OP_REMOVE:
+			if node, ok := members[e.Node.Id]; ok {
+				c.Remove(&node)
+				delete(members, node.Id)
+				e.Node = node
+			}
+		case OP_WEIGHT:
+			if node, ok := members[e.Node.Id]; ok && c.UpdateWeight(node.Id, e.Node.Weight) {
+				node.Weight = e.Node.Weight
+				members[node.Id] = node
+				e.Node = node
+			}
+		default:
+			return nil, ErrUnknownOp
+		}
+
+		next := assign(c, members, sample)
+		moved := 0
+		for i := range next {
+			if next[i] != owners[i] {
+				moved++
+			}
+		}
+		owners = next
+		cumulative += moved
+
+		steps = append(steps, Step{
+			Event:         e,
+			Nodes:         len(members),
+			Moved:         moved,
+			MovedFraction: float64(moved) / float64(keys),
+			Cumulative:    cumulative,
+			Balance:       balance(owners, members, c.ReplicaCount),
+		})
+	}
+
+	return steps, nil
+}
+
+// assign 空环时所有 key 的 owner 都是 -1
+func assign(c *consistent.Consistent, members map[int]consistent.Node, sample []string) []int {
+	owners := make([]int, len(sample))
+	for i, key := range sample {
+		node, err := c.Get(key)
+		if len(members) == 0 || err != nil {
+			owners[i] = -1
+			continue
+		}
+		owners[i] = node.Id
+	}
+
+	return owners
+}
+
+// balance 的 PeakToMean 按期望份额归一化, 1 表示完全按份额分布; 节点的期望份额是 share(node) 占总和的比例,
+// 环用 ReplicaCount, 与 Replicas、Capacity 覆盖权重时一致. 期望为 0 的节点不参与 PeakToMean, 总和为 0 时按节点数平均
+func balance(owners []int, members map[int]consistent.Node, share func(consistent.Node) int) Balance {
+	if len(members) == 0 {
+		return Balance{}
+	}
+
+	counts := make(map[int]int, len(members))
+	for _, id := range owners {
+		counts[id]++
+	}
+
+	total := 0
+	for _, node := range members {
+		if w := share(node); w > 0 {
+			total += w
+		}
+	}
+
+	values := make([]float64, 0, len(members))
+	peak := 0.0
+	for id, node := range members {
+		v := float64(counts[id])
+		values = append(values, v)
+
+		expected := float64(len(owners)) / float64(len(members))
+		if total > 0 {
+			expected = float64(len(owners)) * float64(share(node)) / float64(total)
+		}
+		if expected <= 0 {
+			continue
+		}
+		if ratio := v / expected; ratio > peak {
+			peak = ratio
+		}
+	}
+
+	return Balance{
+		StdDev:     stats.StdDev(values),
+		PeakToMean: peak,
+	}
+}
+
+// weightShare 是 bench 里的算法的期望份额, 它们只按 Weight 分配
+func weightShare(node consistent.Node) int {
+	return node.Weight
+}
@@ -0,0 +1,112 @@
+package simulate
+
+import (
+	"math"
+	"runtime"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+)
+
+var DefaultReplicaCandidates = []int{10, 20, 40, 80, 160, 320, 640, 1280, 2560}
+
+type TuneOption struct {
+	Replicas      int     `json:"replicas"`
+	VirtualNodes  int     `json:"virtual_nodes"`
+	PeakToMean    float64 `json:"peak_to_mean"`
+	ArcPeakToMean float64 `json:"arc_peak_to_mean"`
+	Bytes         uint64  `json:"bytes"`
+}
+
+type TuneResult struct {
+	Target      float64       `json:"target"`
+	Keys        int           `json:"keys"`
+	Source      keygen.Source `json:"source"`
+	Options     []TuneOption  `json:"options"`
+	Recommended *TuneOption   `json:"recommended"`
+}
+
+// Tune 依次尝试候选的副本数, 推荐满足 peak/mean <= target 的最小值.
+// PeakToMean 用 keys 个样本 key 统计, 包含了 key 数量有限带来的抽样误差; ArcPeakToMean 按区间长度计算.
+func Tune(nodes []consistent.Node, keys int, target float64, candidates []int) *TuneResult {
+	if len(candidates) == 0 {
+		candidates = DefaultReplicaCandidates
+	}
+
+	sample := make([]string, keys)
+	for i := range sample {
+		sample[i] = Key(i)
+	}
+
+	res := &TuneResult{Target: target, Keys: keys, Source: keygen.SequentialSource(keys)}
+	for _, replicas := range candidates {
+		c, bytes := buildMeasured(nodes, replicas)
+
+		members := make(map[int]consistent.Node)
+		for _, node := range c.Members() {
+			members[node.Id] = node
+		}
+
+		opt := TuneOption{
+			Replicas:      replicas,
+			VirtualNodes:  len(c.Nodes),
+			ArcPeakToMean: arcPeakToMean(c, members),
+			Bytes:         bytes,
+		}
+		if keys > 0 && len(members) > 0 {
+			opt.PeakToMean = balance(assign(c, members, sample), members, c.ReplicaCount).PeakToMean
+		} else {
+			opt.PeakToMean = opt.ArcPeakToMean
+		}
+
+		res.Options = append(res.Options, opt)
+	}
+
+	for i := range res.Options {
+		if res.Options[i].PeakToMean <= target {
+			res.Recommended = &res.Options[i]
+			break
+		}
+	}
+
+	return res
+}
+
+func buildMeasured(nodes []consistent.Node, replicas int) (*consistent.Consistent, uint64) {
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	c := consistent.NewConsistent(consistent.WithReplicas(replicas))
+	for i := range nodes {
+		node := nodes[i]
+		c.Add(&node)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	if after.HeapAlloc < before.HeapAlloc {
+		return c, 0
+	}
+	return c, after.HeapAlloc - before.HeapAlloc
+}
+
+// arcPeakToMean 的目标份额是节点的 ReplicaCount 占总数的比例, 目标为 0 的节点不参与
+func arcPeakToMean(c *consistent.Consistent, members map[int]consistent.Node) float64 {
+	total := 0
+	for _, node := range members {
+		total += c.ReplicaCount(node)
+	}
+	if total == 0 {
+		return 0
+	}
+
+	peak := 0.0
+	for id, share := range Shares(c) {
+		target := float64(c.ReplicaCount(members[id])) / float64(total)
+		if target > 0 {
+			peak = math.Max(peak, share/target)
+		}
+	}
+	return peak
+}
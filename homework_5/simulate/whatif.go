@@ -0,0 +1,73 @@
+package simulate
+
+import (
+	"sort"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+type ShareChange struct {
+	Node   consistent.Node `json:"node"`
+	Before float64         `json:"before"`
+	After  float64         `json:"after"`
+}
+
+func (s ShareChange) Delta() float64 {
+	return s.After - s.Before
+}
+
+type Prediction struct {
+	Moved  float64       `json:"moved"`
+	Shares []ShareChange `json:"shares"`
+}
+
+// Shares 按环上区间长度计算每个节点拥有的哈希空间比例, 与 Consistent.Ownership 相同
+func Shares(c *consistent.Consistent) map[int]float64 {
+	return c.Ownership()
+}
+
+// Moved 返回归属发生变化的哈希空间比例; 两个环的 RingBits 必须相同, 任一个环为空时返回 0
+func Moved(before, after *consistent.Consistent) float64 {
+	if before.VirtualNodeCount() == 0 || after.VirtualNodeCount() == 0 {
+		return 0
+	}
+
+	moved := 0.0
+	for _, m := range consistent.Diff(before, after) {
+		moved += m.Fraction
+	}
+	return moved
+}
+
+// WhatIf 对比两组节点构成的环, 不会修改任何正在使用的环
+func WhatIf(before, after []consistent.Node) *Prediction {
+	rb, ra := build(before), build(after)
+	sb, sa := Shares(rb), Shares(ra)
+
+	nodes := make(map[int]consistent.Node)
+	for _, node := range before {
+		nodes[node.Id] = node
+	}
+	for _, node := range after {
+		nodes[node.Id] = node
+	}
+
+	p := &Prediction{Moved: Moved(rb, ra)}
+	for id, node := range nodes {
+		p.Shares = append(p.Shares, ShareChange{Node: node, Before: sb[id], After: sa[id]})
+	}
+
+	sort.Slice(p.Shares, func(i, j int) bool {
+		return p.Shares[i].Node.Id < p.Shares[j].Node.Id
+	})
+	return p
+}
+
+func build(nodes []consistent.Node) *consistent.Consistent {
+	c := consistent.NewConsistent()
+	for i := range nodes {
+		node := nodes[i]
+		c.Add(&node)
+	}
+	return c
+}
@@ -0,0 +1,83 @@
+package simulate
+
+import (
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"github.com/axiusilihao/geek_homework/homework_5/keygen"
+	"github.com/axiusilihao/geek_homework/homework_5/stats"
+)
+
+// NodeHits 是一个节点在模拟中收到的请求, Expected 是按权重应得的比例
+type NodeHits struct {
+	Id       int     `json:"id"`
+	Weight   int     `json:"weight"`
+	Hits     int     `json:"hits"`
+	Share    float64 `json:"share"`
+	Expected float64 `json:"expected"`
+}
+
+// Workload 是 Simulate 的结果. Summary 是各节点请求数的统计, PeakToMean 按权重归一化,
+// ChiSquare 检验请求是否按权重分布; 倾斜的负载下热点 key 会让检验显著, 这是负载本身的问题而不是环的问题
+type Workload struct {
+	Requests   int                   `json:"requests"`
+	Unrouted   int                   `json:"unrouted"`
+	Nodes      []NodeHits            `json:"nodes"`
+	Summary    stats.Summary         `json:"summary"`
+	PeakToMean float64               `json:"peak_to_mean"`
+	ChiSquare  stats.ChiSquareResult `json:"chi_square"`
+}
+
+// Simulate 从 gen 取 n 个 key 在 ring 上查询, 统计每个参与路由的节点收到的请求, 不修改 ring.
+// 常用的 gen 是 keygen.Uniform、keygen.Zipf 和 keygen.Sequential
+func Simulate(ring *consistent.Consistent, gen keygen.Generator, n int) Workload {
+	res := Workload{Requests: n, Nodes: make([]NodeHits, 0)}
+
+	counts := make(map[int]int)
+	for i := 0; i < n; i++ {
+		node, err := ring.Get(gen.Next())
+		if err != nil {
+			res.Unrouted++
+			continue
+		}
+		counts[node.Id]++
+	}
+
+	members := make([]consistent.Node, 0)
+	weights := 0
+	for _, node := range ring.Members() {
+		if ring.IsDraining(node.Id) || ring.IsDown(node.Id) {
+			continue
+		}
+		members = append(members, node)
+		weights += node.Weight
+	}
+
+	routed := n - res.Unrouted
+	if len(members) == 0 || routed == 0 || weights == 0 {
+		return res
+	}
+
+	hits := make([]float64, 0, len(members))
+	expected := make([]float64, 0, len(members))
+	for _, node := range members {
+		nh := NodeHits{
+			Id:       node.Id,
+			Weight:   node.Weight,
+			Hits:     counts[node.Id],
+			Share:    float64(counts[node.Id]) / float64(routed),
+			Expected: float64(node.Weight) / float64(weights),
+		}
+		res.Nodes = append(res.Nodes, nh)
+		hits = append(hits, float64(nh.Hits))
+		expected = append(expected, nh.Expected*float64(routed))
+
+		if nh.Expected > 0 {
+			if ratio := nh.Share / nh.Expected; ratio > res.PeakToMean {
+				res.PeakToMean = ratio
+			}
+		}
+	}
+
+	res.Summary = stats.Summarize(hits)
+	res.ChiSquare = stats.ChiSquare(hits, expected)
+	return res
+}
@@ -0,0 +1,187 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary 是一组样本的统计, 方差按总体方差计算 (除以 n), 与仓库里其它地方的标准差一致
+type Summary struct {
+	Count    int     `json:"count"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	StdDev   float64 `json:"stddev"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	P50      float64 `json:"p50"`
+	P95      float64 `json:"p95"`
+	P99      float64 `json:"p99"`
+}
+
+// Summarize 不修改 vals, 样本为空时返回零值
+func Summarize(vals []float64) Summary {
+	if len(vals) == 0 {
+		return Summary{}
+	}
+
+	sorted := append([]float64(nil), vals...)
+	sort.Float64s(sorted)
+
+	s := Summary{
+		Count:    len(vals),
+		Mean:     Mean(vals),
+		Variance: Variance(vals),
+		Min:      sorted[0],
+		Max:      sorted[len(sorted)-1],
+		P50:      Percentile(sorted, 0.50),
+		P95:      Percentile(sorted, 0.95),
+		P99:      Percentile(sorted, 0.99),
+	}
+	s.StdDev = math.Sqrt(s.Variance)
+	return s
+}
+
+// Floats 把计数转成 float64, 方便直接传给其它函数
+func Floats(vals []int) []float64 {
+	fs := make([]float64, len(vals))
+	for i, v := range vals {
+		fs[i] = float64(v)
+	}
+	return fs
+}
+
+// Mean 按样本个数求平均, 样本为空时返回 0
+func Mean(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range vals {
+		sum += v
+	}
+	return sum / float64(len(vals))
+}
+
+func Variance(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+
+	mean := Mean(vals)
+	variance := 0.0
+	for _, v := range vals {
+		variance += (v - mean) * (v - mean)
+	}
+	return variance / float64(len(vals))
+}
+
+func StdDev(vals []float64) float64 {
+	return math.Sqrt(Variance(vals))
+}
+
+// Percentile 用最近秩法取 sorted 的 p 分位 (0 < p <= 1), sorted 必须已经从小到大排好; p 为 1 时返回最大值
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// ChiSquareResult 是卡方拟合优度检验的结果, PValue 小 (例如小于 0.05) 说明观测值显著偏离期望
+type ChiSquareResult struct {
+	Statistic float64 `json:"statistic"`
+	DF        int     `json:"df"`
+	PValue    float64 `json:"p_value"`
+}
+
+// ChiSquare 检验 observed 是否符合 expected, 两者长度相同; expected 为 0 的分组被跳过.
+// 分组少于两个时自由度为 0, PValue 为 1
+func ChiSquare(observed, expected []float64) ChiSquareResult {
+	res := ChiSquareResult{}
+	groups := 0
+	for i, e := range expected {
+		if e <= 0 || i >= len(observed) {
+			continue
+		}
+		d := observed[i] - e
+		res.Statistic += d * d / e
+		groups++
+	}
+
+	res.DF = groups - 1
+	if res.DF <= 0 {
+		res.DF, res.PValue = 0, 1
+		return res
+	}
+	res.PValue = gammaQ(float64(res.DF)/2, res.Statistic/2)
+	return res
+}
+
+// ChiSquareUniform 检验 observed 是否均匀分布在各分组上, 例如每个节点分到的 key 数
+func ChiSquareUniform(observed []float64) ChiSquareResult {
+	total := 0.0
+	for _, v := range observed {
+		total += v
+	}
+
+	expected := make([]float64, len(observed))
+	for i := range expected {
+		expected[i] = total / float64(len(observed))
+	}
+	return ChiSquare(observed, expected)
+}
+
+// gammaQ 是正则化的上不完全伽马函数 Q(a, x), 卡方分布的 p 值是 Q(df/2, x/2).
+// x < a+1 时用级数求 P 再取 1-P, 否则用连分数, 与 Numerical Recipes 的做法相同
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+
+	lg, _ := math.Lgamma(a)
+	if x < a+1 {
+		sum, term := 1/a, 1/a
+		for n := 1; n < 1000; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return 1 - sum*math.Exp(-x+a*math.Log(x)-lg)
+	}
+
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lg) * h
+}
@@ -0,0 +1,78 @@
+package sticky
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// KeyFunc 从请求里取会话 key, 取不到时返回 false
+type KeyFunc func(r *http.Request) (string, bool)
+
+func CookieKey(name string) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		cookie, err := r.Cookie(name)
+		if err != nil || cookie.Value == "" {
+			return "", false
+		}
+
+		return cookie.Value, true
+	}
+}
+
+// JWTClaimKey 只解析 Authorization: Bearer 里的 payload, 不校验签名, 鉴权应该在别处完成
+func JWTClaimKey(claim string) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return "", false
+		}
+
+		parts := strings.Split(strings.TrimPrefix(auth, "Bearer "), ".")
+		if len(parts) != 3 {
+			return "", false
+		}
+
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return "", false
+		}
+
+		claims := make(map[string]interface{})
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			return "", false
+		}
+
+		v, ok := claims[claim]
+		if !ok || v == nil {
+			return "", false
+		}
+
+		return fmt.Sprint(v), true
+	}
+}
+
+// FirstKey 依次尝试多个 KeyFunc
+func FirstKey(fns ...KeyFunc) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		for _, fn := range fns {
+			if key, ok := fn(r); ok {
+				return key, true
+			}
+		}
+
+		return "", false
+	}
+}
+
+func remoteKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,224 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_SESSION_TTL = 30 * time.Minute
+)
+
+var ErrUnknownBackend = errors.New("sticky: unknown backend")
+
+type backend struct {
+	node     consistent.Node
+	proxy    *httputil.ReverseProxy
+	draining bool
+	inflight int
+	idle     chan struct{}
+}
+
+type session struct {
+	id       int
+	lastSeen time.Time
+}
+
+// Proxy 按会话 key 把请求 (包括 WebSocket upgrade) 转发到环上的同一个后端.
+// 已经在某个后端上的会话在 TTL 内保持不动, 即使环的成员发生了变化.
+type Proxy struct {
+	sync.Mutex
+	key        KeyFunc
+	ring       *consistent.Consistent
+	backends   map[int]*backend
+	sessions   map[string]*session
+	active     int
+	SessionTTL time.Duration
+}
+
+func NewProxy(key KeyFunc) *Proxy {
+	return &Proxy{
+		key:        key,
+		ring:       consistent.NewConsistent(),
+		backends:   make(map[int]*backend),
+		sessions:   make(map[string]*session),
+		SessionTTL: DEFAULT_SESSION_TTL,
+	}
+}
+
+func (p *Proxy) AddBackend(node *consistent.Node, target *url.URL) bool {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.backends[node.Id]; ok {
+		return false
+	}
+
+	p.ring.Add(node)
+	p.active++
+	p.backends[node.Id] = &backend{
+		node:  *node,
+		proxy: httputil.NewSingleHostReverseProxy(target),
+	}
+	return true
+}
+
+// Drain 让后端不再接新会话, 已有会话继续转发到它
+func (p *Proxy) Drain(id int) error {
+	p.Lock()
+	defer p.Unlock()
+
+	b, ok := p.backends[id]
+	if !ok {
+		return ErrUnknownBackend
+	}
+
+	if !b.draining {
+		b.draining = true
+		p.ring.Remove(&b.node)
+		p.active--
+	}
+	return nil
+}
+
+// WaitDrained 等到后端上没有进行中的请求 (WebSocket 连接关闭) 或 ctx 结束
+func (p *Proxy) WaitDrained(ctx context.Context, id int) error {
+	p.Lock()
+	b, ok := p.backends[id]
+	if !ok {
+		p.Unlock()
+		return ErrUnknownBackend
+	}
+	if b.inflight == 0 {
+		p.Unlock()
+		return nil
+	}
+	if b.idle == nil {
+		b.idle = make(chan struct{})
+	}
+	idle := b.idle
+	p.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RemoveBackend 直接摘掉后端, 一般在 Drain + WaitDrained 之后调用
+func (p *Proxy) RemoveBackend(id int) {
+	p.Lock()
+	defer p.Unlock()
+
+	b, ok := p.backends[id]
+	if !ok {
+		return
+	}
+
+	if !b.draining {
+		p.ring.Remove(&b.node)
+		p.active--
+	}
+	delete(p.backends, id)
+
+	for key, s := range p.sessions {
+		if s.id == id {
+			delete(p.sessions, key)
+		}
+	}
+}
+
+func (p *Proxy) InFlight(id int) int {
+	p.Lock()
+	defer p.Unlock()
+
+	if b, ok := p.backends[id]; ok {
+		return b.inflight
+	}
+	return 0
+}
+
+func (p *Proxy) pick(key string, now time.Time) (*backend, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	if s, ok := p.sessions[key]; ok && now.Sub(s.lastSeen) < p.SessionTTL {
+		if b, ok := p.backends[s.id]; ok {
+			s.lastSeen = now
+			b.inflight++
+			return b, true
+		}
+	}
+
+	node, err := p.ring.Get(key)
+	if p.active == 0 || err != nil {
+		return nil, false
+	}
+
+	b := p.backends[node.Id]
+	p.sessions[key] = &session{id: b.node.Id, lastSeen: now}
+	b.inflight++
+	return b, true
+}
+
+func (p *Proxy) done(b *backend) {
+	p.Lock()
+	defer p.Unlock()
+
+	b.inflight--
+	if b.inflight == 0 && b.idle != nil {
+		close(b.idle)
+		b.idle = nil
+	}
+}
+
+// Sweep 清理超过 TTL 的会话记录
+func (p *Proxy) Sweep() {
+	p.Lock()
+	defer p.Unlock()
+
+	now := time.Now()
+	for key, s := range p.sessions {
+		if now.Sub(s.lastSeen) >= p.SessionTTL {
+			delete(p.sessions, key)
+		}
+	}
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := p.key(r)
+	if !ok {
+		key = remoteKey(r)
+	}
+
+	b, ok := p.pick(key, time.Now())
+	if !ok {
+		http.Error(w, "no backend available", http.StatusServiceUnavailable)
+		return
+	}
+	defer p.done(b)
+
+	// ReverseProxy 本身支持 Upgrade, WebSocket 连接期间 ServeHTTP 不会返回
+	b.proxy.ServeHTTP(w, r)
+}
+
+// Middleware 只转发能取到会话 key 的请求, 其余交给 next
+func (p *Proxy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := p.key(r); !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,64 @@
+package topology
+
+import (
+	"sort"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+type Reweight struct {
+	Node consistent.Node `json:"node"`
+	From int             `json:"from"`
+	To   int             `json:"to"`
+}
+
+type Change struct {
+	Old consistent.Node `json:"old"`
+	New consistent.Node `json:"new"`
+}
+
+// Diff 描述两份拓扑之间的成员变化, Changed 是同一个 Id 的地址或名字发生了变化
+type Diff struct {
+	Added      []consistent.Node `json:"added"`
+	Removed    []consistent.Node `json:"removed"`
+	Reweighted []Reweight        `json:"reweighted"`
+	Changed    []Change          `json:"changed"`
+}
+
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Reweighted) == 0 && len(d.Changed) == 0
+}
+
+func Compare(old, new *Topology) *Diff {
+	d := &Diff{
+		Added:      []consistent.Node{},
+		Removed:    []consistent.Node{},
+		Reweighted: []Reweight{},
+		Changed:    []Change{},
+	}
+
+	for _, o := range old.Nodes {
+		n, ok := new.Find(o.Id)
+		if !ok {
+			d.Removed = append(d.Removed, o)
+			continue
+		}
+
+		if o.Weight != n.Weight {
+			d.Reweighted = append(d.Reweighted, Reweight{Node: n, From: o.Weight, To: n.Weight})
+		}
+		if o.Ip != n.Ip || o.Port != n.Port || o.HostName != n.HostName {
+			d.Changed = append(d.Changed, Change{Old: o, New: n})
+		}
+	}
+
+	for _, n := range new.Nodes {
+		if _, ok := old.Find(n.Id); !ok {
+			d.Added = append(d.Added, n)
+		}
+	}
+
+	sort.Slice(d.Added, func(i, j int) bool { return d.Added[i].Id < d.Added[j].Id })
+	sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].Id < d.Removed[j].Id })
+	return d
+}
@@ -0,0 +1,177 @@
+package topology
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrDuplicateNode = errors.New("topology: duplicate node id")
+	ErrNodeNotFound  = errors.New("topology: node not found")
+)
+
+type Replication struct {
+	Factor int `json:"factor"`
+	// ZoneSpread 为 strict 时每个 key 的副本必须落在不同的 zone
+	ZoneSpread string `json:"zone_spread,omitempty"`
+}
+
+const (
+	ZONE_SPREAD_STRICT = "strict"
+)
+
+// Topology 是描述环成员的配置文件, JSON 或 YAML 格式
+type Topology struct {
+	Nodes []consistent.Node `json:"nodes"`
+	// Zones 是旧的写法, 新的拓扑直接写在 Node.Zone 里
+	Zones       map[int]string `json:"zones,omitempty"`
+	Replication *Replication   `json:"replication,omitempty"`
+	// Hasher 是 consistent.Hashers 中的名称, 为空时使用 crc32
+	Hasher string `json:"hasher,omitempty"`
+	// RingBits 为 32 或 64, 为空时使用 32 位的环
+	RingBits int `json:"ring_bits,omitempty"`
+	// Seed 混进所有哈希值, 为空时不加 seed; 修改 Seed 会移动几乎所有的 key
+	Seed uint64 `json:"seed,omitempty"`
+	// Replicas 是权重为 1 的节点的虚拟节点数, 为空时使用 consistent.DEFAULT_REPLICAS
+	Replicas int `json:"replicas,omitempty"`
+	// StrictWrap 为 true 时使用顺时针后继的回绕规则, 见 consistent.WithStrictWrap
+	StrictWrap bool `json:"strict_wrap,omitempty"`
+	// Label 是 consistent.Labels 中的名称, 为空时使用 default, 旧拓扑保持原来的位置要写 legacy
+	Label string `json:"label,omitempty"`
+}
+
+func Load(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := filepath.Ext(path)
+	if ext == ".yaml" || ext == ".yml" {
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &Topology{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// yamlToJSON 让 YAML 文件沿用 Node 上的 json tag, 不需要再维护一套 yaml tag
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+func (t *Topology) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Encode(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (t *Topology) Encode(w io.Writer) error {
+	sort.Slice(t.Nodes, func(i, j int) bool {
+		return t.Nodes[i].Id < t.Nodes[j].Id
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// Options 把拓扑里的环参数转换成 NewConsistent 的选项
+func (t *Topology) Options() []consistent.Option {
+	opts := make([]consistent.Option, 0)
+	if h, ok := consistent.Hashers[t.Hasher]; ok {
+		opts = append(opts, consistent.WithHasher(h))
+	}
+	if t.Seed != 0 {
+		opts = append(opts, consistent.WithSeed(t.Seed))
+	}
+	if t.RingBits != 0 {
+		opts = append(opts, consistent.WithRingBits(t.RingBits))
+	}
+	if t.Replicas > 0 {
+		opts = append(opts, consistent.WithReplicas(t.Replicas))
+	}
+	if t.StrictWrap {
+		opts = append(opts, consistent.WithStrictWrap())
+	}
+	if f, ok := consistent.Labels[t.Label]; ok {
+		opts = append(opts, consistent.WithLabel(f))
+	}
+	return opts
+}
+
+// Ring 构造环, Zones 里的 zone 填到没有 Zone 的节点上
+func (t *Topology) Ring() *consistent.Consistent {
+	c := consistent.NewConsistent(t.Options()...)
+	for i := range t.Nodes {
+		node := t.Nodes[i]
+		node.Zone = t.ZoneOf(node)
+		c.Add(&node)
+	}
+
+	return c
+}
+
+// ZoneOf 优先使用节点自己的 Zone, 没有时使用 Zones 里的
+func (t *Topology) ZoneOf(node consistent.Node) string {
+	if node.Zone != "" {
+		return node.Zone
+	}
+	return t.Zones[node.Id]
+}
+
+func (t *Topology) Find(id int) (consistent.Node, bool) {
+	for _, node := range t.Nodes {
+		if node.Id == id {
+			return node, true
+		}
+	}
+
+	return consistent.Node{}, false
+}
+
+func (t *Topology) Add(node consistent.Node) error {
+	if _, ok := t.Find(node.Id); ok {
+		return ErrDuplicateNode
+	}
+
+	t.Nodes = append(t.Nodes, node)
+	return nil
+}
+
+func (t *Topology) Remove(id int) error {
+	for i, node := range t.Nodes {
+		if node.Id == id {
+			t.Nodes = append(t.Nodes[:i], t.Nodes[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrNodeNotFound
+}
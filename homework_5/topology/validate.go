@@ -0,0 +1,149 @@
+package topology
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	SEVERITY_ERROR   = "error"
+	SEVERITY_WARNING = "warning"
+)
+
+type Problem struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+func (p Problem) String() string {
+	if p.Hint == "" {
+		return p.Severity + ": " + p.Message
+	}
+	return p.Severity + ": " + p.Message + " (" + p.Hint + ")"
+}
+
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == SEVERITY_ERROR {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 检查拓扑在上线前是否可用, 返回的问题按发现顺序排列
+func (t *Topology) Validate() []Problem {
+	problems := make([]Problem, 0)
+	add := func(severity, hint, format string, args ...interface{}) {
+		problems = append(problems, Problem{Severity: severity, Message: fmt.Sprintf(format, args...), Hint: hint})
+	}
+
+	if len(t.Nodes) == 0 {
+		add(SEVERITY_ERROR, "add at least one node", "topology has no nodes")
+	}
+
+	ids := make(map[int]int)
+	endpoints := make(map[string]int)
+	names := make(map[string]int)
+	for _, node := range t.Nodes {
+		if other, ok := ids[node.Id]; ok {
+			add(SEVERITY_ERROR, "give every node a unique id", "node id %d is used by more than one node (first at index %d)", node.Id, other)
+		} else {
+			ids[node.Id] = len(ids)
+		}
+
+		if node.Weight <= 0 && node.Capacity <= 0 && node.Replicas <= 0 {
+			add(SEVERITY_ERROR, "use a positive weight, capacity or replicas, or remove the node", "node %d has weight %d and would own no virtual nodes", node.Id, node.Weight)
+		}
+		if node.Ip == "" {
+			add(SEVERITY_ERROR, "set ip", "node %d has no ip", node.Id)
+		}
+		if node.Port <= 0 || node.Port > 65535 {
+			add(SEVERITY_ERROR, "use a port in 1-65535", "node %d has invalid port %d", node.Id, node.Port)
+		}
+
+		endpoint := net.JoinHostPort(node.Ip, strconv.Itoa(node.Port))
+		if other, ok := endpoints[endpoint]; ok {
+			add(SEVERITY_ERROR, "each endpoint must appear once", "nodes %d and %d both use %s", other, node.Id, endpoint)
+		} else {
+			endpoints[endpoint] = node.Id
+		}
+
+		if node.HostName != "" {
+			if other, ok := names[node.HostName]; ok {
+				add(SEVERITY_WARNING, "host names are used by exporters as server names", "nodes %d and %d share host name %q", other, node.Id, node.HostName)
+			} else {
+				names[node.HostName] = node.Id
+			}
+		}
+	}
+
+	for id := range t.Zones {
+		if _, ok := ids[id]; !ok {
+			add(SEVERITY_WARNING, "remove the stale entry", "zone assigned to unknown node %d", id)
+		}
+	}
+
+	if _, ok := consistent.Hashers[t.Hasher]; t.Hasher != "" && !ok {
+		add(SEVERITY_ERROR, "use crc32, fnv1a, xxhash or murmur3", "unknown hasher %q", t.Hasher)
+	}
+	if t.RingBits != 0 && t.RingBits != 32 && t.RingBits != 64 {
+		add(SEVERITY_ERROR, "use 32 or 64", "unsupported ring_bits %d", t.RingBits)
+	}
+	if _, ok := consistent.Labels[t.Label]; t.Label != "" && !ok {
+		add(SEVERITY_ERROR, "use default or legacy", "unknown label %q", t.Label)
+	}
+	if t.Replicas < 0 {
+		add(SEVERITY_ERROR, "use a positive number or leave it empty", "negative replicas %d", t.Replicas)
+	}
+
+	t.validateReplication(add)
+	t.validatePoints(add)
+	return problems
+}
+
+func (t *Topology) validateReplication(add func(severity, hint, format string, args ...interface{})) {
+	r := t.Replication
+	if r == nil {
+		return
+	}
+
+	if r.Factor <= 0 {
+		add(SEVERITY_ERROR, "use a replication factor of at least 1", "replication factor is %d", r.Factor)
+		return
+	}
+	if r.Factor > len(t.Nodes) {
+		add(SEVERITY_ERROR, "add nodes or lower the replication factor", "replication factor %d needs at least %d nodes, have %d", r.Factor, r.Factor, len(t.Nodes))
+	}
+
+	if r.ZoneSpread != ZONE_SPREAD_STRICT {
+		return
+	}
+
+	zones := make(map[string]bool)
+	for _, node := range t.Nodes {
+		zone := t.ZoneOf(node)
+		if zone == "" {
+			add(SEVERITY_ERROR, "assign a zone to every node when zone_spread is strict", "node %d has no zone", node.Id)
+			continue
+		}
+		zones[zone] = true
+	}
+
+	if len(zones) < r.Factor {
+		add(SEVERITY_ERROR, "add zones, lower the replication factor or relax zone_spread",
+			"replication factor %d with strict zone spread needs %d zones, have %d", r.Factor, r.Factor, len(zones))
+	}
+}
+
+// validatePoints 统计加入时需要探测才能落位的虚拟节点, 这些点的位置依赖节点加入的顺序
+func (t *Topology) validatePoints(add func(severity, hint, format string, args ...interface{})) {
+	if collisions := t.Ring().Collisions(); collisions > 0 {
+		add(SEVERITY_WARNING, "colliding virtual nodes are moved by probing, keep the node order stable or use ring_bits 64",
+			"%d virtual node hash collisions resolved by probing", collisions)
+	}
+}
@@ -0,0 +1,78 @@
+package twemproxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	DEFAULT_LISTEN = "127.0.0.1:22121"
+	DEFAULT_HASH   = "md5"
+)
+
+// Pool 对应 nutcracker.yml 里的一个 server pool, distribution 固定为 ketama.
+// twemproxy 按自己的 ketama 建环 (server 名字取 HostName, 没有时是 host, 端口不是 11211 时是 host:port, 每个名字 "name-i" 做 MD5),
+// 与 Consistent 的环 (默认 crc32) 不同, 同一个 key 在两边可能落在不同节点, 只有节点集合和份额一致
+type Pool struct {
+	Name           string
+	Listen         string
+	Hash           string
+	Redis          bool
+	AutoEjectHosts bool
+	Ring           *consistent.Consistent
+}
+
+func NewPool(name string, ring *consistent.Consistent) *Pool {
+	return &Pool{
+		Name:   name,
+		Listen: DEFAULT_LISTEN,
+		Hash:   DEFAULT_HASH,
+		Ring:   ring,
+	}
+}
+
+func (p *Pool) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s:\n", p.Name)
+	fmt.Fprintf(&buf, "  listen: %s\n", p.Listen)
+	fmt.Fprintf(&buf, "  hash: %s\n", p.Hash)
+	buf.WriteString("  distribution: ketama\n")
+	fmt.Fprintf(&buf, "  auto_eject_hosts: %t\n", p.AutoEjectHosts)
+	fmt.Fprintf(&buf, "  redis: %t\n", p.Redis)
+	buf.WriteString("  servers:\n")
+
+	// weight 是节点的虚拟节点数 (ReplicaCount), 份额与环上一致; twemproxy 没有 down,
+	// 虚拟节点数为 0 的节点和 draining、MarkDown 的节点直接不写
+	for _, node := range p.Ring.Members() {
+		replicas := p.Ring.ReplicaCount(node)
+		if replicas <= 0 || p.Ring.IsDraining(node.Id) || p.Ring.IsDown(node.Id) {
+			continue
+		}
+		fmt.Fprintf(&buf, "   - %s:%d:%d", node.Ip, node.Port, replicas)
+		if node.HostName != "" {
+			fmt.Fprintf(&buf, " %s", node.HostName)
+		}
+		buf.WriteString("\n")
+	}
+
+	return buf.WriteTo(w)
+}
+
+func Render(pools ...*Pool) []byte {
+	var buf bytes.Buffer
+
+	for _, p := range pools {
+		p.WriteTo(&buf)
+	}
+
+	return buf.Bytes()
+}
+
+func WriteFile(path string, pools ...*Pool) error {
+	return os.WriteFile(path, Render(pools...), 0644)
+}
@@ -0,0 +1,402 @@
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+const (
+	SNAPSHOT_FILE = "snapshot.json"
+	LOG_FILE      = "wal.log"
+
+	DEFAULT_COMPACT_INTERVAL = time.Minute
+	DEFAULT_COMPACT_RECORDS  = 1000
+)
+
+const (
+	OP_ADD      = "add"
+	OP_REMOVE   = "remove"
+	OP_WEIGHT   = "weight"
+	OP_CAPACITY = "capacity"
+	OP_REPLICAS = "replicas"
+	OP_DRAIN    = "drain"
+	OP_UNDRAIN  = "undrain"
+	OP_COMPLETE = "complete"
+	OP_PIN      = "pin"
+	OP_UNPIN    = "unpin"
+)
+
+var ErrClosed = errors.New("wal: log is closed")
+
+// record 是日志里的一行 JSON, 只用到与 Op 对应的字段
+type record struct {
+	Op       string           `json:"op"`
+	Node     *consistent.Node `json:"node,omitempty"`
+	Id       int              `json:"id,omitempty"`
+	Weight   int              `json:"weight,omitempty"`
+	Capacity float64          `json:"capacity,omitempty"`
+	Replicas int              `json:"replicas,omitempty"`
+	Key      string           `json:"key,omitempty"`
+}
+
+func (r record) apply(ring *consistent.Consistent) bool {
+	switch r.Op {
+	case OP_ADD:
+		return r.Node != nil && ring.Add(r.Node)
+	case OP_REMOVE:
+		return ring.RemoveByID(r.Id)
+	case OP_WEIGHT:
+		return ring.UpdateWeight(r.Id, r.Weight)
+	case OP_CAPACITY:
+		return ring.UpdateCapacity(r.Id, r.Capacity)
+	case OP_REPLICAS:
+		return ring.UpdateReplicas(r.Id, r.Replicas)
+	case OP_DRAIN:
+		return ring.Drain(r.Id)
+	case OP_UNDRAIN:
+		return ring.Undrain(r.Id)
+	case OP_COMPLETE:
+		return ring.CompleteDrain(r.Id)
+	case OP_PIN:
+		return ring.Pin(r.Key, r.Id)
+	case OP_UNPIN:
+		return ring.Unpin(r.Key)
+	}
+	return false
+}
+
+// Recover 用 dir 里的快照和之后的日志重建环, 都不存在时返回按 opts 构造的空环.
+// 日志最后一行不完整 (写到一半时进程退出) 时忽略这一行, 中间的行损坏时返回错误
+func Recover(dir string, opts ...consistent.Option) (*consistent.Consistent, error) {
+	ring, _, _, err := replay(dir, opts...)
+	return ring, err
+}
+
+// replay 返回日志里有效部分的长度和记录数, 之后从这里继续追加; 恢复的结果输出到环的 Logger
+func replay(dir string, opts ...consistent.Option) (*consistent.Consistent, int64, int, error) {
+	ring := consistent.NewConsistent(opts...)
+	logger := ring.Logger()
+
+	data, err := os.ReadFile(filepath.Join(dir, SNAPSHOT_FILE))
+	snapshot := err == nil
+	if err == nil {
+		if err := ring.Restore(data); err != nil {
+			return nil, 0, 0, fmt.Errorf("wal: restore snapshot: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, 0, 0, err
+	}
+
+	f, err := os.Open(filepath.Join(dir, LOG_FILE))
+	if os.IsNotExist(err) {
+		logger.Info("wal recovered", "dir", dir, "snapshot", snapshot, "records", 0, "version", ring.Version())
+		return ring, 0, 0, nil
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	valid := int64(0)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// 没有换行结尾的是写了一半的记录
+			if len(line) > 0 {
+				logger.Warn("wal torn record ignored", "dir", dir, "line", n)
+			}
+			logger.Info("wal recovered", "dir", dir, "snapshot", snapshot, "records", n-1, "version", ring.Version())
+			return ring, valid, n - 1, nil
+		}
+		if err != nil {
+			return nil, 0, 0, err
+		}
+
+		rec := record{}
+		if err := json.Unmarshal(bytes.TrimSpace(line), &rec); err != nil {
+			if _, err := r.Peek(1); err == io.EOF {
+				logger.Warn("wal torn record ignored", "dir", dir, "line", n)
+				logger.Info("wal recovered", "dir", dir, "snapshot", snapshot, "records", n-1, "version", ring.Version())
+				return ring, valid, n - 1, nil
+			}
+			return nil, 0, 0, fmt.Errorf("wal: %s line %d: %w", LOG_FILE, n, err)
+		}
+		rec.apply(ring)
+		valid += int64(len(line))
+	}
+}
+
+// Log 在环的每次修改之前把修改写进 dir 下的日志并 fsync, 返回时修改已经持久化; 进程重启后用 Open 恢复.
+// 日志记录达到 CompactRecords 条或者 Run 的定时器到期时, 把当前的环写成快照并清空日志.
+// 环不对外暴露, 所有修改都只能通过 Log 的方法, 这样日志总是与环一致; MarkDown 这类运行时状态不写进日志
+type Log struct {
+	ring *consistent.Consistent
+	// mu 串行化写日志和修改环
+	mu      sync.Mutex
+	dir     string
+	f       *os.File
+	records int
+	// offset 是最后一条完整记录的末尾, 写失败时截回这里
+	offset int64
+	closed bool
+	// CompactRecords 为 0 时只由 Run 定时压缩
+	CompactRecords int
+	// NoSync 为 true 时不 fsync, 只用于测试和可以丢失最近修改的场景
+	NoSync bool
+}
+
+// Open 从 dir 恢复环并打开日志, dir 不存在时创建; opts 只在没有快照时生效, 有快照时使用快照里的参数
+func Open(dir string, opts ...consistent.Option) (*Log, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	ring, valid, records, err := replay(dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, LOG_FILE), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	// 截掉写了一半的最后一行, 之后的记录接在有效部分后面
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Log{ring: ring, dir: dir, f: f, records: records, offset: valid, CompactRecords: DEFAULT_COMPACT_RECORDS}, nil
+}
+
+// write 在 mu 内调用; 写入或 fsync 失败时截掉这次写的部分, 之后的记录不会接在半行后面
+func (l *Log) write(rec record) error {
+	if l.closed {
+		return ErrClosed
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := l.f.Write(data); err != nil {
+		return l.rewind(err)
+	}
+	if !l.NoSync {
+		if err := l.f.Sync(); err != nil {
+			return l.rewind(err)
+		}
+	}
+	l.offset += int64(len(data))
+	l.records++
+	return nil
+}
+
+// rewind 把日志截回 offset, 截断也失败时一起返回
+func (l *Log) rewind(err error) error {
+	if terr := l.f.Truncate(l.offset); terr != nil {
+		return errors.Join(err, terr)
+	}
+	if _, serr := l.f.Seek(l.offset, io.SeekStart); serr != nil {
+		return errors.Join(err, serr)
+	}
+	return err
+}
+
+// do 先写日志再修改环, 写日志失败时不修改环
+func (l *Log) do(rec record) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.write(rec); err != nil {
+		return false, err
+	}
+	ok := rec.apply(l.ring)
+	if l.CompactRecords > 0 && l.records >= l.CompactRecords {
+		if err := l.compact(); err != nil {
+			return ok, err
+		}
+	}
+	return ok, nil
+}
+
+// Get、GetN、Members、Version 和 Current 是只读的, 直接查环
+func (l *Log) Get(key string) (consistent.Node, error) {
+	return l.ring.Get(key)
+}
+
+func (l *Log) GetN(key string, n int) []consistent.Node {
+	return l.ring.GetN(key, n)
+}
+
+func (l *Log) Members() []consistent.Node {
+	return l.ring.Members()
+}
+
+func (l *Log) Version() uint64 {
+	return l.ring.Version()
+}
+
+// Current 返回当前版本的只读视图
+func (l *Log) Current() *consistent.View {
+	return l.ring.Current()
+}
+
+func (l *Log) Add(node *consistent.Node) (bool, error) {
+	n := *node
+	return l.do(record{Op: OP_ADD, Node: &n})
+}
+
+func (l *Log) Remove(node *consistent.Node) (bool, error) {
+	return l.do(record{Op: OP_REMOVE, Id: node.Id})
+}
+
+func (l *Log) RemoveByID(id int) (bool, error) {
+	return l.do(record{Op: OP_REMOVE, Id: id})
+}
+
+func (l *Log) UpdateWeight(id int, weight int) (bool, error) {
+	return l.do(record{Op: OP_WEIGHT, Id: id, Weight: weight})
+}
+
+func (l *Log) UpdateCapacity(id int, capacity float64) (bool, error) {
+	return l.do(record{Op: OP_CAPACITY, Id: id, Capacity: capacity})
+}
+
+func (l *Log) UpdateReplicas(id int, replicas int) (bool, error) {
+	return l.do(record{Op: OP_REPLICAS, Id: id, Replicas: replicas})
+}
+
+func (l *Log) Drain(id int) (bool, error) {
+	return l.do(record{Op: OP_DRAIN, Id: id})
+}
+
+func (l *Log) Undrain(id int) (bool, error) {
+	return l.do(record{Op: OP_UNDRAIN, Id: id})
+}
+
+func (l *Log) CompleteDrain(id int) (bool, error) {
+	return l.do(record{Op: OP_COMPLETE, Id: id})
+}
+
+func (l *Log) Pin(key string, id int) (bool, error) {
+	return l.do(record{Op: OP_PIN, Key: key, Id: id})
+}
+
+func (l *Log) Unpin(key string) (bool, error) {
+	return l.do(record{Op: OP_UNPIN, Key: key})
+}
+
+// Records 返回上次压缩之后写入的记录数
+func (l *Log) Records() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.records
+}
+
+// Compact 把当前的环写成快照并清空日志. 快照先写到临时文件再 rename,
+// 在 rename 之后、清空日志之前退出时, 恢复时重放的记录在快照上都是重复的修改, 不会改变结果
+func (l *Log) Compact() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	return l.compact()
+}
+
+func (l *Log) compact() error {
+	data, err := l.ring.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(l.dir, ".snapshot-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(l.dir, SNAPSHOT_FILE)); err != nil {
+		return err
+	}
+
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	records := l.records
+	l.records = 0
+	l.offset = 0
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	l.ring.Logger().Info("wal compacted", "dir", l.dir, "records", records, "version", l.ring.Version())
+	return nil
+}
+
+// Run 每 interval 在有新记录时压缩一次, 直到 ctx 结束; interval <= 0 时使用 DEFAULT_COMPACT_INTERVAL
+func (l *Log) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DEFAULT_COMPACT_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if l.Records() == 0 {
+				continue
+			}
+			if err := l.Compact(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Close 关闭日志文件, 之后的修改返回 ErrClosed; 环本身仍然可以读
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	return l.f.Close()
+}
@@ -0,0 +1,128 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/axiusilihao/geek_homework/homework_5/consistent"
+)
+
+func openTest(t *testing.T, dir string) *Log {
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.NoSync = true
+	return l
+}
+
+func addNodes(t *testing.T, l *Log, from, to int) {
+	for i := from; i <= to; i++ {
+		if _, err := l.Add(consistent.NewNode(i, "10.0.0."+strconv.Itoa(i), 8000, "", 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func appendLog(t *testing.T, dir, data string) {
+	f, err := os.OpenFile(filepath.Join(dir, LOG_FILE), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenTornTail(t *testing.T) {
+	dir := t.TempDir()
+	l := openTest(t, dir)
+	addNodes(t, l, 1, 3)
+	l.Close()
+
+	// 进程在写最后一条记录时退出
+	appendLog(t, dir, `{"op":"add","node":{"Id":9`)
+
+	l = openTest(t, dir)
+	if n := len(l.Members()); n != 3 {
+		t.Fatalf("recovered %d members, want 3", n)
+	}
+	if n := l.Records(); n != 3 {
+		t.Fatalf("Records after Open is %d, want 3", n)
+	}
+
+	// 新的记录接在有效部分后面, 不会和半行拼在一起
+	addNodes(t, l, 4, 4)
+	l.Close()
+
+	ring, err := Recover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ring.Members()); n != 4 {
+		t.Fatalf("recovered %d members, want 4", n)
+	}
+}
+
+func TestRecoverCorruptMiddle(t *testing.T) {
+	dir := t.TempDir()
+	l := openTest(t, dir)
+	addNodes(t, l, 1, 2)
+	l.Close()
+
+	// 把第一条记录改坏, 后面还有完整的记录, 不是写了一半
+	path := filepath.Join(dir, LOG_FILE)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[0] = 'x'
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Recover(dir); err == nil {
+		t.Fatal("Recover succeeded with a corrupt record in the middle of the log")
+	}
+	if _, err := Open(dir); err == nil {
+		t.Fatal("Open succeeded with a corrupt record in the middle of the log")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	l := openTest(t, dir)
+	l.CompactRecords = 3
+	addNodes(t, l, 1, 3)
+
+	if n := l.Records(); n != 0 {
+		t.Fatalf("Records after compaction is %d, want 0", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, SNAPSHOT_FILE)); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, LOG_FILE)); err != nil || fi.Size() != 0 {
+		t.Fatalf("log after compaction: %v, %v", fi, err)
+	}
+
+	addNodes(t, l, 4, 4)
+	if _, err := l.Drain(2); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	l = openTest(t, dir)
+	defer l.Close()
+	if n := len(l.Members()); n != 4 {
+		t.Fatalf("recovered %d members, want 4", n)
+	}
+	if n := l.Records(); n != 2 {
+		t.Fatalf("Records after Open is %d, want 2", n)
+	}
+	if !l.ring.IsDraining(2) {
+		t.Fatal("node 2 is not draining after recovery")
+	}
+}